@@ -39,6 +39,8 @@ const (
 	MimetypeDataWithValidator = "data/validator"
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
+	MimetypeAuthority         = "application/x-authority-header"
+	MimetypeAuthorityCommit   = "application/x-authority-commit"
 	MimetypeTextPlain         = "text/plain"
 )
 
@@ -0,0 +1,207 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package gasreport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+var (
+	callerAddr = common.HexToAddress("0xaa")
+	calleeAddr = common.HexToAddress("0xbb")
+
+	// Caller loads slot 0, calls the callee and loads slot 0 again
+	callerCode = common.FromHex("60005450" + "6000600060006000600060bb5af150" + "60005450" + "00")
+	// Callee stores 1 in slot 0
+	calleeCode = common.FromHex("600160005500")
+
+	// Compiler output of the contracts, mapping the loads to the load function
+	// and the call to the callB function of Caller
+	combinedJSONOutput = `{
+	"contracts": {
+		"A.sol:Caller": {
+			"bin-runtime": "600054506000600060006000600060bb5af1506000545000",
+			"srcmap-runtime": "12:5:0;;;45:5;;;;;;;;;12:5;;;0:100",
+			"hashes": {"run()": "c0406226"}
+		},
+		"A.sol:Callee": {
+			"bin-runtime": "600160005500"
+		}
+	},
+	"sources": {
+		"A.sol": {
+			"AST": {
+				"nodeType": "SourceUnit",
+				"src": "0:200:0",
+				"nodes": [{
+					"nodeType": "ContractDefinition",
+					"name": "Caller",
+					"src": "0:100:0",
+					"nodes": [
+						{"nodeType": "FunctionDefinition", "name": "load", "kind": "function", "src": "10:20:0"},
+						{"nodeType": "FunctionDefinition", "name": "callB", "kind": "function", "src": "40:20:0"}
+					]
+				}]
+			}
+		}
+	}
+}`
+)
+
+func runProfiler(t *testing.T, contracts *Contracts, caller, callee []byte, input []byte) *Report {
+	t.Helper()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(callerAddr, caller)
+	statedb.SetCode(calleeAddr, callee)
+
+	profiler := NewProfiler(contracts)
+	cfg := &runtime.Config{
+		State:     statedb,
+		GasLimit:  1000000,
+		EVMConfig: vm.Config{Tracer: profiler},
+	}
+	if _, _, err := runtime.Call(callerAddr, input, cfg); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	return profiler.Report()
+}
+
+func TestParseSourceMap(t *testing.T) {
+	entries, err := parseSourceMap("1:2:0;;3;:4:1;5:6:-1")
+	if err != nil {
+		t.Fatalf("failed to parse source map: %v", err)
+	}
+	want := []srcEntry{{1, 2, 0}, {1, 2, 0}, {3, 2, 0}, {3, 4, 1}, {5, 6, -1}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries mismatch: have %v, want %v", entries, want)
+	}
+	if _, err := parseSourceMap("1:x:0"); err == nil {
+		t.Error("expected error for invalid entry")
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "combined.json")
+	if err := os.WriteFile(file, []byte(combinedJSONOutput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	contracts, err := LoadContracts(file)
+	if err != nil {
+		t.Fatalf("failed to load contracts: %v", err)
+	}
+	report := runProfiler(t, contracts, callerCode, calleeCode, common.FromHex("c0406226"))
+
+	// Cold account access to the callee, then a cold store from zero
+	var (
+		callee = uint64(3 + 3 + 22100)
+		load   = uint64(3+2100+2) + uint64(3+100+2)
+		callB  = uint64(6*3 + 2 + 2600 + 2)
+	)
+	if report.GasUsed != callee+load+callB {
+		t.Errorf("gas used mismatch: have %d, want %d", report.GasUsed, callee+load+callB)
+	}
+	wantFrames := []*Frame{
+		{Depth: 0, Type: "CALL", To: callerAddr, Function: "Caller.run()", GasUsed: report.GasUsed, SelfGas: load + callB},
+		{Depth: 1, Type: "CALL", To: calleeAddr, Function: "Callee.fallback", GasUsed: callee, SelfGas: callee},
+	}
+	if !reflect.DeepEqual(report.Frames, wantFrames) {
+		t.Errorf("frames mismatch")
+		for _, frame := range report.Frames {
+			t.Logf("have %+v", frame)
+		}
+	}
+	wantFuncs := []*Function{
+		{Name: "Callee.fallback", Gas: callee},
+		{Name: "Caller.callB", Gas: callB},
+		{Name: "Caller.load", Gas: load},
+	}
+	if !reflect.DeepEqual(report.Functions, wantFuncs) {
+		t.Errorf("functions mismatch")
+		for _, fn := range report.Functions {
+			t.Logf("have %+v", fn)
+		}
+	}
+	wantStorage := Storage{
+		SloadCold:  Access{Count: 1, Gas: 2100},
+		SloadWarm:  Access{Count: 1, Gas: 100},
+		SstoreCold: Access{Count: 1, Gas: 22100},
+	}
+	if report.Storage != wantStorage {
+		t.Errorf("storage mismatch: have %+v, want %+v", report.Storage, wantStorage)
+	}
+}
+
+func TestProfilerRevertedWarmth(t *testing.T) {
+	// Caller delegates to the callee and loads slot 0 afterwards
+	caller := common.FromHex("600060006000600060bb5af450" + "60005450" + "00")
+
+	tests := []struct {
+		callee string
+		want   Storage
+	}{
+		// Store into slot 0 and stop, warming the slot
+		{"600160005500", Storage{SstoreCold: Access{1, 22100}, SloadWarm: Access{1, 100}}},
+		// Store into slot 0 and revert, leaving the slot cold
+		{"600160005560006000fd", Storage{SstoreCold: Access{1, 22100}, SloadCold: Access{1, 2100}}},
+	}
+	for i, tt := range tests {
+		report := runProfiler(t, nil, caller, common.FromHex(tt.callee), nil)
+		if report.Storage != tt.want {
+			t.Errorf("test %d: storage mismatch: have %+v, want %+v", i, report.Storage, tt.want)
+		}
+		if have := report.Frames[0].Function; have != "fallback" {
+			t.Errorf("test %d: function mismatch: have %q, want %q", i, have, "fallback")
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := &Report{
+		GasUsed:   1000,
+		Functions: []*Function{{Name: "a", Gas: 600}, {Name: "b", Gas: 400}},
+		Storage:   Storage{SloadCold: Access{1, 2100}},
+	}
+	after := &Report{
+		GasUsed:   700,
+		Functions: []*Function{{Name: "a", Gas: 600}, {Name: "c", Gas: 100}},
+		Storage:   Storage{SloadWarm: Access{1, 100}},
+	}
+	want := []*Delta{
+		{Name: "total", Before: 1000, After: 700},
+		{Name: "b", Before: 400},
+		{Name: "c", After: 100},
+		{Name: "SLOAD cold", Before: 2100},
+		{Name: "SLOAD warm", After: 100},
+	}
+	if have := Diff(before, after); !reflect.DeepEqual(have, want) {
+		t.Errorf("diff mismatch")
+		for _, d := range have {
+			t.Logf("have %+v", d)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package gasreport implements a gas profiler for evm run, attributing the gas
+// used by an execution to call frames, functions and storage accesses.
+package gasreport
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Profiler is a vm.EVMLogger building the gas report of an execution.
+//
+// The gas used by an instruction is measured as the difference between the gas
+// available before it and before the next instruction of the same frame, minus
+// the gas used by the frames it created. Unlike the static cost reported to the
+// tracer, this accounts for the gas forwarded to and returned by calls.
+//
+// The EVM warms storage slots before the tracer sees the accessing instruction,
+// so the profiler tracks the EIP-2929 accessed slots itself, dropping the slots
+// warmed by reverted frames.
+type Profiler struct {
+	contracts   *Contracts
+	env         *vm.EVM
+	berlin      bool
+	precompiles map[common.Address]struct{}
+
+	report *Report
+	funcs  map[string]uint64
+	frames []*frame
+	warm   map[storageSlot]struct{}
+}
+
+// storageSlot identifies a storage slot of an account.
+type storageSlot struct {
+	addr common.Address
+	slot common.Hash
+}
+
+// frame is the profiling state of a call frame.
+type frame struct {
+	report   *Frame
+	gas      uint64        // Gas available when entering the frame
+	srcmap   *sourceMap    // Source map of the executing code, nil if unknown
+	children uint64        // Gas used by the child frames
+	stepped  bool          // Whether any instruction was executed
+	warmed   []storageSlot // Slots warmed by the frame and its successful children
+
+	// The last instruction, charged once its gas consumption is known
+	pending   bool
+	lastGas   uint64
+	lastFunc  string
+	lastChild uint64 // Gas used by the child frames of the last instruction
+	access    *Access
+}
+
+// NewProfiler creates a gas profiler, naming functions with the given compiler
+// output if non-nil.
+func NewProfiler(contracts *Contracts) *Profiler {
+	return &Profiler{contracts: contracts}
+}
+
+// Report returns the report of the last execution.
+func (p *Profiler) Report() *Report {
+	return p.report
+}
+
+func (p *Profiler) CaptureTxStart(gasLimit uint64) {}
+
+func (p *Profiler) CaptureTxEnd(restGas uint64) {}
+
+func (p *Profiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	rules := env.ChainConfig().Rules(env.Context.BlockNumber, env.Context.Random != nil, env.Context.Time)
+
+	// Start over, the profiler might be reused across executions
+	p.env = env
+	p.berlin = rules.IsBerlin
+	p.precompiles = make(map[common.Address]struct{})
+	for _, addr := range vm.ActivePrecompiles(rules) {
+		p.precompiles[addr] = struct{}{}
+	}
+	p.report = new(Report)
+	p.funcs = make(map[string]uint64)
+	p.frames = nil
+	p.warm = make(map[storageSlot]struct{})
+
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	p.enter(typ, to, input, gas)
+}
+
+func (p *Profiler) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	p.exit(gasUsed, err)
+	p.report.GasUsed = gasUsed
+
+	for name, gas := range p.funcs {
+		if gas > 0 {
+			p.report.Functions = append(p.report.Functions, &Function{Name: name, Gas: gas})
+		}
+	}
+	sort.Slice(p.report.Functions, func(i, j int) bool {
+		a, b := p.report.Functions[i], p.report.Functions[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		return a.Name < b.Name
+	})
+}
+
+func (p *Profiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	p.enter(typ, to, input, gas)
+}
+
+func (p *Profiler) CaptureExit(output []byte, gasUsed uint64, err error) {
+	p.exit(gasUsed, err)
+}
+
+func (p *Profiler) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if len(p.frames) == 0 {
+		return
+	}
+	f := p.frames[len(p.frames)-1]
+	p.charge(f, gas)
+
+	name, ok := p.contracts.function(f.srcmap, pc)
+	if !ok {
+		name = f.report.Function
+	}
+	f.stepped, f.pending = true, true
+	f.lastGas, f.lastFunc, f.access = gas, name, nil
+
+	if (op == vm.SLOAD || op == vm.SSTORE) && len(scope.Stack.Data()) > 0 {
+		var (
+			key  = storageSlot{scope.Contract.Address(), common.Hash(scope.Stack.Back(0).Bytes32())}
+			warm bool
+		)
+		if p.berlin {
+			if _, warm = p.warm[key]; !warm {
+				p.warm[key] = struct{}{}
+				f.warmed = append(f.warmed, key)
+			}
+		}
+		switch {
+		case op == vm.SLOAD && warm:
+			f.access = &p.report.Storage.SloadWarm
+		case op == vm.SLOAD:
+			f.access = &p.report.Storage.SloadCold
+		case warm:
+			f.access = &p.report.Storage.SstoreWarm
+		default:
+			f.access = &p.report.Storage.SstoreCold
+		}
+		f.access.Count++
+	}
+}
+
+func (p *Profiler) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// enter pushes a new call frame, naming it after the called function.
+func (p *Profiler) enter(typ vm.OpCode, to common.Address, input []byte, gas uint64) {
+	var (
+		create = typ == vm.CREATE || typ == vm.CREATE2
+		code   = input
+	)
+	if !create {
+		code = p.env.StateDB.GetCode(to)
+	}
+	contract, srcmap := p.contracts.lookup(code, create)
+
+	f := &frame{
+		gas:    gas,
+		srcmap: srcmap,
+		report: &Frame{
+			Depth:    len(p.frames),
+			Type:     typ.String(),
+			To:       to,
+			Function: p.frameFunction(contract, create, to, code, input),
+		},
+	}
+	p.report.Frames = append(p.report.Frames, f.report)
+	p.frames = append(p.frames, f)
+}
+
+// frameFunction names the function called by a call frame.
+func (p *Profiler) frameFunction(contract string, create bool, to common.Address, code []byte, input []byte) string {
+	var name string
+	switch {
+	case create:
+		name = "constructor"
+	case len(code) == 0:
+		if _, ok := p.precompiles[to]; ok {
+			return "precompile"
+		}
+		return "transfer"
+	case len(input) < 4:
+		name = "fallback"
+	default:
+		sel := [4]byte(input[:4])
+		if sig, ok := p.contracts.selector(sel); ok {
+			name = sig
+		} else {
+			name = fmt.Sprintf("%#x", sel)
+		}
+	}
+	if contract != "" {
+		name = contract + "." + name
+	}
+	return name
+}
+
+// exit pops the current call frame, charging its last instruction.
+func (p *Profiler) exit(gasUsed uint64, err error) {
+	if len(p.frames) == 0 {
+		return
+	}
+	f := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	var rest uint64
+	if gasUsed < f.gas {
+		rest = f.gas - gasUsed
+	}
+	p.charge(f, rest)
+
+	f.report.GasUsed = gasUsed
+	if gasUsed > f.children {
+		f.report.SelfGas = gasUsed - f.children
+	}
+	if err != nil {
+		f.report.Error = err.Error()
+		for _, key := range f.warmed {
+			delete(p.warm, key)
+		}
+		f.warmed = nil
+	}
+	// Frames without code, like precompiles, are charged to their own name
+	if !f.stepped {
+		p.funcs[f.report.Function] += f.report.SelfGas
+	}
+	if len(p.frames) > 0 {
+		parent := p.frames[len(p.frames)-1]
+		parent.children += gasUsed
+		parent.lastChild += gasUsed
+		parent.warmed = append(parent.warmed, f.warmed...)
+	}
+}
+
+// charge attributes the gas used by the last instruction of a frame, given the
+// gas available after it.
+func (p *Profiler) charge(f *frame, gas uint64) {
+	if !f.pending {
+		return
+	}
+	var used uint64
+	if f.lastGas > gas {
+		used = f.lastGas - gas
+	}
+	if used > f.lastChild {
+		used -= f.lastChild
+	} else {
+		used = 0
+	}
+	p.funcs[f.lastFunc] += used
+	if f.access != nil {
+		f.access.Gas += used
+	}
+	f.pending, f.lastChild = false, 0
+}
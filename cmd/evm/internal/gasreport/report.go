@@ -0,0 +1,188 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package gasreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Report is the gas consumption of a single execution, broken down by call
+// frames, functions and storage accesses. Refunds are not accounted for.
+type Report struct {
+	GasUsed   uint64      `json:"gasUsed"`
+	Frames    []*Frame    `json:"frames"`
+	Functions []*Function `json:"functions"`
+	Storage   Storage     `json:"storage"`
+}
+
+// Frame is the gas used by a call frame, listed in the order of entry.
+type Frame struct {
+	Depth    int            `json:"depth"`
+	Type     string         `json:"type"`
+	To       common.Address `json:"to"`
+	Function string         `json:"function"`
+	GasUsed  uint64         `json:"gasUsed"` // Gas used by the frame and its children
+	SelfGas  uint64         `json:"selfGas"` // Gas used by the frame itself
+	Error    string         `json:"error,omitempty"`
+}
+
+// Function is the gas used by the instructions of a function, excluding the
+// gas used by the call frames it created.
+type Function struct {
+	Name string `json:"name"`
+	Gas  uint64 `json:"gas"`
+}
+
+// Storage is the breakdown of storage accesses by their EIP-2929 access list
+// status. A slot is warm once accessed by a frame that didn't revert, accesses
+// before Berlin are all counted as cold.
+type Storage struct {
+	SloadCold  Access `json:"sloadCold"`
+	SloadWarm  Access `json:"sloadWarm"`
+	SstoreCold Access `json:"sstoreCold"`
+	SstoreWarm Access `json:"sstoreWarm"`
+}
+
+// Access counts the storage accesses of a kind and the gas they used.
+type Access struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// ReadReport loads a report written as JSON by an earlier run.
+func ReadReport(file string) (*Report, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	report := new(Report)
+	if err := json.Unmarshal(blob, report); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return report, nil
+}
+
+// WriteText writes the report in human readable form.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Gas used: %d\n\n", r.GasUsed)
+	fmt.Fprintln(tw, "Call frames:")
+	fmt.Fprintln(tw, "  FRAME\tTO\tFUNCTION\tGAS\tSELF\t")
+	for _, frame := range r.Frames {
+		name := frame.Function
+		if frame.Error != "" {
+			name += " (" + frame.Error + ")"
+		}
+		fmt.Fprintf(tw, "  %s%s\t%s\t%s\t%d\t%d\t\n", strings.Repeat("  ", frame.Depth), frame.Type, frame.To.Hex(), name, frame.GasUsed, frame.SelfGas)
+	}
+	fmt.Fprintln(tw, "\nFunctions:")
+	fmt.Fprintln(tw, "  FUNCTION\tGAS\t")
+	for _, fn := range r.Functions {
+		fmt.Fprintf(tw, "  %s\t%d\t\n", fn.Name, fn.Gas)
+	}
+	fmt.Fprintln(tw, "\nStorage:")
+	fmt.Fprintln(tw, "  ACCESS\tCOUNT\tGAS\t")
+	for _, access := range []struct {
+		name string
+		*Access
+	}{
+		{"SLOAD cold", &r.Storage.SloadCold},
+		{"SLOAD warm", &r.Storage.SloadWarm},
+		{"SSTORE cold", &r.Storage.SstoreCold},
+		{"SSTORE warm", &r.Storage.SstoreWarm},
+	} {
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t\n", access.name, access.Count, access.Gas)
+	}
+	return tw.Flush()
+}
+
+// Delta is the difference in gas used by an item between two reports.
+type Delta struct {
+	Name   string `json:"name"`
+	Before uint64 `json:"before"`
+	After  uint64 `json:"after"`
+}
+
+// Change returns the difference in gas, negative if gas was saved.
+func (d *Delta) Change() int64 {
+	return int64(d.After) - int64(d.Before)
+}
+
+// Diff compares the gas used by two reports, returning the total followed by
+// the changed functions and storage accesses, sorted by name.
+func Diff(before, after *Report) []*Delta {
+	deltas := []*Delta{{Name: "total", Before: before.GasUsed, After: after.GasUsed}}
+
+	funcs := make(map[string]*Delta)
+	for _, fn := range before.Functions {
+		funcs[fn.Name] = &Delta{Name: fn.Name, Before: fn.Gas}
+	}
+	for _, fn := range after.Functions {
+		if d, ok := funcs[fn.Name]; ok {
+			d.After = fn.Gas
+		} else {
+			funcs[fn.Name] = &Delta{Name: fn.Name, After: fn.Gas}
+		}
+	}
+	var changed []*Delta
+	for _, d := range funcs {
+		if d.Before != d.After {
+			changed = append(changed, d)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Name < changed[j].Name })
+	deltas = append(deltas, changed...)
+
+	for _, access := range []struct {
+		name          string
+		before, after Access
+	}{
+		{"SLOAD cold", before.Storage.SloadCold, after.Storage.SloadCold},
+		{"SLOAD warm", before.Storage.SloadWarm, after.Storage.SloadWarm},
+		{"SSTORE cold", before.Storage.SstoreCold, after.Storage.SstoreCold},
+		{"SSTORE warm", before.Storage.SstoreWarm, after.Storage.SstoreWarm},
+	} {
+		if access.before.Gas != access.after.Gas {
+			deltas = append(deltas, &Delta{Name: access.name, Before: access.before.Gas, After: access.after.Gas})
+		}
+	}
+	return deltas
+}
+
+// WriteDiff writes the difference between two reports in human readable form.
+func WriteDiff(w io.Writer, deltas []*Delta) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ITEM\tBEFORE\tAFTER\tCHANGE\t")
+	for _, d := range deltas {
+		change := d.Change()
+		var pct string
+		if d.Before != 0 {
+			pct = fmt.Sprintf(" (%+.2f%%)", float64(change)*100/float64(d.Before))
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%+d%s\t\n", d.Name, d.Before, d.After, change, pct)
+	}
+	return tw.Flush()
+}
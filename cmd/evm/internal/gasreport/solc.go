@@ -0,0 +1,323 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package gasreport
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// combinedJSON is the output of solc --combined-json. Only the fields used by
+// the gas report are decoded, all of them being optional.
+type combinedJSON struct {
+	Contracts map[string]struct {
+		Bin           string            `json:"bin"`
+		BinRuntime    string            `json:"bin-runtime"`
+		Srcmap        string            `json:"srcmap"`
+		SrcmapRuntime string            `json:"srcmap-runtime"`
+		Hashes        map[string]string `json:"hashes"`
+	} `json:"contracts"`
+	Sources map[string]struct {
+		AST        json.RawMessage `json:"AST"`
+		CompactAST json.RawMessage `json:"ast"`
+	} `json:"sources"`
+}
+
+// Contracts is the compiler output of a set of contracts, used to name the
+// functions executed by the EVM.
+type Contracts struct {
+	contracts []*contract
+	functions []*astFunction
+	selectors map[[4]byte]string
+}
+
+// contract is a single compiled contract.
+type contract struct {
+	name    string
+	deploy  *sourceMap
+	runtime *sourceMap
+}
+
+// sourceMap maps the instructions of a piece of code to source ranges.
+type sourceMap struct {
+	code    []byte
+	entries []srcEntry
+	index   []int // Instruction index of each code offset, computed lazily
+}
+
+// srcEntry is a single decompressed source mapping.
+type srcEntry struct {
+	start, length, file int
+}
+
+// astFunction is a function or modifier definition found in the AST.
+type astFunction struct {
+	name                string
+	start, length, file int
+}
+
+// LoadContracts reads the output of solc --combined-json from the given files.
+// The function selectors are taken from the hashes, and the source level
+// functions from the source maps and ASTs when present.
+func LoadContracts(files ...string) (*Contracts, error) {
+	c := &Contracts{selectors: make(map[[4]byte]string)}
+	for _, file := range files {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var out combinedJSON
+		if err := json.Unmarshal(blob, &out); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if err := c.add(&out); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	return c, nil
+}
+
+// add indexes the contracts of a compiler output.
+func (c *Contracts) add(out *combinedJSON) error {
+	ids := make([]string, 0, len(out.Contracts))
+	for id := range out.Contracts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		compiled := out.Contracts[id]
+		// Contracts are identified as path:Name
+		name := id
+		if i := strings.LastIndexByte(id, ':'); i >= 0 {
+			name = id[i+1:]
+		}
+		for sig, hash := range compiled.Hashes {
+			sel, err := hex.DecodeString(hash)
+			if err != nil || len(sel) != 4 {
+				return fmt.Errorf("contract %s: invalid selector %q", name, hash)
+			}
+			c.selectors[[4]byte(sel)] = sig
+		}
+		deploy, err := newSourceMap(compiled.Bin, compiled.Srcmap)
+		if err != nil {
+			return fmt.Errorf("contract %s: %v", name, err)
+		}
+		runtime, err := newSourceMap(compiled.BinRuntime, compiled.SrcmapRuntime)
+		if err != nil {
+			return fmt.Errorf("contract %s: %v", name, err)
+		}
+		if deploy != nil || runtime != nil {
+			c.contracts = append(c.contracts, &contract{name: name, deploy: deploy, runtime: runtime})
+		}
+	}
+	for _, source := range out.Sources {
+		ast := source.AST
+		if len(ast) == 0 {
+			ast = source.CompactAST
+		}
+		if len(ast) == 0 {
+			continue
+		}
+		var root interface{}
+		if err := json.Unmarshal(ast, &root); err != nil {
+			return fmt.Errorf("invalid AST: %v", err)
+		}
+		c.functions = append(c.functions, collectFunctions(root, "")...)
+	}
+	return nil
+}
+
+// newSourceMap decodes the bytecode and source map of a contract. Unlinked code
+// containing library placeholders is skipped, it can't match deployed code.
+func newSourceMap(bin string, srcmap string) (*sourceMap, error) {
+	if bin == "" || strings.Contains(bin, "__") {
+		return nil, nil
+	}
+	code, err := hex.DecodeString(strings.TrimPrefix(bin, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode: %v", err)
+	}
+	entries, err := parseSourceMap(srcmap)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceMap{code: code, entries: entries}, nil
+}
+
+// parseSourceMap decompresses a solc source map, where every instruction is
+// described by s:l:f:j:m and empty fields repeat the previous instruction.
+func parseSourceMap(srcmap string) ([]srcEntry, error) {
+	if srcmap == "" {
+		return nil, nil
+	}
+	var (
+		items   = strings.Split(srcmap, ";")
+		entries = make([]srcEntry, len(items))
+		last    = srcEntry{file: -1}
+	)
+	for i, item := range items {
+		fields := strings.Split(item, ":")
+		for j, dst := range []*int{&last.start, &last.length, &last.file} {
+			if j >= len(fields) || fields[j] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(fields[j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid source map entry %d: %q", i, item)
+			}
+			*dst = n
+		}
+		entries[i] = last
+	}
+	return entries, nil
+}
+
+// collectFunctions walks a compact solc AST, gathering the function and
+// modifier definitions along with their source ranges.
+func collectFunctions(node interface{}, scope string) []*astFunction {
+	var funcs []*astFunction
+	switch node := node.(type) {
+	case []interface{}:
+		for _, child := range node {
+			funcs = append(funcs, collectFunctions(child, scope)...)
+		}
+	case map[string]interface{}:
+		kind, _ := node["nodeType"].(string)
+		name, _ := node["name"].(string)
+		switch kind {
+		case "ContractDefinition":
+			scope = name
+		case "FunctionDefinition", "ModifierDefinition":
+			if name == "" {
+				// Constructors, fallback and receive functions are unnamed
+				name, _ = node["kind"].(string)
+			}
+			if scope != "" {
+				name = scope + "." + name
+			}
+			if src, ok := node["src"].(string); ok {
+				if fn, err := parseSrc(src); err == nil {
+					fn.name = name
+					funcs = append(funcs, fn)
+				}
+			}
+		}
+		for _, child := range node {
+			funcs = append(funcs, collectFunctions(child, scope)...)
+		}
+	}
+	return funcs
+}
+
+// parseSrc decodes an AST source range of the form start:length:file.
+func parseSrc(src string) (*astFunction, error) {
+	fields := strings.Split(src, ":")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid source range %q", src)
+	}
+	var nums [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source range %q", src)
+		}
+		nums[i] = n
+	}
+	return &astFunction{start: nums[0], length: nums[1], file: nums[2]}, nil
+}
+
+// lookup finds the contract whose code is executing. Init code is matched by
+// prefix since it is followed by the constructor arguments.
+func (c *Contracts) lookup(code []byte, create bool) (string, *sourceMap) {
+	if c == nil {
+		return "", nil
+	}
+	for _, contract := range c.contracts {
+		if create {
+			if contract.deploy != nil && bytes.HasPrefix(code, contract.deploy.code) {
+				return contract.name, contract.deploy
+			}
+		} else if contract.runtime != nil && bytes.Equal(code, contract.runtime.code) {
+			return contract.name, contract.runtime
+		}
+	}
+	return "", nil
+}
+
+// selector returns the signature of a function selector, if known.
+func (c *Contracts) selector(sel [4]byte) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	sig, ok := c.selectors[sel]
+	return sig, ok
+}
+
+// function returns the innermost function containing the source of the
+// instruction at the given code offset.
+func (c *Contracts) function(m *sourceMap, pc uint64) (string, bool) {
+	if m == nil || len(m.entries) == 0 {
+		return "", false
+	}
+	if m.index == nil {
+		m.index = instructionIndex(m.code)
+	}
+	if pc >= uint64(len(m.index)) || m.index[pc] >= len(m.entries) {
+		return "", false
+	}
+	src := m.entries[m.index[pc]]
+
+	var best *astFunction
+	for _, fn := range c.functions {
+		if fn.file != src.file || fn.start > src.start || fn.start+fn.length < src.start+src.length {
+			continue
+		}
+		if best == nil || fn.length < best.length || (fn.length == best.length && fn.name < best.name) {
+			best = fn
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.name, true
+}
+
+// instructionIndex maps every code offset to the index of the instruction it
+// belongs to, push data belonging to its push instruction.
+func instructionIndex(code []byte) []int {
+	index := make([]int, len(code))
+	for pc, n := 0, 0; pc < len(code); n++ {
+		op := vm.OpCode(code[pc])
+		size := 1
+		if op >= vm.PUSH1 && op <= vm.PUSH32 {
+			size += int(op - vm.PUSH1 + 1)
+		}
+		for i := pc; i < pc+size && i < len(code); i++ {
+			index[i] = n
+		}
+		pc += size
+	}
+	return index
+}
@@ -308,18 +308,21 @@ func readInput(ctx *cli.Context) (*bbInput, error) {
 	return inputData, nil
 }
 
+// blockInfo is the output of a built block.
+type blockInfo struct {
+	Rlp  hexutil.Bytes `json:"rlp"`
+	Hash common.Hash   `json:"hash"`
+}
+
+func newBlockInfo(block *types.Block) *blockInfo {
+	raw, _ := rlp.EncodeToBytes(block)
+	return &blockInfo{Rlp: raw, Hash: block.Hash()}
+}
+
 // dispatchBlock writes the output data to either stderr or stdout, or to the specified
 // files
 func dispatchBlock(ctx *cli.Context, baseDir string, block *types.Block) error {
-	raw, _ := rlp.EncodeToBytes(block)
-	type blockInfo struct {
-		Rlp  hexutil.Bytes `json:"rlp"`
-		Hash common.Hash   `json:"hash"`
-	}
-	enc := blockInfo{
-		Rlp:  raw,
-		Hash: block.Hash(),
-	}
+	enc := newBlockInfo(block)
 	b, err := json.MarshalIndent(enc, "", "  ")
 	if err != nil {
 		return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
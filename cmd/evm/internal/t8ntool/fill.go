@@ -0,0 +1,644 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FillerSpec describes a blockchain test to fill: the genesis block, the blocks
+// to build on top of it and the post-state conditions to check, together with
+// the forks to generate fixtures for.
+type FillerSpec struct {
+	Forks   []string          `json:"forks"`
+	Genesis *fillerGenesis    `json:"genesis,omitempty"`
+	Pre     core.GenesisAlloc `json:"pre"`
+	Blocks  []*fillerBlock    `json:"blocks"`
+	Expect  []*fillerExpect   `json:"expect,omitempty"`
+}
+
+// fillerGenesis holds the header fields of the genesis block. Unset fields take
+// the defaults of the fork.
+type fillerGenesis struct {
+	Coinbase   common.Address        `json:"coinbase"`
+	GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+	Timestamp  math.HexOrDecimal64   `json:"timestamp"`
+	Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+	BaseFee    *math.HexOrDecimal256 `json:"baseFeePerGas"`
+	ExtraData  hexutil.Bytes         `json:"extraData"`
+}
+
+// fillerBlock is a block of the test. The header is derived from the parent
+// block, with timestamps advancing by 10 seconds.
+type fillerBlock struct {
+	Coinbase         *common.Address     `json:"coinbase"`
+	ParentBeaconRoot *common.Hash        `json:"parentBeaconBlockRoot"`
+	Transactions     []*fillerTx         `json:"transactions"`
+	Withdrawals      []*types.Withdrawal `json:"withdrawals"`
+}
+
+// fillerTx is a transaction of a block, signed with the secret key when filling.
+// Transactions setting maxFeePerGas are dynamic fee transactions, those with an
+// access list access list transactions and the others legacy ones. The nonce
+// defaults to the next nonce of the sender and the gas price of legacy and
+// access list transactions to the base fee of the block.
+type fillerTx struct {
+	SecretKey            common.Hash           `json:"secretKey"`
+	Nonce                *math.HexOrDecimal64  `json:"nonce"`
+	To                   *common.Address       `json:"to"`
+	Value                *math.HexOrDecimal256 `json:"value"`
+	Data                 hexutil.Bytes         `json:"data"`
+	GasLimit             math.HexOrDecimal64   `json:"gasLimit"`
+	GasPrice             *math.HexOrDecimal256 `json:"gasPrice"`
+	MaxFeePerGas         *math.HexOrDecimal256 `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *math.HexOrDecimal256 `json:"maxPriorityFeePerGas"`
+	AccessList           *types.AccessList     `json:"accessList"`
+}
+
+// fillerExpect is a set of post-state conditions, checked for the given forks
+// or all forks of the test if none are listed.
+type fillerExpect struct {
+	Forks  []string                          `json:"forks"`
+	Result map[common.Address]*fillerAccount `json:"result"`
+}
+
+// fillerAccount holds the expected fields of an account, unset fields are not
+// checked. Storage slots not listed are not checked either.
+type fillerAccount struct {
+	Balance *math.HexOrDecimal256            `json:"balance"`
+	Nonce   *math.HexOrDecimal64             `json:"nonce"`
+	Code    *hexutil.Bytes                   `json:"code"`
+	Storage map[string]*math.HexOrDecimal256 `json:"storage"`
+}
+
+// Fixture is a filled blockchain test, in the format of the consensus tests.
+type Fixture struct {
+	Blocks     []*fixtureBlock       `json:"blocks"`
+	Genesis    *fixtureHeader        `json:"genesisBlockHeader"`
+	GenesisRLP hexutil.Bytes         `json:"genesisRLP"`
+	Pre        core.GenesisAlloc     `json:"pre"`
+	Post       core.GenesisAlloc     `json:"postState"`
+	BestBlock  common.UnprefixedHash `json:"lastblockhash"`
+	Network    string                `json:"network"`
+	SealEngine string                `json:"sealEngine"`
+}
+
+type fixtureBlock struct {
+	Header       *fixtureHeader       `json:"blockHeader"`
+	Rlp          hexutil.Bytes        `json:"rlp"`
+	Transactions []*types.Transaction `json:"transactions"`
+	UncleHeaders []*fixtureHeader     `json:"uncleHeaders"`
+	Withdrawals  []*types.Withdrawal  `json:"withdrawals,omitempty"`
+}
+
+type fixtureHeader struct {
+	Bloom                 types.Bloom           `json:"bloom"`
+	Coinbase              common.Address        `json:"coinbase"`
+	MixHash               common.Hash           `json:"mixHash"`
+	Nonce                 types.BlockNonce      `json:"nonce"`
+	Number                *math.HexOrDecimal256 `json:"number"`
+	Hash                  common.Hash           `json:"hash"`
+	ParentHash            common.Hash           `json:"parentHash"`
+	ReceiptTrie           common.Hash           `json:"receiptTrie"`
+	StateRoot             common.Hash           `json:"stateRoot"`
+	TransactionsTrie      common.Hash           `json:"transactionsTrie"`
+	UncleHash             common.Hash           `json:"uncleHash"`
+	ExtraData             hexutil.Bytes         `json:"extraData"`
+	Difficulty            *math.HexOrDecimal256 `json:"difficulty"`
+	GasLimit              math.HexOrDecimal64   `json:"gasLimit"`
+	GasUsed               math.HexOrDecimal64   `json:"gasUsed"`
+	Timestamp             math.HexOrDecimal64   `json:"timestamp"`
+	BaseFeePerGas         *math.HexOrDecimal256 `json:"baseFeePerGas,omitempty"`
+	WithdrawalsRoot       *common.Hash          `json:"withdrawalsRoot,omitempty"`
+	BlobGasUsed           *math.HexOrDecimal64  `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas         *math.HexOrDecimal64  `json:"excessBlobGas,omitempty"`
+	ParentBeaconBlockRoot *common.Hash          `json:"parentBeaconBlockRoot,omitempty"`
+}
+
+func newFixtureHeader(h *types.Header) *fixtureHeader {
+	return &fixtureHeader{
+		Bloom:                 h.Bloom,
+		Coinbase:              h.Coinbase,
+		MixHash:               h.MixDigest,
+		Nonce:                 h.Nonce,
+		Number:                (*math.HexOrDecimal256)(h.Number),
+		Hash:                  h.Hash(),
+		ParentHash:            h.ParentHash,
+		ReceiptTrie:           h.ReceiptHash,
+		StateRoot:             h.Root,
+		TransactionsTrie:      h.TxHash,
+		UncleHash:             h.UncleHash,
+		ExtraData:             h.Extra,
+		Difficulty:            (*math.HexOrDecimal256)(h.Difficulty),
+		GasLimit:              math.HexOrDecimal64(h.GasLimit),
+		GasUsed:               math.HexOrDecimal64(h.GasUsed),
+		Timestamp:             math.HexOrDecimal64(h.Time),
+		BaseFeePerGas:         (*math.HexOrDecimal256)(h.BaseFee),
+		WithdrawalsRoot:       h.WithdrawalsHash,
+		BlobGasUsed:           (*math.HexOrDecimal64)(h.BlobGasUsed),
+		ExcessBlobGas:         (*math.HexOrDecimal64)(h.ExcessBlobGas),
+		ParentBeaconBlockRoot: h.ParentBeaconRoot,
+	}
+}
+
+// Fill is the action of the fill command, generating the blockchain tests of
+// every fork requested by the filler specs.
+func Fill(ctx *cli.Context) error {
+	file := ctx.Args().First()
+	if file == "" {
+		return NewError(ErrorConfig, errors.New("filler file argument required"))
+	}
+	specs, err := ReadFillerSpecs(file)
+	if err != nil {
+		return err
+	}
+	fixtures := make(map[string]*Fixture)
+	for _, name := range sortedSpecNames(specs) {
+		filled, err := FillTest(name, specs[name])
+		if err != nil {
+			return err
+		}
+		for fixtureName, fixture := range filled {
+			fixtures[fixtureName] = fixture
+		}
+	}
+	out := ctx.String(OutputFixturesFlag.Name)
+	if out == "stdout" {
+		b, err := json.MarshalIndent(fixtures, "", "  ")
+		if err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+		}
+		os.Stdout.Write(b)
+		os.Stdout.WriteString("\n")
+		return nil
+	}
+	baseDir, err := createBasedir(ctx)
+	if err != nil {
+		return NewError(ErrorIO, fmt.Errorf("failed creating output basedir: %v", err))
+	}
+	return saveFile(baseDir, out, fixtures)
+}
+
+// ReadFillerSpecs loads the filler specs of a file, keyed by test name. Files
+// with a .yml or .yaml extension are parsed as YAML, any other as JSON.
+func ReadFillerSpecs(file string) (map[string]*FillerSpec, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, NewError(ErrorIO, fmt.Errorf("failed reading filler file: %v", err))
+	}
+	if ext := strings.ToLower(filepath.Ext(file)); ext == ".yml" || ext == ".yaml" {
+		if blob, err = yamlToJSON(blob); err != nil {
+			return nil, NewError(ErrorJson, fmt.Errorf("failed parsing filler file: %v", err))
+		}
+	}
+	var specs map[string]*FillerSpec
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&specs); err != nil {
+		return nil, NewError(ErrorJson, fmt.Errorf("failed unmarshalling filler file: %v", err))
+	}
+	return specs, nil
+}
+
+// yamlToJSON converts a YAML document into JSON. Integers are converted into
+// hex strings, keeping the hex literals as written so that addresses and hashes
+// retain their leading zeros.
+func yamlToJSON(blob []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(blob, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return []byte("{}"), nil
+	}
+	obj, err := yamlValue(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		obj := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := yamlScalar(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			val, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			obj[fmt.Sprint(key)] = val
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			val, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		return list, nil
+	case yaml.ScalarNode:
+		return yamlScalar(node)
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+func yamlScalar(node *yaml.Node) (interface{}, error) {
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("line %d: expected a scalar", node.Line)
+	}
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	case "!!int":
+		if strings.HasPrefix(node.Value, "0x") || strings.HasPrefix(node.Value, "0X") {
+			return node.Value, nil
+		}
+		n, ok := new(big.Int).SetString(strings.ReplaceAll(node.Value, "_", ""), 0)
+		if !ok || n.Sign() < 0 {
+			return nil, fmt.Errorf("line %d: invalid number %q", node.Line, node.Value)
+		}
+		return hexutil.EncodeBig(n), nil
+	}
+	return node.Value, nil
+}
+
+func sortedSpecNames(specs map[string]*FillerSpec) []string {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FillTest generates the blockchain tests of a filler spec, keyed by the test
+// name suffixed with the fork name.
+func FillTest(name string, spec *FillerSpec) (map[string]*Fixture, error) {
+	if len(spec.Forks) == 0 {
+		return nil, NewError(ErrorConfig, fmt.Errorf("test %s: no forks to fill", name))
+	}
+	fixtures := make(map[string]*Fixture)
+	for _, fork := range spec.Forks {
+		fixture, err := spec.fill(fork)
+		if err != nil {
+			return nil, fmt.Errorf("test %s, fork %s: %w", name, fork, err)
+		}
+		fixtures[name+"_"+fork] = fixture
+	}
+	return fixtures, nil
+}
+
+// fill builds the blocks of the spec on top of the genesis block for a fork,
+// importing each block into a chain to validate it and to serve BLOCKHASH.
+func (spec *FillerSpec) fill(fork string) (fixture *Fixture, err error) {
+	config, ok := tests.Forks[fork]
+	if !ok {
+		return nil, NewError(ErrorConfig, tests.UnsupportedForkError{Name: fork})
+	}
+	gspec := spec.genesis(config)
+
+	// Blocks are generated on a separate database, which has the state of
+	// every block flushed to disk
+	gendb := rawdb.NewMemoryDatabase()
+	triedb := trie.NewDatabase(gendb, trie.HashDefaults)
+	genesis, err := gspec.Commit(gendb, triedb)
+	triedb.Close()
+	if err != nil {
+		return nil, NewError(ErrorConfig, fmt.Errorf("failed committing genesis: %v", err))
+	}
+	engine := beacon.New(ethash.NewFaker())
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), &core.CacheConfig{
+		StateScheme: rawdb.HashScheme,
+		Preimages:   true,
+	}, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		return nil, NewError(ErrorConfig, fmt.Errorf("failed creating chain: %v", err))
+	}
+	defer chain.Stop()
+
+	fixture = &Fixture{
+		Blocks:     make([]*fixtureBlock, 0, len(spec.Blocks)),
+		Genesis:    newFixtureHeader(genesis.Header()),
+		Pre:        gspec.Alloc,
+		Network:    fork,
+		SealEngine: "NoProof",
+	}
+	if fixture.GenesisRLP, err = rlp.EncodeToBytes(genesis); err != nil {
+		return nil, NewError(ErrorRlp, err)
+	}
+	parent := genesis
+	for i, block := range spec.Blocks {
+		built, err := block.generate(config, parent, engine, gendb, chain, gspec.Coinbase)
+		if err != nil {
+			return nil, NewError(ErrorEVM, fmt.Errorf("block %d: %v", i+1, err))
+		}
+		if _, err := chain.InsertChain(types.Blocks{built}); err != nil {
+			return nil, NewError(ErrorEVM, fmt.Errorf("block %d: %v", i+1, err))
+		}
+		enc, err := rlp.EncodeToBytes(built)
+		if err != nil {
+			return nil, NewError(ErrorRlp, err)
+		}
+		fixture.Blocks = append(fixture.Blocks, &fixtureBlock{
+			Header:       newFixtureHeader(built.Header()),
+			Rlp:          enc,
+			Transactions: built.Transactions(),
+			UncleHeaders: []*fixtureHeader{},
+			Withdrawals:  built.Withdrawals(),
+		})
+		parent = built
+	}
+	fixture.BestBlock = common.UnprefixedHash(parent.Hash())
+
+	statedb, err := chain.State()
+	if err != nil {
+		return nil, NewError(ErrorEVM, err)
+	}
+	for _, expect := range spec.Expect {
+		if !expect.covers(fork) {
+			continue
+		}
+		if err := expect.check(statedb); err != nil {
+			return nil, NewError(ErrorEVM, fmt.Errorf("post-state mismatch: %v", err))
+		}
+	}
+	post := make(Alloc)
+	statedb.DumpToCollector(post, nil)
+	fixture.Post = core.GenesisAlloc(post)
+	return fixture, nil
+}
+
+// genesis assembles the genesis of the test for a chain config, starting from
+// the header fields of the consensus tests.
+func (spec *FillerSpec) genesis(config *params.ChainConfig) *core.Genesis {
+	gspec := &core.Genesis{
+		Config:     config,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(0x20000),
+		Alloc:      spec.Pre,
+	}
+	if gspec.Alloc == nil {
+		gspec.Alloc = make(core.GenesisAlloc)
+	}
+	if ttd := config.TerminalTotalDifficulty; ttd != nil && ttd.Sign() == 0 {
+		gspec.Difficulty = new(big.Int)
+	}
+	if config.IsLondon(common.Big0) {
+		gspec.BaseFee = big.NewInt(params.InitialBaseFee)
+	}
+	if config.IsCancun(common.Big0, 0) {
+		gspec.BlobGasUsed, gspec.ExcessBlobGas = new(uint64), new(uint64)
+	}
+	if g := spec.Genesis; g != nil {
+		gspec.Coinbase = g.Coinbase
+		gspec.Timestamp = uint64(g.Timestamp)
+		gspec.ExtraData = g.ExtraData
+		if g.GasLimit != 0 {
+			gspec.GasLimit = uint64(g.GasLimit)
+		}
+		if g.Difficulty != nil {
+			gspec.Difficulty = (*big.Int)(g.Difficulty)
+		}
+		if g.BaseFee != nil {
+			gspec.BaseFee = (*big.Int)(g.BaseFee)
+		}
+		if config.IsCancun(common.Big0, gspec.Timestamp) && gspec.BlobGasUsed == nil {
+			gspec.BlobGasUsed, gspec.ExcessBlobGas = new(uint64), new(uint64)
+		}
+	}
+	return gspec
+}
+
+// generate builds the block on top of its parent. The chain generator panics
+// on invalid contents, which is turned into an error.
+func (block *fillerBlock) generate(config *params.ChainConfig, parent *types.Block, engine consensus.Engine, db ethdb.Database, chain *core.BlockChain, coinbase common.Address) (built *types.Block, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			built, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	blocks, _ := core.GenerateChain(config, parent, engine, db, 1, func(_ int, b *core.BlockGen) {
+		if err == nil {
+			err = block.build(b, config, chain, coinbase)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks[0], nil
+}
+
+// build fills the generated block with the contents of the spec.
+func (block *fillerBlock) build(b *core.BlockGen, config *params.ChainConfig, chain *core.BlockChain, coinbase common.Address) error {
+	if block.Coinbase != nil {
+		coinbase = *block.Coinbase
+	}
+	b.SetCoinbase(coinbase)
+
+	// The generator can't tell the total difficulty, ask the chain instead
+	parent := b.PrevBlock(-1)
+	if reached, _ := beacon.IsTTDReached(chain, parent.Hash(), parent.NumberU64()); reached {
+		b.SetPoS()
+	}
+	if config.IsCancun(b.Number(), b.Timestamp()) {
+		var root common.Hash
+		if block.ParentBeaconRoot != nil {
+			root = *block.ParentBeaconRoot
+		}
+		b.SetParentBeaconRoot(root)
+	} else if block.ParentBeaconRoot != nil {
+		return errors.New("parent beacon root set before Cancun")
+	}
+	for i, stx := range block.Transactions {
+		tx, err := stx.sign(b, config)
+		if err != nil {
+			return fmt.Errorf("tx %d: %v", i, err)
+		}
+		b.AddTxWithChain(chain, tx)
+	}
+	for _, w := range block.Withdrawals {
+		b.AddWithdrawal(w)
+	}
+	return nil
+}
+
+// sign assembles the transaction and signs it with the signer of the block.
+func (stx *fillerTx) sign(b *core.BlockGen, config *params.ChainConfig) (*types.Transaction, error) {
+	key, err := crypto.ToECDSA(stx.SecretKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %v", err)
+	}
+	if stx.GasLimit == 0 {
+		return nil, errors.New("missing gas limit")
+	}
+	nonce := b.TxNonce(crypto.PubkeyToAddress(key.PublicKey))
+	if stx.Nonce != nil {
+		nonce = uint64(*stx.Nonce)
+	}
+	value := new(big.Int)
+	if stx.Value != nil {
+		value = (*big.Int)(stx.Value)
+	}
+	gasPrice := new(big.Int)
+	if stx.GasPrice != nil {
+		gasPrice = (*big.Int)(stx.GasPrice)
+	} else if config.IsLondon(b.Number()) {
+		gasPrice = b.BaseFee()
+	}
+	var data types.TxData
+	switch {
+	case stx.MaxFeePerGas != nil:
+		tip := new(big.Int)
+		if stx.MaxPriorityFeePerGas != nil {
+			tip = (*big.Int)(stx.MaxPriorityFeePerGas)
+		}
+		tx := &types.DynamicFeeTx{
+			Nonce:     nonce,
+			GasTipCap: tip,
+			GasFeeCap: (*big.Int)(stx.MaxFeePerGas),
+			Gas:       uint64(stx.GasLimit),
+			To:        stx.To,
+			Value:     value,
+			Data:      stx.Data,
+		}
+		if stx.AccessList != nil {
+			tx.AccessList = *stx.AccessList
+		}
+		data = tx
+	case stx.AccessList != nil:
+		data = &types.AccessListTx{
+			Nonce:      nonce,
+			GasPrice:   gasPrice,
+			Gas:        uint64(stx.GasLimit),
+			To:         stx.To,
+			Value:      value,
+			Data:       stx.Data,
+			AccessList: *stx.AccessList,
+		}
+	default:
+		data = &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: gasPrice,
+			Gas:      uint64(stx.GasLimit),
+			To:       stx.To,
+			Value:    value,
+			Data:     stx.Data,
+		}
+	}
+	return types.SignNewTx(key, b.Signer(), data)
+}
+
+// covers reports whether the conditions apply to a fork.
+func (expect *fillerExpect) covers(fork string) bool {
+	if len(expect.Forks) == 0 {
+		return true
+	}
+	for _, f := range expect.Forks {
+		if f == fork {
+			return true
+		}
+	}
+	return false
+}
+
+// check verifies the conditions against the post-state.
+func (expect *fillerExpect) check(statedb *state.StateDB) error {
+	addrs := make([]common.Address, 0, len(expect.Result))
+	for addr := range expect.Result {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	for _, addr := range addrs {
+		want := expect.Result[addr]
+		if want == nil {
+			continue
+		}
+		if want.Balance != nil {
+			if have := statedb.GetBalance(addr).ToBig(); have.Cmp((*big.Int)(want.Balance)) != 0 {
+				return fmt.Errorf("account %x: balance %v, want %v", addr, have, (*big.Int)(want.Balance))
+			}
+		}
+		if want.Nonce != nil {
+			if have := statedb.GetNonce(addr); have != uint64(*want.Nonce) {
+				return fmt.Errorf("account %x: nonce %d, want %d", addr, have, uint64(*want.Nonce))
+			}
+		}
+		if want.Code != nil {
+			if have := statedb.GetCode(addr); !bytes.Equal(have, *want.Code) {
+				return fmt.Errorf("account %x: code %#x, want %#x", addr, have, []byte(*want.Code))
+			}
+		}
+		for k, v := range want.Storage {
+			slot, ok := math.ParseBig256(k)
+			if !ok {
+				return fmt.Errorf("account %x: invalid storage slot %q", addr, k)
+			}
+			var val common.Hash
+			if v != nil {
+				val = common.BigToHash((*big.Int)(v))
+			}
+			key := common.BigToHash(slot)
+			if have := statedb.GetState(addr, key); have != val {
+				return fmt.Errorf("account %x: storage %x is %x, want %x", addr, key, have, val)
+			}
+		}
+	}
+	return nil
+}
@@ -38,6 +38,10 @@ var (
 		Name:  "trace.jsonconfig",
 		Usage: "The configurations for the custom tracer specified by --trace.tracer. If provided, must be in JSON format",
 	}
+	TraceStateDiffFlag = &cli.BoolFlag{
+		Name:  "trace.statediff",
+		Usage: "Configures the use of the state diff tracer. The diff of every transaction is emitted as a line of statediff.jsonl, the diff of the whole block into statediff.json",
+	}
 	TraceEnableMemoryFlag = &cli.BoolFlag{
 		Name:  "trace.memory",
 		Usage: "Enable full memory dump in traces",
@@ -84,6 +88,21 @@ var (
 			"\t<file> - into the file <file> ",
 		Value: "block.json",
 	}
+	OutputBlocksFlag = &cli.StringFlag{
+		Name: "output.blocks",
+		Usage: "Determines where to put the RLP encoded blocks of a multi-block transition (see --input.blocks).\n" +
+			"\t`stdout` - into the stdout output\n" +
+			"\t`stderr` - into the stderr output\n" +
+			"\t<file> - into the file <file> ",
+		Value: "",
+	}
+	OutputFixturesFlag = &cli.StringFlag{
+		Name: "output.fixtures",
+		Usage: "Determines where to put the filled blockchain tests.\n" +
+			"\t`stdout` - into the stdout output\n" +
+			"\t<file> - into the file <file> ",
+		Value: "stdout",
+	}
 	InputAllocFlag = &cli.StringFlag{
 		Name:  "input.alloc",
 		Usage: "`stdin` or file name of where to find the prestate alloc to use.",
@@ -101,6 +120,12 @@ var (
 			"The '.rlp' format is identical to the output.body format.",
 		Value: "txs.json",
 	}
+	InputBlocksFlag = &cli.StringFlag{
+		Name: "input.blocks",
+		Usage: "`stdin` or file name of where to find a list of blocks to apply in sequence, instead of --input.env and --input.txs. " +
+			"Every block holds an `env` and either `txs` or `txsRlp`, the parent fields of the env default to the values of the previous block. " +
+			"The result and body outputs become lists with an item per block.",
+	}
 	InputHeaderFlag = &cli.StringFlag{
 		Name:  "input.header",
 		Usage: "`stdin` or file name of where to find the block header to use.",
@@ -0,0 +1,205 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/urfave/cli/v2"
+)
+
+// blockInput is a block of a multi-block transition, holding the environment
+// and the transactions to apply, either as JSON or as an RLP list.
+type blockInput struct {
+	Env   *stEnv       `json:"env"`
+	Txs   []*txWithKey `json:"txs,omitempty"`
+	TxRlp string       `json:"txsRlp,omitempty"`
+}
+
+// transactions returns the transactions of the block, signing them if needed.
+func (b *blockInput) transactions(chainConfig *params.ChainConfig) (txIterator, error) {
+	if len(b.TxRlp) > 0 {
+		return newRlpTxIterator(common.FromHex(b.TxRlp)), nil
+	}
+	signer := types.LatestSignerForChainID(chainConfig.ChainID)
+	txs, err := signUnsignedTransactions(b.Txs, signer)
+	return newSliceTxIterator(txs), err
+}
+
+// transitionBlocks applies a sequence of blocks on top of the prestate alloc,
+// keeping the state in memory between blocks. The parent fields missing from
+// the env of a block are taken from the block before it, and the hashes of the
+// earlier blocks are made available to BLOCKHASH.
+func transitionBlocks(ctx *cli.Context, baseDir string, inputData *input, vmConfig vm.Config, chainConfig *params.ChainConfig,
+	getTracer func(txIndex int, txHash common.Hash) (vm.EVMLogger, error), diffs *stateDiffCollector) error {
+	if len(inputData.Blocks) == 0 {
+		return NewError(ErrorConfig, errors.New("no blocks to apply"))
+	}
+	var (
+		statedb = MakePreState(rawdb.NewMemoryDatabase(), inputData.Alloc)
+		results = make([]*ExecutionResult, 0, len(inputData.Blocks))
+		bodies  = make([]hexutil.Bytes, 0, len(inputData.Blocks))
+		blocks  = make([]*blockInfo, 0, len(inputData.Blocks))
+		hashes  = make(map[math.HexOrDecimal64]common.Hash)
+		parent  *types.Header
+	)
+	for i, block := range inputData.Blocks {
+		if block.Env == nil {
+			return NewError(ErrorConfig, fmt.Errorf("block %d: missing env", i))
+		}
+		if len(block.Env.Ommers) > 0 && ctx.String(OutputBlocksFlag.Name) != "" {
+			return NewError(ErrorConfig, fmt.Errorf("block %d: ommers can't be encoded into blocks", i))
+		}
+		prestate := Prestate{Env: *block.Env}
+		inheritParent(&prestate.Env, parent, hashes)
+
+		txIt, err := block.transactions(chainConfig)
+		if err != nil {
+			return err
+		}
+		if err := applyLondonChecks(&prestate.Env, chainConfig); err != nil {
+			return err
+		}
+		if err := applyShanghaiChecks(&prestate.Env, chainConfig); err != nil {
+			return err
+		}
+		if err := applyMergeChecks(&prestate.Env, chainConfig); err != nil {
+			return err
+		}
+		if err := applyCancunChecks(&prestate.Env, chainConfig); err != nil {
+			return err
+		}
+		s, result, body, err := prestate.applyTo(statedb, vmConfig, chainConfig, txIt, ctx.Int64(RewardFlag.Name), getTracer)
+		if err != nil {
+			return err
+		}
+		statedb = s
+
+		var txs types.Transactions
+		if err := rlp.DecodeBytes(body, &txs); err != nil {
+			return NewError(ErrorRlp, fmt.Errorf("block %d: failed decoding body: %v", i, err))
+		}
+		// The parent of the first block is only known if its hash was provided
+		var parentHash common.Hash
+		if parent != nil {
+			parentHash = parent.Hash()
+		} else if prestate.Env.Number > 0 {
+			parentHash = prestate.Env.BlockHashes[math.HexOrDecimal64(prestate.Env.Number-1)]
+		}
+		built := newBlock(&prestate.Env, parentHash, result, txs)
+		hashes[math.HexOrDecimal64(prestate.Env.Number)] = built.Hash()
+		parent = built.Header()
+
+		results = append(results, result)
+		bodies = append(bodies, body)
+		blocks = append(blocks, newBlockInfo(built))
+	}
+	if diffs != nil {
+		if err := saveFile(baseDir, "statediff.json", diffs.diff); err != nil {
+			return err
+		}
+	}
+	// Dump the state after the last block
+	collector := make(Alloc)
+	statedb.DumpToCollector(collector, nil)
+	return dispatchOutput(ctx, baseDir, results, collector, bodies, blocks)
+}
+
+// inheritParent fills the parent fields missing from the env of a block with
+// the values of its parent, if any, and adds the hashes of the earlier blocks.
+func inheritParent(env *stEnv, parent *types.Header, hashes map[math.HexOrDecimal64]common.Hash) {
+	if parent == nil {
+		return
+	}
+	if env.ParentDifficulty == nil {
+		env.ParentDifficulty = parent.Difficulty
+	}
+	if env.ParentBaseFee == nil && parent.BaseFee != nil {
+		env.ParentBaseFee = parent.BaseFee
+	}
+	if env.ParentGasUsed == 0 {
+		env.ParentGasUsed = parent.GasUsed
+	}
+	if env.ParentGasLimit == 0 {
+		env.ParentGasLimit = parent.GasLimit
+	}
+	if env.ParentTimestamp == 0 {
+		env.ParentTimestamp = parent.Time
+	}
+	if env.ParentUncleHash == (common.Hash{}) {
+		env.ParentUncleHash = parent.UncleHash
+	}
+	if env.ParentExcessBlobGas == nil && parent.ExcessBlobGas != nil {
+		excessBlobGas := *parent.ExcessBlobGas
+		env.ParentExcessBlobGas = &excessBlobGas
+	}
+	if env.ParentBlobGasUsed == nil && parent.BlobGasUsed != nil {
+		blobGasUsed := *parent.BlobGasUsed
+		env.ParentBlobGasUsed = &blobGasUsed
+	}
+	// Copy the block hashes, the env of the input must not be modified
+	merged := make(map[math.HexOrDecimal64]common.Hash, len(env.BlockHashes)+len(hashes))
+	for num, hash := range hashes {
+		merged[num] = hash
+	}
+	for num, hash := range env.BlockHashes {
+		merged[num] = hash
+	}
+	env.BlockHashes = merged
+}
+
+// newBlock assembles the block resulting from the transition of an env.
+func newBlock(env *stEnv, parentHash common.Hash, result *ExecutionResult, txs types.Transactions) *types.Block {
+	header := &types.Header{
+		ParentHash:       parentHash,
+		UncleHash:        types.EmptyUncleHash,
+		Coinbase:         env.Coinbase,
+		Root:             result.StateRoot,
+		TxHash:           result.TxRoot,
+		ReceiptHash:      result.ReceiptRoot,
+		Bloom:            result.Bloom,
+		Difficulty:       new(big.Int),
+		Number:           new(big.Int).SetUint64(env.Number),
+		GasLimit:         env.GasLimit,
+		GasUsed:          uint64(result.GasUsed),
+		Time:             env.Timestamp,
+		BaseFee:          (*big.Int)(result.BaseFee),
+		WithdrawalsHash:  result.WithdrawalsRoot,
+		ParentBeaconRoot: env.ParentBeaconBlockRoot,
+	}
+	if result.Difficulty != nil {
+		header.Difficulty = (*big.Int)(result.Difficulty)
+	}
+	if env.Random != nil {
+		header.MixDigest = common.BigToHash(env.Random)
+	}
+	if result.CurrentExcessBlobGas != nil {
+		excessBlobGas, blobGasUsed := uint64(*result.CurrentExcessBlobGas), uint64(*result.CurrentBlobGasUsed)
+		header.ExcessBlobGas, header.BlobGasUsed = &excessBlobGas, &blobGasUsed
+	}
+	return types.NewBlockWithHeader(header).WithBody(txs, nil).WithWithdrawals(env.Withdrawals)
+}
@@ -79,3 +79,105 @@ func (t *traceWriter) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, sc
 func (t *traceWriter) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
 	t.inner.CaptureFault(pc, op, gas, cost, scope, depth, err)
 }
+
+// muxLogger is a vm.EVMLogger passing all events to multiple loggers.
+type muxLogger []vm.EVMLogger
+
+func (m muxLogger) CaptureTxStart(gasLimit uint64) {
+	for _, l := range m {
+		l.CaptureTxStart(gasLimit)
+	}
+}
+
+func (m muxLogger) CaptureTxEnd(restGas uint64) {
+	for _, l := range m {
+		l.CaptureTxEnd(restGas)
+	}
+}
+
+func (m muxLogger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	for _, l := range m {
+		l.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (m muxLogger) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	for _, l := range m {
+		l.CaptureEnd(output, gasUsed, err)
+	}
+}
+
+func (m muxLogger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	for _, l := range m {
+		l.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (m muxLogger) CaptureExit(output []byte, gasUsed uint64, err error) {
+	for _, l := range m {
+		l.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (m muxLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	for _, l := range m {
+		l.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (m muxLogger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	for _, l := range m {
+		l.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
+
+// stateDiffCollector writes the results of the stateDiffTracer of every
+// transaction as a line of JSON, and aggregates them into the diff of the block.
+type stateDiffCollector struct {
+	enc  *json.Encoder
+	diff tracers.StateDiff
+}
+
+func newStateDiffCollector(w io.Writer) *stateDiffCollector {
+	return &stateDiffCollector{enc: json.NewEncoder(w), diff: make(tracers.StateDiff)}
+}
+
+// tracer wraps the state diff tracer of a transaction, collecting its result
+// when the transaction ends.
+func (c *stateDiffCollector) tracer(tracer tracers.Tracer, txIndex int, txHash common.Hash) vm.EVMLogger {
+	return &stateDiffWriter{Tracer: tracer, collector: c, txIndex: txIndex, txHash: txHash}
+}
+
+// stateDiffWriter is a vm.EVMLogger passing all events to a state diff tracer,
+// handing its result to the collector when the TxEnd event happens.
+type stateDiffWriter struct {
+	tracers.Tracer
+	collector *stateDiffCollector
+	txIndex   int
+	txHash    common.Hash
+}
+
+func (t *stateDiffWriter) CaptureTxEnd(restGas uint64) {
+	t.Tracer.CaptureTxEnd(restGas)
+
+	result, err := t.Tracer.GetResult()
+	if err != nil {
+		log.Warn("Error in tracer", "err", err)
+		return
+	}
+	var diff tracers.StateDiff
+	if err := json.Unmarshal(result, &diff); err != nil {
+		log.Warn("Error decoding state diff", "err", err)
+		return
+	}
+	t.collector.diff.Merge(diff)
+
+	err = t.collector.enc.Encode(struct {
+		TxIndex   int               `json:"txIndex"`
+		TxHash    common.Hash       `json:"txHash"`
+		StateDiff tracers.StateDiff `json:"stateDiff"`
+	}{t.txIndex, t.txHash, diff})
+	if err != nil {
+		log.Warn("Error writing state diff", "err", err)
+	}
+}
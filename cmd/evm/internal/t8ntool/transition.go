@@ -74,14 +74,18 @@ var (
 )
 
 type input struct {
-	Alloc core.GenesisAlloc `json:"alloc,omitempty"`
-	Env   *stEnv            `json:"env,omitempty"`
-	Txs   []*txWithKey      `json:"txs,omitempty"`
-	TxRlp string            `json:"txsRlp,omitempty"`
+	Alloc  core.GenesisAlloc `json:"alloc,omitempty"`
+	Env    *stEnv            `json:"env,omitempty"`
+	Txs    []*txWithKey      `json:"txs,omitempty"`
+	TxRlp  string            `json:"txsRlp,omitempty"`
+	Blocks []*blockInput     `json:"blocks,omitempty"`
 }
 
 func Transition(ctx *cli.Context) error {
-	var getTracer = func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) { return nil, nil }
+	var (
+		getTracer = func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) { return nil, nil }
+		diffs     *stateDiffCollector
+	)
 
 	baseDir, err := createBasedir(ctx)
 	if err != nil {
@@ -120,6 +124,31 @@ func Transition(ctx *cli.Context) error {
 			return &traceWriter{tracer, traceFile}, nil
 		}
 	}
+	// The state diff tracer runs alongside any of the above
+	if ctx.Bool(TraceStateDiffFlag.Name) {
+		diffFile, err := os.Create(path.Join(baseDir, "statediff.jsonl"))
+		if err != nil {
+			return NewError(ErrorIO, fmt.Errorf("failed creating statediff-file: %v", err))
+		}
+		defer diffFile.Close()
+
+		diffs = newStateDiffCollector(diffFile)
+		getTxTracer := getTracer
+		getTracer = func(txIndex int, txHash common.Hash) (vm.EVMLogger, error) {
+			tracer, err := tracers.DefaultDirectory.New("stateDiffTracer", nil, nil)
+			if err != nil {
+				return nil, NewError(ErrorConfig, fmt.Errorf("failed instantiating tracer: %w", err))
+			}
+			txTracer, err := getTxTracer(txIndex, txHash)
+			if err != nil {
+				return nil, err
+			}
+			if txTracer == nil {
+				return diffs.tracer(tracer, txIndex, txHash), nil
+			}
+			return muxLogger{txTracer, diffs.tracer(tracer, txIndex, txHash)}, nil
+		}
+	}
 	// We need to load three things: alloc, env and transactions. May be either in
 	// stdin input or in files.
 	// Check if anything needs to be read from stdin
@@ -130,10 +159,11 @@ func Transition(ctx *cli.Context) error {
 
 		envStr    = ctx.String(InputEnvFlag.Name)
 		txStr     = ctx.String(InputTxsFlag.Name)
+		blocksStr = ctx.String(InputBlocksFlag.Name)
 		inputData = &input{}
 	)
 	// Figure out the prestate alloc
-	if allocStr == stdinSelector || envStr == stdinSelector || txStr == stdinSelector {
+	if allocStr == stdinSelector || envStr == stdinSelector || txStr == stdinSelector || blocksStr == stdinSelector {
 		decoder := json.NewDecoder(os.Stdin)
 		if err := decoder.Decode(inputData); err != nil {
 			return NewError(ErrorJson, fmt.Errorf("failed unmarshaling stdin: %v", err))
@@ -146,16 +176,6 @@ func Transition(ctx *cli.Context) error {
 	}
 	prestate.Pre = inputData.Alloc
 
-	// Set the block environment
-	if envStr != stdinSelector {
-		var env stEnv
-		if err := readFile(envStr, "env", &env); err != nil {
-			return err
-		}
-		inputData.Env = &env
-	}
-	prestate.Env = *inputData.Env
-
 	vmConfig := vm.Config{}
 	// Construct the chainconfig
 	var chainConfig *params.ChainConfig
@@ -168,6 +188,25 @@ func Transition(ctx *cli.Context) error {
 	// Set the chain id
 	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
 
+	// A sequence of blocks replaces the env and transactions of a single block
+	if blocksStr != "" {
+		if blocksStr != stdinSelector {
+			if err := readFile(blocksStr, "blocks", &inputData.Blocks); err != nil {
+				return err
+			}
+		}
+		return transitionBlocks(ctx, baseDir, inputData, vmConfig, chainConfig, getTracer, diffs)
+	}
+	// Set the block environment
+	if envStr != stdinSelector {
+		var env stEnv
+		if err := readFile(envStr, "env", &env); err != nil {
+			return err
+		}
+		inputData.Env = &env
+	}
+	prestate.Env = *inputData.Env
+
 	if txIt, err = loadTransactions(txStr, inputData, prestate.Env, chainConfig); err != nil {
 		return err
 	}
@@ -188,10 +227,15 @@ func Transition(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if diffs != nil {
+		if err := saveFile(baseDir, "statediff.json", diffs.diff); err != nil {
+			return err
+		}
+	}
 	// Dump the execution result
 	collector := make(Alloc)
 	s.DumpToCollector(collector, nil)
-	return dispatchOutput(ctx, baseDir, result, collector, body)
+	return dispatchOutput(ctx, baseDir, result, collector, hexutil.Bytes(body), nil)
 }
 
 func applyLondonChecks(env *stEnv, chainConfig *params.ChainConfig) error {
@@ -312,8 +356,9 @@ func saveFile(baseDir, filename string, data interface{}) error {
 }
 
 // dispatchOutput writes the output data to either stderr or stdout, or to the specified
-// files
-func dispatchOutput(ctx *cli.Context, baseDir string, result *ExecutionResult, alloc Alloc, body hexutil.Bytes) error {
+// files. The result and body are lists in multi-block transitions, which also
+// output the encoded blocks.
+func dispatchOutput(ctx *cli.Context, baseDir string, result interface{}, alloc Alloc, body interface{}, blocks []*blockInfo) error {
 	stdOutObject := make(map[string]interface{})
 	stdErrObject := make(map[string]interface{})
 	dispatch := func(baseDir, fName, name string, obj interface{}) error {
@@ -340,6 +385,11 @@ func dispatchOutput(ctx *cli.Context, baseDir string, result *ExecutionResult, a
 	if err := dispatch(baseDir, ctx.String(OutputBodyFlag.Name), "body", body); err != nil {
 		return err
 	}
+	if blocks != nil {
+		if err := dispatch(baseDir, ctx.String(OutputBlocksFlag.Name), "blocks", blocks); err != nil {
+			return err
+		}
+	}
 	if len(stdOutObject) > 0 {
 		b, err := json.MarshalIndent(stdOutObject, "", "  ")
 		if err != nil {
@@ -138,6 +138,26 @@ var (
 		Usage:    "enable return data output",
 		Category: flags.VMCategory,
 	}
+	GasReportFlag = &cli.BoolFlag{
+		Name:     "gasreport",
+		Usage:    "output the gas used by call frame, function and storage access",
+		Category: flags.VMCategory,
+	}
+	GasReportContractsFlag = &cli.StringSliceFlag{
+		Name:     "gasreport.solc",
+		Usage:    "solc --combined-json output (bin,bin-runtime,srcmap,srcmap-runtime,hashes,ast) naming the executed functions",
+		Category: flags.VMCategory,
+	}
+	GasReportOutputFlag = &cli.StringFlag{
+		Name:     "gasreport.out",
+		Usage:    "file to write the gas report to in JSON format",
+		Category: flags.VMCategory,
+	}
+	GasReportDiffFlag = &cli.StringFlag{
+		Name:     "gasreport.diff",
+		Usage:    "JSON gas report of an earlier run to compare against",
+		Category: flags.VMCategory,
+	}
 )
 
 var stateTransitionCommand = &cli.Command{
@@ -149,6 +169,7 @@ var stateTransitionCommand = &cli.Command{
 		t8ntool.TraceFlag,
 		t8ntool.TraceTracerFlag,
 		t8ntool.TraceTracerConfigFlag,
+		t8ntool.TraceStateDiffFlag,
 		t8ntool.TraceEnableMemoryFlag,
 		t8ntool.TraceDisableStackFlag,
 		t8ntool.TraceEnableReturnDataFlag,
@@ -156,9 +177,11 @@ var stateTransitionCommand = &cli.Command{
 		t8ntool.OutputAllocFlag,
 		t8ntool.OutputResultFlag,
 		t8ntool.OutputBodyFlag,
+		t8ntool.OutputBlocksFlag,
 		t8ntool.InputAllocFlag,
 		t8ntool.InputEnvFlag,
 		t8ntool.InputTxsFlag,
+		t8ntool.InputBlocksFlag,
 		t8ntool.ForknameFlag,
 		t8ntool.ChainIDFlag,
 		t8ntool.RewardFlag,
@@ -193,6 +216,17 @@ var blockBuilderCommand = &cli.Command{
 	},
 }
 
+var fillCommand = &cli.Command{
+	Name:      "fill",
+	Usage:     "Generates blockchain tests from YAML or JSON filler specs",
+	ArgsUsage: "<file>",
+	Action:    t8ntool.Fill,
+	Flags: []cli.Flag{
+		t8ntool.OutputBasedir,
+		t8ntool.OutputFixturesFlag,
+	},
+}
+
 // vmFlags contains flags related to running the EVM.
 var vmFlags = []cli.Flag{
 	CodeFlag,
@@ -219,6 +253,10 @@ var traceFlags = []cli.Flag{
 	DisableStackFlag,
 	DisableStorageFlag,
 	DisableReturnDataFlag,
+	GasReportFlag,
+	GasReportContractsFlag,
+	GasReportOutputFlag,
+	GasReportDiffFlag,
 }
 
 var app = flags.NewApp("the evm command line interface")
@@ -234,6 +272,7 @@ func init() {
 		stateTransitionCommand,
 		transactionCommand,
 		blockBuilderCommand,
+		fillCommand,
 	}
 	app.Before = func(ctx *cli.Context) error {
 		flags.MigrateGlobalFlags(ctx)
@@ -19,6 +19,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -28,6 +29,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/compiler"
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/gasreport"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -118,6 +120,7 @@ func runCmd(ctx *cli.Context) error {
 	var (
 		tracer      vm.EVMLogger
 		debugLogger *logger.StructLogger
+		profiler    *gasreport.Profiler
 		statedb     *state.StateDB
 		chainConfig *params.ChainConfig
 		sender      = common.BytesToAddress([]byte("sender"))
@@ -126,11 +129,26 @@ func runCmd(ctx *cli.Context) error {
 		blobHashes  []common.Hash  // TODO (MariusVanDerWijden) implement blob hashes in state tests
 		blobBaseFee = new(big.Int) // TODO (MariusVanDerWijden) implement blob fee in state tests
 	)
+	if ctx.Bool(GasReportFlag.Name) || ctx.IsSet(GasReportOutputFlag.Name) || ctx.IsSet(GasReportDiffFlag.Name) {
+		if ctx.Bool(MachineFlag.Name) || ctx.Bool(DebugFlag.Name) {
+			return errors.New("--gasreport can't be combined with --json or --debug")
+		}
+		var contracts *gasreport.Contracts
+		if files := ctx.StringSlice(GasReportContractsFlag.Name); len(files) > 0 {
+			var err error
+			if contracts, err = gasreport.LoadContracts(files...); err != nil {
+				return err
+			}
+		}
+		profiler = gasreport.NewProfiler(contracts)
+	}
 	if ctx.Bool(MachineFlag.Name) {
 		tracer = logger.NewJSONLogger(logconfig, os.Stdout)
 	} else if ctx.Bool(DebugFlag.Name) {
 		debugLogger = logger.NewStructLogger(logconfig)
 		tracer = debugLogger
+	} else if profiler != nil {
+		tracer = profiler
 	} else {
 		debugLogger = logger.NewStructLogger(logconfig)
 	}
@@ -291,7 +309,12 @@ allocations:     %d
 allocated bytes: %d
 `, initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
 	}
-	if tracer == nil {
+	if profiler != nil {
+		if err := writeGasReport(ctx, profiler.Report()); err != nil {
+			return err
+		}
+	}
+	if tracer == nil || profiler != nil {
 		fmt.Printf("%#x\n", output)
 		if err != nil {
 			fmt.Printf(" error: %v\n", err)
@@ -300,3 +323,32 @@ allocated bytes: %d
 
 	return nil
 }
+
+// writeGasReport outputs the gas report of a run, comparing it to the report of
+// an earlier run if requested.
+func writeGasReport(ctx *cli.Context, report *gasreport.Report) error {
+	fmt.Fprintln(os.Stderr, "#### GAS REPORT ####")
+	if err := report.WriteText(os.Stderr); err != nil {
+		return err
+	}
+	if file := ctx.String(GasReportOutputFlag.Name); file != "" {
+		blob, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, blob, 0644); err != nil {
+			return err
+		}
+	}
+	if file := ctx.String(GasReportDiffFlag.Name); file != "" {
+		before, err := gasreport.ReadReport(file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "#### GAS DIFF ####")
+		if err := gasreport.WriteDiff(os.Stderr, gasreport.Diff(before, report)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -25,8 +25,10 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/internal/cmdtest"
 	"github.com/ethereum/go-ethereum/internal/reexec"
+	"github.com/ethereum/go-ethereum/tests"
 )
 
 func TestMain(m *testing.M) {
@@ -284,6 +286,14 @@ func TestT8n(t *testing.T) {
 			output: t8nOutput{alloc: true, result: true},
 			expOut: "exp.json",
 		},
+		{ // Cancun test with an explicit excess blob gas
+			base: "./testdata/33",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "Cancun", "",
+			},
+			output: t8nOutput{alloc: true, result: true},
+			expOut: "exp.json",
+		},
 	} {
 		args := []string{"t8n"}
 		args = append(args, tc.output.get()...)
@@ -321,6 +331,78 @@ func TestT8n(t *testing.T) {
 	}
 }
 
+func TestT8nBlocks(t *testing.T) {
+	t.Parallel()
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+
+	args := []string{"t8n",
+		"--input.alloc", "./testdata/31/alloc.json",
+		"--input.blocks", "./testdata/31/blocks.json",
+		"--state.fork", "London",
+		"--output.alloc", "stdout",
+		"--output.result", "stdout",
+		"--output.blocks", "stdout",
+		"--output.body", "",
+	}
+	tt.Logf("args: %v\n", strings.Join(args, " "))
+	tt.Run("evm-test", args...)
+
+	want, err := os.ReadFile("./testdata/31/exp.json")
+	if err != nil {
+		t.Fatalf("could not read expected output: %v", err)
+	}
+	have := tt.Output()
+	ok, err := cmpJson(have, want)
+	switch {
+	case err != nil:
+		t.Fatalf("json parsing failed: %v", err)
+	case !ok:
+		t.Fatalf("output wrong, have \n%v\nwant\n%v\n", string(have), string(want))
+	}
+	tt.WaitExit()
+	if have := tt.ExitStatus(); have != 0 {
+		t.Fatalf("wrong exit code, have %d, want 0", have)
+	}
+}
+
+func TestFill(t *testing.T) {
+	t.Parallel()
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+
+	args := []string{"fill", "./testdata/32/filler.yml"}
+	tt.Logf("args: %v\n", strings.Join(args, " "))
+	tt.Run("evm-test", args...)
+
+	want, err := os.ReadFile("./testdata/32/exp.json")
+	if err != nil {
+		t.Fatalf("could not read expected output: %v", err)
+	}
+	have := tt.Output()
+	ok, err := cmpJson(have, want)
+	switch {
+	case err != nil:
+		t.Fatalf("json parsing failed: %v", err)
+	case !ok:
+		t.Fatalf("output wrong, have \n%v\nwant\n%v\n", string(have), string(want))
+	}
+	tt.WaitExit()
+	if have := tt.ExitStatus(); have != 0 {
+		t.Fatalf("wrong exit code, have %d, want 0", have)
+	}
+	// The filled tests must pass on their own
+	var fixtures map[string]tests.BlockTest
+	if err := json.Unmarshal(have, &fixtures); err != nil {
+		t.Fatalf("failed to decode fixtures: %v", err)
+	}
+	for name, test := range fixtures {
+		if err := test.Run(false, rawdb.HashScheme, nil, nil); err != nil {
+			t.Errorf("test %s: %v", name, err)
+		}
+	}
+}
+
 type t9nInput struct {
 	inTxs  string
 	stFork string
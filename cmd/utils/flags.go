@@ -40,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/forkstate"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -51,6 +52,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/plugin"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/ethstats"
@@ -172,6 +174,16 @@ var (
 		Value:    11500000,
 		Category: flags.DevCategory,
 	}
+	DeveloperForkURLFlag = &cli.StringFlag{
+		Name:     "dev.fork.url",
+		Usage:    "RPC endpoint of a remote chain to fork the developer chain off, fetching its state on demand",
+		Category: flags.DevCategory,
+	}
+	DeveloperForkBlockFlag = &cli.Uint64Flag{
+		Name:     "dev.fork.block",
+		Usage:    "Block number of the remote chain to fork off (default = latest)",
+		Category: flags.DevCategory,
+	}
 
 	IdentityFlag = &cli.StringFlag{
 		Name:     "identity",
@@ -304,6 +316,12 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolRemoteJournalFlag = &cli.StringFlag{
+		Name:     "txpool.remotejournal",
+		Usage:    "Disk journal for remote transactions to survive node restarts (disabled if empty)",
+		Value:    ethconfig.Defaults.TxPool.RemoteJournal,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price tip to enforce for acceptance into the pool",
@@ -346,6 +364,22 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrivateLifetimeFlag = &cli.DurationFlag{
+		Name:     "txpool.privatelifetime",
+		Usage:    "Maximum amount of time private transactions are withheld from the network",
+		Value:    ethconfig.Defaults.TxPool.PrivateLifetime,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrivatePublishFlag = &cli.BoolFlag{
+		Name:     "txpool.privatepublish",
+		Usage:    "Publishes private transactions outliving their lifetime instead of dropping them",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolFeeMarketPolicyFlag = &cli.BoolFlag{
+		Name:     "txpool.feemarketpolicy",
+		Usage:    "Evicts and replaces transactions based on their distance from the base fee instead of plain prices",
+		Category: flags.TxPoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -412,6 +446,11 @@ var (
 		Category: flags.PerfCategory,
 		Value:    ethconfig.Defaults.FilterLogCacheSize,
 	}
+	CacheTracesFlag = &cli.IntFlag{
+		Name:     "cache.traces",
+		Usage:    "Megabytes of disk to use for caching debug_traceBlock results (0 = disabled)",
+		Category: flags.PerfCategory,
+	}
 	FDLimitFlag = &cli.IntFlag{
 		Name:     "fdlimit",
 		Usage:    "Raise the open file descriptor resource limit (default = system fd limit)",
@@ -496,6 +535,11 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	VMTracerPluginFlag = &cli.StringSliceFlag{
+		Name:     "vmtrace.plugin",
+		Usage:    "Registers an out-of-process tracer as name=endpoint, the endpoint being an executable or unix://<socket path>",
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -510,6 +554,12 @@ var (
 		Value:    ethconfig.Defaults.RPCEVMTimeout,
 		Category: flags.APICategory,
 	}
+	RPCGlobalTraceRangeFlag = &cli.Uint64Flag{
+		Name:     "rpc.tracerange",
+		Usage:    "Sets a limit on the number of blocks trace_filter can span (0=infinite)",
+		Value:    ethconfig.Defaults.RPCTraceRange,
+		Category: flags.APICategory,
+	}
 	RPCGlobalTxFeeCapFlag = &cli.Float64Flag{
 		Name:     "rpc.txfeecap",
 		Usage:    "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
@@ -813,6 +863,11 @@ var (
 		Value:    ethconfig.Defaults.GPO.IgnorePrice.Int64(),
 		Category: flags.GasPriceCategory,
 	}
+	GpoPendingFromPoolFlag = &cli.BoolFlag{
+		Name:     "gpo.pendingfrompool",
+		Usage:    "Estimate the pending block fee history from the transaction pool instead of the miner",
+		Category: flags.GasPriceCategory,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = &cli.BoolFlag{
@@ -1450,6 +1505,9 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.IsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.Int64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.IsSet(GpoPendingFromPoolFlag.Name) {
+		cfg.PendingFromPool = ctx.Bool(GpoPendingFromPoolFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
@@ -1472,6 +1530,9 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.String(TxPoolRemoteJournalFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1493,6 +1554,15 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.Duration(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPrivatePublishFlag.Name) {
+		cfg.PrivatePublish = ctx.Bool(TxPoolPrivatePublishFlag.Name)
+	}
+	if ctx.IsSet(TxPoolFeeMarketPolicyFlag.Name) {
+		cfg.FeeMarketPolicy = ctx.Bool(TxPoolFeeMarketPolicyFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1681,6 +1751,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheLogSizeFlag.Name) {
 		cfg.FilterLogCacheSize = ctx.Int(CacheLogSizeFlag.Name)
 	}
+	if ctx.IsSet(CacheTracesFlag.Name) {
+		cfg.TraceCache = ctx.Int(CacheTracesFlag.Name)
+	}
 	if !ctx.Bool(SnapshotFlag.Name) || cfg.SnapshotCache == 0 {
 		// If snap-sync is requested, this flag is also required
 		if cfg.SyncMode == downloader.SnapSync {
@@ -1703,6 +1776,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.Bool(VMEnableDebugFlag.Name)
 	}
+	for _, spec := range ctx.StringSlice(VMTracerPluginFlag.Name) {
+		name, endpoint, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || endpoint == "" {
+			Fatalf("Invalid tracer plugin %q, expected name=endpoint", spec)
+		}
+		plugin.Register(name, endpoint)
+		log.Info("Registered tracer plugin", "name", name, "endpoint", endpoint)
+	}
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
@@ -1715,6 +1796,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalEVMTimeoutFlag.Name) {
 		cfg.RPCEVMTimeout = ctx.Duration(RPCGlobalEVMTimeoutFlag.Name)
 	}
+	if ctx.IsSet(RPCGlobalTraceRangeFlag.Name) {
+		cfg.RPCTraceRange = ctx.Uint64(RPCGlobalTraceRangeFlag.Name)
+	}
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
@@ -1804,6 +1888,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 
 		// Create a new developer genesis block or reuse existing one
 		cfg.Genesis = core.DeveloperGenesisBlock(ctx.Uint64(DeveloperGasLimitFlag.Name), &developer.Address)
+		if ctx.IsSet(DeveloperForkURLFlag.Name) {
+			setDeveloperFork(ctx, cfg)
+		}
 		if ctx.IsSet(DataDirFlag.Name) {
 			chaindb := tryMakeReadOnlyDatabase(ctx, stack)
 			if rawdb.ReadCanonicalHash(chaindb, 0) != (common.Hash{}) {
@@ -1844,6 +1931,38 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	}
 }
 
+// setDeveloperFork configures the developer chain to fork off a remote chain,
+// retrieving the state of the forked block on demand.
+func setDeveloperFork(ctx *cli.Context, cfg *ethconfig.Config) {
+	client, err := rpc.DialContext(ctx.Context, ctx.String(DeveloperForkURLFlag.Name))
+	if err != nil {
+		Fatalf("Failed to connect to the forked chain: %v", err)
+	}
+	var number *big.Int
+	if ctx.IsSet(DeveloperForkBlockFlag.Name) {
+		number = new(big.Int).SetUint64(ctx.Uint64(DeveloperForkBlockFlag.Name))
+	}
+	fork, err := forkstate.NewFork(ctx.Context, forkstate.NewClient(client), number)
+	if err != nil {
+		Fatalf("Failed to retrieve the forked block: %v", err)
+	}
+	if cfg.Genesis != nil {
+		fork.ConfigureGenesis(cfg.Genesis)
+		if ctx.IsSet(DeveloperGasLimitFlag.Name) {
+			cfg.Genesis.GasLimit = ctx.Uint64(DeveloperGasLimitFlag.Name)
+		}
+	}
+	cfg.StateWrapper = fork.Wrap
+
+	// The snapshots are built from the local tries only and would hide the
+	// remote state, so they need to be disabled on forks
+	if cfg.SnapshotCache > 0 {
+		cfg.TrieCleanCache += cfg.SnapshotCache
+		cfg.SnapshotCache = 0
+	}
+	log.Info("Forking remote chain", "chainid", fork.ChainID, "number", fork.Header.Number, "hash", fork.Header.Hash(), "root", fork.Header.Root)
+}
+
 // SetDNSDiscoveryDefaults configures DNS discovery with the given URL if
 // no URLs are set.
 func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
@@ -1864,7 +1983,16 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 	if err != nil {
 		Fatalf("Failed to register the Ethereum service: %v", err)
 	}
-	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	var cache *tracers.TraceCache
+	if cfg.TraceCache > 0 {
+		db, err := stack.OpenDatabase("tracecache", 16, 16, "eth/db/tracecache/", false)
+		if err != nil {
+			Fatalf("Failed to open the trace cache: %v", err)
+		}
+		cache = tracers.NewTraceCache(db, uint64(cfg.TraceCache)*1024*1024, backend.BlockChain())
+		stack.RegisterLifecycle(cache)
+	}
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend, cache))
 	return backend.APIBackend, backend
 }
 
@@ -0,0 +1,141 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package authority
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// API is a user facing RPC API to allow inspecting the validators, the block
+// signatures and the finality of the permissioned proof-of-authority scheme.
+type API struct {
+	chain     consensus.ChainHeaderReader
+	authority *Authority
+}
+
+// header retrieves the requested header, or the current one if none requested.
+func (api *API) header(number *rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else if *number == rpc.FinalizedBlockNumber {
+		api.authority.rewindFinality(api.chain)
+		final := api.authority.Finalized()
+		header = api.chain.GetHeader(final.Hash, final.Number)
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
+
+// GetValidators retrieves the validator set in effect after the specified block.
+func (api *API) GetValidators(number *rpc.BlockNumber) (*Validators, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.authority.validators(api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// GetValidatorsAtHash retrieves the validator set in effect after the specified block.
+func (api *API) GetValidatorsAtHash(hash common.Hash) (*Validators, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.authority.validators(api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// GetSigner returns the validator that sealed the specified block.
+func (api *API) GetSigner(number *rpc.BlockNumber) (common.Address, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return api.authority.Author(header)
+}
+
+// GetCommits returns the validators whose commit signatures over the specified
+// block were included in its canonical child.
+func (api *API) GetCommits(number *rpc.BlockNumber) ([]common.Address, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	child := api.chain.GetHeaderByNumber(header.Number.Uint64() + 1)
+	if child == nil || child.ParentHash != header.Hash() {
+		return nil, nil
+	}
+	extra, err := decodeExtra(child)
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]common.Address, 0, len(extra.Commits))
+	for _, sig := range extra.Commits {
+		signer, err := recoverCommit(header.Hash(), sig)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// GetFinalized returns the last block finalized by a quorum of validators.
+func (api *API) GetFinalized() Finality {
+	api.authority.rewindFinality(api.chain)
+	return api.authority.Finalized()
+}
+
+// Commit signs a commitment of the local validator to the specified block and
+// returns the signature. The commit is gossiped to the other validators too.
+func (api *API) Commit(hash common.Hash) (hexutil.Bytes, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	api.authority.lock.RLock()
+	signer, signFn := api.authority.signer, api.authority.signFn
+	api.authority.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errors.New("no local validator configured")
+	}
+	set, err := api.authority.validators(api.chain, header.Number.Uint64(), hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !set.contains(signer) {
+		return nil, errUnauthorizedValidator
+	}
+	return api.authority.commit(signer, signFn, header)
+}
+
+// SubmitCommit adds a commit signature made by another validator to the local
+// commit pool, to be included in the next block proposed by this node.
+func (api *API) SubmitCommit(hash common.Hash, sig hexutil.Bytes) (common.Address, error) {
+	return api.authority.SubmitCommit(api.chain, hash, sig)
+}
@@ -0,0 +1,1001 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package authority implements a permissioned proof-of-authority consensus engine
+// with a contract managed validator set and instant finality.
+//
+// Blocks are sealed by the validators of the current epoch, in a round-robin
+// fashion similar to clique. The validator set is refreshed at every epoch
+// block from a system contract, and every block carries the commit signatures
+// of the validators over its parent. Once a quorum of more than two thirds of
+// the validators committed to a block, it is final and no reorg below it is
+// accepted anymore. The commit signatures are gossiped between the validators
+// over the `commit` devp2p protocol.
+package authority
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	inmemoryValidators = 1024 // Number of recent validator sets to keep in memory
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+
+	wiggleTime = 500 * time.Millisecond // Random delay (per validator) to allow concurrent proposers
+)
+
+// Authority proof-of-authority protocol constants.
+var (
+	epochLength = uint64(30000) // Default number of blocks after which to refresh the validator set
+
+	extraVanity = 32                     // Fixed number of extra-data prefix bytes reserved for proposer vanity
+	extraSeal   = crypto.SignatureLength // Fixed number of extra-data suffix bytes reserved for proposer seal
+
+	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+
+	diffInTurn = big.NewInt(2) // Block difficulty for in-turn signatures
+	diffNoTurn = big.NewInt(1) // Block difficulty for out-of-turn signatures
+
+	finalityKey = []byte("authority-finalized") // Database key of the last finalized block
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the proposer vanity.
+	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errMissingSignature is returned if a block's extra-data section doesn't seem
+	// to contain a 65 byte secp256k1 signature.
+	errMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
+
+	// errInvalidExtraData is returned if the consensus payload of a block's
+	// extra-data section cannot be decoded.
+	errInvalidExtraData = errors.New("invalid extra-data payload")
+
+	// errExtraValidators is returned if a non-epoch block contains a validator list.
+	errExtraValidators = errors.New("non-epoch block contains validator list")
+
+	// errEmptyValidators is returned if an epoch block or the validator contract
+	// defines an empty validator set.
+	errEmptyValidators = errors.New("empty validator set")
+
+	// errMismatchingValidators is returned if an epoch block contains a validator
+	// set different than the one the local node retrieved from the contract.
+	errMismatchingValidators = errors.New("mismatching validator set on epoch block")
+
+	// errInvalidCommit is returned if a block contains a malformed commit signature,
+	// one not made by a validator, or more than one made by the same validator.
+	errInvalidCommit = errors.New("invalid commit signature")
+
+	// errFinalityViolation is returned if a block is not a descendant of the last
+	// finalized block.
+	errFinalityViolation = errors.New("block conflicts with finalized block")
+
+	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
+	errInvalidMixDigest = errors.New("non-zero mix digest")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block neither 1 or 2.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errWrongDifficulty is returned if the difficulty of a block doesn't match the
+	// turn of the proposer.
+	errWrongDifficulty = errors.New("wrong difficulty")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errUnauthorizedValidator is returned if a header is signed by a non-validator.
+	errUnauthorizedValidator = errors.New("unauthorized validator")
+)
+
+// SignerFn hashes and signs the data to be signed by a backing account.
+type SignerFn func(signer accounts.Account, mimeType string, message []byte) ([]byte, error)
+
+// ecrecover extracts the Ethereum account address from a signed header.
+func ecrecover(header *types.Header, sigcache *lru.Cache[common.Hash, common.Address]) (common.Address, error) {
+	// If the signature's already cached, return that
+	hash := header.Hash()
+	if address, known := sigcache.Get(hash); known {
+		return address, nil
+	}
+	// Retrieve the signature from the header extra-data
+	if len(header.Extra) < extraSeal {
+		return common.Address{}, errMissingSignature
+	}
+	signature := header.Extra[len(header.Extra)-extraSeal:]
+
+	// Recover the public key and the Ethereum address
+	pubkey, err := crypto.Ecrecover(SealHash(header).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	sigcache.Add(hash, signer)
+	return signer, nil
+}
+
+// Finality is a reference to the last block finalized by a validator quorum.
+type Finality struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// CommitEvent is posted when a new commit signature of a validator over a block
+// is added to the commit pool.
+type CommitEvent struct {
+	Number    uint64
+	Hash      common.Hash
+	Signature []byte
+}
+
+// commitSet is the set of commit signatures collected for a block.
+type commitSet struct {
+	number uint64
+	sigs   map[common.Address][]byte
+}
+
+// Authority is the permissioned proof-of-authority consensus engine with a
+// contract managed validator set and instant finality.
+type Authority struct {
+	config *params.AuthorityConfig // Consensus engine configuration parameters
+	db     ethdb.Database          // Database to store and retrieve the finalized block
+
+	recents    *lru.Cache[common.Hash, *Validators]    // Validator sets for recent blocks to speed up reorgs
+	signatures *lru.Cache[common.Hash, common.Address] // Signatures of recent blocks to speed up mining
+
+	finalized Finality                   // Last block finalized by a validator quorum
+	commits   map[common.Hash]*commitSet // Commit signatures collected for recent blocks
+	committed map[uint64]common.Hash     // Blocks committed to by the local validator
+	flock     sync.RWMutex               // Protects the finality and commit fields
+
+	commitFeed  event.Feed              // Feed announcing newly added commit signatures
+	commitScope event.SubscriptionScope // Scope tracking the commit subscriptions
+
+	signer common.Address // Ethereum address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer fields
+
+	// The fields below are for testing only
+	fakeDiff bool // Skip difficulty verifications
+}
+
+// New creates an Authority proof-of-authority consensus engine with the initial
+// validators set to the ones in the genesis block.
+func New(config *params.AuthorityConfig, db ethdb.Database) *Authority {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = epochLength
+	}
+	a := &Authority{
+		config:     &conf,
+		db:         db,
+		recents:    lru.NewCache[common.Hash, *Validators](inmemoryValidators),
+		signatures: lru.NewCache[common.Hash, common.Address](inmemorySignatures),
+		commits:    make(map[common.Hash]*commitSet),
+		committed:  make(map[uint64]common.Hash),
+	}
+	if blob, err := db.Get(finalityKey); err == nil {
+		if err := rlp.DecodeBytes(blob, &a.finalized); err != nil {
+			log.Error("Failed to decode finalized block", "err", err)
+		}
+	}
+	return a
+}
+
+// Author implements consensus.Engine, returning the Ethereum address recovered
+// from the signature in the header's extra-data section.
+func (a *Authority) Author(header *types.Header) (common.Address, error) {
+	return ecrecover(header, a.signatures)
+}
+
+// Finalized returns the last block finalized by a quorum of validators.
+func (a *Authority) Finalized() Finality {
+	a.flock.RLock()
+	defer a.flock.RUnlock()
+
+	return a.finalized
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+func (a *Authority) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return a.verifyHeader(chain, header, nil)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (a *Authority) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := a.verifyHeader(chain, header, headers[:i])
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database. This is useful for concurrently verifying
+// a batch of new headers.
+func (a *Authority) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time > uint64(time.Now().Unix()) {
+		return consensus.ErrFutureBlock
+	}
+	// Check that the extra-data contains the vanity, payload and signature, and
+	// that validators are only listed in epoch blocks
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return err
+	}
+	epoch := number%a.config.Epoch == 0
+	if !epoch && len(extra.Validators) != 0 {
+		return errExtraValidators
+	}
+	if epoch && len(extra.Validators) == 0 {
+		return errEmptyValidators
+	}
+	// Ensure that the mix digest is zero as we don't have fork protection currently
+	if header.MixDigest != (common.Hash{}) {
+		return errInvalidMixDigest
+	}
+	// Ensure that the block doesn't contain any uncles which are meaningless in PoA
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	// Ensure that the block's difficulty is meaningful (may not be correct at this point)
+	if number > 0 {
+		if header.Difficulty == nil || (header.Difficulty.Cmp(diffInTurn) != 0 && header.Difficulty.Cmp(diffNoTurn) != 0) {
+			return errInvalidDifficulty
+		}
+	}
+	// Verify that the gas limit is <= 2^63-1
+	if header.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("invalid gasLimit: have %v, max %v", header.GasLimit, params.MaxGasLimit)
+	}
+	if chain.Config().IsShanghai(header.Number, header.Time) {
+		return errors.New("authority does not support shanghai fork")
+	}
+	// Verify the non-existence of withdrawalsHash.
+	if header.WithdrawalsHash != nil {
+		return fmt.Errorf("invalid withdrawalsHash: have %x, expected nil", header.WithdrawalsHash)
+	}
+	if chain.Config().IsCancun(header.Number, header.Time) {
+		return errors.New("authority does not support cancun fork")
+	}
+	// Verify the non-existence of cancun-specific header fields
+	switch {
+	case header.ExcessBlobGas != nil:
+		return fmt.Errorf("invalid excessBlobGas: have %d, expected nil", header.ExcessBlobGas)
+	case header.BlobGasUsed != nil:
+		return fmt.Errorf("invalid blobGasUsed: have %d, expected nil", header.BlobGasUsed)
+	case header.ParentBeaconRoot != nil:
+		return fmt.Errorf("invalid parentBeaconRoot, have %#x, expected nil", header.ParentBeaconRoot)
+	}
+	// All basic checks passed, verify cascading fields
+	return a.verifyCascadingFields(chain, header, extra, parents)
+}
+
+// verifyCascadingFields verifies all the header fields that are not standalone,
+// rather depend on a batch of previous headers. The caller may optionally pass
+// in a batch of parents (ascending order) to avoid looking those up from the
+// database. This is useful for concurrently verifying a batch of new headers.
+func (a *Authority) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, extra *extraData, parents []*types.Header) error {
+	// The genesis block is the always valid dead-end
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	// Ensure that the block's timestamp isn't too close to its parent
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time+a.config.Period > header.Time {
+		return errInvalidTimestamp
+	}
+	// Verify that the gasUsed is <= gasLimit
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if !chain.Config().IsLondon(header.Number) {
+		// Verify BaseFee not present before EIP-1559 fork.
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %d, want <nil>", header.BaseFee)
+		}
+		if err := misc.VerifyGaslimit(parent.GasLimit, header.GasLimit); err != nil {
+			return err
+		}
+	} else if err := eip1559.VerifyEIP1559Header(chain.Config(), parent, header); err != nil {
+		// Verify the header's EIP-1559 attributes.
+		return err
+	}
+	// Reject any block not descending from the last finalized one
+	if err := a.verifyFinality(chain, header, parents); err != nil {
+		return err
+	}
+	// Retrieve the validator set needed to verify this header
+	set, err := a.validators(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	// Verify the seal first, so only headers of validators are checked further.
+	// The validator list of epoch blocks depends on the parent state, which is
+	// not necessarily available during header verification. It is verified on
+	// import by VerifyUncles.
+	if err := a.verifySeal(set, header); err != nil {
+		return err
+	}
+	// Verify the parent commits, finality is only updated on import by Finalize
+	_, err = a.verifyCommits(set, parent, extra.Commits)
+	return err
+}
+
+// verifyFinality checks that the header is a descendant of the last finalized
+// block, or one of its ancestors, rejecting any reorg past finality.
+func (a *Authority) verifyFinality(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	a.rewindFinality(chain)
+
+	final := a.Finalized()
+	if final.Hash == (common.Hash{}) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	if number == final.Number {
+		if header.Hash() != final.Hash {
+			return errFinalityViolation
+		}
+		return nil
+	}
+	if number < final.Number {
+		// The header may only be an ancestor of the finalized block. If that is
+		// canonical, so are its ancestors, otherwise walk back its ancestry.
+		if canonical := chain.GetHeaderByNumber(final.Number); canonical != nil && canonical.Hash() == final.Hash {
+			if canonical := chain.GetHeaderByNumber(number); canonical == nil || canonical.Hash() != header.Hash() {
+				return errFinalityViolation
+			}
+			return nil
+		}
+		ancestor := chain.GetHeader(final.Hash, final.Number)
+		for ancestor != nil && ancestor.Number.Uint64() > number {
+			ancestor = chain.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+		}
+		// If the finalized block is not known locally, its ancestry is enforced
+		// once the chain reaches its height
+		if ancestor != nil && ancestor.Hash() != header.Hash() {
+			return errFinalityViolation
+		}
+		return nil
+	}
+	// Walk back the ancestry until the finalized height
+	hash := header.ParentHash
+	for number--; number > final.Number; number-- {
+		var ancestor *types.Header
+		if len(parents) > 0 && parents[len(parents)-1].Hash() == hash {
+			ancestor = parents[len(parents)-1]
+			parents = parents[:len(parents)-1]
+		} else {
+			ancestor = chain.GetHeader(hash, number)
+		}
+		if ancestor == nil {
+			return consensus.ErrUnknownAncestor
+		}
+		hash = ancestor.ParentHash
+	}
+	if hash != final.Hash {
+		return errFinalityViolation
+	}
+	return nil
+}
+
+// rewindFinality moves the finalized block back if the local chain was rewound
+// below it, e.g. by debug_setHead or when recovering from a crash. Any ancestor
+// of a finalized block is final too, so finality is moved to the common ancestor
+// of the local head and the finalized block, or to the head itself if the latter
+// is not known locally anymore.
+func (a *Authority) rewindFinality(chain consensus.ChainHeaderReader) {
+	final := a.Finalized()
+	if final.Hash == (common.Hash{}) {
+		return
+	}
+	head := chain.CurrentHeader()
+	if head == nil || head.Number.Uint64() >= final.Number {
+		return
+	}
+	ancestor := head
+	if header := chain.GetHeader(final.Hash, final.Number); header != nil {
+		for header != nil && header.Number.Uint64() > head.Number.Uint64() {
+			header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		}
+		for header != nil && ancestor != nil && header.Hash() != ancestor.Hash() {
+			header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+			ancestor = chain.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+		}
+		if header == nil || ancestor == nil {
+			ancestor = head
+		}
+	}
+	a.flock.Lock()
+	defer a.flock.Unlock()
+
+	if a.finalized != final {
+		return // finality updated concurrently
+	}
+	a.finalized = Finality{Number: ancestor.Number.Uint64(), Hash: ancestor.Hash()}
+	a.storeFinality()
+
+	log.Warn("Rewound finalized block", "old", final.Number, "new", a.finalized.Number, "hash", a.finalized.Hash)
+}
+
+// verifyCommits checks the commit signatures over the parent block carried by
+// a header, returning whether a quorum of validators committed to it.
+func (a *Authority) verifyCommits(set *Validators, parent *types.Header, commits [][]byte) (bool, error) {
+	hash := parent.Hash()
+
+	signers := make(map[common.Address]struct{}, len(commits))
+	for _, sig := range commits {
+		signer, err := recoverCommit(hash, sig)
+		if err != nil {
+			return false, errInvalidCommit
+		}
+		if !set.contains(signer) {
+			return false, errInvalidCommit
+		}
+		if _, ok := signers[signer]; ok {
+			return false, errInvalidCommit
+		}
+		signers[signer] = struct{}{}
+	}
+	return len(signers) >= set.quorum(), nil
+}
+
+// finalize marks the given block final if it's newer than the current one,
+// persisting it and dropping all commits collected for older blocks.
+func (a *Authority) finalize(number uint64, hash common.Hash) {
+	a.flock.Lock()
+	defer a.flock.Unlock()
+
+	if number <= a.finalized.Number {
+		return
+	}
+	a.finalized = Finality{Number: number, Hash: hash}
+	a.storeFinality()
+
+	for h, set := range a.commits {
+		if set.number < number {
+			delete(a.commits, h)
+		}
+	}
+	for n := range a.committed {
+		if n < number {
+			delete(a.committed, n)
+		}
+	}
+	log.Debug("Finalized block", "number", number, "hash", hash)
+}
+
+// storeFinality persists the finalized block. The finality lock must be held.
+func (a *Authority) storeFinality() {
+	blob, err := rlp.EncodeToBytes(&a.finalized)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	if err := a.db.Put(finalityKey, blob); err != nil {
+		log.Error("Failed to store finalized block", "err", err)
+	}
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+//
+// As it is invoked during block import after the parent block was processed, it
+// also verifies the validator list of epoch blocks against the system contract,
+// which needs the parent state.
+func (a *Authority) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return a.verifyValidators(chain, block.Header())
+}
+
+// verifyValidators checks the validator list of an epoch block against the one
+// returned by the validator contract on the parent state. A missing parent state
+// is an error, the list is never trusted unverified.
+func (a *Authority) verifyValidators(chain consensus.ChainHeaderReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 || number%a.config.Epoch != 0 {
+		return nil
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return err
+	}
+	set, err := a.validators(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	validators, err := a.nextValidators(chain, header, parent, set)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(validators, extra.Validators) {
+		return errMismatchingValidators
+	}
+	return nil
+}
+
+// verifySeal checks whether the signature contained in the header satisfies the
+// consensus protocol requirements.
+func (a *Authority) verifySeal(set *Validators, header *types.Header) error {
+	// Verifying the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	// Resolve the authorization key and check against validators
+	signer, err := ecrecover(header, a.signatures)
+	if err != nil {
+		return err
+	}
+	if !set.contains(signer) {
+		return errUnauthorizedValidator
+	}
+	// Ensure that the difficulty corresponds to the turn-ness of the signer
+	if !a.fakeDiff {
+		inturn := set.inturn(number, signer)
+		if inturn && header.Difficulty.Cmp(diffInTurn) != 0 {
+			return errWrongDifficulty
+		}
+		if !inturn && header.Difficulty.Cmp(diffNoTurn) != 0 {
+			return errWrongDifficulty
+		}
+	}
+	return nil
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (a *Authority) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	header.Nonce = types.BlockNonce{}
+
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	set, err := a.validators(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	a.lock.RLock()
+	signer, signFn := a.signer, a.signFn
+	a.lock.RUnlock()
+
+	// Set the correct difficulty
+	header.Difficulty = calcDifficulty(set, number, signer)
+
+	// Commit to the parent ourselves and gather all the known parent commits
+	if set.contains(signer) && signFn != nil {
+		if _, err := a.commit(signer, signFn, parent); err != nil {
+			log.Warn("Failed to commit to parent block", "number", number-1, "err", err)
+		}
+	}
+	extra := &extraData{Commits: a.parentCommits(parent.Hash())}
+
+	// On epoch blocks, retrieve the next validator set from the contract
+	if number%a.config.Epoch == 0 {
+		if extra.Validators, err = a.nextValidators(chain, header, parent, set); err != nil {
+			return err
+		}
+	}
+	// Ensure the extra data has all its components
+	vanity := header.Extra
+	if len(vanity) > extraVanity {
+		vanity = vanity[:extraVanity]
+	}
+	header.Extra = encodeExtra(vanity, extra)
+
+	// Mix digest is reserved for now, set to empty
+	header.MixDigest = common.Hash{}
+
+	// Ensure the timestamp has the correct delay
+	header.Time = parent.Time + a.config.Period
+	if header.Time < uint64(time.Now().Unix()) {
+		header.Time = uint64(time.Now().Unix())
+	}
+	return nil
+}
+
+// commit signs a commitment of the given validator to a block and adds it to
+// the commit pool. A validator only ever commits to a single block per height.
+func (a *Authority) commit(signer common.Address, signFn SignerFn, header *types.Header) ([]byte, error) {
+	var (
+		number = header.Number.Uint64()
+		hash   = header.Hash()
+	)
+	a.flock.Lock()
+	if committed, ok := a.committed[number]; ok && committed != hash {
+		a.flock.Unlock()
+		return nil, fmt.Errorf("already committed to block %x at height %d", committed, number)
+	}
+	if set, ok := a.commits[hash]; ok {
+		if sig, ok := set.sigs[signer]; ok {
+			a.flock.Unlock()
+			return sig, nil
+		}
+	}
+	a.committed[number] = hash
+	a.flock.Unlock()
+
+	sig, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeAuthorityCommit, commitData(hash))
+	if err != nil {
+		return nil, err
+	}
+	a.addCommit(number, hash, signer, sig)
+	return sig, nil
+}
+
+// addCommit inserts a verified commit signature into the commit pool, announcing
+// it to the commit subscribers if it was not known yet.
+func (a *Authority) addCommit(number uint64, hash common.Hash, signer common.Address, sig []byte) {
+	a.flock.Lock()
+	if number < a.finalized.Number {
+		a.flock.Unlock()
+		return
+	}
+	set, ok := a.commits[hash]
+	if !ok {
+		set = &commitSet{number: number, sigs: make(map[common.Address][]byte)}
+		a.commits[hash] = set
+	}
+	_, known := set.sigs[signer]
+	set.sigs[signer] = sig
+	a.flock.Unlock()
+
+	if !known {
+		a.commitFeed.Send(CommitEvent{Number: number, Hash: hash, Signature: sig})
+	}
+}
+
+// SubmitCommit verifies a commit signature made by a validator over the given
+// block and adds it to the commit pool, to be included in the next block proposed
+// by this node and to be relayed to the other validators.
+func (a *Authority) SubmitCommit(chain consensus.ChainHeaderReader, hash common.Hash, sig []byte) (common.Address, error) {
+	header := chain.GetHeaderByHash(hash)
+	if header == nil {
+		return common.Address{}, errUnknownBlock
+	}
+	signer, err := recoverCommit(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	set, err := a.validators(chain, header.Number.Uint64(), hash, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !set.contains(signer) {
+		return common.Address{}, fmt.Errorf("%w: %x", errUnauthorizedValidator, signer)
+	}
+	a.addCommit(header.Number.Uint64(), hash, signer, sig)
+	return signer, nil
+}
+
+// SubscribeCommits registers a subscription for the commit signatures newly
+// added to the commit pool, whether made locally or received from others.
+func (a *Authority) SubscribeCommits(ch chan<- CommitEvent) event.Subscription {
+	return a.commitScope.Track(a.commitFeed.Subscribe(ch))
+}
+
+// parentCommits returns the known commit signatures for a block, ordered by
+// validator address.
+func (a *Authority) parentCommits(hash common.Hash) [][]byte {
+	a.flock.RLock()
+	defer a.flock.RUnlock()
+
+	set, ok := a.commits[hash]
+	if !ok {
+		return nil
+	}
+	signers := make([]common.Address, 0, len(set.sigs))
+	for signer := range set.sigs {
+		signers = append(signers, signer)
+	}
+	slices.SortFunc(signers, common.Address.Cmp)
+
+	commits := make([][]byte, len(signers))
+	for i, signer := range signers {
+		commits[i] = set.sigs[signer]
+	}
+	return commits
+}
+
+// Finalize implements consensus.Engine. There are no block rewards in authority,
+// but if the block carries a quorum of commits over its parent, the parent gets
+// finalized. This is done on import rather than during header verification, so
+// that only blocks actually processed can move the finality.
+func (a *Authority) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return
+	}
+	extra, err := decodeExtra(header)
+	if err != nil {
+		return
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return
+	}
+	set, err := a.validators(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return
+	}
+	if quorum, err := a.verifyCommits(set, parent, extra.Commits); err == nil && quorum {
+		a.finalize(number-1, parent.Hash())
+	}
+}
+
+// FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
+// nor block rewards given, and returns the final block.
+func (a *Authority) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if len(withdrawals) > 0 {
+		return nil, errors.New("authority does not support withdrawals")
+	}
+	// Finalize block
+	a.Finalize(chain, header, state, txs, uncles, nil)
+
+	// Assign the final state root to header.
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+
+	// Assemble and return the final block for sealing.
+	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
+}
+
+// Authorize injects a private key into the consensus engine to mint new blocks
+// and sign commits with.
+func (a *Authority) Authorize(signer common.Address, signFn SignerFn) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.signer = signer
+	a.signFn = signFn
+}
+
+// Seal implements consensus.Engine, attempting to create a sealed block using
+// the local signing credentials.
+func (a *Authority) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
+	if a.config.Period == 0 && len(block.Transactions()) == 0 {
+		return errors.New("sealing paused while waiting for transactions")
+	}
+	// Don't hold the signer fields for the entire sealing procedure
+	a.lock.RLock()
+	signer, signFn := a.signer, a.signFn
+	a.lock.RUnlock()
+
+	// Bail out if we're unauthorized to sign a block
+	set, err := a.validators(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if !set.contains(signer) {
+		return errUnauthorizedValidator
+	}
+	// Sweet, the protocol permits us to sign the block, wait for our time
+	delay := time.Until(time.Unix(int64(header.Time), 0))
+	if header.Difficulty.Cmp(diffNoTurn) == 0 {
+		// It's not our turn explicitly to sign, delay it a bit
+		wiggle := time.Duration(len(set.Validators)/2+1) * wiggleTime
+		delay += time.Duration(rand.Int63n(int64(wiggle)))
+
+		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
+	}
+	// Sign all the things!
+	sighash, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeAuthority, AuthorityRLP(header))
+	if err != nil {
+		return err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+	// Wait until sealing is terminated or delay timeout.
+	log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
+	go func() {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		select {
+		case results <- block.WithSeal(header):
+		default:
+			log.Warn("Sealing result is not read by miner", "sealhash", SealHash(header))
+		}
+	}()
+
+	return nil
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
+// that a new block should have:
+// * DIFF_NOTURN(1) if BLOCK_NUMBER % VALIDATOR_COUNT != VALIDATOR_INDEX
+// * DIFF_INTURN(2) if BLOCK_NUMBER % VALIDATOR_COUNT == VALIDATOR_INDEX
+func (a *Authority) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	set, err := a.validators(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil
+	}
+	a.lock.RLock()
+	signer := a.signer
+	a.lock.RUnlock()
+	return calcDifficulty(set, parent.Number.Uint64()+1, signer)
+}
+
+func calcDifficulty(set *Validators, number uint64, signer common.Address) *big.Int {
+	if set.inturn(number, signer) {
+		return new(big.Int).Set(diffInTurn)
+	}
+	return new(big.Int).Set(diffNoTurn)
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+func (a *Authority) SealHash(header *types.Header) common.Hash {
+	return SealHash(header)
+}
+
+// Close implements consensus.Engine, terminating all the commit subscriptions.
+func (a *Authority) Close() error {
+	a.commitScope.Close()
+	return nil
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to allow
+// inspecting the validators and their signatures.
+func (a *Authority) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "authority",
+		Service:   &API{chain: chain, authority: a},
+	}}
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+func SealHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewLegacyKeccak256()
+	encodeSigHeader(hasher, header)
+	hasher.(crypto.KeccakState).Read(hash[:])
+	return hash
+}
+
+// AuthorityRLP returns the rlp bytes which needs to be signed for sealing. The
+// RLP to sign consists of the entire header apart from the 65 byte signature
+// contained at the end of the extra data.
+//
+// Note, the method requires the extra data to be at least 65 bytes, otherwise it
+// panics. This is done to avoid accidentally using both forms (signature present
+// or not), which could be abused to produce different hashes for the same header.
+func AuthorityRLP(header *types.Header) []byte {
+	b := new(bytes.Buffer)
+	encodeSigHeader(b, header)
+	return b.Bytes()
+}
+
+func encodeSigHeader(w io.Writer, header *types.Header) {
+	enc := []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-crypto.SignatureLength], // Yes, this will panic if extra is too short
+		header.MixDigest,
+		header.Nonce,
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if header.WithdrawalsHash != nil {
+		panic("unexpected withdrawal hash value in authority")
+	}
+	if header.ExcessBlobGas != nil {
+		panic("unexpected excess blob gas value in authority")
+	}
+	if header.BlobGasUsed != nil {
+		panic("unexpected blob gas used value in authority")
+	}
+	if header.ParentBeaconRoot != nil {
+		panic("unexpected parent beacon root value in authority")
+	}
+	if err := rlp.Encode(w, enc); err != nil {
+		panic("can't encode: " + err.Error())
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package authority
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// validatorCode returns the code of a validator contract which ignores its
+// input and always returns the ABI encoding of the given validators.
+func validatorCode(validators ...common.Address) []byte {
+	output := make([]byte, 64+32*len(validators))
+	output[31] = 0x20
+	output[63] = byte(len(validators))
+	for i, v := range validators {
+		copy(output[64+32*i+12:], v[:])
+	}
+	// PUSH1 len PUSH1 12 PUSH1 0 CODECOPY PUSH1 len PUSH1 0 RETURN
+	code := []byte{
+		byte(vm.PUSH1), byte(len(output)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(output)), byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	return append(code, output...)
+}
+
+// testChain is a helper to generate and seal authority chains.
+type testChain struct {
+	config  *params.ChainConfig
+	genesis *core.Genesis
+	key     *ecdsa.PrivateKey
+	addr    common.Address
+}
+
+var (
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+func newTestChain(t *testing.T, contract []common.Address) *testChain {
+	key, addr := testKey, testAddr
+
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.Authority = &params.AuthorityConfig{Epoch: 4, ValidatorContract: common.Address{0xaa}}
+
+	genesis := &core.Genesis{
+		Config:    &config,
+		ExtraData: GenesisExtra([]common.Address{addr}),
+		Alloc: core.GenesisAlloc{
+			addr: {Balance: big.NewInt(10000000000000000)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	if contract != nil {
+		genesis.Alloc[common.Address{0xaa}] = core.GenesisAccount{Code: validatorCode(contract...), Balance: new(big.Int)}
+	}
+	return &testChain{config: &config, genesis: genesis, key: key, addr: addr}
+}
+
+// generate creates n blocks on top of the genesis, committing to every parent
+// and listing the given validators in epoch blocks. Blocks from index fork on
+// get a different vanity, forking them off from the ones without.
+func (tc *testChain) generate(engine *Authority, n int, fork int, validators []common.Address) []*types.Block {
+	_, blocks, _ := core.GenerateChainWithGenesis(tc.genesis, engine, n, func(i int, block *core.BlockGen) {
+		block.SetDifficulty(diffInTurn)
+	})
+	parent := tc.genesis.ToBlock().Hash()
+	for i, block := range blocks {
+		header := block.Header()
+		header.ParentHash = parent
+
+		sig, _ := crypto.Sign(CommitHash(header.ParentHash).Bytes(), tc.key)
+		extra := &extraData{Commits: [][]byte{sig}}
+		if header.Number.Uint64()%4 == 0 {
+			extra.Validators = validators
+		}
+		var vanity []byte
+		if i >= fork {
+			vanity = []byte{0x01}
+		}
+		header.Extra = encodeExtra(vanity, extra)
+		header.Difficulty = diffInTurn
+
+		seal, _ := crypto.Sign(SealHash(header).Bytes(), tc.key)
+		copy(header.Extra[len(header.Extra)-extraSeal:], seal)
+		blocks[i] = block.WithSeal(header)
+		parent = blocks[i].Hash()
+	}
+	return blocks
+}
+
+// Tests that blocks committed to by the validators become final, and that no
+// reorg below the finalized block is accepted.
+func TestFinality(t *testing.T) {
+	var (
+		tc     = newTestChain(t, nil)
+		db     = rawdb.NewMemoryDatabase()
+		engine = New(tc.config.Authority, db)
+	)
+	engine.fakeDiff = true
+
+	chain, err := core.NewBlockChain(db, nil, tc.genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks := tc.generate(engine, 6, 6, []common.Address{tc.addr})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 5 || final.Hash != blocks[4].Hash() {
+		t.Fatalf("finalized block mismatch: have %d/%x, want %d/%x", final.Number, final.Hash, 5, blocks[4].Hash())
+	}
+	// Try to reorg below the finalized block
+	fork := tc.generate(engine, 7, 3, []common.Address{tc.addr})
+	if _, err := chain.InsertChain(fork[3:]); !errors.Is(err, errFinalityViolation) {
+		t.Fatalf("reorg past finality: have %v, want %v", err, errFinalityViolation)
+	}
+	// Extending the finalized block must still work
+	next := tc.generate(engine, 8, 8, []common.Address{tc.addr})
+	if _, err := chain.InsertChain(next[6:]); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 7 {
+		t.Fatalf("finalized number mismatch: have %d, want %d", final.Number, 7)
+	}
+	// Ensure the finality survives a restart
+	if final := New(tc.config.Authority, db).Finalized(); final.Number != 7 {
+		t.Fatalf("persisted finalized number mismatch: have %d, want %d", final.Number, 7)
+	}
+}
+
+// Tests that rewinding the chain below the finalized block moves the finality
+// back, so the blocks can be reimported.
+func TestFinalityRewind(t *testing.T) {
+	var (
+		tc     = newTestChain(t, nil)
+		db     = rawdb.NewMemoryDatabase()
+		engine = New(tc.config.Authority, db)
+	)
+	engine.fakeDiff = true
+
+	chain, err := core.NewBlockChain(db, nil, tc.genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks := tc.generate(engine, 6, 6, []common.Address{tc.addr})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 5 {
+		t.Fatalf("finalized number mismatch: have %d, want %d", final.Number, 5)
+	}
+	// Rewind below the finalized block and reimport the dropped blocks
+	if err := chain.SetHead(2); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("failed to reimport blocks: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 5 || final.Hash != blocks[4].Hash() {
+		t.Fatalf("finalized block mismatch: have %d/%x, want %d/%x", final.Number, final.Hash, 5, blocks[4].Hash())
+	}
+	// Ancestors of the finalized block are accepted by hash, others rejected
+	if err := engine.verifyFinality(chain, blocks[3].Header(), nil); err != nil {
+		t.Fatalf("ancestor of finalized block rejected: %v", err)
+	}
+	fork := tc.generate(engine, 4, 3, []common.Address{tc.addr})
+	if err := engine.verifyFinality(chain, fork[3].Header(), nil); !errors.Is(err, errFinalityViolation) {
+		t.Fatalf("conflicting block: have %v, want %v", err, errFinalityViolation)
+	}
+}
+
+// Tests that finality is only moved by imported blocks, not by headers merely
+// verified or ones failing verification.
+func TestFinalityOnImport(t *testing.T) {
+	var (
+		tc     = newTestChain(t, nil)
+		db     = rawdb.NewMemoryDatabase()
+		engine = New(tc.config.Authority, db)
+	)
+	engine.fakeDiff = true
+
+	chain, err := core.NewBlockChain(db, nil, tc.genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks := tc.generate(engine, 3, 3, []common.Address{tc.addr})
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 1 {
+		t.Fatalf("finalized number mismatch: have %d, want %d", final.Number, 1)
+	}
+	// A header with a valid commit quorum but a bad seal must not finalize
+	header := blocks[2].Header()
+	key, _ := crypto.GenerateKey()
+	seal, _ := crypto.Sign(SealHash(header).Bytes(), key)
+	copy(header.Extra[len(header.Extra)-extraSeal:], seal)
+	if _, err := chain.InsertChain(types.Blocks{blocks[2].WithSeal(header)}); !errors.Is(err, errUnauthorizedValidator) {
+		t.Fatalf("bad seal: have %v, want %v", err, errUnauthorizedValidator)
+	}
+	if final := engine.Finalized(); final.Number != 1 {
+		t.Fatalf("finalized number mismatch after bad seal: have %d, want %d", final.Number, 1)
+	}
+	// A valid header only verified must not finalize either
+	if err := engine.VerifyHeader(chain, blocks[2].Header()); err != nil {
+		t.Fatalf("failed to verify header: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 1 {
+		t.Fatalf("finalized number mismatch after verification: have %d, want %d", final.Number, 1)
+	}
+	// Importing the block must finalize its parent
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	if final := engine.Finalized(); final.Number != 2 || final.Hash != blocks[1].Hash() {
+		t.Fatalf("finalized block mismatch: have %d/%x, want %d/%x", final.Number, final.Hash, 2, blocks[1].Hash())
+	}
+}
+
+// Tests that the validator set of epoch blocks is verified against the system
+// contract.
+func TestValidatorContract(t *testing.T) {
+	var (
+		other  = common.Address{0xbb}
+		tc     = newTestChain(t, []common.Address{other, testAddr})
+		engine = New(tc.config.Authority, rawdb.NewMemoryDatabase())
+	)
+	engine.fakeDiff = true
+
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, tc.genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// An epoch block not matching the contract must be rejected
+	bad := tc.generate(engine, 4, 4, []common.Address{tc.addr})
+	if _, err := chain.InsertChain(bad); !errors.Is(err, errMismatchingValidators) {
+		t.Fatalf("mismatching validators: have %v, want %v", err, errMismatchingValidators)
+	}
+	// An epoch block matching the contract must be accepted and take effect
+	want := []common.Address{tc.addr, other}
+	if other.Cmp(tc.addr) < 0 {
+		want = []common.Address{other, tc.addr}
+	}
+	good := tc.generate(engine, 5, 5, want)
+	if _, err := chain.InsertChain(good); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	set, err := engine.validators(chain, 4, good[3].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve validators: %v", err)
+	}
+	if len(set.Validators) != 2 || set.Epoch != 4 {
+		t.Fatalf("validator set mismatch: have %v at epoch %d", set.Validators, set.Epoch)
+	}
+	// With two validators, a single commit over the epoch block is not a quorum
+	if final := engine.Finalized(); final.Number != 3 {
+		t.Fatalf("finalized number mismatch: have %d, want %d", final.Number, 3)
+	}
+}
+
+func TestSealHash(t *testing.T) {
+	have := SealHash(&types.Header{
+		Difficulty: new(big.Int),
+		Number:     new(big.Int),
+		Extra:      make([]byte, 32+65),
+		BaseFee:    new(big.Int),
+	})
+	want := common.HexToHash("0xbd3d1fa43fbc4c5bfcc91b179ec92e2861df3654de60468beb908ff805359e8f")
+	if have != want {
+		t.Errorf("have %x, want %x", have, want)
+	}
+}
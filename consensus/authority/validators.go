@@ -0,0 +1,254 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package authority
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+	"golang.org/x/exp/slices"
+)
+
+// validatorSetABI is the interface the validator system contract must implement.
+const validatorSetABI = `[{"inputs":[],"name":"getValidators","outputs":[{"name":"","type":"address[]"}],"stateMutability":"view","type":"function"}]`
+
+// validatorSetGas is the gas allowance for querying the validator system contract.
+const validatorSetGas = 50_000_000
+
+var validatorSet, _ = abi.JSON(strings.NewReader(validatorSetABI))
+
+// extraData is the consensus specific payload stored in a header's extra-data
+// section, between the vanity prefix and the seal suffix.
+type extraData struct {
+	Validators []common.Address // Validator set for the next epoch, only set in epoch blocks
+	Commits    [][]byte         // Validator commit signatures finalizing the parent block
+}
+
+// decodeExtra extracts the consensus payload from a header's extra-data.
+func decodeExtra(header *types.Header) (*extraData, error) {
+	if len(header.Extra) < extraVanity {
+		return nil, errMissingVanity
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	extra := new(extraData)
+	if err := rlp.DecodeBytes(header.Extra[extraVanity:len(header.Extra)-extraSeal], extra); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidExtraData, err)
+	}
+	return extra, nil
+}
+
+// encodeExtra assembles a header's extra-data from the given vanity and payload,
+// reserving an empty seal at the end.
+func encodeExtra(vanity []byte, extra *extraData) []byte {
+	payload, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	buf := make([]byte, extraVanity, extraVanity+len(payload)+extraSeal)
+	copy(buf, vanity)
+	buf = append(buf, payload...)
+	return append(buf, make([]byte, extraSeal)...)
+}
+
+// GenesisExtra returns the extra-data for a genesis block that sets the initial
+// validator set of an authority chain.
+func GenesisExtra(validators []common.Address) []byte {
+	validators = slices.Clone(validators)
+	slices.SortFunc(validators, common.Address.Cmp)
+	return encodeExtra(nil, &extraData{Validators: validators})
+}
+
+// CommitHash returns the hash a validator signs to commit to a block.
+func CommitHash(hash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(commitData(hash))
+}
+
+// commitData returns the message a validator signs to commit to a block.
+func commitData(hash common.Hash) []byte {
+	return append([]byte("authority commit"), hash.Bytes()...)
+}
+
+// recoverCommit extracts the validator address from a commit signature.
+func recoverCommit(hash common.Hash, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errInvalidCommit
+	}
+	pubkey, err := crypto.Ecrecover(CommitHash(hash).Bytes(), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// Validators is the validator set in effect after a given block.
+type Validators struct {
+	Number     uint64           `json:"number"`     // Block number the set was retrieved at
+	Hash       common.Hash      `json:"hash"`       // Block hash the set was retrieved at
+	Epoch      uint64           `json:"epoch"`      // Number of the epoch block defining the set
+	Validators []common.Address `json:"validators"` // Sorted list of validators
+}
+
+// contains returns whether the given address is in the validator set.
+func (v *Validators) contains(addr common.Address) bool {
+	_, found := slices.BinarySearchFunc(v.Validators, addr, common.Address.Cmp)
+	return found
+}
+
+// inturn returns whether the given address is the designated proposer of the
+// given block number.
+func (v *Validators) inturn(number uint64, addr common.Address) bool {
+	if len(v.Validators) == 0 {
+		return false
+	}
+	return v.Validators[number%uint64(len(v.Validators))] == addr
+}
+
+// quorum returns the number of commits needed to finalize a block.
+func (v *Validators) quorum() int {
+	return len(v.Validators)*2/3 + 1
+}
+
+// validators retrieves the validator set in effect after the given block, i.e.
+// the set which is allowed to seal its children.
+func (a *Authority) validators(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Validators, error) {
+	var (
+		hashes []common.Hash
+		set    *Validators
+	)
+	for set == nil {
+		if s, ok := a.recents.Get(hash); ok {
+			set = s
+			break
+		}
+		var header *types.Header
+		if len(parents) > 0 && parents[len(parents)-1].Hash() == hash {
+			header = parents[len(parents)-1]
+			parents = parents[:len(parents)-1]
+		} else {
+			header = chain.GetHeader(hash, number)
+		}
+		if header == nil || header.Number.Uint64() != number {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		if number%a.config.Epoch == 0 {
+			extra, err := decodeExtra(header)
+			if err != nil {
+				return nil, err
+			}
+			set = &Validators{
+				Epoch:      number,
+				Validators: extra.Validators,
+			}
+			break
+		}
+		hashes = append(hashes, hash)
+		number, hash = number-1, header.ParentHash
+	}
+	if set.Hash == (common.Hash{}) {
+		set.Number, set.Hash = number, hash
+		a.recents.Add(hash, set)
+	}
+	// Propagate the set to all the traversed blocks and cache them
+	for i := len(hashes) - 1; i >= 0; i-- {
+		cpy := *set
+		cpy.Number, cpy.Hash = cpy.Number+1, hashes[i]
+		a.recents.Add(hashes[i], &cpy)
+		set = &cpy
+	}
+	return set, nil
+}
+
+// stateReader is implemented by chains capable of providing historical state,
+// which is needed to query the validator system contract.
+type stateReader interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// errStateUnavailable is returned if the validator set cannot be queried as the
+// chain is not capable of providing historical state.
+var errStateUnavailable = errors.New("state unavailable")
+
+// nextValidators queries the validator system contract for the validator set of
+// the epoch starting at the given header, evaluated on the state of its parent.
+// If the contract is not deployed, the current set is retained. A missing parent
+// state is reported as consensus.ErrPrunedAncestor.
+func (a *Authority) nextValidators(chain consensus.ChainHeaderReader, header *types.Header, parent *types.Header, current *Validators) ([]common.Address, error) {
+	reader, ok := chain.(stateReader)
+	if !ok {
+		return nil, errStateUnavailable
+	}
+	statedb, err := reader.StateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", consensus.ErrPrunedAncestor, err)
+	}
+	if statedb.GetCodeSize(a.config.ValidatorContract) == 0 {
+		return current.Validators, nil
+	}
+	input, err := validatorSet.Pack("getValidators")
+	if err != nil {
+		return nil, err
+	}
+	var (
+		context = vm.BlockContext{
+			CanTransfer: func(db vm.StateDB, addr common.Address, amount *uint256.Int) bool {
+				return db.GetBalance(addr).Cmp(amount) >= 0
+			},
+			Transfer: func(vm.StateDB, common.Address, common.Address, *uint256.Int) {},
+			GetHash:  func(n uint64) common.Hash { return common.Hash{} },
+
+			Coinbase:    header.Coinbase,
+			GasLimit:    math.MaxUint64,
+			BlockNumber: new(big.Int).Set(header.Number),
+			Time:        header.Time,
+			Difficulty:  new(big.Int),
+			BaseFee:     new(big.Int),
+			BlobBaseFee: new(big.Int),
+		}
+		evm = vm.NewEVM(context, vm.TxContext{GasPrice: new(big.Int)}, statedb, chain.Config(), vm.Config{NoBaseFee: true})
+	)
+	output, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), a.config.ValidatorContract, input, validatorSetGas)
+	if err != nil {
+		return nil, fmt.Errorf("validator contract call failed: %w", err)
+	}
+	results, err := validatorSet.Unpack("getValidators", output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validator contract output: %w", err)
+	}
+	validators := slices.Clone(results[0].([]common.Address))
+	slices.SortFunc(validators, common.Address.Cmp)
+	validators = slices.Compact(validators)
+	if len(validators) == 0 {
+		return nil, errEmptyValidators
+	}
+	return validators, nil
+}
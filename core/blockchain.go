@@ -146,6 +146,11 @@ type CacheConfig struct {
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
+
+	// StateWrapper optionally wraps the state database of the chain, e.g. to
+	// serve the state of a remote chain on development forks. Snapshots must
+	// be disabled when it is set, as they bypass the state database.
+	StateWrapper func(state.Database) state.Database
 }
 
 // triedbConfig derives the configures for trie database.
@@ -306,6 +311,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.flushInterval.Store(int64(cacheConfig.TrieTimeLimit))
 	bc.forker = NewForkChoice(bc, shouldPreserve)
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
+	if cacheConfig.StateWrapper != nil {
+		bc.stateCache = cacheConfig.StateWrapper(bc.stateCache)
+	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
 	bc.processor = NewStateProcessor(chainConfig, bc, engine)
@@ -489,6 +489,35 @@ func (g *Genesis) ToBlock() *types.Block {
 	return types.NewBlock(head, nil, nil, nil, trie.NewStackTrie(nil)).WithWithdrawals(withdrawals)
 }
 
+// verifyAuthorityExtra checks that the extra-data of an authority genesis block
+// defines the initial validators: a 32 byte vanity, followed by the RLP encoded
+// non-empty, sorted list of distinct validators with no commits, and a 65 byte
+// seal.
+func verifyAuthorityExtra(extra []byte) error {
+	if len(extra) < 32+crypto.SignatureLength {
+		return errors.New("extra-data too short")
+	}
+	var payload struct {
+		Validators []common.Address
+		Commits    [][]byte
+	}
+	if err := rlp.DecodeBytes(extra[32:len(extra)-crypto.SignatureLength], &payload); err != nil {
+		return fmt.Errorf("invalid extra-data payload: %v", err)
+	}
+	if len(payload.Validators) == 0 {
+		return errors.New("empty validator set")
+	}
+	if len(payload.Commits) != 0 {
+		return errors.New("unexpected commits")
+	}
+	for i := 1; i < len(payload.Validators); i++ {
+		if payload.Validators[i-1].Cmp(payload.Validators[i]) >= 0 {
+			return errors.New("validators not sorted or duplicated")
+		}
+	}
+	return nil
+}
+
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database, triedb *trie.Database) (*types.Block, error) {
@@ -506,6 +535,11 @@ func (g *Genesis) Commit(db ethdb.Database, triedb *trie.Database) (*types.Block
 	if config.Clique != nil && len(block.Extra()) < 32+crypto.SignatureLength {
 		return nil, errors.New("can't start clique chain without signers")
 	}
+	if config.Authority != nil {
+		if err := verifyAuthorityExtra(block.Extra()); err != nil {
+			return nil, fmt.Errorf("can't start authority chain without validators: %w", err)
+		}
+	}
 	// All the checks has passed, flush the states derived from the genesis
 	// specification as well as the specification itself into the provided
 	// database.
@@ -28,8 +28,10 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
 )
@@ -43,6 +45,37 @@ func TestInvalidCliqueConfig(t *testing.T) {
 	}
 }
 
+func TestInvalidAuthorityConfig(t *testing.T) {
+	extra := func(validators []common.Address, commits [][]byte) []byte {
+		payload, _ := rlp.EncodeToBytes([]interface{}{validators, commits})
+		buf := append(make([]byte, 32), payload...)
+		return append(buf, make([]byte, crypto.SignatureLength)...)
+	}
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.Authority = &params.AuthorityConfig{Epoch: 30000}
+
+	tests := []struct {
+		extra []byte
+		fail  bool
+	}{
+		{extra: nil, fail: true},
+		{extra: make([]byte, 32+crypto.SignatureLength), fail: true},
+		{extra: extra(nil, nil), fail: true},
+		{extra: extra([]common.Address{{0x02}, {0x01}}, nil), fail: true},
+		{extra: extra([]common.Address{{0x01}, {0x01}}, nil), fail: true},
+		{extra: extra([]common.Address{{0x01}}, [][]byte{{0x01}}), fail: true},
+		{extra: extra([]common.Address{{0x01}, {0x02}}, nil), fail: false},
+	}
+	for i, tt := range tests {
+		genesis := &Genesis{Config: &config, ExtraData: tt.extra, BaseFee: big.NewInt(params.InitialBaseFee)}
+		db := rawdb.NewMemoryDatabase()
+		if _, err := genesis.Commit(db, trie.NewDatabase(db, nil)); (err != nil) != tt.fail {
+			t.Errorf("test %d: failure mismatch: have %v, want failure %v", i, err, tt.fail)
+		}
+	}
+}
+
 func TestSetupGenesis(t *testing.T) {
 	testSetupGenesis(t, rawdb.HashScheme)
 	testSetupGenesis(t, rawdb.PathScheme)
@@ -0,0 +1,115 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkstate
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is the access to the remote chain needed to fork off its state.
+type Client interface {
+	// ChainID retrieves the chain ID of the remote chain.
+	ChainID(ctx context.Context) (*big.Int, error)
+
+	// HeaderByNumber retrieves a header of the remote chain, or the latest one
+	// if number is nil.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+
+	// GetProof retrieves the Merkle proofs of an account and some of its storage
+	// slots at the given block.
+	GetProof(ctx context.Context, account common.Address, slots []common.Hash, number uint64) (*AccountResult, error)
+
+	// CodeAt retrieves the code of an account at the given block.
+	CodeAt(ctx context.Context, account common.Address, number uint64) ([]byte, error)
+}
+
+// AccountResult is the part of an eth_getProof response needed to verify the
+// account and storage values against the state root of the forked block.
+type AccountResult struct {
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the proof of a single storage slot in an eth_getProof response.
+type StorageResult struct {
+	Key   string          `json:"key"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// rpcClient is a Client backed by the JSON-RPC API of a remote node.
+type rpcClient struct {
+	client *rpc.Client
+}
+
+// NewClient creates a Client using the JSON-RPC API of a remote node.
+func NewClient(client *rpc.Client) Client {
+	return &rpcClient{client: client}
+}
+
+// ChainID implements Client, retrieving the chain ID via eth_chainId.
+func (c *rpcClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.client.CallContext(ctx, &result, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// HeaderByNumber implements Client, retrieving a header via eth_getBlockByNumber.
+func (c *rpcClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	tag := "latest"
+	if number != nil {
+		tag = hexutil.EncodeBig(number)
+	}
+	var head *types.Header
+	if err := c.client.CallContext(ctx, &head, "eth_getBlockByNumber", tag, false); err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, errors.New("remote block not found")
+	}
+	return head, nil
+}
+
+// GetProof implements Client, retrieving the proofs via eth_getProof.
+func (c *rpcClient) GetProof(ctx context.Context, account common.Address, slots []common.Hash, number uint64) (*AccountResult, error) {
+	keys := make([]string, len(slots))
+	for i, slot := range slots {
+		keys[i] = slot.Hex()
+	}
+	var result AccountResult
+	if err := c.client.CallContext(ctx, &result, "eth_getProof", account, keys, hexutil.Uint64(number)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CodeAt implements Client, retrieving the code via eth_getCode.
+func (c *rpcClient) CodeAt(ctx context.Context, account common.Address, number uint64) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := c.client.CallContext(ctx, &result, "eth_getCode", account, hexutil.Uint64(number)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
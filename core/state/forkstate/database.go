@@ -0,0 +1,358 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package forkstate implements a state database which lazily retrieves the state
+// of a remote chain, allowing a local development chain to fork off it.
+//
+// The local chain only stores the accounts and storage slots it modifies in its
+// state tries, everything else is read from the forked block of the remote
+// chain via eth_getProof and eth_getCode. Remote values are verified against the
+// state root of the forked block and cached in the local database.
+//
+// As the state tries only contain the local modifications, the state roots of
+// the local chain differ from the remote ones, and its blocks cannot be verified
+// by nodes without access to the same remote state.
+package forkstate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+)
+
+// remoteTimeout is the maximum time allowed for a single remote state request.
+const remoteTimeout = 30 * time.Second
+
+var (
+	// accountPrefix + remote root + address -> presence flag + account RLP
+	accountPrefix = []byte("forkstate-a")
+
+	// storagePrefix + remote root + address + slot -> slot value
+	storagePrefix = []byte("forkstate-s")
+
+	// originKey tracks the remote block the local chain was forked off
+	originKey = []byte("forkstate-origin")
+
+	// deletedCodeHash marks a remote account deleted on the local chain. No code
+	// is known to hash to it, so it cannot collide with live accounts.
+	deletedCodeHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+	// deletedSlot marks a remote storage slot cleared on the local chain. Slot
+	// values are stored with their leading zeroes trimmed, so a single zero byte
+	// never collides with a live value.
+	deletedSlot = []byte{0x00}
+)
+
+// Origin identifies the remote block the local chain forked off.
+type Origin struct {
+	Number uint64      // Number of the forked remote block
+	Root   common.Hash // State root of the forked remote block
+}
+
+// Database is a state database holding the local modifications of a forked
+// chain, and lazily retrieving everything else from the remote chain.
+type Database struct {
+	state.Database
+
+	client Client
+	origin Origin
+	disk   ethdb.KeyValueStore
+}
+
+// NewDatabase wraps a local state database to fall back to the state of the
+// remote chain at the given origin. If the local database was already forked
+// off a different block, the original fork point is retained.
+func NewDatabase(db state.Database, client Client, origin Origin) *Database {
+	disk := db.DiskDB()
+	if blob, _ := disk.Get(originKey); len(blob) > 0 {
+		var stored Origin
+		if err := rlp.DecodeBytes(blob, &stored); err != nil {
+			log.Error("Invalid fork origin in database", "err", err)
+		} else if stored != origin {
+			log.Warn("Retaining original fork point", "number", stored.Number, "root", stored.Root, "requested", origin.Number)
+			origin = stored
+		}
+	} else {
+		blob, err := rlp.EncodeToBytes(&origin)
+		if err != nil {
+			panic(err) // can't happen
+		}
+		if err := disk.Put(originKey, blob); err != nil {
+			log.Crit("Failed to store fork origin", "err", err)
+		}
+	}
+	return &Database{
+		Database: db,
+		client:   client,
+		origin:   origin,
+		disk:     disk,
+	}
+}
+
+// Origin returns the remote block the database forked off.
+func (db *Database) Origin() Origin {
+	return db.origin
+}
+
+// OpenTrie implements state.Database, opening the local account trie.
+func (db *Database) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, db: db}, nil
+}
+
+// OpenStorageTrie implements state.Database, opening the local storage trie of
+// an account.
+func (db *Database) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self state.Trie) (state.Trie, error) {
+	if ft, ok := self.(*forkTrie); ok {
+		self = ft.Trie
+	}
+	tr, err := db.Database.OpenStorageTrie(stateRoot, address, root, self)
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, db: db}, nil
+}
+
+// CopyTrie implements state.Database, returning an independent copy of a trie.
+func (db *Database) CopyTrie(t state.Trie) state.Trie {
+	if ft, ok := t.(*forkTrie); ok {
+		return &forkTrie{Trie: db.Database.CopyTrie(ft.Trie), db: db}
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// ContractCode implements state.Database, retrieving the code from the remote
+// chain if it's not available locally.
+func (db *Database) ContractCode(addr common.Address, codeHash common.Hash) ([]byte, error) {
+	if code, err := db.Database.ContractCode(addr, codeHash); err == nil {
+		return code, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+
+	code, err := db.client.CodeAt(ctx, addr, db.origin.Number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve remote code of %x: %w", addr, err)
+	}
+	if hash := crypto.Keccak256Hash(code); hash != codeHash {
+		return nil, fmt.Errorf("remote code hash mismatch for %x: have %x, want %x", addr, hash, codeHash)
+	}
+	rawdb.WriteCode(db.disk, codeHash, code)
+	return code, nil
+}
+
+// ContractCodeSize implements state.Database, retrieving the code size from the
+// remote chain if it's not available locally.
+func (db *Database) ContractCodeSize(addr common.Address, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addr, codeHash)
+	return len(code), err
+}
+
+// remoteAccount retrieves an account from the remote chain, or nil if it does
+// not exist there.
+func (db *Database) remoteAccount(addr common.Address) (*types.StateAccount, error) {
+	key := append(append(append([]byte{}, accountPrefix...), db.origin.Root[:]...), addr[:]...)
+	if blob, err := db.disk.Get(key); err == nil && len(blob) > 0 {
+		if blob[0] == 0 {
+			return nil, nil
+		}
+		return types.FullAccount(blob[1:])
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+
+	result, err := db.client.GetProof(ctx, addr, nil, db.origin.Number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve remote account %x: %w", addr, err)
+	}
+	enc, err := verifyProof(db.origin.Root, crypto.Keccak256(addr[:]), result.AccountProof)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proof for remote account %x: %w", addr, err)
+	}
+	var account *types.StateAccount
+	if len(enc) > 0 {
+		if account, err = types.FullAccount(enc); err != nil {
+			return nil, err
+		}
+	}
+	// Cache the account, flagging whether it exists on the remote chain
+	blob := []byte{0}
+	if account != nil {
+		blob = append([]byte{1}, enc...)
+	}
+	if err := db.disk.Put(key, blob); err != nil {
+		log.Warn("Failed to cache remote account", "addr", addr, "err", err)
+	}
+	return account, nil
+}
+
+// remoteStorage retrieves a storage slot of an account from the remote chain.
+func (db *Database) remoteStorage(addr common.Address, slot common.Hash) (common.Hash, error) {
+	key := append(append(append(append([]byte{}, storagePrefix...), db.origin.Root[:]...), addr[:]...), slot[:]...)
+	if blob, err := db.disk.Get(key); err == nil && len(blob) == common.HashLength {
+		return common.BytesToHash(blob), nil
+	}
+	account, err := db.remoteAccount(addr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Avoid remote requests for accounts without storage
+	if account == nil || account.Root == types.EmptyRootHash {
+		return common.Hash{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+
+	result, err := db.client.GetProof(ctx, addr, []common.Hash{slot}, db.origin.Number)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to retrieve remote storage %x/%x: %w", addr, slot, err)
+	}
+	if len(result.StorageProof) != 1 {
+		return common.Hash{}, fmt.Errorf("missing proof for remote storage %x/%x", addr, slot)
+	}
+	enc, err := verifyProof(account.Root, crypto.Keccak256(slot[:]), result.StorageProof[0].Proof)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid proof for remote storage %x/%x: %w", addr, slot, err)
+	}
+	var value common.Hash
+	if len(enc) > 0 {
+		_, content, _, err := rlp.Split(enc)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		value.SetBytes(content)
+	}
+	if err := db.disk.Put(key, value[:]); err != nil {
+		log.Warn("Failed to cache remote storage", "addr", addr, "slot", slot, "err", err)
+	}
+	return value, nil
+}
+
+// verifyProof checks a Merkle proof against a trie root, returning the value of
+// the key or nil if the proof shows its absence.
+func verifyProof(root common.Hash, key []byte, proof []hexutil.Bytes) ([]byte, error) {
+	proofDb := memorydb.New()
+	for _, node := range proof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	return trie.VerifyProof(root, key, proofDb)
+}
+
+// forkTrie wraps a local state trie, falling back to the remote chain for the
+// accounts and storage slots never modified locally.
+type forkTrie struct {
+	state.Trie
+	db *Database
+}
+
+// GetAccount implements state.Trie, retrieving the account from the local trie
+// or from the remote chain if it was never modified locally.
+func (t *forkTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	account, err := t.Trie.GetAccount(address)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		if common.BytesToHash(account.CodeHash) == deletedCodeHash {
+			return nil, nil
+		}
+		return account, nil
+	}
+	remote, err := t.db.remoteAccount(address)
+	if err != nil || remote == nil {
+		return nil, err
+	}
+	// The storage of the account is served from the remote chain, locally it
+	// only contains the modified slots, starting from none.
+	return &types.StateAccount{
+		Nonce:    remote.Nonce,
+		Balance:  remote.Balance,
+		Root:     types.EmptyRootHash,
+		CodeHash: remote.CodeHash,
+	}, nil
+}
+
+// DeleteAccount implements state.Trie. Accounts existing on the remote chain
+// are replaced by a marker, so they are not read from there again.
+//
+// Note, storage slots of a deleted and later recreated remote account will
+// still be read from the remote chain. Since EIP-6780 accounts can only be
+// destroyed in the transaction creating them, so this cannot happen unless a
+// remote account with storage but without code and nonce is emptied.
+func (t *forkTrie) DeleteAccount(address common.Address) error {
+	remote, err := t.db.remoteAccount(address)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		return t.Trie.DeleteAccount(address)
+	}
+	return t.Trie.UpdateAccount(address, &types.StateAccount{
+		Balance:  new(uint256.Int),
+		Root:     types.EmptyRootHash,
+		CodeHash: deletedCodeHash[:],
+	})
+}
+
+// GetStorage implements state.Trie, retrieving the slot from the local trie or
+// from the remote chain if it was never modified locally.
+func (t *forkTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	enc, err := t.Trie.GetStorage(addr, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) > 0 {
+		if bytes.Equal(enc, deletedSlot) {
+			return nil, nil
+		}
+		return enc, nil
+	}
+	value, err := t.db.remoteStorage(addr, common.BytesToHash(key))
+	if err != nil || value == (common.Hash{}) {
+		return nil, err
+	}
+	return common.TrimLeftZeroes(value[:]), nil
+}
+
+// DeleteStorage implements state.Trie. Slots set on the remote chain are replaced
+// by a marker, so they are not read from there again.
+func (t *forkTrie) DeleteStorage(addr common.Address, key []byte) error {
+	value, err := t.db.remoteStorage(addr, common.BytesToHash(key))
+	if err != nil {
+		return err
+	}
+	if value == (common.Hash{}) {
+		return t.Trie.DeleteStorage(addr, key)
+	}
+	return t.Trie.UpdateStorage(addr, key, deletedSlot)
+}
@@ -0,0 +1,253 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkstate
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+)
+
+var (
+	remoteContract = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	remoteAccount  = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	missingAccount = common.HexToAddress("0x3000000000000000000000000000000000000003")
+
+	remoteCode = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	slotA      = common.HexToHash("0x01")
+	slotB      = common.HexToHash("0x02")
+	slotC      = common.HexToHash("0x03")
+)
+
+// proofList collects the nodes of a Merkle proof.
+type proofList []hexutil.Bytes
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// testRemote is a fake remote chain serving a single block over the eth namespace.
+type testRemote struct {
+	db     state.Database
+	header *types.Header
+	calls  atomic.Int32
+}
+
+func newTestRemote(t *testing.T) *testRemote {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(types.EmptyRootHash, db, nil)
+
+	statedb.SetBalance(remoteContract, uint256.NewInt(1))
+	statedb.SetNonce(remoteContract, 1)
+	statedb.SetCode(remoteContract, remoteCode)
+	statedb.SetState(remoteContract, slotA, common.HexToHash("0xc0"))
+	statedb.SetState(remoteContract, slotB, common.HexToHash("0xdeadbeef"))
+	statedb.SetBalance(remoteAccount, uint256.NewInt(1000))
+	statedb.SetNonce(remoteAccount, 5)
+
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		t.Fatalf("failed to commit remote state: %v", err)
+	}
+	header := &types.Header{
+		Number:     big.NewInt(100),
+		Root:       root,
+		Difficulty: new(big.Int),
+		GasLimit:   30_000_000,
+		Time:       1700000000,
+		BaseFee:    big.NewInt(7),
+	}
+	return &testRemote{db: db, header: header}
+}
+
+func (r *testRemote) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (r *testRemote) GetBlockByNumber(tag string, full bool) *types.Header {
+	return r.header
+}
+
+func (r *testRemote) GetProof(addr common.Address, keys []string, number hexutil.Uint64) (*AccountResult, error) {
+	r.calls.Add(1)
+
+	tr, err := r.db.OpenTrie(r.header.Root)
+	if err != nil {
+		return nil, err
+	}
+	var result AccountResult
+	if err := tr.Prove(crypto.Keccak256(addr[:]), (*proofList)(&result.AccountProof)); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return &result, nil
+	}
+	account, err := tr.GetAccount(addr)
+	if err != nil {
+		return nil, err
+	}
+	str, err := r.db.OpenStorageTrie(r.header.Root, addr, account.Root, tr)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		var proof proofList
+		if err := str.Prove(crypto.Keccak256(common.HexToHash(key).Bytes()), &proof); err != nil {
+			return nil, err
+		}
+		result.StorageProof = append(result.StorageProof, StorageResult{Key: key, Proof: proof})
+	}
+	return &result, nil
+}
+
+func (r *testRemote) GetCode(addr common.Address, number hexutil.Uint64) (hexutil.Bytes, error) {
+	r.calls.Add(1)
+
+	statedb, err := state.New(r.header.Root, r.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(addr), nil
+}
+
+// newTestFork creates a fake remote chain and a local state database forked
+// off it over an in-process RPC connection.
+func newTestFork(t *testing.T) (*testRemote, *Database) {
+	remote := newTestRemote(t)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", remote); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	fork, err := NewFork(context.Background(), NewClient(rpc.DialInProc(server)), nil)
+	if err != nil {
+		t.Fatalf("failed to fork remote chain: %v", err)
+	}
+	if fork.Header.Hash() != remote.header.Hash() {
+		t.Fatalf("forked block mismatch: have %x, want %x", fork.Header.Hash(), remote.header.Hash())
+	}
+	return remote, fork.Wrap(state.NewDatabase(rawdb.NewMemoryDatabase())).(*Database)
+}
+
+func TestRemoteState(t *testing.T) {
+	remote, db := newTestFork(t)
+
+	check := func(statedb *state.StateDB) {
+		t.Helper()
+		if balance := statedb.GetBalance(remoteAccount); balance.Uint64() != 1000 {
+			t.Errorf("balance mismatch: have %v, want 1000", balance)
+		}
+		if nonce := statedb.GetNonce(remoteAccount); nonce != 5 {
+			t.Errorf("nonce mismatch: have %d, want 5", nonce)
+		}
+		if code := statedb.GetCode(remoteContract); !bytes.Equal(code, remoteCode) {
+			t.Errorf("code mismatch: have %x, want %x", code, remoteCode)
+		}
+		if value := statedb.GetState(remoteContract, slotA); value != common.HexToHash("0xc0") {
+			t.Errorf("slot A mismatch: have %x", value)
+		}
+		if value := statedb.GetState(remoteContract, slotB); value != common.HexToHash("0xdeadbeef") {
+			t.Errorf("slot B mismatch: have %x", value)
+		}
+		if value := statedb.GetState(remoteContract, slotC); value != (common.Hash{}) {
+			t.Errorf("slot C mismatch: have %x, want empty", value)
+		}
+		if statedb.Exist(missingAccount) {
+			t.Error("missing account exists")
+		}
+		if err := statedb.Error(); err != nil {
+			t.Fatalf("state error: %v", err)
+		}
+	}
+	statedb, _ := state.New(types.EmptyRootHash, db, nil)
+	check(statedb)
+
+	// Accessing the same state again should be served from the local cache
+	calls := remote.calls.Load()
+	statedb, _ = state.New(types.EmptyRootHash, db, nil)
+	check(statedb)
+	if have := remote.calls.Load(); have != calls {
+		t.Errorf("remote calls after caching: have %d, want %d", have, calls)
+	}
+}
+
+func TestLocalModifications(t *testing.T) {
+	_, db := newTestFork(t)
+
+	statedb, _ := state.New(types.EmptyRootHash, db, nil)
+	statedb.AddBalance(remoteAccount, uint256.NewInt(1))
+	statedb.SetState(remoteContract, slotA, common.Hash{})
+	statedb.SetState(remoteContract, slotC, common.HexToHash("0x01"))
+	statedb.SetBalance(missingAccount, uint256.NewInt(42))
+
+	root, err := statedb.Commit(1, true)
+	if err != nil {
+		t.Fatalf("failed to commit local state: %v", err)
+	}
+	statedb, _ = state.New(root, db, nil)
+	if balance := statedb.GetBalance(remoteAccount); balance.Uint64() != 1001 {
+		t.Errorf("balance mismatch: have %v, want 1001", balance)
+	}
+	if nonce := statedb.GetNonce(remoteAccount); nonce != 5 {
+		t.Errorf("nonce mismatch: have %d, want 5", nonce)
+	}
+	if value := statedb.GetState(remoteContract, slotA); value != (common.Hash{}) {
+		t.Errorf("cleared slot mismatch: have %x, want empty", value)
+	}
+	if value := statedb.GetState(remoteContract, slotB); value != common.HexToHash("0xdeadbeef") {
+		t.Errorf("untouched slot mismatch: have %x", value)
+	}
+	if value := statedb.GetState(remoteContract, slotC); value != common.HexToHash("0x01") {
+		t.Errorf("new slot mismatch: have %x", value)
+	}
+	if balance := statedb.GetBalance(missingAccount); balance.Uint64() != 42 {
+		t.Errorf("new account balance mismatch: have %v, want 42", balance)
+	}
+	// Destroy a remote account and ensure it's not resurrected from the remote
+	statedb.SelfDestruct(remoteContract)
+	if root, err = statedb.Commit(2, true); err != nil {
+		t.Fatalf("failed to commit local state: %v", err)
+	}
+	statedb, _ = state.New(root, db, nil)
+	if statedb.Exist(remoteContract) {
+		t.Error("destroyed remote account exists")
+	}
+	if value := statedb.GetState(remoteContract, slotB); value != (common.Hash{}) {
+		t.Errorf("destroyed account slot mismatch: have %x, want empty", value)
+	}
+	if err := statedb.Error(); err != nil {
+		t.Fatalf("state error: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkstate
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Fork is a block of a remote chain to fork a local development chain off.
+type Fork struct {
+	ChainID *big.Int      // Chain ID of the remote chain
+	Header  *types.Header // Header of the forked remote block
+
+	client Client
+}
+
+// NewFork retrieves the block to fork off from the remote chain, or the latest
+// one if no number is given.
+func NewFork(ctx context.Context, client Client, number *big.Int) (*Fork, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header, err := client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return &Fork{ChainID: chainID, Header: header, client: client}, nil
+}
+
+// Origin returns the identifier of the forked remote block.
+func (f *Fork) Origin() Origin {
+	return Origin{Number: f.Header.Number.Uint64(), Root: f.Header.Root}
+}
+
+// ConfigureGenesis adapts a development genesis to continue the remote chain:
+// it takes over the chain ID, the block parameters of the forked block and
+// activates Cancun if the remote chain already did so.
+//
+// The local chain still starts at block zero, so the block numbers and hashes
+// of the remote chain are not available to contracts.
+func (f *Fork) ConfigureGenesis(genesis *core.Genesis) {
+	config := *genesis.Config
+	config.ChainID = new(big.Int).Set(f.ChainID)
+	if f.Header.ExcessBlobGas != nil && config.CancunTime == nil {
+		config.CancunTime = new(uint64)
+	}
+	genesis.Config = &config
+	genesis.Timestamp = f.Header.Time
+	genesis.GasLimit = f.Header.GasLimit
+	if f.Header.BaseFee != nil {
+		genesis.BaseFee = new(big.Int).Set(f.Header.BaseFee)
+	}
+}
+
+// Wrap wraps a local state database to serve the state of the forked block. It
+// is meant to be set as the state wrapper of the local chain.
+func (f *Fork) Wrap(db state.Database) state.Database {
+	return NewDatabase(db, f.client, f.Origin())
+}
@@ -24,7 +24,6 @@ import (
 	"math"
 	"math/big"
 	"os"
-	"path/filepath"
 	"sort"
 	"sync"
 	"time"
@@ -314,6 +313,9 @@ type BlobPool struct {
 	discoverFeed event.Feed // Event feed to send out new tx events on pool discovery (reorg excluded)
 	insertFeed   event.Feed // Event feed to send out new tx events on pool inclusion (reorg included)
 
+	lifecycle     []txpool.TxEvent     // Lifecycle events waiting to be delivered once the lock is released
+	lifecycleFeed txpool.LifecycleFeed // Event feed to send out the lifecycle events of the transactions
+
 	lock sync.RWMutex // Mutex protecting the pool during reorg handling
 }
 
@@ -346,17 +348,14 @@ func (p *BlobPool) Init(gasTip *big.Int, head *types.Header, reserve txpool.Addr
 	p.reserve = reserve
 
 	var (
-		queuedir string
-		limbodir string
+		queuedirs = p.config.directories(pendingTransactionStore)
+		limbodirs = p.config.directories(limboedTransactionStore)
 	)
 	if p.config.Datadir != "" {
-		queuedir = filepath.Join(p.config.Datadir, pendingTransactionStore)
-		if err := os.MkdirAll(queuedir, 0700); err != nil {
-			return err
-		}
-		limbodir = filepath.Join(p.config.Datadir, limboedTransactionStore)
-		if err := os.MkdirAll(limbodir, 0700); err != nil {
-			return err
+		for _, dir := range append(queuedirs, limbodirs...) {
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return err
+			}
 		}
 	}
 	// Initialize the state with head block, or fallback to empty one in
@@ -378,7 +377,7 @@ func (p *BlobPool) Init(gasTip *big.Int, head *types.Header, reserve txpool.Addr
 			fails = append(fails, id)
 		}
 	}
-	store, err := billy.Open(billy.Options{Path: queuedir}, newSlotter(), index)
+	store, err := openStore(queuedirs, maxBlobsPerTransaction, index)
 	if err != nil {
 		return err
 	}
@@ -411,7 +410,7 @@ func (p *BlobPool) Init(gasTip *big.Int, head *types.Header, reserve txpool.Addr
 
 	// Pool initialized, attach the blob limbo to it to track blobs included
 	// recently but not yet finalized
-	p.limbo, err = newLimbo(limbodir)
+	p.limbo, err = newLimbo(limbodirs, maxBlobsPerTransaction)
 	if err != nil {
 		p.Close()
 		return err
@@ -524,12 +523,17 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			ids    []uint64
 			nonces []uint64
 		)
+		reason := txpool.DropStaleNonce
+		if gapped {
+			reason = txpool.DropNonceGap
+		}
 		for i := 0; i < len(txs); i++ {
 			ids = append(ids, txs[i].id)
 			nonces = append(nonces, txs[i].nonce)
 
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
+			p.trackDrop(txs[i].hash, reason)
 
 			// Included transactions blobs need to be moved to the limbo
 			if filled && inclusions != nil {
@@ -571,6 +575,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[0].costCap)
 			p.stored -= uint64(txs[0].size)
 			delete(p.lookup, txs[0].hash)
+			p.trackDrop(txs[0].hash, txpool.DropStaleNonce)
 
 			// Included transactions blobs need to be moved to the limbo
 			if inclusions != nil {
@@ -627,6 +632,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
+			p.trackDrop(txs[i].hash, txpool.DropInvalid)
 
 			if err := p.store.Delete(id); err != nil {
 				log.Error("Failed to delete blob transaction", "from", addr, "id", id, "err", err)
@@ -649,6 +655,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[j].costCap)
 			p.stored -= uint64(txs[j].size)
 			delete(p.lookup, txs[j].hash)
+			p.trackDrop(txs[j].hash, txpool.DropNonceGap)
 		}
 		txs = txs[:i]
 
@@ -687,6 +694,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
+			p.trackDrop(last.hash, txpool.DropUnpayable)
 		}
 		if len(txs) == 0 {
 			delete(p.index, addr)
@@ -727,6 +735,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
+			p.trackDrop(last.hash, txpool.DropAccountLimit)
 		}
 		p.index[addr] = txs
 
@@ -778,6 +787,8 @@ func (p *BlobPool) offload(addr common.Address, nonce uint64, id uint64, inclusi
 // Reset implements txpool.SubPool, allowing the blob pool's internal state to be
 // kept in sync with the main transaction pool's internal state.
 func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
+	defer p.flushLifecycle()
+
 	waitStart := time.Now()
 	p.lock.Lock()
 	resetwaitHist.Update(time.Since(waitStart).Nanoseconds())
@@ -1012,6 +1023,8 @@ func (p *BlobPool) reinject(addr common.Address, txhash common.Hash) error {
 // SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
 // to be kept in sync with the main transaction pool's gas requirements.
 func (p *BlobPool) SetGasTip(tip *big.Int) {
+	defer p.flushLifecycle()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -1032,6 +1045,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 					p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 					p.stored -= uint64(tx.size)
 					delete(p.lookup, tx.hash)
+					p.trackDrop(tx.hash, txpool.DropBelowTip)
 					txs[i] = nil
 
 					// Drop everything afterwards, no gaps allowed
@@ -1042,6 +1056,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 						p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], tx.costCap)
 						p.stored -= uint64(tx.size)
 						delete(p.lookup, tx.hash)
+						p.trackDrop(tx.hash, txpool.DropNonceGap)
 						txs[i+1+j] = nil
 					}
 					// Clear out the dropped transactions from the index
@@ -1217,6 +1232,7 @@ func (p *BlobPool) Add(txs []*types.Transaction, local bool, sync bool) []error
 		p.discoverFeed.Send(core.NewTxsEvent{Txs: adds})
 		p.insertFeed.Send(core.NewTxsEvent{Txs: adds})
 	}
+	p.flushLifecycle()
 	return errs
 }
 
@@ -1315,6 +1331,7 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 
 		delete(p.lookup, prev.hash)
 		p.lookup[meta.hash] = meta.id
+		p.trackReplaced(prev.hash, meta.hash)
 		p.stored += uint64(meta.size) - uint64(prev.size)
 	} else {
 		// Transaction extends previously scheduled ones
@@ -1381,6 +1398,7 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 	}
 	p.updateStorageMetrics()
 
+	p.track(txpool.TxEvent{Hash: meta.hash, Type: txpool.TxEventReceived})
 	addValidMeter.Mark(1)
 	return nil
 }
@@ -1414,6 +1432,7 @@ func (p *BlobPool) drop() {
 	}
 	p.stored -= uint64(drop.size)
 	delete(p.lookup, drop.hash)
+	p.trackDrop(drop.hash, txpool.DropPoolOverflow)
 
 	// Remove the transaction from the pool's eviction heap:
 	//   - If the entire account was dropped, pop off the address
@@ -1561,6 +1580,50 @@ func (p *BlobPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool
 	}
 }
 
+// SubscribeLifecycle subscribes to the lifecycle events of the transactions of
+// the pool, delivered in batches. Batches are dropped while the channel is full.
+func (p *BlobPool) SubscribeLifecycle(ch chan<- []txpool.TxEvent) event.Subscription {
+	return p.lifecycleFeed.Subscribe(ch)
+}
+
+// track records a lifecycle event of a transaction, to be delivered once the
+// pool lock is released.
+//
+// The pool lock must be held.
+func (p *BlobPool) track(ev txpool.TxEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	p.lifecycle = append(p.lifecycle, ev)
+}
+
+// trackDrop records the removal of a transaction for the given reason.
+//
+// The pool lock must be held.
+func (p *BlobPool) trackDrop(hash common.Hash, reason string) {
+	p.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventDropped, Reason: reason})
+}
+
+// trackReplaced records the replacement of a transaction by another one.
+//
+// The pool lock must be held.
+func (p *BlobPool) trackReplaced(hash common.Hash, by common.Hash) {
+	p.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventReplaced, ReplacedBy: by})
+}
+
+// flushLifecycle delivers the lifecycle events recorded since the last flush to
+// the subscribers. It must be called without holding the pool lock.
+func (p *BlobPool) flushLifecycle() {
+	p.lock.Lock()
+	events := p.lifecycle
+	p.lifecycle = nil
+	p.lock.Unlock()
+
+	if len(events) > 0 {
+		p.lifecycleFeed.Send(events)
+	}
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *BlobPool) Nonce(addr common.Address) uint64 {
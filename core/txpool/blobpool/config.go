@@ -17,14 +17,17 @@
 package blobpool
 
 import (
+	"path/filepath"
+
 	"github.com/ethereum/go-ethereum/log"
 )
 
 // Config are the configuration parameters of the blob transaction pool.
 type Config struct {
-	Datadir   string // Data directory containing the currently executable blobs
-	Datacap   uint64 // Soft-cap of database storage (hard cap is larger due to overhead)
-	PriceBump uint64 // Minimum price bump percentage to replace an already existing nonce
+	Datadir   string   // Data directory containing the currently executable blobs
+	Datadirs  []string // Extra data directories (e.g. on other disks) to spread the storage across
+	Datacap   uint64   // Soft-cap of database storage (hard cap is larger due to overhead)
+	PriceBump uint64   // Minimum price bump percentage to replace an already existing nonce
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -46,5 +49,38 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid blobpool price bump", "provided", conf.PriceBump, "updated", DefaultConfig.PriceBump)
 		conf.PriceBump = DefaultConfig.PriceBump
 	}
+	if len(conf.Datadirs) > 0 {
+		// Extra directories are meaningless for an in-memory pool, and any path
+		// listed multiple times would end up holding the same shelves twice
+		seen := map[string]bool{filepath.Clean(conf.Datadir): true}
+
+		var dirs []string
+		for _, dir := range conf.Datadirs {
+			switch {
+			case conf.Datadir == "":
+				log.Warn("Sanitizing blobpool data directory without primary one", "provided", dir)
+			case dir == "" || seen[filepath.Clean(dir)]:
+				log.Warn("Sanitizing duplicate blobpool data directory", "provided", dir)
+			default:
+				seen[filepath.Clean(dir)] = true
+				dirs = append(dirs, dir)
+			}
+		}
+		conf.Datadirs = dirs
+	}
 	return conf
 }
+
+// directories returns the directories the given store of the pool is spread
+// across: a subfolder in the primary data directory and in each of the extra
+// ones. Without a data directory, a single in-memory store is used.
+func (config *Config) directories(store string) []string {
+	if config.Datadir == "" {
+		return []string{""}
+	}
+	dirs := []string{filepath.Join(config.Datadir, store)}
+	for _, dir := range config.Datadirs {
+		dirs = append(dirs, filepath.Join(dir, store))
+	}
+	return dirs
+}
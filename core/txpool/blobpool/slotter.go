@@ -25,14 +25,63 @@ package blobpool
 // The slotter also creates a shelf for 0-blob transactions. Whilst those are not
 // allowed in the current protocol, having an empty shelf is not a relevant use
 // of resources, but it makes stress testing with junk transactions simpler.
-func newSlotter() func() (uint32, bool) {
+func newSlotter(maxBlobs uint32) func() (uint32, bool) {
 	slotsize := uint32(txAvgSize)
 	slotsize -= uint32(blobSize) // underflows, it's ok, will overflow back in the first return
 
 	return func() (size uint32, done bool) {
 		slotsize += blobSize
-		finished := slotsize > maxBlobsPerTransaction*blobSize+txMaxSize
+		finished := slotsize > maxBlobs*blobSize+txMaxSize
 
 		return slotsize, finished
 	}
 }
+
+// newShelfSlotter creates a helper method for the Billy datastore that returns
+// exactly the given, ascending shelf sizes.
+func newShelfSlotter(sizes []uint32) func() (uint32, bool) {
+	var next int
+	return func() (size uint32, done bool) {
+		size = sizes[next]
+		next++
+		return size, next == len(sizes)
+	}
+}
+
+// slotSizes collects all the shelf sizes yielded by a slotter.
+func slotSizes(slotter func() (uint32, bool)) []uint32 {
+	var sizes []uint32
+	for {
+		size, done := slotter()
+		sizes = append(sizes, size)
+		if done {
+			return sizes
+		}
+	}
+}
+
+// shardSlots distributes the given, ascending shelf sizes across a number of
+// shards, balancing the slot sizes held by each of them. Larger shelves store
+// larger transactions, so they are expected to take up more disk space: they
+// are placed first, each onto the shard holding the least slot size so far.
+// The shelf sizes of each shard are returned in ascending order.
+//
+// The distribution only depends on its inputs, so the same layout is derived
+// on every startup for an unchanged configuration.
+func shardSlots(sizes []uint32, shards int) [][]uint32 {
+	var (
+		layout = make([][]uint32, shards)
+		totals = make([]uint64, shards)
+	)
+	for i := len(sizes) - 1; i >= 0; i-- {
+		var shard int
+		for j := 1; j < shards; j++ {
+			if totals[j] < totals[shard] {
+				shard = j
+			}
+		}
+		layout[shard] = append([]uint32{sizes[i]}, layout[shard]...)
+		totals[shard] += uint64(sizes[i])
+	}
+	return layout
+}
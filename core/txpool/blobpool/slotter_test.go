@@ -21,7 +21,7 @@ import "testing"
 // Tests that the slotter creates the expected database shelves.
 func TestNewSlotter(t *testing.T) {
 	// Generate the database shelve sizes
-	slotter := newSlotter()
+	slotter := newSlotter(maxBlobsPerTransaction)
 
 	var shelves []uint32
 	for {
@@ -58,3 +58,48 @@ func TestNewSlotter(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the shelves are distributed across the shards without losing any,
+// balancing the slot sizes held by each shard.
+func TestShardSlots(t *testing.T) {
+	sizes := slotSizes(newSlotter(maxBlobsPerTransaction))
+
+	// A single shard must hold all the shelves
+	if layout := shardSlots(sizes, 1); len(layout) != 1 || len(layout[0]) != len(sizes) {
+		t.Fatalf("single shard layout mismatch: have %v, want %v", layout, sizes)
+	}
+	for shards := 2; shards <= 4; shards++ {
+		var (
+			layout = shardSlots(sizes, shards)
+			seen   = make(map[uint32]bool)
+			totals []uint64
+		)
+		for i, shelves := range layout {
+			var total uint64
+			for j, size := range shelves {
+				if j > 0 && shelves[j-1] >= size {
+					t.Errorf("shards %d: shard %d shelves not ascending: %v", shards, i, shelves)
+				}
+				if seen[size] {
+					t.Errorf("shards %d: shelf %d assigned multiple times", shards, size)
+				}
+				seen[size] = true
+				total += uint64(size)
+			}
+			totals = append(totals, total)
+		}
+		if len(seen) != len(sizes) {
+			t.Errorf("shards %d: shelf count mismatch: have %d, want %d", shards, len(seen), len(sizes))
+		}
+		// No two shards may differ by more than the largest shelf in size
+		for i := 1; i < len(totals); i++ {
+			diff := int64(totals[i]) - int64(totals[0])
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > int64(sizes[len(sizes)-1]) {
+				t.Errorf("shards %d: unbalanced layout: %v", shards, totals)
+			}
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/billy"
+)
+
+const (
+	// storeShardShift is the bit position at which the shard index is encoded
+	// into the item ids of a sharded store. Billy only uses the lower 40 bits.
+	storeShardShift = 48
+
+	// storeItemOverhead is the size of the header Billy prepends to each item,
+	// which needs to fit into the shelf slot along with the data.
+	storeItemOverhead = 4
+
+	// storeShelfFormat is the file name format of the Billy shelves.
+	storeShelfFormat = "bkt_%08d.bag"
+
+	// storeShardsFile is the name of the file in the primary directory of a
+	// store, listing all the directories the store was last spread across.
+	storeShardsFile = "shards"
+)
+
+// shardedShelf is a single shelf of a sharded store, along with the shard it
+// belongs to.
+type shardedShelf struct {
+	size  uint32 // Slot size of the shelf
+	shard int    // Index of the shard holding the shelf
+}
+
+// shardedStore is a Billy datastore spreading its shelves across multiple data
+// directories, typically on different disks. Each directory holds a standalone
+// Billy database with a subset of the shelves, and the index of the directory
+// is encoded into the upper bits of the item ids.
+type shardedStore struct {
+	shards  []billy.Database // Billy databases of the shards, nil if holding no shelves
+	shelves []shardedShelf   // Shelves of all the shards, ordered by slot size
+}
+
+// openStore opens a Billy datastore with shelves sized for transactions with up
+// to the given number of blobs, spread across the given directories. An empty
+// directory denotes an in-memory store.
+//
+// Shelves found on disk which are not part of the layout anymore, because the
+// maximum blob count or the set of directories changed, are migrated into the
+// store and deleted afterwards. This includes the shelves of the directories
+// the store is not spread across anymore, which are tracked in the primary
+// directory. If such a directory is missing, the store refuses to open instead
+// of silently losing its items. Items not fitting into the new shelves are lost.
+// The onData callback is invoked for all the items in the store, migrated ones
+// included.
+//
+// The method is a variable to allow tests to simulate failing to open a store.
+var openStore = func(dirs []string, maxBlobs uint32, onData billy.OnDataFn) (billy.Database, error) {
+	var (
+		sizes  = slotSizes(newSlotter(maxBlobs))
+		layout = shardSlots(sizes, len(dirs))
+		store  = &shardedStore{shards: make([]billy.Database, len(dirs))}
+	)
+	for i, dir := range dirs {
+		if len(layout[i]) == 0 {
+			continue
+		}
+		for _, size := range layout[i] {
+			store.shelves = append(store.shelves, shardedShelf{size: size, shard: i})
+		}
+		db, err := billy.Open(billy.Options{Path: dir}, newShelfSlotter(layout[i]), shardDataFn(i, onData))
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store.shards[i] = db
+	}
+	sort.Slice(store.shelves, func(i, j int) bool {
+		return store.shelves[i].size < store.shelves[j].size
+	})
+	// Move any items out of the shelves left over from a previous layout, and
+	// out of the directories not part of the store anymore
+	for i, dir := range dirs {
+		if err := store.migrate(dir, layout[i], onData); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	removed, err := removedShards(dirs)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	for _, dir := range removed {
+		if err := store.migrate(dir, nil, onData); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	if err := writeShards(dirs); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// removedShards returns the directories the store was last spread across, as
+// tracked in its primary directory, which are not part of the given ones. An
+// error is returned if any of them is missing, as its items would be lost.
+func removedShards(dirs []string) ([]string, error) {
+	if dirs[0] == "" {
+		return nil, nil // in-memory store, nothing tracked
+	}
+	blob, err := os.ReadFile(filepath.Join(dirs[0], storeShardsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]struct{}, len(dirs))
+	for _, dir := range dirs {
+		current[filepath.Clean(dir)] = struct{}{}
+	}
+	var removed []string
+	for _, dir := range strings.Split(string(blob), "\n") {
+		if dir == "" {
+			continue
+		}
+		if _, ok := current[filepath.Clean(dir)]; ok {
+			continue
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("blob pool directory %s removed from the configuration is not accessible (%v), restore it or delete %s to drop its transactions", dir, err, filepath.Join(dirs[0], storeShardsFile))
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// writeShards tracks the directories the store is spread across in its primary
+// directory.
+func writeShards(dirs []string) error {
+	if dirs[0] == "" {
+		return nil // in-memory store, nothing to track
+	}
+	return os.WriteFile(filepath.Join(dirs[0], storeShardsFile), []byte(strings.Join(dirs, "\n")+"\n"), 0600)
+}
+
+// shardDataFn wraps a data callback to encode the shard index into the ids of
+// the items iterated over in a single shard.
+func shardDataFn(shard int, onData billy.OnDataFn) billy.OnDataFn {
+	if onData == nil {
+		return nil
+	}
+	return func(id uint64, size uint32, data []byte) {
+		onData(id|uint64(shard)<<storeShardShift, size, data)
+	}
+}
+
+// migrate moves the items of the shelves found in a directory, but not part of
+// its current layout, into the store, deleting the stale shelves afterwards.
+func (s *shardedStore) migrate(dir string, layout []uint32, onData billy.OnDataFn) error {
+	if dir == "" {
+		return nil // in-memory store, nothing to migrate
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	current := make(map[uint32]struct{}, len(layout))
+	for _, size := range layout {
+		current[size] = struct{}{}
+	}
+	var stale []uint32
+	for _, file := range files {
+		var size uint32
+		if _, err := fmt.Sscanf(file.Name(), storeShelfFormat, &size); err != nil {
+			continue
+		}
+		if _, ok := current[size]; !ok {
+			stale = append(stale, size)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i] < stale[j] })
+
+	var moved, lost int
+	migrate := func(_ uint64, _ uint32, data []byte) {
+		id, err := s.Put(data)
+		if err != nil {
+			lost++
+			return
+		}
+		moved++
+		if onData != nil {
+			onData(id, s.Size(id), data)
+		}
+	}
+	db, err := billy.Open(billy.Options{Path: dir}, newShelfSlotter(stale), migrate)
+	if err != nil {
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return err
+	}
+	for _, size := range stale {
+		if err := os.Remove(filepath.Join(dir, fmt.Sprintf(storeShelfFormat, size))); err != nil {
+			return err
+		}
+	}
+	log.Info("Migrated blob pool storage shelves", "dir", dir, "shelves", len(stale), "moved", moved, "lost", lost)
+	return nil
+}
+
+// shard retrieves the Billy database holding the item with the given id.
+func (s *shardedStore) shard(id uint64) billy.Database {
+	return s.shards[id>>storeShardShift]
+}
+
+// Put stores the data into the smallest shelf it fits into, returning the id
+// needed to access it later.
+func (s *shardedStore) Put(data []byte) (uint64, error) {
+	index := sort.Search(len(s.shelves), func(i int) bool {
+		return len(data)+storeItemOverhead <= int(s.shelves[i].size)
+	})
+	if index == len(s.shelves) {
+		return 0, fmt.Errorf("no shelf found for size %d", len(data))
+	}
+	shard := s.shelves[index].shard
+	id, err := s.shards[shard].Put(data)
+	if err != nil {
+		return 0, err
+	}
+	return id | uint64(shard)<<storeShardShift, nil
+}
+
+// Get retrieves the data stored with the given id.
+func (s *shardedStore) Get(id uint64) ([]byte, error) {
+	return s.shard(id).Get(id & (1<<storeShardShift - 1))
+}
+
+// Delete marks the data stored with the given id for deletion.
+func (s *shardedStore) Delete(id uint64) error {
+	return s.shard(id).Delete(id & (1<<storeShardShift - 1))
+}
+
+// Size returns the storage size of the item with the given id.
+func (s *shardedStore) Size(id uint64) uint32 {
+	return s.shard(id).Size(id & (1<<storeShardShift - 1))
+}
+
+// Limits returns the smallest and largest slot size.
+func (s *shardedStore) Limits() (uint32, uint32) {
+	return s.shelves[0].size, s.shelves[len(s.shelves)-1].size
+}
+
+// Infos retrieves the statistics of the shelves of all the shards, ordered by
+// slot size.
+func (s *shardedStore) Infos() *billy.Infos {
+	infos := new(billy.Infos)
+	for _, shard := range s.shards {
+		if shard != nil {
+			infos.Shelves = append(infos.Shelves, shard.Infos().Shelves...)
+		}
+	}
+	sort.Slice(infos.Shelves, func(i, j int) bool {
+		return infos.Shelves[i].SlotSize < infos.Shelves[j].SlotSize
+	})
+	return infos
+}
+
+// Iterate iterates through all the data in all the shards.
+func (s *shardedStore) Iterate(onData billy.OnDataFn) error {
+	var errs []error
+	for i, shard := range s.shards {
+		if shard == nil {
+			continue
+		}
+		if err := shard.Iterate(shardDataFn(i, onData)); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the Billy databases of all the shards.
+func (s *shardedStore) Close() error {
+	var errs []error
+	for _, shard := range s.shards {
+		if shard == nil {
+			continue
+		}
+		if err := shard.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/billy"
+	"github.com/holiman/uint256"
+)
+
+// shelvesOnDisk retrieves the slot sizes of the shelves found in a directory.
+func shelvesOnDisk(t *testing.T, dir string) []uint32 {
+	t.Helper()
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list shelves: %v", err)
+	}
+	var sizes []uint32
+	for _, file := range files {
+		var size uint32
+		if _, err := fmt.Sscanf(file.Name(), storeShelfFormat, &size); err == nil {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	return sizes
+}
+
+// verifyShelvesOnDisk checks that the directories hold exactly the shelves of
+// the layout derived for the given maximum blob count.
+func verifyShelvesOnDisk(t *testing.T, dirs []string, maxBlobs uint32) {
+	t.Helper()
+
+	layout := shardSlots(slotSizes(newSlotter(maxBlobs)), len(dirs))
+	for i, dir := range dirs {
+		if have := shelvesOnDisk(t, dir); fmt.Sprint(have) != fmt.Sprint(layout[i]) {
+			t.Errorf("dir %d shelves mismatch: have %v, want %v", i, have, layout[i])
+		}
+	}
+}
+
+// Tests that items stored across multiple directories are retrievable, and that
+// they are migrated when the set of directories or the blob limit changes.
+func TestShardedStoreMigration(t *testing.T) {
+	storage := t.TempDir()
+
+	dirs := []string{filepath.Join(storage, "a"), filepath.Join(storage, "b"), filepath.Join(storage, "c")}
+	for _, dir := range dirs {
+		os.MkdirAll(dir, 0700)
+	}
+	// Fill a single directory store with items of various sizes
+	items := make(map[string]bool)
+	store, err := openStore(dirs[:1], maxBlobsPerTransaction, nil)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		data := bytes.Repeat([]byte{byte(i)}, i*blobSize+1)
+		if _, err := store.Put(data); err != nil {
+			t.Fatalf("failed to store item %d: %v", i, err)
+		}
+		items[string(data)] = true
+	}
+	store.Close()
+
+	// Reopen the store across more directories and with a higher blob limit,
+	// all the items must be migrated and stale shelves deleted
+	reopen := func(dirs []string, maxBlobs uint32) (billy.Database, map[string]uint64) {
+		found := make(map[string]uint64)
+		store, err := openStore(dirs, maxBlobs, func(id uint64, size uint32, data []byte) {
+			found[string(data)] = id
+		})
+		if err != nil {
+			t.Fatalf("failed to reopen store: %v", err)
+		}
+		return store, found
+	}
+	store, found := reopen(dirs, 2*maxBlobsPerTransaction)
+	if len(found) != len(items) {
+		t.Fatalf("migrated item count mismatch: have %d, want %d", len(found), len(items))
+	}
+	for data, id := range found {
+		if !items[data] {
+			t.Fatalf("unknown item migrated")
+		}
+		blob, err := store.Get(id)
+		if err != nil || !bytes.Equal(blob, []byte(data)) {
+			t.Fatalf("migrated item %d mismatch: err %v", id, err)
+		}
+	}
+	var shards = make(map[uint64]bool)
+	for _, id := range found {
+		shards[id>>storeShardShift] = true
+	}
+	if len(shards) < 2 {
+		t.Errorf("items not spread across directories: shards %v", shards)
+	}
+	store.Close()
+	verifyShelvesOnDisk(t, dirs, 2*maxBlobsPerTransaction)
+
+	// Reopen the store unchanged, the items must be found without any migration
+	store, found = reopen(dirs, 2*maxBlobsPerTransaction)
+	if len(found) != len(items) {
+		t.Fatalf("reopened item count mismatch: have %d, want %d", len(found), len(items))
+	}
+	store.Close()
+	verifyShelvesOnDisk(t, dirs, 2*maxBlobsPerTransaction)
+
+	// Drop a directory from the store, its items must be migrated into the
+	// remaining ones
+	store, found = reopen(dirs[:2], maxBlobsPerTransaction)
+	if len(found) != len(items) {
+		t.Fatalf("shrunk item count mismatch: have %d, want %d", len(found), len(items))
+	}
+	store.Close()
+	verifyShelvesOnDisk(t, dirs[:2], maxBlobsPerTransaction)
+	if shelves := shelvesOnDisk(t, dirs[2]); len(shelves) != 0 {
+		t.Errorf("removed directory shelves not migrated: %v", shelves)
+	}
+	// Lose a directory dropped from the store, opening must fail until the
+	// loss is acknowledged
+	os.RemoveAll(dirs[1])
+	if _, err := openStore(dirs[:1], maxBlobsPerTransaction, nil); err == nil {
+		t.Fatalf("store opened with a missing directory")
+	}
+	os.Remove(filepath.Join(dirs[0], storeShardsFile))
+	store, _ = reopen(dirs[:1], maxBlobsPerTransaction)
+	store.Close()
+}
+
+// Tests that the pool migrates the storage of a previous run into its layout,
+// keeping all the transactions accessible.
+func TestPoolMigration(t *testing.T) {
+	storage := t.TempDir()
+	config := Config{Datadir: filepath.Join(storage, "main"), Datadirs: []string{filepath.Join(storage, "extra")}}
+
+	// Seed the single directory layout of a previous run
+	os.MkdirAll(filepath.Join(config.Datadir, pendingTransactionStore), 0700)
+	store, _ := billy.Open(billy.Options{Path: filepath.Join(config.Datadir, pendingTransactionStore)}, newSlotter(maxBlobsPerTransaction), nil)
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		hashes []common.Hash
+	)
+	for i := 0; i < 3; i++ {
+		tx := makeTx(uint64(i), 1, 1, 1, key)
+		blob, _ := rlp.EncodeToBytes(tx)
+		store.Put(blob)
+		hashes = append(hashes, tx.Hash())
+	}
+	store.Close()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewDatabase(memorydb.New())), nil)
+	statedb.AddBalance(addr, uint256.NewInt(1_000_000_000))
+	statedb.Commit(0, true)
+
+	chain := &testBlockChain{
+		config:  testChainConfig,
+		basefee: uint256.NewInt(params.InitialBaseFee),
+		blobfee: uint256.NewInt(params.BlobTxMinBlobGasprice),
+		statedb: statedb,
+	}
+	pool := New(config, chain)
+	if err := pool.Init(big.NewInt(1), chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	defer pool.Close()
+
+	// The seeded transactions must be migrated into the sharded layout
+	for i, hash := range hashes {
+		if tx := pool.Get(hash); tx == nil || tx.Hash() != hash {
+			t.Errorf("tx %d not retrievable", i)
+		}
+	}
+	verifyPoolInternals(t, pool)
+	verifyShelvesOnDisk(t, config.directories(pendingTransactionStore), maxBlobsPerTransaction)
+	verifyShelvesOnDisk(t, config.directories(limboedTransactionStore), maxBlobsPerTransaction)
+}
@@ -0,0 +1,365 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bundlepool implements a transaction pool for bundles: lists of
+// transactions to be included atomically into a specific block.
+package bundlepool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// ErrEmptyBundle is returned if a bundle contains no transactions.
+	ErrEmptyBundle = errors.New("empty bundle")
+
+	// ErrBundleTooLarge is returned if a bundle contains more transactions than
+	// permitted by the pool.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleExpired is returned if the target block of a bundle is already
+	// part of the chain.
+	ErrBundleExpired = errors.New("bundle target block already passed")
+
+	// ErrBundleTooFarAhead is returned if the target block of a bundle is further
+	// away from the head than permitted by the pool.
+	ErrBundleTooFarAhead = errors.New("bundle target block too far ahead")
+
+	// ErrInvalidTimestamps is returned if the timestamp range of a bundle is
+	// empty.
+	ErrInvalidTimestamps = errors.New("invalid bundle timestamp range")
+
+	// ErrBlobTxInBundle is returned if a bundle contains a blob transaction,
+	// which are not supported in bundles.
+	ErrBlobTxInBundle = errors.New("blob transaction in bundle")
+
+	// ErrTxReverted is returned if a transaction of a bundle reverts during the
+	// simulation, without being allowed to.
+	ErrTxReverted = errors.New("transaction reverted")
+
+	// ErrBundlePoolFull is returned if the pool already holds the maximum number
+	// of bundles, all of them paying at least as much as the new one.
+	ErrBundlePoolFull = errors.New("bundle pool is full")
+)
+
+// bundleEntry is a bundle held by the pool, along with the results of its
+// simulation used for prioritization.
+type bundleEntry struct {
+	bundle *txpool.Bundle
+	hash   common.Hash
+	fees   *big.Int  // Priority fees paid by the bundle in its simulation
+	added  time.Time // Time when the bundle was added to the pool
+}
+
+// BundlePool is the transaction pool dedicated to bundles. Bundles are lists of
+// transactions to be included atomically and in order into a target block. On
+// submission, they are simulated on top of the current head and rejected if any
+// of their transactions fails or reverts without being allowed to. The pool
+// doesn't accept individual transactions, nor does it reserve the accounts of
+// the bundled transactions: bundles are only exposed to block building.
+type BundlePool struct {
+	config Config
+	chain  BlockChain
+	signer types.Signer
+
+	head    *types.Header                // Current head of the chain
+	bundles map[common.Hash]*bundleEntry // Bundles held by the pool, keyed by hash
+
+	txFeed event.Feed // Never fired, bundled transactions are not announced
+	lock   sync.RWMutex
+}
+
+// New creates a new bundle pool to gather bundles submitted locally for their
+// atomic inclusion into blocks.
+func New(config Config, chain BlockChain) *BundlePool {
+	return &BundlePool{
+		config:  config.sanitize(),
+		chain:   chain,
+		signer:  types.LatestSigner(chain.Config()),
+		bundles: make(map[common.Hash]*bundleEntry),
+	}
+}
+
+// Filter returns whether the given transaction can be consumed by the bundle
+// pool, which is never the case: transactions enter the pool only as bundles.
+func (p *BundlePool) Filter(tx *types.Transaction) bool {
+	return false
+}
+
+// Init sets the base parameters of the subpool. The bundle pool doesn't persist
+// anything, nor does it need to reserve accounts, so the address reserver is
+// not used.
+func (p *BundlePool) Init(gasTip *big.Int, head *types.Header, reserve txpool.AddressReserver) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.head = head
+	return nil
+}
+
+// Close terminates the bundle pool.
+func (p *BundlePool) Close() error {
+	log.Info("Bundle pool stopped")
+	return nil
+}
+
+// Reset implements txpool.SubPool, dropping the bundles targeting blocks which
+// are already part of the chain.
+func (p *BundlePool) Reset(oldHead, newHead *types.Header) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.head = newHead
+	for hash, entry := range p.bundles {
+		if entry.bundle.BlockNumber <= newHead.Number.Uint64() {
+			delete(p.bundles, hash)
+		}
+	}
+}
+
+// SetGasTip implements txpool.SubPool. Bundles are simulated and paid for as a
+// whole, so the minimum tip of the individual transactions is not enforced.
+func (p *BundlePool) SetGasTip(tip *big.Int) {}
+
+// Has always returns false, bundled transactions are not individually tracked.
+func (p *BundlePool) Has(hash common.Hash) bool {
+	return false
+}
+
+// Get always returns nil, bundled transactions are not individually tracked.
+func (p *BundlePool) Get(hash common.Hash) *types.Transaction {
+	return nil
+}
+
+// Add rejects all the transactions, which may only enter the pool as bundles.
+func (p *BundlePool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	errs := make([]error, len(txs))
+	for i := range txs {
+		errs[i] = core.ErrTxTypeNotSupported
+	}
+	return errs
+}
+
+// AddBundle validates a bundle, simulates it on top of the current head and, if
+// successful, enqueues it into the pool for inclusion into its target block.
+func (p *BundlePool) AddBundle(bundle *txpool.Bundle) error {
+	if len(bundle.Txs) == 0 {
+		return ErrEmptyBundle
+	}
+	if uint64(len(bundle.Txs)) > p.config.MaxBundleTxs {
+		return fmt.Errorf("%w: %d transactions, limit %d", ErrBundleTooLarge, len(bundle.Txs), p.config.MaxBundleTxs)
+	}
+	if bundle.MaxTimestamp != 0 && bundle.MaxTimestamp < bundle.MinTimestamp {
+		return fmt.Errorf("%w: min %d, max %d", ErrInvalidTimestamps, bundle.MinTimestamp, bundle.MaxTimestamp)
+	}
+	for _, tx := range bundle.Txs {
+		if tx.Type() == types.BlobTxType {
+			return ErrBlobTxInBundle
+		}
+		if _, err := types.Sender(p.signer, tx); err != nil {
+			return fmt.Errorf("%w: %v", txpool.ErrInvalidSender, err)
+		}
+	}
+	hash := bundle.Hash()
+
+	p.lock.RLock()
+	head := p.head
+	_, known := p.bundles[hash]
+	p.lock.RUnlock()
+
+	if known {
+		return txpool.ErrAlreadyKnown
+	}
+	if bundle.BlockNumber <= head.Number.Uint64() {
+		return fmt.Errorf("%w: target %d, head %d", ErrBundleExpired, bundle.BlockNumber, head.Number)
+	}
+	if bundle.BlockNumber > head.Number.Uint64()+p.config.MaxBlocksAhead {
+		return fmt.Errorf("%w: target %d, head %d, limit %d", ErrBundleTooFarAhead, bundle.BlockNumber, head.Number, p.config.MaxBlocksAhead)
+	}
+	// Simulate the bundle without holding the lock, it might take a while
+	fees, err := p.simulate(bundle, head)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.bundles[hash]; ok {
+		return txpool.ErrAlreadyKnown
+	}
+	// If the pool is full, make room by evicting the bundle paying the least,
+	// the most recent one on ties, if the new bundle pays more
+	if uint64(len(p.bundles)) >= p.config.MaxBundles {
+		var worst *bundleEntry
+		for _, entry := range p.bundles {
+			if worst == nil {
+				worst = entry
+				continue
+			}
+			if c := entry.fees.Cmp(worst.fees); c < 0 || (c == 0 && entry.added.After(worst.added)) {
+				worst = entry
+			}
+		}
+		if fees.Cmp(worst.fees) <= 0 {
+			return fmt.Errorf("%w: fees %v, cheapest %v", ErrBundlePoolFull, fees, worst.fees)
+		}
+		delete(p.bundles, worst.hash)
+		log.Debug("Bundle evicted from pool", "hash", worst.hash, "fees", worst.fees)
+	}
+	p.bundles[hash] = &bundleEntry{
+		bundle: bundle,
+		hash:   hash,
+		fees:   fees,
+		added:  time.Now(),
+	}
+	log.Debug("Bundle added to pool", "hash", hash, "txs", len(bundle.Txs), "block", bundle.BlockNumber, "fees", fees)
+	return nil
+}
+
+// simulate executes the bundle on top of the given head, as it would be within
+// the next block, returning the priority fees paid by its transactions. Errors
+// are returned if any transaction fails to apply, or if it reverts without being
+// allowed to.
+func (p *BundlePool) simulate(bundle *txpool.Bundle, head *types.Header) (*big.Int, error) {
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		return nil, err
+	}
+	config := p.chain.Config()
+	header := &types.Header{
+		ParentHash: head.Hash(),
+		Number:     new(big.Int).Add(head.Number, common.Big1),
+		GasLimit:   head.GasLimit,
+		Time:       head.Time + 1,
+		Coinbase:   head.Coinbase,
+		Difficulty: head.Difficulty,
+	}
+	if header.Time < bundle.MinTimestamp {
+		header.Time = bundle.MinTimestamp
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, head)
+	}
+	if config.IsCancun(header.Number, header.Time) {
+		var excessBlobGas uint64
+		if config.IsCancun(head.Number, head.Time) {
+			excessBlobGas = eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, *head.BlobGasUsed)
+		}
+		header.ExcessBlobGas = &excessBlobGas
+		header.BlobGasUsed = new(uint64)
+	}
+	var (
+		gp   = new(core.GasPool).AddGas(header.GasLimit)
+		fees = new(big.Int)
+	)
+	for i, tx := range bundle.Txs {
+		statedb.SetTxContext(tx.Hash(), i)
+
+		receipt, err := core.ApplyTransaction(config, p.chain, &header.Coinbase, gp, statedb, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x) failed: %w", i, tx.Hash(), err)
+		}
+		if receipt.Status == types.ReceiptStatusFailed && !bundle.CanRevert(tx.Hash()) {
+			return nil, fmt.Errorf("%w: transaction %d (%x)", ErrTxReverted, i, tx.Hash())
+		}
+		tip, _ := tx.EffectiveGasTip(header.BaseFee) // Base fee already checked during application
+		fees.Add(fees, new(big.Int).Mul(tip, new(big.Int).SetUint64(receipt.GasUsed)))
+	}
+	return fees, nil
+}
+
+// Bundles retrieves the bundles includable into the block with the given number
+// and timestamp, sorted by decreasing simulated fees, oldest first on ties.
+func (p *BundlePool) Bundles(number uint64, time uint64) []*txpool.Bundle {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var entries []*bundleEntry
+	for _, entry := range p.bundles {
+		if entry.bundle.Includable(number, time) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if c := entries[i].fees.Cmp(entries[j].fees); c != 0 {
+			return c > 0
+		}
+		return entries[i].added.Before(entries[j].added)
+	})
+	bundles := make([]*txpool.Bundle, len(entries))
+	for i, entry := range entries {
+		bundles[i] = entry.bundle
+	}
+	return bundles
+}
+
+// Pending always returns an empty set, bundles are exposed through Bundles.
+func (p *BundlePool) Pending(enforceTips bool) map[common.Address][]*txpool.LazyTransaction {
+	return make(map[common.Address][]*txpool.LazyTransaction)
+}
+
+// SubscribeTransactions registers a subscription for new transaction events,
+// which never fire as bundled transactions are not announced.
+func (p *BundlePool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
+	return p.txFeed.Subscribe(ch)
+}
+
+// Nonce returns 0, the bundle pool doesn't track account nonces.
+func (p *BundlePool) Nonce(addr common.Address) uint64 {
+	return 0
+}
+
+// Stats returns 0 for both pending and queued, bundled transactions are not
+// individually tracked.
+func (p *BundlePool) Stats() (int, int) {
+	return 0, 0
+}
+
+// Content returns empty sets, bundled transactions are not individually tracked.
+func (p *BundlePool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	return make(map[common.Address][]*types.Transaction), make(map[common.Address][]*types.Transaction)
+}
+
+// ContentFrom returns empty sets, bundled transactions are not individually
+// tracked.
+func (p *BundlePool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	return []*types.Transaction{}, []*types.Transaction{}
+}
+
+// Locals returns nil, the bundle pool has no concept of local accounts.
+func (p *BundlePool) Locals() []common.Address {
+	return nil
+}
+
+// Status returns unknown, bundled transactions are not individually tracked.
+func (p *BundlePool) Status(hash common.Hash) txpool.TxStatus {
+	return txpool.TxStatusUnknown
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundlepool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _  = crypto.GenerateKey()
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testReverts = common.HexToAddress("0xdead") // Contract reverting on any call
+)
+
+// newTestPool creates a bundle pool on top of a chain with a single funded
+// account and a reverting contract.
+func newTestPool(t *testing.T) (*BundlePool, *core.BlockChain) {
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testAddr:    {Balance: big.NewInt(params.Ether)},
+			testReverts: {Code: common.FromHex("0x60006000fd")},
+		},
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	pool := New(DefaultConfig, chain)
+	if err := pool.Init(big.NewInt(1), chain.CurrentBlock(), nil); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	return pool, chain
+}
+
+// makeTx creates a signed transaction of the test account paying the given tip.
+func makeTx(nonce uint64, to common.Address, tip int64) *types.Transaction {
+	return types.MustSignNewTx(testKey, types.LatestSigner(params.TestChainConfig), &types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		Nonce:     nonce,
+		To:        &to,
+		Gas:       100000,
+		GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+		GasTipCap: big.NewInt(tip),
+	})
+}
+
+// Tests that bundles are validated and simulated on submission.
+func TestAddBundle(t *testing.T) {
+	pool, chain := newTestPool(t)
+	defer chain.Stop()
+
+	var (
+		recipient = common.HexToAddress("0xbb")
+		revert    = makeTx(1, testReverts, 1)
+	)
+	tests := []struct {
+		bundle *txpool.Bundle
+		err    error
+	}{
+		{&txpool.Bundle{BlockNumber: 1}, ErrEmptyBundle},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1)}, BlockNumber: 0}, ErrBundleExpired},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1)}, BlockNumber: DefaultConfig.MaxBlocksAhead + 1}, ErrBundleTooFarAhead},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1)}, BlockNumber: 1, MinTimestamp: 10, MaxTimestamp: 5}, ErrInvalidTimestamps},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(1, recipient, 1)}, BlockNumber: 1}, core.ErrNonceTooHigh},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1), revert}, BlockNumber: 1}, ErrTxReverted},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1), revert}, BlockNumber: 1, RevertingTxHashes: []common.Hash{revert.Hash()}}, nil},
+		{&txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1), revert}, BlockNumber: 1, RevertingTxHashes: []common.Hash{revert.Hash()}}, txpool.ErrAlreadyKnown},
+	}
+	for i, tt := range tests {
+		err := pool.AddBundle(tt.bundle)
+		switch {
+		case tt.err == nil && err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case tt.err != nil && err == nil:
+			t.Errorf("test %d: missing error, want %v", i, tt.err)
+		case tt.err != nil && !errors.Is(err, tt.err):
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that a full pool evicts its cheapest bundle, the most recent one on ties,
+// in favour of a bundle paying more.
+func TestBundleEviction(t *testing.T) {
+	pool, chain := newTestPool(t)
+	defer chain.Stop()
+
+	pool.config.MaxBundles = 2
+
+	var (
+		older  = &txpool.Bundle{Txs: types.Transactions{makeTx(0, common.HexToAddress("0xb1"), 1)}, BlockNumber: 1}
+		newer  = &txpool.Bundle{Txs: types.Transactions{makeTx(0, common.HexToAddress("0xb2"), 1)}, BlockNumber: 1}
+		cheap  = &txpool.Bundle{Txs: types.Transactions{makeTx(0, common.HexToAddress("0xb3"), 1)}, BlockNumber: 1}
+		pricey = &txpool.Bundle{Txs: types.Transactions{makeTx(0, common.HexToAddress("0xb4"), 2)}, BlockNumber: 1}
+	)
+	for _, bundle := range []*txpool.Bundle{older, newer} {
+		if err := pool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	pool.bundles[newer.Hash()].added = pool.bundles[older.Hash()].added.Add(time.Second)
+
+	if err := pool.AddBundle(cheap); !errors.Is(err, ErrBundlePoolFull) {
+		t.Fatalf("equally paying bundle error mismatch: have %v, want %v", err, ErrBundlePoolFull)
+	}
+	if err := pool.AddBundle(pricey); err != nil {
+		t.Fatalf("failed to add better paying bundle: %v", err)
+	}
+	if _, ok := pool.bundles[newer.Hash()]; ok {
+		t.Error("most recent cheapest bundle not evicted")
+	}
+	for _, bundle := range []*txpool.Bundle{older, pricey} {
+		if _, ok := pool.bundles[bundle.Hash()]; !ok {
+			t.Errorf("bundle %x evicted", bundle.Hash())
+		}
+	}
+}
+
+// Tests that bundles are retrieved for their target block only, sorted by
+// their simulated fees, and dropped once their target block is passed.
+func TestBundlesSelection(t *testing.T) {
+	pool, chain := newTestPool(t)
+	defer chain.Stop()
+
+	var (
+		recipient = common.HexToAddress("0xbb")
+		cheap     = &txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 1)}, BlockNumber: 1}
+		pricey    = &txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 2)}, BlockNumber: 1}
+		timed     = &txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 3)}, BlockNumber: 1, MinTimestamp: 100, MaxTimestamp: 200}
+		later     = &txpool.Bundle{Txs: types.Transactions{makeTx(0, recipient, 4)}, BlockNumber: 2}
+	)
+	for _, bundle := range []*txpool.Bundle{cheap, pricey, timed, later} {
+		if err := pool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	check := func(number, time uint64, want ...*txpool.Bundle) {
+		t.Helper()
+
+		have := pool.Bundles(number, time)
+		if len(have) != len(want) {
+			t.Fatalf("block %d, time %d: bundle count mismatch: have %d, want %d", number, time, len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Hash() != want[i].Hash() {
+				t.Errorf("block %d, time %d: bundle %d mismatch: have %x, want %x", number, time, i, have[i].Hash(), want[i].Hash())
+			}
+		}
+	}
+	check(1, 50, pricey, cheap)
+	check(1, 150, timed, pricey, cheap)
+	check(2, 150, later)
+
+	// Move the head past the first block and ensure its bundles are dropped
+	head := types.CopyHeader(chain.CurrentBlock())
+	head.Number = big.NewInt(1)
+	pool.Reset(chain.CurrentBlock(), head)
+
+	check(1, 150)
+	check(2, 150, later)
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxConditionalCost is the maximum number of storage roots and slots a single
+// conditional may check, bounding the state reads done on every pool reset and
+// block built.
+const MaxConditionalCost = 1000
+
+// KnownAccount is the expected storage of an account: either its whole storage
+// root, or the values of some of its storage slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash                // Expected storage root, nil if checking slots
+	StorageSlots map[common.Hash]common.Hash // Expected values of individual storage slots
+}
+
+// Conditional is a set of conditions a transaction may only be included under:
+// the storage of some accounts must hold the expected values and the including
+// block must be within the given number and timestamp ranges.
+type Conditional struct {
+	KnownAccounts  map[common.Address]KnownAccount // Expected storage of accounts before the transaction
+	BlockNumberMin uint64                          // Minimum number of the including block (0 = no limit)
+	BlockNumberMax uint64                          // Maximum number of the including block (0 = no limit)
+	TimestampMin   uint64                          // Minimum timestamp of the including block (0 = no limit)
+	TimestampMax   uint64                          // Maximum timestamp of the including block (0 = no limit)
+}
+
+// ConditionalState is the subset of the state needed to check the known accounts
+// of a conditional.
+type ConditionalState interface {
+	GetState(addr common.Address, slot common.Hash) common.Hash
+	GetStorageRoot(addr common.Address) common.Hash
+}
+
+// Cost returns the number of storage roots and slots checked by the conditional.
+func (c *Conditional) Cost() int {
+	var cost int
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		}
+		cost += len(account.StorageSlots)
+	}
+	return cost
+}
+
+// Validate checks the sanity of the conditional, without looking at any state.
+func (c *Conditional) Validate() error {
+	if c.BlockNumberMax != 0 && c.BlockNumberMin > c.BlockNumberMax {
+		return fmt.Errorf("%w: block number range [%d, %d] empty", ErrInvalidConditional, c.BlockNumberMin, c.BlockNumberMax)
+	}
+	if c.TimestampMax != 0 && c.TimestampMin > c.TimestampMax {
+		return fmt.Errorf("%w: timestamp range [%d, %d] empty", ErrInvalidConditional, c.TimestampMin, c.TimestampMax)
+	}
+	if cost := c.Cost(); cost > MaxConditionalCost {
+		return fmt.Errorf("%w: cost %d above limit %d", ErrInvalidConditional, cost, MaxConditionalCost)
+	}
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil && len(account.StorageSlots) > 0 {
+			return fmt.Errorf("%w: both storage root and slots given for %v", ErrInvalidConditional, addr)
+		}
+	}
+	return nil
+}
+
+// Includable returns whether the block number and timestamp ranges allow the
+// inclusion into the block with the given number and timestamp.
+func (c *Conditional) Includable(number uint64, time uint64) bool {
+	if number < c.BlockNumberMin || (c.BlockNumberMax != 0 && number > c.BlockNumberMax) {
+		return false
+	}
+	if time < c.TimestampMin || (c.TimestampMax != 0 && time > c.TimestampMax) {
+		return false
+	}
+	return true
+}
+
+// Expired returns whether the block number and timestamp ranges were passed by
+// the block with the given number and timestamp, so no block following it can
+// include the transaction anymore.
+func (c *Conditional) Expired(number uint64, time uint64) bool {
+	if c.BlockNumberMax != 0 && number > c.BlockNumberMax {
+		return true
+	}
+	if c.TimestampMax != 0 && time > c.TimestampMax {
+		return true
+	}
+	return false
+}
+
+// CheckState verifies that the storage of the known accounts holds the expected
+// values in the given state.
+func (c *Conditional) CheckState(state ConditionalState) error {
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			if root := state.GetStorageRoot(addr); root != *account.StorageRoot {
+				return fmt.Errorf("%w: storage root of %v is %v, want %v", ErrConditionsNotMet, addr, root, *account.StorageRoot)
+			}
+			continue
+		}
+		for slot, want := range account.StorageSlots {
+			if have := state.GetState(addr, slot); have != want {
+				return fmt.Errorf("%w: slot %v of %v is %v, want %v", ErrConditionsNotMet, slot, addr, have, want)
+			}
+		}
+	}
+	return nil
+}
@@ -54,4 +54,29 @@ var (
 	// ErrFutureReplacePending is returned if a future transaction replaces a pending
 	// one. Future transactions should only be able to replace other future transactions.
 	ErrFutureReplacePending = errors.New("future transaction tries to replace pending")
+
+	// ErrPrivateNotSupported is returned if a transaction is submitted privately,
+	// but the subpool handling its type can't hold private transactions.
+	ErrPrivateNotSupported = errors.New("private transaction not supported")
+
+	// ErrBundlesNotSupported is returned if a bundle is submitted, but there is
+	// no subpool able to hold bundles.
+	ErrBundlesNotSupported = errors.New("bundles not supported")
+
+	// ErrConditionalNotSupported is returned if a transaction is submitted with
+	// inclusion conditions, but the subpool handling its type can't hold them.
+	ErrConditionalNotSupported = errors.New("conditional transaction not supported")
+
+	// ErrInvalidConditional is returned if the inclusion conditions of a
+	// transaction are malformed or too expensive to check.
+	ErrInvalidConditional = errors.New("invalid transaction conditions")
+
+	// ErrConditionsNotMet is returned if the inclusion conditions of a transaction
+	// do not hold, or can't be met by any future block.
+	ErrConditionsNotMet = errors.New("transaction conditions not met")
+
+	// ErrConditionalNoLocals is returned if a conditional transaction is submitted
+	// while local transaction handling is disabled. Conditional transactions rely
+	// on being local, as they must not be evicted in favour of remote ones.
+	ErrConditionalNoLocals = errors.New("conditional transactions require local transaction handling")
 )
@@ -19,6 +19,7 @@ package legacypool
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -78,6 +79,13 @@ var (
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
 
+	// Metrics for the private transactions
+	privateEvictionMeter = metrics.NewRegisteredMeter("txpool/private/eviction", nil) // Dropped due to private lifetime
+	privatePublishMeter  = metrics.NewRegisteredMeter("txpool/private/publish", nil)  // Published due to private lifetime
+
+	// Metrics for the conditional transactions
+	conditionalDropMeter = metrics.NewRegisteredMeter("txpool/conditional/drop", nil) // Dropped due to failing conditions
+
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
@@ -125,6 +133,8 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal string // Journal of remote transactions to survive node restarts (disabled if empty)
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -134,6 +144,11 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PrivateLifetime time.Duration // Maximum amount of time private transactions are withheld from the network
+	PrivatePublish  bool          // Whether to publish expired private transactions instead of dropping them
+
+	FeeMarketPolicy bool // Whether to evict and replace transactions based on the fee market instead of plain prices
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -150,6 +165,8 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrivateLifetime: time.Hour,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -188,6 +205,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultConfig.PrivateLifetime
+	}
 	return conf
 }
 
@@ -211,8 +232,9 @@ type LegacyPool struct {
 	currentState  *state.StateDB               // Current state in the blockchain head
 	pendingNonces *noncer                      // Pending state tracking virtual nonces
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *journal    // Journal of local transaction to back up to disk
+	locals        *accountSet // Set of local transaction to exempt from eviction rules
+	journal       *journal    // Journal of local transaction to back up to disk
+	remoteJournal *journal    // Journal of remote transactions to back up to disk
 
 	reserve txpool.AddressReserver       // Address reserver to ensure exclusivity across subpools
 	pending map[common.Address]*list     // All currently processable transactions
@@ -220,6 +242,12 @@ type LegacyPool struct {
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *lookup                      // All transactions to allow lookups
 	priced  *pricedList                  // All transactions sorted by price
+	private map[common.Hash]time.Time    // Private transactions withheld from the network and their submission time
+
+	conditionals map[common.Hash]*txpool.Conditional // Conditional transactions withheld from the network and their conditions
+
+	lifecycle     []txpool.TxEvent     // Lifecycle events waiting to be delivered once the lock is released
+	lifecycleFeed txpool.LifecycleFeed // Feed delivering the lifecycle events of the transactions
 
 	reqResetCh      chan *txpoolResetRequest
 	reqPromoteCh    chan *accountSet
@@ -252,6 +280,8 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		queue:           make(map[common.Address]*list),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		private:         make(map[common.Hash]time.Time),
+		conditionals:    make(map[common.Hash]*txpool.Conditional),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
@@ -264,11 +294,14 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
 	}
-	pool.priced = newPricedList(pool.all)
+	pool.priced = newPricedList(pool.all, config.FeeMarketPolicy)
 
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
 	}
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newTxJournal(config.RemoteJournal)
+	}
 	return pool
 }
 
@@ -322,6 +355,19 @@ func (pool *LegacyPool) Init(gasTip *big.Int, head *types.Header, reserve txpool
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction journaling is enabled, load from disk too. The
+	// transactions are revalidated against the current head during insertion.
+	if pool.remoteJournal != nil {
+		if err := pool.remoteJournal.load(pool.addRemotes); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		pool.mu.Lock()
+		err := pool.remoteJournal.rotate(pool.remote())
+		pool.mu.Unlock()
+		if err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 	pool.wg.Add(1)
 	go pool.loop()
 	return nil
@@ -377,12 +423,19 @@ func (pool *LegacyPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.trackDrop(tx.Hash(), txpool.DropExpired)
 						pool.removeTx(tx.Hash(), true, true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
+			published := pool.expirePrivate()
 			pool.mu.Unlock()
+			pool.flushLifecycle()
+
+			if len(published) > 0 {
+				pool.txFeed.Send(core.NewTxsEvent{Txs: published})
+			}
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -393,6 +446,13 @@ func (pool *LegacyPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				pool.mu.Lock()
+				if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -406,6 +466,16 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	// Persist the latest remote transactions, the journal is only written in
+	// batches during rotations
+	if pool.remoteJournal != nil {
+		pool.mu.Lock()
+		if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote tx journal", "err", err)
+		}
+		pool.mu.Unlock()
+		pool.remoteJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -427,9 +497,17 @@ func (pool *LegacyPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs
 	return pool.txFeed.Subscribe(ch)
 }
 
+// SubscribeLifecycle subscribes to the lifecycle events of the transactions of
+// the pool, delivered in batches. Batches are dropped while the channel is full.
+func (pool *LegacyPool) SubscribeLifecycle(ch chan<- []txpool.TxEvent) event.Subscription {
+	return pool.lifecycleFeed.Subscribe(ch)
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *LegacyPool) SetGasTip(tip *big.Int) {
+	defer pool.flushLifecycle()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -441,6 +519,7 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(tip)
 		for _, tx := range drop {
+			pool.trackDrop(tx.Hash(), txpool.DropBelowTip)
 			pool.removeTx(tx.Hash(), false, true)
 		}
 		pool.priced.Removed(len(drop))
@@ -550,6 +629,8 @@ func (pool *LegacyPool) Pending(enforceTips bool) map[common.Address][]*txpool.L
 					GasTipCap: txs[i].GasTipCap(),
 					Gas:       txs[i].Gas(),
 					BlobGas:   txs[i].BlobGas(),
+
+					Conditional: pool.conditionals[txs[i].Hash()],
 				}
 			}
 			pending[addr] = lazies
@@ -567,21 +648,92 @@ func (pool *LegacyPool) Locals() []common.Address {
 }
 
 // local retrieves all currently known local transactions, grouped by origin
-// account and sorted by nonce. The returned transaction set is a copy and can be
+// account and sorted by nonce. Private and conditional transactions are left out,
+// as they must not outlive a restart. The returned transaction set is a copy and can be
 // freely modified by calling code.
 func (pool *LegacyPool) local() map[common.Address]types.Transactions {
 	txs := make(map[common.Address]types.Transactions)
 	for addr := range pool.locals.accounts {
 		if pending := pool.pending[addr]; pending != nil {
-			txs[addr] = append(txs[addr], pending.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(pending.Flatten())...)
 		}
 		if queued := pool.queue[addr]; queued != nil {
-			txs[addr] = append(txs[addr], queued.Flatten()...)
+			txs[addr] = append(txs[addr], pool.public(queued.Flatten())...)
 		}
 	}
 	return txs
 }
 
+// remote retrieves the currently known remote transactions to be journaled,
+// grouped by origin account and sorted by nonce. Private and conditional
+// transactions are left out, as they are remote too if local transaction handling
+// is disabled. The executable and the queued transactions are bounded by the
+// global slot and queue limits respectively, trimming the highest nonces of the
+// accounts above the limits. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *LegacyPool) remote() map[common.Address]types.Transactions {
+	var (
+		txs     = make(map[common.Address]types.Transactions)
+		pending = pool.config.GlobalSlots
+		queued  = pool.config.GlobalQueue
+	)
+	for addr, list := range pool.pending {
+		if pool.locals.contains(addr) || pending == 0 {
+			continue
+		}
+		flat := pool.public(list.Flatten())
+		if len(flat) == 0 {
+			continue
+		}
+		if uint64(len(flat)) > pending {
+			flat = flat[:pending]
+		}
+		txs[addr] = append(txs[addr], flat...)
+		pending -= uint64(len(flat))
+	}
+	for addr, list := range pool.queue {
+		if pool.locals.contains(addr) || queued == 0 {
+			continue
+		}
+		flat := pool.public(list.Flatten())
+		if len(flat) == 0 {
+			continue
+		}
+		if uint64(len(flat)) > queued {
+			flat = flat[:queued]
+		}
+		txs[addr] = append(txs[addr], flat...)
+		queued -= uint64(len(flat))
+	}
+	return txs
+}
+
+// public filters the private and conditional transactions out of the given list.
+func (pool *LegacyPool) public(txs types.Transactions) types.Transactions {
+	if len(pool.private) == 0 && len(pool.conditionals) == 0 {
+		return txs
+	}
+	filtered := make(types.Transactions, 0, len(txs))
+	for _, tx := range txs {
+		if !pool.withheld(tx.Hash()) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
+// withheld returns whether the transaction with the given hash is a private or a
+// conditional one, which must neither be announced nor journaled.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) withheld(hash common.Hash) bool {
+	if _, ok := pool.private[hash]; ok {
+		return true
+	}
+	_, ok := pool.conditionals[hash]
+	return ok
+}
+
 // validateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -749,6 +901,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			underpricedTxMeter.Mark(1)
 
 			sender, _ := types.Sender(pool.signer, tx)
+			pool.trackDrop(tx.Hash(), txpool.DropUnderpriced)
 			dropped := pool.removeTx(tx.Hash(), false, sender != from) // Don't unreserve the sender of the tx being added if last from the acc
 
 			pool.changesSinceReorg += dropped
@@ -758,7 +911,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	// Try to replace an existing transaction in the pending pool
 	if list := pool.pending[from]; list != nil && list.Contains(tx.Nonce()) {
 		// Nonce already pending, check if required price bump is met
-		inserted, old := list.Add(tx, pool.config.PriceBump)
+		inserted, old := list.Add(tx, pool.config.PriceBump, pool.replacementBaseFee())
 		if !inserted {
 			pendingDiscardMeter.Mark(1)
 			return false, txpool.ErrReplaceUnderpriced
@@ -768,11 +921,14 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.trackReplaced(old.Hash(), hash)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
+		pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventReceived})
+		pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventPromoted})
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// Successful promotion, bump the heartbeat
@@ -794,6 +950,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		localGauge.Inc(1)
 	}
 	pool.journalTx(from, tx)
+	pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventReceived})
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replaced, nil
@@ -832,7 +989,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 	if pool.queue[from] == nil {
 		pool.queue[from] = newList(false)
 	}
-	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump)
+	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump, pool.replacementBaseFee())
 	if !inserted {
 		// An older transaction was better, discard this
 		queuedDiscardMeter.Mark(1)
@@ -843,6 +1000,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.trackReplaced(old.Hash(), hash)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -863,13 +1021,27 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 	return old != nil, nil
 }
 
+// replacementBaseFee returns the base fee replacements are checked against, or
+// nil if replacements are checked against the plain transaction prices.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) replacementBaseFee() *big.Int {
+	if !pool.config.FeeMarketPolicy {
+		return nil
+	}
+	return pool.priced.urgent.baseFee
+}
+
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *LegacyPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
+	// Only journal if it's enabled and the transaction is local, but not withheld
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	if pool.withheld(tx.Hash()) {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -886,12 +1058,13 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 	}
 	list := pool.pending[addr]
 
-	inserted, old := list.Add(tx, pool.config.PriceBump)
+	inserted, old := list.Add(tx, pool.config.PriceBump, pool.replacementBaseFee())
 	if !inserted {
 		// An older transaction was better, discard this
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.trackDrop(hash, txpool.DropUnderpriced)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -899,10 +1072,12 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.trackReplaced(old.Hash(), hash)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
+	pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventPromoted})
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
 
@@ -957,6 +1132,118 @@ func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
 // If sync is set, the method will block until all internal maintenance related
 // to the add is finished. Only use this during tests for determinism!
 func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error {
+	return pool.addTxs(txs, local, false, sync)
+}
+
+// AddPrivate enqueues a batch of local transactions into the pool, flagging them
+// as private. Private transactions are available for block building, but they
+// are never announced to the network. Once they outlive the configured private
+// lifetime, they are either dropped or published, depending on the config.
+func (pool *LegacyPool) AddPrivate(txs []*types.Transaction, sync bool) []error {
+	return pool.addTxs(txs, true, true, sync)
+}
+
+// IsPrivate returns whether the transaction with the given hash is a private
+// one, which must not be announced to the network. Conditional transactions are
+// reported as private too, as other nodes could not enforce their conditions.
+func (pool *LegacyPool) IsPrivate(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.withheld(hash)
+}
+
+// AddConditional enqueues a local transaction into the pool, to be included only
+// while the given conditions hold. The conditions are checked against the head
+// state on submission and on every pool reset, the transaction being dropped as
+// soon as they fail. Conditional transactions are never announced nor journaled,
+// and are rejected if local transaction handling is disabled.
+func (pool *LegacyPool) AddConditional(tx *types.Transaction, cond *txpool.Conditional, sync bool) error {
+	if pool.config.NoLocals {
+		return txpool.ErrConditionalNoLocals
+	}
+	if err := cond.Validate(); err != nil {
+		return err
+	}
+	if pool.all.Get(tx.Hash()) != nil {
+		knownTxMeter.Mark(1)
+		return txpool.ErrAlreadyKnown
+	}
+	if err := pool.validateTxBasics(tx, true); err != nil {
+		log.Trace("Discarding invalid conditional transaction", "hash", tx.Hash(), "err", err)
+		invalidTxMeter.Mark(1)
+		return err
+	}
+	pool.mu.Lock()
+	if err := pool.checkConditional(cond); err != nil {
+		pool.mu.Unlock()
+		return err
+	}
+	// Flag the transaction before insertion, so that it is never journaled nor
+	// announced
+	pool.conditionals[tx.Hash()] = cond
+	errs, dirty := pool.addTxsLocked([]*types.Transaction{tx}, true, false)
+	if errs[0] != nil {
+		delete(pool.conditionals, tx.Hash())
+	}
+	pool.mu.Unlock()
+	pool.flushLifecycle()
+
+	done := pool.requestPromoteExecutables(dirty)
+	if sync {
+		<-done
+	}
+	return errs[0]
+}
+
+// DropConditional removes a conditional transaction from the pool, after its
+// conditions were found to be no longer met. Unconditional transactions are left
+// untouched.
+func (pool *LegacyPool) DropConditional(hash common.Hash) {
+	pool.mu.Lock()
+	if _, ok := pool.conditionals[hash]; ok {
+		pool.trackDrop(hash, txpool.DropConditions)
+		pool.removeTx(hash, true, true)
+		conditionalDropMeter.Mark(1)
+	}
+	pool.mu.Unlock()
+	pool.flushLifecycle()
+}
+
+// checkConditional verifies that the conditions hold in the head state, and that
+// they can still be met by the blocks following the head.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) checkConditional(cond *txpool.Conditional) error {
+	head := pool.currentHead.Load()
+	if cond.Expired(head.Number.Uint64()+1, head.Time+1) {
+		return fmt.Errorf("%w: block range passed", txpool.ErrConditionsNotMet)
+	}
+	return cond.CheckState(pool.currentState)
+}
+
+// checkConditionals drops the conditional transactions whose conditions no longer
+// hold after a pool reset. Stale entries of transactions already gone from the
+// pool are cleaned up too.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) checkConditionals() {
+	for hash, cond := range pool.conditionals {
+		if pool.all.Get(hash) == nil {
+			delete(pool.conditionals, hash)
+			continue
+		}
+		if err := pool.checkConditional(cond); err != nil {
+			log.Trace("Dropping conditional transaction", "hash", hash, "err", err)
+			pool.trackDrop(hash, txpool.DropConditions)
+			pool.removeTx(hash, true, true)
+			conditionalDropMeter.Mark(1)
+		}
+	}
+}
+
+// addTxs is the shared implementation of Add and AddPrivate.
+func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, private, sync bool) []error {
 	// Do not treat as local if local transactions have been disabled
 	local = local && !pool.config.NoLocals
 
@@ -990,8 +1277,9 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, local, private)
 	pool.mu.Unlock()
+	pool.flushLifecycle()
 
 	var nilSlot = 0
 	for _, err := range newErrs {
@@ -1011,11 +1299,23 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid.
 // The transaction pool lock must be held.
-func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool, private bool) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
+		// Flag private transactions before insertion, so that they are never
+		// journaled nor announced
+		var marked bool
+		if private {
+			if _, ok := pool.private[tx.Hash()]; !ok {
+				pool.private[tx.Hash()] = time.Now()
+				marked = true
+			}
+		}
 		replaced, err := pool.add(tx, local)
+		if err != nil && marked {
+			delete(pool.private, tx.Hash())
+		}
 		errs[i] = err
 		if err == nil && !replaced {
 			dirty.addTx(tx)
@@ -1098,6 +1398,8 @@ func (pool *LegacyPool) removeTx(hash common.Hash, outofbound bool, unreserve bo
 	}
 	// Remove it from the list of known transactions
 	pool.all.Remove(hash)
+	delete(pool.private, hash)
+	delete(pool.conditionals, hash)
 	if outofbound {
 		pool.priced.Removed(1)
 	}
@@ -1115,6 +1417,7 @@ func (pool *LegacyPool) removeTx(hash common.Hash, outofbound bool, unreserve bo
 			for _, tx := range invalids {
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(tx.Hash(), tx, false, false)
+				pool.track(txpool.TxEvent{Hash: tx.Hash(), Type: txpool.TxEventDemoted})
 			}
 			// Update the account nonce if needed
 			pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1137,6 +1440,36 @@ func (pool *LegacyPool) removeTx(hash common.Hash, outofbound bool, unreserve bo
 	return 0
 }
 
+// expirePrivate drops the private transactions which outlived the configured
+// private lifetime, or publishes them if the pool is configured to do so. The
+// published transactions are returned to be announced by the caller. Stale
+// markers of transactions already gone from the pool are cleaned up too.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) expirePrivate() []*types.Transaction {
+	var published []*types.Transaction
+	for hash, added := range pool.private {
+		tx := pool.all.Get(hash)
+		if tx == nil {
+			delete(pool.private, hash)
+			continue
+		}
+		if time.Since(added) <= pool.config.PrivateLifetime {
+			continue
+		}
+		if pool.config.PrivatePublish {
+			delete(pool.private, hash)
+			published = append(published, tx)
+			privatePublishMeter.Mark(1)
+			continue
+		}
+		pool.trackDrop(hash, txpool.DropPrivateExpiry)
+		pool.removeTx(hash, true, true)
+		privateEvictionMeter.Mark(1)
+	}
+	return published
+}
+
 // requestReset requests a pool reset to the new head block.
 // The returned channel is closed when the reset has occurred.
 func (pool *LegacyPool) requestReset(oldHead *types.Header, newHead *types.Header) chan struct{} {
@@ -1167,6 +1500,44 @@ func (pool *LegacyPool) queueTxEvent(tx *types.Transaction) {
 	}
 }
 
+// track records a lifecycle event of a transaction, to be delivered once the
+// pool lock is released.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) track(ev txpool.TxEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	pool.lifecycle = append(pool.lifecycle, ev)
+}
+
+// trackDrop records the removal of a transaction for the given reason.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) trackDrop(hash common.Hash, reason string) {
+	pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventDropped, Reason: reason})
+}
+
+// trackReplaced records the replacement of a transaction by another one.
+//
+// The transaction pool lock must be held.
+func (pool *LegacyPool) trackReplaced(hash common.Hash, by common.Hash) {
+	pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventReplaced, ReplacedBy: by})
+}
+
+// flushLifecycle delivers the lifecycle events recorded since the last flush to
+// the subscribers. It must be called without holding the pool lock.
+func (pool *LegacyPool) flushLifecycle() {
+	pool.mu.Lock()
+	events := pool.lifecycle
+	pool.lifecycle = nil
+	pool.mu.Unlock()
+
+	if len(events) > 0 {
+		pool.lifecycleFeed.Send(events)
+	}
+}
+
 // scheduleReorgLoop schedules runs of reset and promoteExecutables. Code above should not
 // call those methods directly, but request them being run using requestReset and
 // requestPromoteExecutables instead.
@@ -1258,6 +1629,9 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		pool.reset(reset.oldHead, reset.newHead)
 
+		// Drop the conditional transactions invalidated by the new head
+		pool.checkConditionals()
+
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
 			events[addr].Forward(pool.pendingNonces.get(addr))
@@ -1302,6 +1676,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
 	pool.mu.Unlock()
+	pool.flushLifecycle()
 
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
@@ -1416,7 +1791,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	core.SenderCacher.Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, false)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1438,6 +1813,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.trackDrop(hash, txpool.DropStaleNonce)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1445,6 +1821,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.trackDrop(hash, txpool.DropUnpayable)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1467,6 +1844,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.trackDrop(hash, txpool.DropAccountLimit)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1532,6 +1910,7 @@ func (pool *LegacyPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.trackDrop(hash, txpool.DropPoolOverflow)
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1559,6 +1938,7 @@ func (pool *LegacyPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.trackDrop(hash, txpool.DropPoolOverflow)
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1605,6 +1985,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.trackDrop(tx.Hash(), txpool.DropPoolOverflow)
 				pool.removeTx(tx.Hash(), true, true)
 			}
 			drop -= size
@@ -1614,6 +1995,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.trackDrop(txs[i].Hash(), txpool.DropPoolOverflow)
 			pool.removeTx(txs[i].Hash(), true, true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1639,6 +2021,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.trackDrop(hash, txpool.DropStaleNonce)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1647,6 +2030,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.trackDrop(hash, txpool.DropUnpayable)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -1656,6 +2040,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 
 			// Internal shuffle shouldn't touch the lookup set.
 			pool.enqueueTx(hash, tx, false, false)
+			pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventDemoted})
 		}
 		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
 		if pool.locals.contains(addr) {
@@ -1670,6 +2055,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 
 				// Internal shuffle shouldn't touch the lookup set.
 				pool.enqueueTx(hash, tx, false, false)
+				pool.track(txpool.TxEvent{Hash: hash, Type: txpool.TxEventDemoted})
 			}
 			pendingGauge.Dec(int64(len(gapped)))
 		}
@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -1143,6 +1144,295 @@ func testQueueTimeLimiting(t *testing.T, nolocals bool) {
 	}
 }
 
+// Tests that private transactions are withheld from the journal and events, and
+// that they are dropped or published once their private lifetime passes.
+func TestPrivateTimeLimiting(t *testing.T)        { testPrivateTimeLimiting(t, false) }
+func TestPrivateTimeLimitingPublish(t *testing.T) { testPrivateTimeLimiting(t, true) }
+
+func testPrivateTimeLimiting(t *testing.T, publish bool) {
+	// Reduce the eviction interval to a testable amount
+	defer func(old time.Duration) { evictionInterval = old }(evictionInterval)
+	evictionInterval = time.Millisecond * 100
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.PrivateLifetime = time.Second
+	config.PrivatePublish = publish
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	events := make(chan core.NewTxsEvent, 32)
+	sub := pool.txFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// Add a public and a private transaction, both local
+	public, private := transaction(0, 100000, key), transaction(1, 100000, key)
+	if err := pool.addLocal(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if err := pool.AddPrivate([]*types.Transaction{private}, true)[0]; err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if pool.IsPrivate(public.Hash()) || !pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private flags mismatch: public %v, private %v", pool.IsPrivate(public.Hash()), pool.IsPrivate(private.Hash()))
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	// Private transactions must not be journaled
+	pool.mu.RLock()
+	locals := pool.local()
+	pool.mu.RUnlock()
+	for _, txs := range locals {
+		for _, tx := range txs {
+			if tx.Hash() == private.Hash() {
+				t.Fatalf("private transaction selected for journaling")
+			}
+		}
+	}
+	if err := validateEvents(events, 2); err != nil {
+		t.Fatalf("original event firing failed: %v", err)
+	}
+	// Wait for the private lifetime to pass and check the transaction's fate
+	time.Sleep(config.PrivateLifetime + 2*evictionInterval)
+
+	if pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private transaction still withheld after its lifetime")
+	}
+	pending, _ := pool.Stats()
+	if publish {
+		if pending != 2 {
+			t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+		}
+		if err := validateEvents(events, 1); err != nil {
+			t.Fatalf("publish event firing failed: %v", err)
+		}
+	} else {
+		if pending != 1 || pool.Has(private.Hash()) {
+			t.Fatalf("private transaction not dropped: pending %d", pending)
+		}
+		if err := validateEvents(events, 0); err != nil {
+			t.Fatalf("drop event firing failed: %v", err)
+		}
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that conditional transactions are checked on submission, withheld from
+// the journal and the network, and dropped once their conditions fail on a reset.
+func TestConditionalTransactions(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool := New(testTxPoolConfig, blockchain)
+	pool.Init(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	var (
+		contract = common.Address{0xc0}
+		slot     = common.Hash{0x01}
+		value    = common.Hash{0x02}
+	)
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, value)
+	pool.mu.Unlock()
+
+	expect := func(value common.Hash) *txpool.Conditional {
+		return &txpool.Conditional{
+			KnownAccounts: map[common.Address]txpool.KnownAccount{
+				contract: {StorageSlots: map[common.Hash]common.Hash{slot: value}},
+			},
+		}
+	}
+	// Malformed and failing conditions must be rejected on submission
+	invalid := expect(value)
+	invalid.BlockNumberMin, invalid.BlockNumberMax = 10, 5
+	if err := pool.AddConditional(transaction(0, 100000, key), invalid, true); !errors.Is(err, txpool.ErrInvalidConditional) {
+		t.Fatalf("invalid conditional error mismatch: have %v, want %v", err, txpool.ErrInvalidConditional)
+	}
+	if err := pool.AddConditional(transaction(0, 100000, key), expect(common.Hash{0xff}), true); !errors.Is(err, txpool.ErrConditionsNotMet) {
+		t.Fatalf("failing conditional error mismatch: have %v, want %v", err, txpool.ErrConditionsNotMet)
+	}
+	// Add a conditional transaction whose conditions hold and an unconditional one
+	// on top of it
+	conditional, public := transaction(0, 100000, key), transaction(1, 100000, key)
+	if err := pool.AddConditional(conditional, expect(value), true); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	if err := pool.addLocal(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if !pool.IsPrivate(conditional.Hash()) || pool.IsPrivate(public.Hash()) {
+		t.Fatalf("withheld flags mismatch: conditional %v, public %v", pool.IsPrivate(conditional.Hash()), pool.IsPrivate(public.Hash()))
+	}
+	for _, txs := range pool.Pending(false) {
+		for _, ltx := range txs {
+			if (ltx.Conditional != nil) != (ltx.Hash == conditional.Hash()) {
+				t.Fatalf("transaction %v conditions mismatch: have %v", ltx.Hash, ltx.Conditional)
+			}
+		}
+	}
+	pool.mu.RLock()
+	locals := pool.local()
+	pool.mu.RUnlock()
+	for _, txs := range locals {
+		for _, tx := range txs {
+			if tx.Hash() == conditional.Hash() {
+				t.Fatalf("conditional transaction selected for journaling")
+			}
+		}
+	}
+	// Unconditional transactions must survive explicit conditional drops
+	pool.DropConditional(public.Hash())
+	if !pool.Has(public.Hash()) {
+		t.Fatalf("unconditional transaction dropped")
+	}
+	// Change the known storage and ensure the reset drops the transaction
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, common.Hash{0x03})
+	pool.mu.Unlock()
+	<-pool.requestReset(nil, nil)
+
+	if pool.Has(conditional.Hash()) {
+		t.Fatalf("conditional transaction not dropped after its conditions failed")
+	}
+	if pool.IsPrivate(conditional.Hash()) {
+		t.Fatalf("dropped conditional transaction still withheld")
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 0, 1)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the lifecycle changes of the transactions are reported, along with
+// the replacements and the reasons of the drops.
+func TestTransactionLifecycle(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	events := make(chan []txpool.TxEvent, 32)
+	sub := pool.SubscribeLifecycle(events)
+	defer sub.Unsubscribe()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// Add an executable and a gapped transaction, then replace the executable one
+	var (
+		tx0  = pricedTransaction(0, 100000, big.NewInt(1), key)
+		tx2  = pricedTransaction(2, 100000, big.NewInt(1), key)
+		tx0b = pricedTransaction(0, 100000, big.NewInt(2), key)
+	)
+	for _, tx := range []*types.Transaction{tx0, tx2, tx0b} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// Raise the minimum tip to drop the remaining transactions
+	pool.SetGasTip(big.NewInt(3))
+
+	want := []txpool.TxEvent{
+		{Hash: tx0.Hash(), Type: txpool.TxEventReceived},
+		{Hash: tx0.Hash(), Type: txpool.TxEventPromoted},
+		{Hash: tx2.Hash(), Type: txpool.TxEventReceived},
+		{Hash: tx0.Hash(), Type: txpool.TxEventReplaced, ReplacedBy: tx0b.Hash()},
+		{Hash: tx0b.Hash(), Type: txpool.TxEventReceived},
+		{Hash: tx0b.Hash(), Type: txpool.TxEventPromoted},
+		{Hash: tx0b.Hash(), Type: txpool.TxEventDropped, Reason: txpool.DropBelowTip},
+		{Hash: tx2.Hash(), Type: txpool.TxEventDropped, Reason: txpool.DropBelowTip},
+	}
+	var have []txpool.TxEvent
+	for len(have) < len(want) {
+		select {
+		case batch := <-events:
+			have = append(have, batch...)
+		case <-time.After(time.Second):
+			t.Fatalf("event count mismatch: have %d, want %d", len(have), len(want))
+		}
+	}
+	// The drop order of the tip update is not deterministic, compare as a set
+	if len(have) != len(want) {
+		t.Fatalf("event count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i, ev := range have {
+		if ev.Time.IsZero() {
+			t.Errorf("event %d: missing timestamp", i)
+		}
+		have[i].Time = time.Time{}
+	}
+	for i := 0; i < len(want)-2; i++ {
+		if have[i] != want[i] {
+			t.Errorf("event %d mismatch: have %+v, want %+v", i, have[i], want[i])
+		}
+	}
+	drops := map[txpool.TxEvent]bool{have[len(have)-2]: true, have[len(have)-1]: true}
+	for _, ev := range want[len(want)-2:] {
+		if !drops[ev] {
+			t.Errorf("missing drop event %+v", ev)
+		}
+	}
+}
+
+// Tests that lifecycle subscribers not keeping up miss events instead of stalling
+// the pool.
+func TestTransactionLifecycleSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	stalled := make(chan []txpool.TxEvent) // Never read
+	sub := pool.SubscribeLifecycle(stalled)
+	defer sub.Unsubscribe()
+
+	events := make(chan []txpool.TxEvent, 1)
+	sub = pool.SubscribeLifecycle(events)
+	defer sub.Unsubscribe()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := uint64(0); i < 4; i++ {
+			if err := pool.addRemoteSync(transaction(i, 100000, key)); err != nil {
+				t.Errorf("failed to add transaction %d: %v", i, err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool stalled by lifecycle subscriber")
+	}
+	select {
+	case batch := <-events:
+		if len(batch) == 0 || batch[0].Type != txpool.TxEventReceived {
+			t.Errorf("first batch mismatch: have %+v", batch)
+		}
+	default:
+		t.Error("no lifecycle events delivered to the buffered subscriber")
+	}
+}
+
 // Tests that even if the transaction count belonging to a single account goes
 // above some threshold, as long as the transactions are executable, they are
 // accepted.
@@ -2344,6 +2634,51 @@ func TestReplacementDynamicFee(t *testing.T) {
 	}
 }
 
+// Tests that with the fee market policy enabled, replacements of transactions
+// includable at the current base fee only need to bump the effective tip.
+func TestReplacementFeeMarket(t *testing.T) {
+	t.Parallel()
+
+	for _, market := range []bool{false, true} {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		blockchain := newTestBlockChain(eip1559Config, 10000000, statedb, new(event.Feed))
+
+		config := testTxPoolConfig
+		config.FeeMarketPolicy = market
+
+		pool := New(config, blockchain)
+		pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+		defer pool.Close()
+
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+		pool.mu.Lock()
+		pool.priced.SetBaseFee(big.NewInt(500))
+		pool.mu.Unlock()
+
+		if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(1000), big.NewInt(100), key)); err != nil {
+			t.Fatalf("market %v: failed to add original transaction: %v", market, err)
+		}
+		// Bumping the tip by the price bump is not enough, the whole effective
+		// gas price must be bumped
+		if err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(1000), big.NewInt(110), key)); err != txpool.ErrReplaceUnderpriced {
+			t.Fatalf("market %v: replacement error mismatch: have %v, want %v", market, err, txpool.ErrReplaceUnderpriced)
+		}
+		// Bump the tip only, which is what the transaction pays at the base fee
+		err := pool.addRemoteSync(dynamicFeeTx(0, 100000, big.NewInt(1000), big.NewInt(160), key))
+		if market && err != nil {
+			t.Fatalf("market %v: failed to replace transaction: %v", market, err)
+		}
+		if !market && err != txpool.ErrReplaceUnderpriced {
+			t.Fatalf("market %v: replacement error mismatch: have %v, want %v", market, err, txpool.ErrReplaceUnderpriced)
+		}
+		if err := validatePoolInternals(pool); err != nil {
+			t.Fatalf("market %v: pool internal state corrupted: %v", market, err)
+		}
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestJournaling(t *testing.T)         { testJournaling(t, false) }
@@ -2460,6 +2795,151 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Close()
 }
 
+// Tests that remote transactions are persisted into the remote journal if one is
+// configured, and revalidated when reloaded.
+func TestRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject transaction into the journal
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+
+	local, _ := crypto.GenerateKey()
+	remote1, _ := crypto.GenerateKey()
+	remote2, _ := crypto.GenerateKey()
+
+	for _, key := range []*ecdsa.PrivateKey{local, remote1, remote2} {
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	// Add a local transaction, and executable and gapped remote ones
+	if err := pool.addLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	remotes := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), remote1),
+		pricedTransaction(1, 100000, big.NewInt(1), remote1),
+		pricedTransaction(3, 100000, big.NewInt(1), remote1),
+		pricedTransaction(0, 100000, big.NewInt(1), remote2),
+	}
+	for i, err := range pool.addRemotesSync(remotes) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 4 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 4, 1)
+	}
+	// Ensure the journaled transactions are bounded by the global limits
+	pool.mu.Lock()
+	pool.config.GlobalSlots, pool.config.GlobalQueue = 1, 0
+	var bounded int
+	for _, txs := range pool.remote() {
+		bounded += len(txs)
+	}
+	pool.config.GlobalSlots, pool.config.GlobalQueue = config.GlobalSlots, config.GlobalQueue
+	pool.mu.Unlock()
+
+	if bounded != 1 {
+		t.Fatalf("bounded journal size mismatch: have %d, want %d", bounded, 1)
+	}
+	// Terminate the old pool, bump a remote nonce, create a new pool and ensure
+	// the still valid remote transactions survive
+	pool.Close()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote1.PublicKey), 1)
+	blockchain = newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer, crypto.PubkeyToAddress(remote1.PublicKey), crypto.PubkeyToAddress(remote2.PublicKey)))
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	for i, tx := range remotes {
+		if have, want := pool.Has(tx.Hash()), i != 0; have != want {
+			t.Errorf("remote transaction %d presence mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if len(pool.Locals()) != 0 {
+		t.Errorf("local transactions reloaded from the remote journal")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that private transactions, treated as remote if local transaction
+// handling is disabled, are not persisted into the remote journal. Conditional
+// transactions are rejected altogether.
+func TestRemoteJournalingNoLocals(t *testing.T) {
+	t.Parallel()
+
+	journal := filepath.Join(t.TempDir(), "remotes.rlp")
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.RemoteJournal = journal
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	public, private, conditional := transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key)
+	if err := pool.addRemoteSync(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if err := pool.AddPrivate([]*types.Transaction{private}, true)[0]; err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddConditional(conditional, new(txpool.Conditional), true); !errors.Is(err, txpool.ErrConditionalNoLocals) {
+		t.Fatalf("conditional transaction error mismatch: have %v, want %v", err, txpool.ErrConditionalNoLocals)
+	}
+	pool.mu.RLock()
+	remotes := pool.remote()
+	pool.mu.RUnlock()
+
+	if txs := remotes[crypto.PubkeyToAddress(key.PublicKey)]; len(txs) != 1 || txs[0].Hash() != public.Hash() {
+		t.Fatalf("journaled remote transactions mismatch: have %v", txs)
+	}
+	// Restart the pool and ensure only the public transaction is reloaded
+	pool.Close()
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	if !pool.Has(public.Hash()) {
+		t.Errorf("public transaction not reloaded")
+	}
+	if pool.Has(private.Hash()) {
+		t.Errorf("private transaction reloaded from the remote journal")
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
@@ -295,30 +295,16 @@ func (l *list) Contains(nonce uint64) bool {
 
 // Add tries to insert a new transaction into the list, returning whether the
 // transaction was accepted, and if yes, any previous transaction it replaced.
+// If a base fee is given, replacements are checked against the effective gas prices
+// at it, otherwise against the plain fee caps and tips.
 //
 // If the new transaction is accepted into the list, the lists' cost and gas
 // thresholds are also potentially updated.
-func (l *list) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
+func (l *list) Add(tx *types.Transaction, priceBump uint64, baseFee *big.Int) (bool, *types.Transaction) {
 	// If there's an older better transaction, abort
 	old := l.txs.Get(tx.Nonce())
 	if old != nil {
-		if old.GasFeeCapCmp(tx) >= 0 || old.GasTipCapCmp(tx) >= 0 {
-			return false, nil
-		}
-		// thresholdFeeCap = oldFC  * (100 + priceBump) / 100
-		a := big.NewInt(100 + int64(priceBump))
-		aFeeCap := new(big.Int).Mul(a, old.GasFeeCap())
-		aTip := a.Mul(a, old.GasTipCap())
-
-		// thresholdTip    = oldTip * (100 + priceBump) / 100
-		b := big.NewInt(100)
-		thresholdFeeCap := aFeeCap.Div(aFeeCap, b)
-		thresholdTip := aTip.Div(aTip, b)
-
-		// We have to ensure that both the new fee cap and tip are higher than the
-		// old ones as well as checking the percentage threshold to ensure that
-		// this is accurate for low (Wei-level) gas price replacements.
-		if tx.GasFeeCapIntCmp(thresholdFeeCap) < 0 || tx.GasTipCapIntCmp(thresholdTip) < 0 {
+		if !replacementAllowed(old, tx, priceBump, baseFee) {
 			return false, nil
 		}
 		// Old is being replaced, subtract old cost
@@ -463,9 +449,11 @@ func (l *list) subTotalCost(txs []*types.Transaction) {
 // priceHeap is a heap.Interface implementation over transactions for retrieving
 // price-sorted transactions to discard when the pool fills up. If baseFee is set
 // then the heap is sorted based on the effective tip based on the given base fee.
-// If baseFee is nil then the sorting is based on gasFeeCap.
+// If baseFee is nil then the sorting is based on gasFeeCap. If market is set, the
+// heap is sorted based on the fee market priority and then the gasTipCap.
 type priceHeap struct {
-	baseFee *big.Int // heap should always be re-sorted after baseFee is changed
+	baseFee *big.Int   // heap should always be re-sorted after baseFee is changed
+	market  *feeMarket // heap should always be re-sorted after the market's base fee is changed
 	list    []*types.Transaction
 }
 
@@ -484,6 +472,20 @@ func (h *priceHeap) Less(i, j int) bool {
 }
 
 func (h *priceHeap) cmp(a, b *types.Transaction) int {
+	if h.market != nil {
+		// Compare fee market priorities if enabled, then the tips paid on
+		// inclusion, then the fee caps
+		if pa, pb := h.market.priority(a), h.market.priority(b); pa != pb {
+			if pa < pb {
+				return -1
+			}
+			return 1
+		}
+		if c := a.GasTipCapCmp(b); c != 0 {
+			return c
+		}
+		return a.GasFeeCapCmp(b)
+	}
 	if h.baseFee != nil {
 		// Compare effective tips if baseFee is specified
 		if c := a.EffectiveGasTipCmp(b, h.baseFee); c != 0 {
@@ -529,6 +531,7 @@ type pricedList struct {
 
 	all              *lookup    // Pointer to the map of all transactions
 	urgent, floating priceHeap  // Heaps of prices of all the stored **remote** transactions
+	market           *feeMarket // Fee market ordering of the heaps, nil if prices only are used
 	reheapMu         sync.Mutex // Mutex asserts that only one routine is reheaping the list
 }
 
@@ -538,11 +541,17 @@ const (
 	floatingRatio = 1
 )
 
-// newPricedList creates a new price-sorted transaction heap.
-func newPricedList(all *lookup) *pricedList {
-	return &pricedList{
+// newPricedList creates a new price-sorted transaction heap, optionally ordering
+// the transactions by their fee market priority first.
+func newPricedList(all *lookup, feeMarketPolicy bool) *pricedList {
+	l := &pricedList{
 		all: all,
 	}
+	if feeMarketPolicy {
+		l.market = newFeeMarket()
+		l.urgent.market, l.floating.market = l.market, l.market
+	}
+	return l
 }
 
 // Put inserts a new transaction into the heap.
@@ -648,6 +657,9 @@ func (l *pricedList) Reheap() {
 	defer l.reheapMu.Unlock()
 	start := time.Now()
 	l.stales.Store(0)
+	if l.market != nil {
+		l.market.reset()
+	}
 	l.urgent.list = make([]*types.Transaction, 0, l.all.RemoteCount())
 	l.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
 		l.urgent.list = append(l.urgent.list, tx)
@@ -673,5 +685,8 @@ func (l *pricedList) Reheap() {
 // necessary to call right before SetBaseFee when processing a new block.
 func (l *pricedList) SetBaseFee(baseFee *big.Int) {
 	l.urgent.baseFee = baseFee
+	if l.market != nil {
+		l.market.setBaseFee(baseFee)
+	}
 	l.Reheap()
 }
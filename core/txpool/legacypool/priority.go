@@ -0,0 +1,175 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// feeMarketHorizon is the number of blocks ahead the base fee is projected to
+// when ranking the fee caps.
+const feeMarketHorizon = 4
+
+var (
+	// log2_1_125 is used in the eviction priority calculation.
+	log2_1_125 = math.Log2(1.125)
+
+	// emptyBlockJumps is the number of base fee jumps an empty block moves the
+	// base fee by, which is slightly more than one jump down.
+	emptyBlockJumps = math.Log2(0.875) / log2_1_125
+)
+
+// feeMarket implements the fee market aware eviction ordering of the pool, the
+// same one as used by the blob pool. Instead of ranking transactions by plain
+// prices, their fee caps are ranked by their distance from the projected base fee,
+// measured in base fee jumps: the number of consecutive full (or empty) blocks
+// needed for the base fee to reach the fee cap. The base fee is projected to the
+// lowest one reachable within feeMarketHorizon blocks, so the transactions which
+// may become includable by then are not penalized.
+//
+// The distance is bucketed logarithmically, so transactions 4-7 jumps under the
+// base fee rank the same, 8-15 jumps too, and so on. Transactions at or above
+// the base fee all share the best bucket, as being even more above it does not
+// make them any more includable. Within a bucket, transactions are ranked by their
+// tip caps. The effect is that transactions likely to become includable in the
+// next few blocks are retained over the ones paying the most at the current base
+// fee: a transaction with a fee cap barely above the base fee but a high tip is
+// kept over one with a huge fee cap but a low tip.
+type feeMarket struct {
+	basefeeJumps float64                 // Base fee jumps of the projected base fee
+	feecapJumps  map[common.Hash]float64 // Cache of the fee cap jumps of the transactions
+}
+
+// newFeeMarket creates a fee market ordering with an empty fee cap cache.
+func newFeeMarket() *feeMarket {
+	return &feeMarket{feecapJumps: make(map[common.Hash]float64)}
+}
+
+// setBaseFee updates the base fee the fee caps are measured against, projecting
+// the given base fee of the next block feeMarketHorizon empty blocks ahead.
+func (m *feeMarket) setBaseFee(baseFee *big.Int) {
+	m.basefeeJumps = dynamicFeeJumps(baseFee) + feeMarketHorizon*emptyBlockJumps
+}
+
+// reset drops the cached fee cap jumps, releasing the ones of the transactions
+// already gone from the pool.
+func (m *feeMarket) reset() {
+	m.feecapJumps = make(map[common.Hash]float64)
+}
+
+// priority calculates the eviction priority of a transaction. Lower priorities
+// are evicted first.
+func (m *feeMarket) priority(tx *types.Transaction) int {
+	hash := tx.Hash()
+	jumps, ok := m.feecapJumps[hash]
+	if !ok {
+		jumps = dynamicFeeJumps(tx.GasFeeCap())
+		m.feecapJumps[hash] = jumps
+	}
+	if priority := evictionPriority(m.basefeeJumps, jumps); priority < 0 {
+		return priority
+	}
+	return 0
+}
+
+// evictionPriority calculates the eviction priority of a fee cap based on its
+// logarithmic distance from the base fee.
+func evictionPriority(basefeeJumps float64, txfeeJumps float64) int {
+	jumps := txfeeJumps - basefeeJumps
+	if int(jumps) == 0 {
+		return 0 // can't log2 0
+	}
+	if jumps < 0 {
+		return -intLog2(uint(-math.Floor(jumps)))
+	}
+	return intLog2(uint(math.Ceil(jumps)))
+}
+
+// dynamicFeeJumps calculates the log1.125(fee), namely the number of fee jumps
+// needed to reach the requested one. We only use it when calculating the jumps
+// between 2 fees, so it doesn't matter from what exact number with returns.
+//
+// The method is expensive compared to the heap operations using it, so its
+// results are cached per transaction.
+func dynamicFeeJumps(fee *big.Int) float64 {
+	if fee == nil || fee.Sign() <= 0 {
+		return 0 // can't log2 zero, should never happen outside tests, but don't choke
+	}
+	f, _ := new(big.Float).SetInt(fee).Float64()
+	return math.Log2(f) / log2_1_125
+}
+
+// intLog2 is a helper to calculate the integral part of a log2 of an unsigned
+// integer.
+func intLog2(n uint) int {
+	switch {
+	case n == 0:
+		panic("log2(0) is undefined")
+
+	case n < 2048:
+		return bits.UintSize - bits.LeadingZeros(n) - 1
+
+	default:
+		// The input is log1.125(uint256) = log2(uint256) / log2(1.125), which
+		// is at most ~257 / ~0.17 ~= ~1511.
+		panic("dynamic fee jump diffs cannot reach this")
+	}
+}
+
+// replacementAllowed checks whether a transaction may replace an older one with
+// the same nonce. By default, both its fee cap and tip must be higher by at least
+// priceBump percent. If a base fee is given and the old transaction is includable
+// at it, the fee market aware rule is used instead: the fee cap and tip may not
+// decrease, and the effective gas price at the base fee - what the transaction
+// actually pays - must be higher by priceBump percent.
+func replacementAllowed(old, tx *types.Transaction, priceBump uint64, baseFee *big.Int) bool {
+	if old.GasFeeCapCmp(tx) > 0 || old.GasTipCapCmp(tx) > 0 {
+		return false
+	}
+	if baseFee != nil {
+		if oldTip, err := old.EffectiveGasTip(baseFee); err == nil {
+			// The bump is measured against the whole effective gas price, not
+			// only the tip, so that replacing a transaction paying a negligible
+			// tip is not almost free.
+			tip, _ := tx.EffectiveGasTip(baseFee) // fee cap not lower, can't fail
+			var (
+				price    = new(big.Int).Add(tip, baseFee)
+				oldPrice = new(big.Int).Add(oldTip, baseFee)
+			)
+			return tip.Cmp(oldTip) > 0 && price.Cmp(bumped(oldPrice, priceBump)) >= 0
+		}
+	}
+	if old.GasFeeCapCmp(tx) >= 0 || old.GasTipCapCmp(tx) >= 0 {
+		return false
+	}
+	// We have to ensure that both the new fee cap and tip are higher than the
+	// old ones as well as checking the percentage threshold to ensure that
+	// this is accurate for low (Wei-level) gas price replacements.
+	return tx.GasFeeCapIntCmp(bumped(old.GasFeeCap(), priceBump)) >= 0 &&
+		tx.GasTipCapIntCmp(bumped(old.GasTipCap(), priceBump)) >= 0
+}
+
+// bumped returns the given price raised by priceBump percent.
+func bumped(price *big.Int, priceBump uint64) *big.Int {
+	threshold := new(big.Int).Mul(price, big.NewInt(100+int64(priceBump)))
+	return threshold.Div(threshold, big.NewInt(100))
+}
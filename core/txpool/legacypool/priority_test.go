@@ -0,0 +1,226 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"container/heap"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the priority fees are calculated correctly as the log2 of the fee
+// jumps needed to go from the base fee to the tx's fee cap.
+func TestPriorityCalculation(t *testing.T) {
+	tests := []struct {
+		basefee uint64
+		txfee   uint64
+		result  int
+	}{
+		{basefee: 7, txfee: 10, result: 2},                          // 3.02 jumps, 4 ceil, 2 log2
+		{basefee: 17_200_000_000, txfee: 17_200_000_000, result: 0}, // 0 jumps, special case 0 log2
+		{basefee: 9_853_941_692, txfee: 11_085_092_510, result: 0},  // 0.99 jumps, 1 ceil, 0 log2
+		{basefee: 11_544_106_391, txfee: 10_356_781_100, result: 0}, // -0.92 jumps, -1 floor, 0 log2
+		{basefee: 17_200_000_000, txfee: 7, result: -7},             // -183.57 jumps, -184 floor, -7 log2
+		{basefee: 7, txfee: 17_200_000_000, result: 7},              // 183.57 jumps, 184 ceil, 7 log2
+	}
+	for i, tt := range tests {
+		var (
+			baseJumps = dynamicFeeJumps(new(big.Int).SetUint64(tt.basefee))
+			feeJumps  = dynamicFeeJumps(new(big.Int).SetUint64(tt.txfee))
+		)
+		if prio := evictionPriority(baseJumps, feeJumps); prio != tt.result {
+			t.Errorf("test %d priority mismatch: have %d, want %d", i, prio, tt.result)
+		}
+	}
+}
+
+// Tests that replacements are checked against the plain prices by default and
+// against the effective gas prices if a base fee is given.
+func TestReplacementAllowed(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		oldFeeCap, oldTip int64
+		newFeeCap, newTip int64
+		baseFee           *big.Int
+		allowed           bool
+	}{
+		// Plain price bumps on both fields
+		{oldFeeCap: 100, oldTip: 10, newFeeCap: 110, newTip: 11, allowed: true},
+		{oldFeeCap: 100, oldTip: 10, newFeeCap: 109, newTip: 11, allowed: false},
+		{oldFeeCap: 100, oldTip: 10, newFeeCap: 110, newTip: 10, allowed: false},
+
+		// Effective gas price bumps, fee caps and tips not decreasing
+		{oldFeeCap: 1000, oldTip: 10, newFeeCap: 1000, newTip: 61, baseFee: big.NewInt(500), allowed: true},
+		{oldFeeCap: 1000, oldTip: 10, newFeeCap: 1000, newTip: 60, baseFee: big.NewInt(500), allowed: false},
+		{oldFeeCap: 1000, oldTip: 1, newFeeCap: 1000, newTip: 2, baseFee: big.NewInt(500), allowed: false}, // negligible tip doubled
+		{oldFeeCap: 1000, oldTip: 10, newFeeCap: 999, newTip: 100, baseFee: big.NewInt(500), allowed: false},
+		{oldFeeCap: 1000, oldTip: 100, newFeeCap: 1000, newTip: 109, baseFee: big.NewInt(500), allowed: false},
+		{oldFeeCap: 505, oldTip: 100, newFeeCap: 505, newTip: 200, baseFee: big.NewInt(500), allowed: false}, // capped effective tip unchanged
+		{oldFeeCap: 505, oldTip: 100, newFeeCap: 600, newTip: 100, baseFee: big.NewInt(500), allowed: true},  // effective tip raised via the fee cap
+
+		// Fall back to plain price bumps for transactions below the base fee
+		{oldFeeCap: 100, oldTip: 10, newFeeCap: 100, newTip: 20, baseFee: big.NewInt(500), allowed: false},
+		{oldFeeCap: 100, oldTip: 10, newFeeCap: 110, newTip: 11, baseFee: big.NewInt(500), allowed: true},
+	}
+	for i, tt := range tests {
+		var (
+			old = dynamicFeeTx(0, 21000, big.NewInt(tt.oldFeeCap), big.NewInt(tt.oldTip), key)
+			tx  = dynamicFeeTx(0, 21000, big.NewInt(tt.newFeeCap), big.NewInt(tt.newTip), key)
+		)
+		if allowed := replacementAllowed(old, tx, DefaultConfig.PriceBump, tt.baseFee); allowed != tt.allowed {
+			t.Errorf("test %d: replacement mismatch: have %v, want %v", i, allowed, tt.allowed)
+		}
+	}
+}
+
+// Tests that the fee market policy evicts transactions far below the projected
+// base fee first, then the ones with the lowest tips, even if they pay the most
+// at the current base fee.
+func TestFeeMarketDiscard(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		baseFee = big.NewInt(1000)
+		far     = dynamicFeeTx(0, 21000, big.NewInt(100), big.NewInt(100), key)   // far below the base fee
+		near    = dynamicFeeTx(1, 21000, big.NewInt(1010), big.NewInt(500), key)  // barely above the base fee, high tip
+		rich    = dynamicFeeTx(2, 21000, big.NewInt(100000), big.NewInt(50), key) // far above the base fee, low tip
+		under   = dynamicFeeTx(3, 21000, big.NewInt(990), big.NewInt(990), key)   // barely below the base fee
+		soon    = dynamicFeeTx(4, 21000, big.NewInt(700), big.NewInt(700), key)   // below the base fee, above the projected one
+		txs     = []*types.Transaction{far, near, rich, under, soon}
+		want    = []*types.Transaction{far, rich, near, soon, under}
+		legacy  = []*types.Transaction{far, soon, under, near, rich}
+		markets = []bool{true, false}
+		orders  = [][]*types.Transaction{want, legacy}
+	)
+	for i, market := range markets {
+		all := newLookup()
+		priced := newPricedList(all, market)
+		for _, tx := range txs {
+			all.Add(tx, false)
+			priced.Put(tx, false)
+		}
+		priced.SetBaseFee(baseFee)
+
+		// The worst transaction is moved to the floating heap, pop it first and
+		// then the rest from the urgent heap in eviction order
+		for j, tx := range orders[i] {
+			h := &priced.urgent
+			if priced.floating.Len() > 0 {
+				h = &priced.floating
+			}
+			if have := heap.Pop(h).(*types.Transaction); have != tx {
+				t.Errorf("market %v, eviction %d: have nonce %d, want nonce %d", market, j, have.Nonce(), tx.Nonce())
+			}
+		}
+	}
+}
+
+// Benchmarks how many dynamic fee jump values can be done.
+func BenchmarkDynamicFeeJumpCalculation(b *testing.B) {
+	fees := make([]*big.Int, b.N)
+	for i := 0; i < b.N; i++ {
+		fees[i] = new(big.Int).SetUint64(rand.Uint64())
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dynamicFeeJumps(fees[i])
+	}
+}
+
+// Benchmarks how many priority recalculations can be done.
+func BenchmarkPriorityCalculation(b *testing.B) {
+	// The basefee is constant for all transactions across a block, so we can
+	// assume its absolute jump count can be pre-computed.
+	basefeeJumps := dynamicFeeJumps(big.NewInt(17_200_000_000))
+
+	// The transaction's fee cap is constant across the life of the transaction,
+	// so we can pre-calculate and cache it.
+	txBasefeeJumps := make([]float64, b.N)
+	for i := 0; i < b.N; i++ {
+		txBasefeeJumps[i] = dynamicFeeJumps(new(big.Int).SetUint64(rand.Uint64()))
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		evictionPriority(basefeeJumps, txBasefeeJumps[i])
+	}
+}
+
+// Benchmarks the speed of re-heaping the priced list on a base fee change, with
+// and without the fee market policy.
+func BenchmarkReheap1000(b *testing.B)           { benchmarkReheap(b, 1000, false) }
+func BenchmarkReheap10000(b *testing.B)          { benchmarkReheap(b, 10000, false) }
+func BenchmarkReheapFeeMarket1000(b *testing.B)  { benchmarkReheap(b, 1000, true) }
+func BenchmarkReheapFeeMarket10000(b *testing.B) { benchmarkReheap(b, 10000, true) }
+
+func benchmarkReheap(b *testing.B, size int, market bool) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		all    = newLookup()
+		priced = newPricedList(all, market)
+	)
+	for i := 0; i < size; i++ {
+		tx := dynamicFeeTx(uint64(i), 21000, big.NewInt(1+rand.Int63n(100_000_000_000)), big.NewInt(1+rand.Int63n(1_000_000_000)), key)
+		all.Add(tx, false)
+		priced.Put(tx, false)
+	}
+	baseFees := make([]*big.Int, b.N)
+	for i := 0; i < b.N; i++ {
+		baseFees[i] = big.NewInt(1 + rand.Int63n(100_000_000_000))
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		priced.SetBaseFee(baseFees[i])
+	}
+}
+
+// Benchmarks the speed of discarding transactions from a full priced list, with
+// and without the fee market policy.
+func BenchmarkDiscard(b *testing.B)          { benchmarkDiscard(b, false) }
+func BenchmarkDiscardFeeMarket(b *testing.B) { benchmarkDiscard(b, true) }
+
+func benchmarkDiscard(b *testing.B, market bool) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		all    = newLookup()
+		priced = newPricedList(all, market)
+	)
+	for i := 0; i < b.N; i++ {
+		tx := dynamicFeeTx(uint64(i), 21000, big.NewInt(1+rand.Int63n(100_000_000_000)), big.NewInt(1+rand.Int63n(1_000_000_000)), key)
+		all.Add(tx, false)
+		priced.Put(tx, false)
+	}
+	priced.SetBaseFee(big.NewInt(50_000_000_000))
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drop, _ := priced.Discard(1, true)
+		for _, tx := range drop {
+			all.Remove(tx.Hash())
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// lifecycleDropMeter counts the event batches not delivered to subscribers which
+// didn't keep up.
+var lifecycleDropMeter = metrics.NewRegisteredMeter("txpool/lifecycle/dropped", nil)
+
+// txHistoryLimit is the number of transactions whose lifecycle is remembered
+// by the pool, the least recently touched ones being forgotten first.
+const txHistoryLimit = 16384
+
+// TxEventType is the kind of a transaction lifecycle event.
+type TxEventType uint8
+
+const (
+	TxEventReceived TxEventType = iota // Transaction accepted into the pool
+	TxEventPromoted                    // Transaction moved into the executable set
+	TxEventDemoted                     // Transaction moved back into the non-executable set
+	TxEventReplaced                    // Transaction replaced by another one with the same nonce
+	TxEventDropped                     // Transaction removed from the pool without inclusion
+)
+
+// String implements fmt.Stringer.
+func (t TxEventType) String() string {
+	switch t {
+	case TxEventReceived:
+		return "received"
+	case TxEventPromoted:
+		return "promoted"
+	case TxEventDemoted:
+		return "demoted"
+	case TxEventReplaced:
+		return "replaced"
+	case TxEventDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// Reasons for which a subpool may drop a transaction.
+const (
+	DropUnderpriced   = "underpriced"        // Evicted by better paying transactions of a full pool
+	DropBelowTip      = "below minimum tip"  // Paying less than a raised minimum tip
+	DropStaleNonce    = "stale nonce"        // Nonce used up, usually by the inclusion of the transaction
+	DropUnpayable     = "unpayable"          // Sender unable to pay for the transaction, or gas above the block limit
+	DropAccountLimit  = "account limit"      // Exceeding the number of slots allowed per account
+	DropPoolOverflow  = "pool overflow"      // Evicted to bring a full pool back within its limits
+	DropExpired       = "lifetime expired"   // Non-executable for longer than the configured lifetime
+	DropPrivateExpiry = "private lifetime"   // Private transaction exceeding its lifetime
+	DropNonceGap      = "nonce gap"          // Made non-executable by a drop in front of it
+	DropInvalid       = "invalid"            // Conflicting with the internal state of the pool
+	DropConditions    = "conditions not met" // Conditional transaction whose conditions no longer hold
+)
+
+// TxEvent is a change in the lifecycle of a transaction within the pool.
+type TxEvent struct {
+	Hash common.Hash // Hash of the transaction the event is about
+	Type TxEventType // Kind of lifecycle change
+	Time time.Time   // Time when the change happened
+
+	ReplacedBy common.Hash // Hash of the replacing transaction, for replacements
+	Reason     string      // Reason of the removal, for drops
+}
+
+// LifecyclePool is an optional extension of SubPool, implemented by the subpools
+// reporting the lifecycle changes of their transactions.
+type LifecyclePool interface {
+	// SubscribeLifecycle subscribes to the lifecycle events of the transactions
+	// of the pool, delivered in batches. Batches are dropped while the channel
+	// is full.
+	SubscribeLifecycle(ch chan<- []TxEvent) event.Subscription
+}
+
+// LifecycleFeed delivers batches of lifecycle events to its subscribers. Unlike
+// event.Feed, sending never blocks: the batches are dropped for the subscribers
+// whose channel is full, so slow consumers can't stall the pool. The zero value
+// is ready to use.
+type LifecycleFeed struct {
+	subs map[*lifecycleSub]struct{}
+	lock sync.Mutex
+}
+
+// Subscribe adds a channel to the feed. Subscribers are expected to buffer the
+// channel, as batches arriving while it's full are dropped.
+func (f *LifecycleFeed) Subscribe(ch chan<- []TxEvent) event.Subscription {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.subs == nil {
+		f.subs = make(map[*lifecycleSub]struct{})
+	}
+	sub := &lifecycleSub{feed: f, ch: ch, err: make(chan error)}
+	f.subs[sub] = struct{}{}
+	return sub
+}
+
+// Send delivers a batch of events to the subscribers able to receive it without
+// blocking, returning their number.
+func (f *LifecycleFeed) Send(batch []TxEvent) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var sent int
+	for sub := range f.subs {
+		select {
+		case sub.ch <- batch:
+			sent++
+		default:
+			lifecycleDropMeter.Mark(1)
+		}
+	}
+	return sent
+}
+
+// lifecycleSub is a subscription to a LifecycleFeed.
+type lifecycleSub struct {
+	feed *LifecycleFeed
+	ch   chan<- []TxEvent
+	err  chan error
+	once sync.Once
+}
+
+// Unsubscribe implements event.Subscription, removing the channel from the feed.
+func (s *lifecycleSub) Unsubscribe() {
+	s.once.Do(func() {
+		s.feed.lock.Lock()
+		delete(s.feed.subs, s)
+		s.feed.lock.Unlock()
+
+		close(s.err)
+	})
+}
+
+// Err implements event.Subscription. The channel is closed on Unsubscribe.
+func (s *lifecycleSub) Err() <-chan error {
+	return s.err
+}
+
+// TxHistory is the recorded lifecycle of a transaction. Zero timestamps mark the
+// changes which did not happen to the transaction. The promotion and demotion
+// times are the ones of the last such change, as the transaction may go back
+// and forth between the executable and non-executable sets.
+type TxHistory struct {
+	Hash     common.Hash
+	Received time.Time
+	Promoted time.Time
+	Demoted  time.Time
+	Replaced time.Time
+	Dropped  time.Time
+
+	ReplacedBy common.Hash // Hash of the replacing transaction, if replaced
+	DropReason string      // Reason of the removal, if dropped
+}
+
+// txHistory is a bounded record of the lifecycle of recently seen transactions.
+type txHistory struct {
+	txs  lru.BasicLRU[common.Hash, *TxHistory]
+	lock sync.Mutex
+}
+
+// newTxHistory creates a lifecycle record holding at most limit transactions.
+func newTxHistory(limit int) *txHistory {
+	return &txHistory{txs: lru.NewBasicLRU[common.Hash, *TxHistory](limit)}
+}
+
+// record applies a batch of lifecycle events to the transaction histories.
+func (h *txHistory) record(events []TxEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, ev := range events {
+		entry, ok := h.txs.Get(ev.Hash)
+		if !ok {
+			entry = &TxHistory{Hash: ev.Hash}
+			h.txs.Add(ev.Hash, entry)
+		}
+		switch ev.Type {
+		case TxEventReceived:
+			// A transaction may come back after having been dropped, e.g. when
+			// resubmitted or reorged back in, start a fresh removal record
+			entry.Received = ev.Time
+			entry.Dropped, entry.DropReason = time.Time{}, ""
+		case TxEventPromoted:
+			entry.Promoted = ev.Time
+		case TxEventDemoted:
+			entry.Demoted = ev.Time
+		case TxEventReplaced:
+			entry.Replaced, entry.ReplacedBy = ev.Time, ev.ReplacedBy
+		case TxEventDropped:
+			entry.Dropped, entry.DropReason = ev.Time, ev.Reason
+		}
+	}
+}
+
+// get retrieves a copy of the recorded lifecycle of a transaction.
+func (h *txHistory) get(hash common.Hash) *TxHistory {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	entry, ok := h.txs.Peek(hash)
+	if !ok {
+		return nil
+	}
+	cpy := *entry
+	return &cpy
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 )
 
@@ -40,6 +41,8 @@ type LazyTransaction struct {
 
 	Gas     uint64 // Amount of gas required by the transaction
 	BlobGas uint64 // Amount of blob gas required by the transaction
+
+	Conditional *Conditional // Conditions of the inclusion, nil for unconditional transactions
 }
 
 // Resolve retrieves the full transaction belonging to a lazy handle if it is still
@@ -144,3 +147,89 @@ type SubPool interface {
 	// identified by their hashes.
 	Status(hash common.Hash) TxStatus
 }
+
+// PrivatePool is an optional extension of SubPool, implemented by the subpools
+// able to hold private transactions: locally submitted ones which are available
+// for block building, but never announced to the network.
+type PrivatePool interface {
+	// AddPrivate enqueues a batch of local transactions into the pool, flagging
+	// them as private.
+	AddPrivate(txs []*types.Transaction, sync bool) []error
+
+	// IsPrivate returns whether the transaction with the given hash is a private
+	// one, which must not be announced to the network.
+	IsPrivate(hash common.Hash) bool
+}
+
+// ConditionalPool is an optional extension of SubPool, implemented by the subpools
+// able to hold conditional transactions: locally submitted ones which may only be
+// included while their conditions hold. Like private transactions, they are never
+// announced to the network, as other nodes could not enforce the conditions.
+type ConditionalPool interface {
+	// AddConditional enqueues a local transaction into the pool, to be included
+	// only while the given conditions hold.
+	AddConditional(tx *types.Transaction, cond *Conditional, sync bool) error
+
+	// DropConditional removes a conditional transaction from the pool, after its
+	// conditions were found to be no longer met.
+	DropConditional(hash common.Hash)
+}
+
+// Bundle is a list of transactions to be included atomically, in order, into a
+// specific block: either all of them make it into the block or none of them.
+// None of the transactions may revert, unless explicitly allowed to.
+type Bundle struct {
+	Txs          types.Transactions // Transactions to include, in order
+	BlockNumber  uint64             // Number of the block to include the bundle into
+	MinTimestamp uint64             // Minimum timestamp of the including block (0 = no limit)
+	MaxTimestamp uint64             // Maximum timestamp of the including block (0 = no limit)
+
+	RevertingTxHashes []common.Hash // Transactions allowed to revert without invalidating the bundle
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// CanRevert returns whether the transaction with the given hash is allowed to
+// revert without invalidating the bundle.
+func (b *Bundle) CanRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Includable returns whether the bundle may be included into the block with the
+// given number and timestamp.
+func (b *Bundle) Includable(number uint64, time uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && time < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && time > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// BundlePool is an optional extension of SubPool, implemented by the subpools
+// able to hold bundles of transactions to be included atomically.
+type BundlePool interface {
+	// AddBundle validates a bundle and enqueues it into the pool for inclusion
+	// into its target block.
+	AddBundle(bundle *Bundle) error
+
+	// Bundles retrieves the bundles includable into the block with the given
+	// number and timestamp, sorted by decreasing priority.
+	Bundles(number uint64, time uint64) []*Bundle
+}
@@ -70,6 +70,11 @@ type TxPool struct {
 	reservations map[common.Address]SubPool // Map with the account to pool reservations
 	reserveLock  sync.Mutex                 // Lock protecting the account reservations
 
+	history       *txHistory         // Lifecycle record of the recently seen transactions
+	lifecycleFeed LifecycleFeed      // Feed re-broadcasting the lifecycle events of the subpools
+	lifecycleSub  event.Subscription // Subscription to the lifecycle events of the subpools
+	lifecycleDone chan struct{}      // Channel closed when the lifecycle recorder terminates
+
 	subs event.SubscriptionScope // Subscription scope to unsubscribe all on shutdown
 	quit chan chan error         // Quit channel to tear down the head updater
 	term chan struct{}           // Termination channel to detect a closed pool
@@ -86,11 +91,13 @@ func New(gasTip *big.Int, chain BlockChain, subpools []SubPool) (*TxPool, error)
 	head := chain.CurrentBlock()
 
 	pool := &TxPool{
-		subpools:     subpools,
-		reservations: make(map[common.Address]SubPool),
-		quit:         make(chan chan error),
-		term:         make(chan struct{}),
-		sync:         make(chan chan error),
+		subpools:      subpools,
+		reservations:  make(map[common.Address]SubPool),
+		history:       newTxHistory(txHistoryLimit),
+		lifecycleDone: make(chan struct{}),
+		quit:          make(chan chan error),
+		term:          make(chan struct{}),
+		sync:          make(chan chan error),
 	}
 	for i, subpool := range subpools {
 		if err := subpool.Init(gasTip, head, pool.reserver(i, subpool)); err != nil {
@@ -100,7 +107,20 @@ func New(gasTip *big.Int, chain BlockChain, subpools []SubPool) (*TxPool, error)
 			return nil, err
 		}
 	}
+	// Subscribe to the lifecycle events of the subpools reporting them
+	var (
+		events = make(chan []TxEvent, 256)
+		subs   []event.Subscription
+	)
+	for _, subpool := range subpools {
+		if lifecycle, ok := subpool.(LifecyclePool); ok {
+			subs = append(subs, lifecycle.SubscribeLifecycle(events))
+		}
+	}
+	pool.lifecycleSub = event.JoinSubscriptions(subs...)
+
 	go pool.loop(head, chain)
+	go pool.recordLifecycle(events)
 	return pool, nil
 }
 
@@ -160,6 +180,10 @@ func (p *TxPool) Close() error {
 	if err := <-errc; err != nil {
 		errs = append(errs, err)
 	}
+	// Stop recording transaction lifecycles
+	p.lifecycleSub.Unsubscribe()
+	<-p.lifecycleDone
+
 	// Terminate each subpool
 	for _, subpool := range p.subpools {
 		if err := subpool.Close(); err != nil {
@@ -276,6 +300,23 @@ func (p *TxPool) loop(head *types.Header, chain BlockChain) {
 	errc <- nil
 }
 
+// recordLifecycle records the lifecycle events of the subpools into the history
+// of the pool and re-broadcasts them to the subscribers of the pool.
+func (p *TxPool) recordLifecycle(events chan []TxEvent) {
+	defer close(p.lifecycleDone)
+
+	for {
+		select {
+		case batch := <-events:
+			p.history.record(batch)
+			p.lifecycleFeed.Send(batch)
+
+		case <-p.lifecycleSub.Err():
+			return
+		}
+	}
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (p *TxPool) SetGasTip(tip *big.Int) {
@@ -351,6 +392,91 @@ func (p *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
 	return errs
 }
 
+// AddPrivate enqueues a batch of local transactions into the pool, flagging them
+// as private: they are made available for block building, but they are never
+// announced to the network. Transactions handled by subpools unable to hold
+// private transactions are rejected.
+func (p *TxPool) AddPrivate(txs []*types.Transaction, sync bool) []error {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		// Find the subpool accepting the transaction and ensure it supports
+		// private submissions
+		errs[i] = core.ErrTxTypeNotSupported
+		for _, subpool := range p.subpools {
+			if !subpool.Filter(tx) {
+				continue
+			}
+			if pool, ok := subpool.(PrivatePool); ok {
+				errs[i] = pool.AddPrivate([]*types.Transaction{tx}, sync)[0]
+			} else {
+				errs[i] = ErrPrivateNotSupported
+			}
+			break
+		}
+	}
+	return errs
+}
+
+// IsPrivate returns whether the transaction with the given hash is a private
+// one, which must not be announced to the network.
+func (p *TxPool) IsPrivate(hash common.Hash) bool {
+	for _, subpool := range p.subpools {
+		if pool, ok := subpool.(PrivatePool); ok && pool.IsPrivate(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddConditional enqueues a local transaction into the pool, to be included only
+// while the given conditions hold. Transactions handled by subpools unable to hold
+// conditional transactions are rejected.
+func (p *TxPool) AddConditional(tx *types.Transaction, cond *Conditional, sync bool) error {
+	for _, subpool := range p.subpools {
+		if !subpool.Filter(tx) {
+			continue
+		}
+		if pool, ok := subpool.(ConditionalPool); ok {
+			return pool.AddConditional(tx, cond, sync)
+		}
+		return ErrConditionalNotSupported
+	}
+	return core.ErrTxTypeNotSupported
+}
+
+// DropConditional removes a conditional transaction from the pool, after its
+// conditions were found to be no longer met.
+func (p *TxPool) DropConditional(hash common.Hash) {
+	for _, subpool := range p.subpools {
+		if pool, ok := subpool.(ConditionalPool); ok {
+			pool.DropConditional(hash)
+		}
+	}
+}
+
+// AddBundle enqueues a bundle of transactions into the first subpool able to
+// hold bundles, for atomic inclusion into its target block.
+func (p *TxPool) AddBundle(bundle *Bundle) error {
+	for _, subpool := range p.subpools {
+		if pool, ok := subpool.(BundlePool); ok {
+			return pool.AddBundle(bundle)
+		}
+	}
+	return ErrBundlesNotSupported
+}
+
+// Bundles retrieves the bundles includable into the block with the given number
+// and timestamp, sorted by decreasing priority.
+func (p *TxPool) Bundles(number uint64, time uint64) []*Bundle {
+	var bundles []*Bundle
+	for _, subpool := range p.subpools {
+		if pool, ok := subpool.(BundlePool); ok {
+			bundles = append(bundles, pool.Bundles(number, time)...)
+		}
+	}
+	return bundles
+}
+
 // Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce.
 func (p *TxPool) Pending(enforceTips bool) map[common.Address][]*LazyTransaction {
@@ -373,6 +499,19 @@ func (p *TxPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool)
 	return p.subs.Track(event.JoinSubscriptions(subs...))
 }
 
+// SubscribeLifecycle registers a subscription for the lifecycle events of the
+// transactions of the pool, delivered in batches. Batches are dropped while the
+// channel is full, so slow subscribers miss events instead of stalling the pool.
+func (p *TxPool) SubscribeLifecycle(ch chan<- []TxEvent) event.Subscription {
+	return p.subs.Track(p.lifecycleFeed.Subscribe(ch))
+}
+
+// History retrieves the recorded lifecycle of a recently seen transaction, or
+// nil if the pool does not remember the transaction.
+func (p *TxPool) History(hash common.Hash) *TxHistory {
+	return p.history.get(hash)
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *TxPool) Nonce(addr common.Address) uint64 {
@@ -51,6 +51,9 @@ type Contract struct {
 	jumpdests map[common.Hash]bitvec // Aggregated result of JUMPDEST analysis.
 	analysis  bitvec                 // Locally cached result of JUMPDEST analysis
 
+	supercodes map[common.Hash]superCode // Aggregated result of superinstruction analysis.
+	supercode  superCode                 // Locally cached result of superinstruction analysis
+
 	Code     []byte
 	CodeHash common.Hash
 	CodeAddr *common.Address
@@ -65,10 +68,12 @@ func NewContract(caller ContractRef, object ContractRef, value *uint256.Int, gas
 	c := &Contract{CallerAddress: caller.Address(), caller: caller, self: object}
 
 	if parent, ok := caller.(*Contract); ok {
-		// Reuse JUMPDEST analysis from parent context if available.
+		// Reuse JUMPDEST and superinstruction analysis from parent context if available.
 		c.jumpdests = parent.jumpdests
+		c.supercodes = parent.supercodes
 	} else {
 		c.jumpdests = make(map[common.Hash]bitvec)
+		c.supercodes = make(map[common.Hash]superCode)
 	}
 
 	// Gas should be a pointer so it can safely be reduced through the run
@@ -127,6 +132,29 @@ func (c *Contract) isCode(udest uint64) bool {
 	return c.analysis.codeSegment(udest)
 }
 
+// superCode returns the superinstruction analysis of the contract code, running
+// it if it's not yet available. Similarly to the JUMPDEST analysis, the result
+// is shared with the parent context for regular contracts, and kept locally
+// for initcode.
+func (c *Contract) superCode(table *JumpTable) superCode {
+	if c.supercode != nil {
+		return c.supercode
+	}
+	if c.CodeHash != (common.Hash{}) {
+		supercode, exist := c.supercodes[c.CodeHash]
+		if !exist {
+			c.isCode(0) // ensure the JUMPDEST analysis is available
+			supercode = superAnalysis(c.Code, c.analysis, table)
+			c.supercodes[c.CodeHash] = supercode
+		}
+		c.supercode = supercode
+		return supercode
+	}
+	c.isCode(0)
+	c.supercode = superAnalysis(c.Code, c.analysis, table)
+	return c.supercode
+}
+
 // AsDelegate sets the contract to be a delegate call and returns the current
 // contract (for chaining calls)
 func (c *Contract) AsDelegate() *Contract {
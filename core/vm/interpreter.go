@@ -29,6 +29,7 @@ type Config struct {
 	NoBaseFee               bool      // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
 	EnablePreimageRecording bool      // Enables recording of SHA3/keccak preimages
 	ExtraEips               []int     // Additional EIPS that are to be enabled
+	SuperInstructions       bool      // Enables fusing common opcode sequences into superinstructions
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -145,6 +146,7 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		logged  bool   // deferred EVMLogger should ignore already logged steps
 		res     []byte // result of the opcode execution function
 		debug   = in.evm.Config.Tracer != nil
+		fused   superCode // superinstruction analysis, nil if disabled
 	)
 	// Don't move this deferred function, it's placed before the capturestate-deferred method,
 	// so that it gets executed _after_: the capturestate needs the stacks before
@@ -154,6 +156,11 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	// Superinstructions are only used when tracing is disabled, since the fused
+	// sequences do not report the individual steps to the tracer.
+	if in.evm.Config.SuperInstructions && !debug {
+		fused = contract.superCode(in.table)
+	}
 	if debug {
 		defer func() {
 			if err != nil {
@@ -174,6 +181,17 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			// Capture pre-execution values for tracing.
 			logged, pcCopy, gasCopy = false, pc, contract.Gas
 		}
+		// Execute a fused instruction sequence if one starts here and its
+		// aggregated preconditions hold, otherwise step a single opcode.
+		if pc < uint64(len(fused)) && fused[pc].kind != superNone {
+			var done bool
+			if done, res, err = in.runSuper(&fused[pc], &pc, callContext); done {
+				if err != nil {
+					break
+				}
+				continue
+			}
+		}
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
@@ -913,3 +913,26 @@ func BenchmarkTracerStepVsCallFrame(b *testing.B) {
 	benchmarkNonModifyingCode(10000000, code, "tracer-step-10M", stepTracer, b)
 	benchmarkNonModifyingCode(10000000, code, "tracer-call-frame-10M", callFrameTracer, b)
 }
+
+// BenchmarkSuperInstructions compares the interpreter loop with and without
+// superinstruction fusion on a tight arithmetic loop.
+func BenchmarkSuperInstructions(b *testing.B) {
+	// Counts down from 0xffff, shuffling the stack around on every iteration
+	code := common.Hex2Bytes("61ffff5b80818190505050600190038060035700")
+
+	for _, fused := range []bool{false, true} {
+		b.Run(fmt.Sprintf("fused=%v", fused), func(b *testing.B) {
+			cfg := &Config{EVMConfig: vm.Config{SuperInstructions: fused}, GasLimit: 100_000_000}
+			setDefaults(cfg)
+			cfg.State, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := Execute(code, nil, cfg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// maxSuperBlockOps is the maximum number of opcodes fused into a single static
+// block. It bounds the analysis cost and keeps the per-block counters small.
+const maxSuperBlockOps = 64
+
+// superKind is the type of a fused instruction sequence.
+type superKind uint8
+
+const (
+	superNone      superKind = iota // No fusion starts at this position
+	superBlock                      // Run of opcodes with only static gas
+	superPushJump                   // PUSHn followed by JUMP to a valid destination
+	superPushJumpi                  // PUSHn followed by JUMPI to a valid destination
+)
+
+// superOp is the precomputed description of a fused instruction sequence
+// starting at a given program counter.
+type superOp struct {
+	kind     superKind
+	ops      uint8  // Number of opcodes in the sequence
+	minStack uint16 // Minimum stack height needed to execute the whole sequence
+	maxStack uint16 // Maximum stack height allowed to execute the whole sequence
+	gas      uint32 // Total static gas of the sequence
+	dest     uint32 // Jump destination for superPushJump(i)
+}
+
+// superCode holds the fused instruction analysis of a piece of code, indexed by
+// the program counter at which each sequence starts.
+type superCode []superOp
+
+// superAnalysis fuses common instruction sequences of the given code into
+// superinstructions, using the gas and stack requirements of the jump table.
+//
+// The analysis only ever fuses sequences whose combined execution is
+// indistinguishable from running the opcodes one by one, provided that the
+// interpreter verifies the aggregated stack and gas preconditions before
+// entering one. If those do not hold, the interpreter falls back to the
+// regular single-step loop, which produces the exact same errors.
+func superAnalysis(code []byte, jumpdests bitvec, table *JumpTable) superCode {
+	var (
+		fused = make(superCode, len(code))
+		start = -1 // Start of the block currently being built, -1 if none
+
+		ops    int    // Number of opcodes in the current block
+		gas    uint64 // Accumulated static gas of the current block
+		height int    // Stack height change relative to the block start
+		lower  int    // Minimum stack height required at the block start
+		upper  int    // Maximum stack height allowed at the block start
+	)
+	flush := func() {
+		if start >= 0 && ops > 1 {
+			fused[start] = superOp{
+				kind:     superBlock,
+				ops:      uint8(ops),
+				minStack: uint16(lower),
+				maxStack: uint16(upper),
+				gas:      uint32(gas),
+			}
+		}
+		start, ops, gas, height, lower, upper = -1, 0, 0, 0, 0, math.MaxInt
+	}
+	flush()
+
+	for pc := 0; pc < len(code); {
+		op := OpCode(code[pc])
+		size := 1
+		if op.IsPush() {
+			size += int(op - PUSH0)
+		}
+		// Fuse PUSHn+JUMP(I) if the destination is statically known to be valid
+		if op.IsPush() && op != PUSH0 && pc+size < len(code) {
+			if next := OpCode(code[pc+size]); next == JUMP || next == JUMPI {
+				flush()
+				if dest, ok := staticJumpdest(code, jumpdests, pc, size); ok {
+					fused[pc] = newPushJump(table, op, next, dest)
+				}
+				pc += size
+				continue
+			}
+		}
+		operation := table[op]
+		if !fusable(op, operation) {
+			flush()
+			pc += size
+			continue
+		}
+		// A JUMPDEST may be entered from anywhere, so it always starts a new block
+		if op == JUMPDEST || ops == maxSuperBlockOps {
+			flush()
+		}
+		if start < 0 {
+			start = pc
+		}
+		if need := operation.minStack - height; need > lower {
+			lower = need
+		}
+		if limit := operation.maxStack - height; limit < upper {
+			upper = limit
+		}
+		height += int(params.StackLimit) - operation.maxStack
+		gas += operation.constantGas
+		ops++
+
+		pc += size
+	}
+	flush()
+	return fused
+}
+
+// fusable returns whether an opcode may be executed as part of a static block:
+// its gas must be fully static and it must neither alter the control flow nor
+// observe the gas counter, which is charged upfront for the entire block.
+func fusable(op OpCode, operation *operation) bool {
+	if operation.dynamicGas != nil || !operation.HasCost() {
+		return false
+	}
+	switch op {
+	case JUMP, JUMPI, GAS:
+		return false
+	}
+	return true
+}
+
+// staticJumpdest returns the destination of a PUSHn at pc if it is a valid
+// JUMPDEST within the code.
+func staticJumpdest(code []byte, jumpdests bitvec, pc int, size int) (uint32, bool) {
+	dest := new(uint256.Int).SetBytes(code[pc+1 : pc+size])
+	udest, overflow := dest.Uint64WithOverflow()
+	if overflow || udest >= uint64(len(code)) || udest > math.MaxUint32 {
+		return 0, false
+	}
+	if OpCode(code[udest]) != JUMPDEST || !jumpdests.codeSegment(udest) {
+		return 0, false
+	}
+	return uint32(udest), true
+}
+
+// newPushJump creates the fused description of a PUSHn+JUMP(I) pair.
+func newPushJump(table *JumpTable, push, jump OpCode, dest uint32) superOp {
+	var (
+		pushOp = table[push]
+		jumpOp = table[jump]
+		kind   = superPushJump
+	)
+	if jump == JUMPI {
+		kind = superPushJumpi
+	}
+	lower := jumpOp.minStack - 1
+	if pushOp.minStack > lower {
+		lower = pushOp.minStack
+	}
+	upper := pushOp.maxStack
+	if limit := jumpOp.maxStack - 1; limit < upper {
+		upper = limit
+	}
+	return superOp{
+		kind:     kind,
+		ops:      2,
+		minStack: uint16(lower),
+		maxStack: uint16(upper),
+		gas:      uint32(pushOp.constantGas + jumpOp.constantGas),
+		dest:     dest,
+	}
+}
+
+// runSuper attempts to execute the superinstruction starting at pc. It returns
+// whether it was executed; if not, the caller must fall back to executing the
+// next opcode on its own.
+func (in *EVMInterpreter) runSuper(sop *superOp, pc *uint64, scope *ScopeContext) (bool, []byte, error) {
+	var (
+		stack    = scope.Stack
+		contract = scope.Contract
+	)
+	if sLen := stack.len(); sLen < int(sop.minStack) || sLen > int(sop.maxStack) {
+		return false, nil, nil
+	}
+	if contract.Gas < uint64(sop.gas) {
+		return false, nil, nil
+	}
+	switch sop.kind {
+	case superPushJump:
+		if in.evm.abort.Load() {
+			return false, nil, nil
+		}
+		contract.Gas -= uint64(sop.gas)
+		*pc = uint64(sop.dest)
+		return true, nil, nil
+
+	case superPushJumpi:
+		if in.evm.abort.Load() {
+			return false, nil, nil
+		}
+		contract.Gas -= uint64(sop.gas)
+		if cond := stack.pop(); !cond.IsZero() {
+			*pc = uint64(sop.dest)
+		} else {
+			*pc += uint64(contract.Code[*pc]-byte(PUSH0)) + 2
+		}
+		return true, nil, nil
+
+	case superBlock:
+		contract.Gas -= uint64(sop.gas)
+		for i := uint8(0); i < sop.ops; i++ {
+			res, err := in.table[contract.Code[*pc]].execute(pc, in, scope)
+			if err != nil {
+				return true, res, err
+			}
+			*pc++
+		}
+		return true, nil, nil
+	}
+	return false, nil, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var superInstructionTests = []string{
+	// countdown loop: push(16) jumpdest push(1) swap1 sub dup1 push(2) jumpi stop
+	"60105b600190038060025700",
+	// static block with DUP/SWAP shuffling, stored to memory and returned
+	"6001600260038182919091019060005260206000f3",
+	// jump to an invalid destination
+	"600356",
+	// jumpi to an invalid destination with a zero condition, then stack underflow
+	"60006003575050",
+	// stack underflow in the middle of a static block
+	"600101",
+	// push past the end of code into a jump
+	"5b6000",
+	// arithmetic with a conditional jump over an invalid opcode
+	"6001600a5760fe5b600160020260005260206000f3",
+}
+
+// Tests that enabling superinstructions does not change the result, error or
+// gas usage of any execution, including those running out of gas midway.
+func TestSuperInstructions(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+	vmctx := BlockContext{
+		Transfer: func(StateDB, common.Address, common.Address, *uint256.Int) {},
+	}
+	run := func(code []byte, gas uint64, fused bool) ([]byte, uint64, error) {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, code)
+		statedb.Finalise(true)
+
+		evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{SuperInstructions: fused})
+		return evm.Call(AccountRef(common.Address{}), address, nil, gas, new(uint256.Int))
+	}
+	for i, tt := range superInstructionTests {
+		code := common.Hex2Bytes(tt)
+		for gas := uint64(0); gas < 400; gas++ {
+			wantRet, wantGas, wantErr := run(code, gas, false)
+			haveRet, haveGas, haveErr := run(code, gas, true)
+
+			if !bytes.Equal(haveRet, wantRet) {
+				t.Errorf("test %d, gas %d: return mismatch: have %x, want %x", i, gas, haveRet, wantRet)
+			}
+			if haveGas != wantGas {
+				t.Errorf("test %d, gas %d: leftover gas mismatch: have %d, want %d", i, gas, haveGas, wantGas)
+			}
+			if (haveErr == nil) != (wantErr == nil) || (haveErr != nil && haveErr.Error() != wantErr.Error() && !errors.Is(haveErr, wantErr)) {
+				t.Errorf("test %d, gas %d: error mismatch: have %v, want %v", i, gas, haveErr, wantErr)
+			}
+		}
+	}
+}
+
+func TestSuperAnalysis(t *testing.T) {
+	// push(4) jump invalid jumpdest dup1 dup1 swap1 pop pop pop pop stop
+	code := common.Hex2Bytes("600456fe5b8080905050505000")
+	fused := superAnalysis(code, codeBitmap(code), &cancunInstructionSet)
+
+	if have := fused[0]; have.kind != superPushJump || have.dest != 4 || have.gas != 11 {
+		t.Errorf("push+jump not fused: %+v", have)
+	}
+	if have := fused[4]; have.kind != superBlock || have.ops != 8 || have.gas != 1+3*3+4*2 || have.minStack != 2 {
+		t.Errorf("static block not fused: %+v", have)
+	}
+}
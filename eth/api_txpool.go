@@ -0,0 +1,167 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TxLifecycleAPI provides an API to introspect the lifecycle of the transactions
+// going through the transaction pool: when they were received, promoted,
+// demoted, replaced or dropped, and why.
+type TxLifecycleAPI struct {
+	e *Ethereum
+}
+
+// NewTxLifecycleAPI creates a new TxLifecycleAPI instance.
+func NewTxLifecycleAPI(e *Ethereum) *TxLifecycleAPI {
+	return &TxLifecycleAPI{e}
+}
+
+// TxStatusResult is the lifecycle of a transaction within the pool. Timestamps
+// are in milliseconds since the unix epoch and are omitted for the changes which
+// did not happen to the transaction.
+type TxStatusResult struct {
+	Hash       common.Hash     `json:"hash"`
+	Status     string          `json:"status"`
+	Received   *hexutil.Uint64 `json:"received,omitempty"`
+	Promoted   *hexutil.Uint64 `json:"promoted,omitempty"`
+	Demoted    *hexutil.Uint64 `json:"demoted,omitempty"`
+	Replaced   *hexutil.Uint64 `json:"replaced,omitempty"`
+	ReplacedBy *common.Hash    `json:"replacedBy,omitempty"`
+	Dropped    *hexutil.Uint64 `json:"dropped,omitempty"`
+	DropReason string          `json:"dropReason,omitempty"`
+}
+
+// GetTransactionStatus returns the current status of a transaction along with
+// its recorded lifecycle within the pool. The pool only remembers a bounded
+// number of recently seen transactions, null is returned for unknown ones.
+func (api *TxLifecycleAPI) GetTransactionStatus(hash common.Hash) (*TxStatusResult, error) {
+	res := &TxStatusResult{Hash: hash}
+	switch api.e.txPool.Status(hash) {
+	case txpool.TxStatusPending:
+		res.Status = "pending"
+	case txpool.TxStatusQueued:
+		res.Status = "queued"
+	default:
+		res.Status = "unknown"
+		if lookup, _, err := api.e.blockchain.GetTransactionLookup(hash); err != nil {
+			return nil, err
+		} else if lookup != nil {
+			res.Status = "included"
+		}
+	}
+	history := api.e.txPool.History(hash)
+	if history == nil {
+		if res.Status == "unknown" {
+			return nil, nil
+		}
+		return res, nil
+	}
+	res.Received = unixMilli(history.Received)
+	res.Promoted = unixMilli(history.Promoted)
+	res.Demoted = unixMilli(history.Demoted)
+	res.Replaced = unixMilli(history.Replaced)
+	res.Dropped = unixMilli(history.Dropped)
+	res.DropReason = history.DropReason
+	if history.ReplacedBy != (common.Hash{}) {
+		res.ReplacedBy = &history.ReplacedBy
+	}
+	return res, nil
+}
+
+// TxEventFilter restricts the lifecycle events delivered to a subscription.
+type TxEventFilter struct {
+	Hashes []common.Hash `json:"hashes"` // Transactions to report events about, all if empty
+}
+
+// TxEventResult is a lifecycle event of a transaction delivered to subscribers.
+type TxEventResult struct {
+	Hash       common.Hash    `json:"hash"`
+	Type       string         `json:"type"`
+	Time       hexutil.Uint64 `json:"time"` // Milliseconds since the unix epoch
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+}
+
+// Events creates a subscription delivering the lifecycle events of the
+// transactions of the pool, optionally restricted to the given transactions.
+func (api *TxLifecycleAPI) Events(ctx context.Context, filter *TxEventFilter) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var hashes map[common.Hash]struct{}
+	if filter != nil && len(filter.Hashes) > 0 {
+		hashes = make(map[common.Hash]struct{}, len(filter.Hashes))
+		for _, hash := range filter.Hashes {
+			hashes[hash] = struct{}{}
+		}
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan []txpool.TxEvent, 128)
+		eventsSub := api.e.txPool.SubscribeLifecycle(events)
+		defer eventsSub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-events:
+				for _, ev := range batch {
+					if hashes != nil {
+						if _, ok := hashes[ev.Hash]; !ok {
+							continue
+						}
+					}
+					res := &TxEventResult{
+						Hash:   ev.Hash,
+						Type:   ev.Type.String(),
+						Time:   hexutil.Uint64(ev.Time.UnixMilli()),
+						Reason: ev.Reason,
+					}
+					if ev.ReplacedBy != (common.Hash{}) {
+						replacedBy := ev.ReplacedBy
+						res.ReplacedBy = &replacedBy
+					}
+					notifier.Notify(rpcSub.ID, res)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// unixMilli converts a timestamp into milliseconds since the unix epoch, or nil
+// if the timestamp is not set.
+func unixMilli(t time.Time) *hexutil.Uint64 {
+	if t.IsZero() {
+		return nil
+	}
+	ms := hexutil.Uint64(t.UnixMilli())
+	return &ms
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/authority"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
@@ -36,12 +37,14 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/protocols/commit"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -72,6 +75,7 @@ type Ethereum struct {
 
 	blockchain         *core.BlockChain
 	handler            *handler
+	commits            *commit.Handler // Commit gossip handler, only set on authority chains
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
 	merger             *consensus.Merger
@@ -203,6 +207,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			Preimages:           config.Preimages,
 			StateHistory:        config.StateHistory,
 			StateScheme:         scheme,
+			StateWrapper:        config.StateWrapper,
 		}
 	)
 	// Override the chain config with provided settings.
@@ -222,14 +227,25 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.BlobPool.Datadir != "" {
 		config.BlobPool.Datadir = stack.ResolvePath(config.BlobPool.Datadir)
 	}
+	if len(config.BlobPool.Datadirs) > 0 {
+		dirs := make([]string, len(config.BlobPool.Datadirs))
+		for i, dir := range config.BlobPool.Datadirs {
+			dirs[i] = stack.ResolvePath(dir)
+		}
+		config.BlobPool.Datadirs = dirs
+	}
 	blobPool := blobpool.New(config.BlobPool, eth.blockchain)
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
 	legacyPool := legacypool.New(config.TxPool, eth.blockchain)
+	bundlePool := bundlepool.New(config.BundlePool, eth.blockchain)
 
-	eth.txPool, err = txpool.New(new(big.Int).SetUint64(config.TxPool.PriceLimit), eth.blockchain, []txpool.SubPool{legacyPool, blobPool})
+	eth.txPool, err = txpool.New(new(big.Int).SetUint64(config.TxPool.PriceLimit), eth.blockchain, []txpool.SubPool{legacyPool, blobPool, bundlePool})
 	if err != nil {
 		return nil, err
 	}
@@ -249,6 +265,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		return nil, err
 	}
 
+	if auth := authorityEngine(eth.engine); auth != nil {
+		eth.commits = commit.NewHandler(&commitHandler{chain: eth.blockchain, engine: auth})
+	}
 	eth.miner = miner.New(eth, &config.Miner, eth.blockchain.Config(), eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
@@ -317,6 +336,12 @@ func (s *Ethereum) APIs() []rpc.API {
 		{
 			Namespace: "eth",
 			Service:   NewEthereumAPI(s),
+		}, {
+			Namespace: "mev",
+			Service:   NewBundleAPI(s),
+		}, {
+			Namespace: "txpool",
+			Service:   NewTxLifecycleAPI(s),
 		}, {
 			Namespace: "miner",
 			Service:   NewMinerAPI(s),
@@ -448,6 +473,14 @@ func (s *Ethereum) StartMining() error {
 			}
 			cli.Authorize(eb, wallet.SignData)
 		}
+		if auth := authorityEngine(s.engine); auth != nil {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("validator missing: %v", err)
+			}
+			auth.Authorize(eb, wallet.SignData)
+		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
 		s.handler.enableSyncedFeatures()
@@ -457,6 +490,20 @@ func (s *Ethereum) StartMining() error {
 	return nil
 }
 
+// authorityEngine returns the authority consensus engine, if the chain is run
+// by one, directly or wrapped into the beacon engine.
+func authorityEngine(engine consensus.Engine) *authority.Authority {
+	if a, ok := engine.(*authority.Authority); ok {
+		return a
+	}
+	if cl, ok := engine.(*beacon.Beacon); ok {
+		if a, ok := cl.InnerEngine().(*authority.Authority); ok {
+			return a
+		}
+	}
+	return nil
+}
+
 // StopMining terminates the miner, both at the consensus engine level as well as
 // at the block creation level.
 func (s *Ethereum) StopMining() {
@@ -499,6 +546,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.commits != nil {
+		protos = append(protos, s.commits.MakeProtocols()...)
+	}
 	return protos
 }
 
@@ -523,6 +573,9 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+	if s.commits != nil {
+		s.commits.Start()
+	}
 	return nil
 }
 
@@ -532,6 +585,9 @@ func (s *Ethereum) Stop() error {
 	// Stop all the peer-related stuff first.
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
+	if s.commits != nil {
+		s.commits.Stop()
+	}
 	s.handler.Stop()
 
 	// Then stop everything else.
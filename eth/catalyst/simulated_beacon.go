@@ -18,18 +18,25 @@ package catalyst
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"math/big"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/beacon/engine"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/forks"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -71,12 +78,21 @@ func (w *withdrawalQueue) gatherPending(maxCount int) []*types.Withdrawal {
 type SimulatedBeacon struct {
 	shutdownCh  chan struct{}
 	eth         *eth.Ethereum
-	period      uint64
 	withdrawals withdrawalQueue
 
 	feeRecipient     common.Address
 	feeRecipientLock sync.Mutex // lock gates concurrent access to the feeRecipient
 
+	period        uint64     // Block period in seconds, 0 to seal on demand
+	paused        bool       // Whether automatic block production is suspended
+	finalityDelay uint64     // Number of blocks the finalized block lags behind
+	emptySlots    uint64     // Number of slots to leave empty before the next block
+	timeOffset    uint64     // Number of seconds block timestamps are ahead of the clock
+	settingsLock  sync.Mutex // lock gates concurrent access to the runtime settings
+	settingsFeed  event.Feed // Notification feed for runtime setting changes
+
+	sealLock           sync.Mutex    // lock serializes block production and chain modifications
+	snapshots          []common.Hash // Chain heads saved via Snapshot, indexed by id
 	engineAPI          *ConsensusAPI
 	curForkchoiceState engine.ForkchoiceStateV1
 	lastBlockTime      uint64
@@ -87,6 +103,8 @@ type SimulatedBeacon struct {
 //
 //   - If period is set to 0, a block is produced on every transaction.
 //     via Commit, Fork and AdjustTime.
+//
+// The period can be changed at runtime via SetPeriod.
 func NewSimulatedBeacon(period uint64, eth *eth.Ethereum) (*SimulatedBeacon, error) {
 	block := eth.BlockChain().CurrentBlock()
 	current := engine.ForkchoiceStateV1{
@@ -120,14 +138,12 @@ func (c *SimulatedBeacon) setFeeRecipient(feeRecipient common.Address) {
 }
 
 // Start invokes the SimulatedBeacon life-cycle function in a goroutine.
+//
+// If the period is set to 0, the loop does not mine at all until a period is
+// configured. This is used in the simulated backend where blocks are explicitly
+// mined via Commit, AdjustTime and Fork.
 func (c *SimulatedBeacon) Start() error {
-	if c.period == 0 {
-		// if period is set to 0, do not mine at all
-		// this is used in the simulated backend where blocks
-		// are explicitly mined via Commit, AdjustTime and Fork
-	} else {
-		go c.loop()
-	}
+	go c.loop()
 	return nil
 }
 
@@ -140,6 +156,10 @@ func (c *SimulatedBeacon) Stop() error {
 // sealBlock initiates payload building for a new block and creates a new block
 // with the completed payload.
 func (c *SimulatedBeacon) sealBlock(withdrawals []*types.Withdrawal, timestamp uint64) error {
+	c.sealLock.Lock()
+	defer c.sealLock.Unlock()
+
+	timestamp += c.takeEmptySlots()
 	if timestamp <= c.lastBlockTime {
 		timestamp = c.lastBlockTime + 1
 	}
@@ -148,19 +168,23 @@ func (c *SimulatedBeacon) sealBlock(withdrawals []*types.Withdrawal, timestamp u
 	c.feeRecipientLock.Unlock()
 
 	// Reset to CurrentBlock in case of the chain was rewound
-	if header := c.eth.BlockChain().CurrentBlock(); c.curForkchoiceState.HeadBlockHash != header.Hash() {
+	header := c.eth.BlockChain().CurrentBlock()
+	if c.curForkchoiceState.HeadBlockHash != header.Hash() {
 		finalizedHash := c.finalizedBlockHash(header.Number.Uint64())
 		c.setCurrentState(header.Hash(), *finalizedHash)
 	}
 
 	var random [32]byte
 	rand.Read(random[:])
+
+	version, beaconRoot := c.payloadVersion(header, timestamp, random)
 	fcResponse, err := c.engineAPI.forkchoiceUpdated(c.curForkchoiceState, &engine.PayloadAttributes{
 		Timestamp:             timestamp,
 		SuggestedFeeRecipient: feeRecipient,
 		Withdrawals:           withdrawals,
 		Random:                random,
-	}, engine.PayloadV2, true)
+		BeaconRoot:            beaconRoot,
+	}, version, true)
 	if err != nil {
 		return err
 	}
@@ -175,7 +199,7 @@ func (c *SimulatedBeacon) sealBlock(withdrawals []*types.Withdrawal, timestamp u
 	payload := envelope.ExecutionPayload
 
 	var finalizedHash common.Hash
-	if payload.Number%devEpochLength == 0 {
+	if c.getFinalityDelay() == 0 && payload.Number%devEpochLength == 0 {
 		finalizedHash = payload.BlockHash
 	} else {
 		if fh := c.finalizedBlockHash(payload.Number); fh == nil {
@@ -186,7 +210,7 @@ func (c *SimulatedBeacon) sealBlock(withdrawals []*types.Withdrawal, timestamp u
 	}
 
 	// Mark the payload as canon
-	if _, err = c.engineAPI.NewPayloadV2(*payload); err != nil {
+	if err = c.insertPayload(envelope, beaconRoot); err != nil {
 		return err
 	}
 	c.setCurrentState(payload.BlockHash, finalizedHash)
@@ -199,19 +223,320 @@ func (c *SimulatedBeacon) sealBlock(withdrawals []*types.Withdrawal, timestamp u
 	return nil
 }
 
+// payloadVersion returns the engine API version to build a child of the given
+// header with, along with the beacon root to use after Cancun.
+func (c *SimulatedBeacon) payloadVersion(parent *types.Header, timestamp uint64, random common.Hash) (engine.PayloadVersion, *common.Hash) {
+	number := new(big.Int).Add(parent.Number, common.Big1)
+	if c.eth.BlockChain().Config().IsCancun(number, timestamp) {
+		// There is no beacon chain, use the randomness as a stand-in block root
+		return engine.PayloadV3, &random
+	}
+	return engine.PayloadV2, nil
+}
+
+// insertPayload imports a payload built by the local miner into the chain
+// without marking it canonical, using the engine API matching its fork.
+func (c *SimulatedBeacon) insertPayload(envelope *engine.ExecutionPayloadEnvelope, beaconRoot *common.Hash) error {
+	var blobHashes []common.Hash
+	if beaconRoot != nil {
+		blobHashes = make([]common.Hash, 0)
+		if envelope.BlobsBundle != nil {
+			hasher := sha256.New()
+			for _, commit := range envelope.BlobsBundle.Commitments {
+				var commitment kzg4844.Commitment
+				if len(commit) != len(commitment) {
+					return errors.New("invalid commitment length")
+				}
+				copy(commitment[:], commit)
+				blobHashes = append(blobHashes, kzg4844.CalcBlobHashV1(hasher, &commitment))
+			}
+		}
+	}
+	var (
+		status engine.PayloadStatusV1
+		err    error
+	)
+	if beaconRoot == nil {
+		status, err = c.engineAPI.NewPayloadV2(*envelope.ExecutionPayload)
+	} else {
+		status, err = c.engineAPI.NewPayloadV3(*envelope.ExecutionPayload, blobHashes, beaconRoot)
+	}
+	if err != nil {
+		return err
+	}
+	if status.Status != engine.VALID && status.Status != engine.ACCEPTED {
+		if status.ValidationError != nil {
+			return fmt.Errorf("payload rejected: %s", *status.ValidationError)
+		}
+		return fmt.Errorf("payload rejected: %s", status.Status)
+	}
+	return nil
+}
+
+// takeEmptySlots returns the time to skip for the empty slots requested since
+// the last block, and resets the request.
+func (c *SimulatedBeacon) takeEmptySlots() uint64 {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	slot := c.period
+	if slot == 0 {
+		slot = 1
+	}
+	skip := c.emptySlots * slot
+	c.emptySlots = 0
+	return skip
+}
+
+// now returns the current time as seen by the chain, i.e. the wall clock time
+// adjusted by IncreaseTime.
+func (c *SimulatedBeacon) now() uint64 {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	return uint64(time.Now().Unix()) + c.timeOffset
+}
+
+// IncreaseTime moves the clock used to timestamp new blocks forward by the given
+// number of seconds and returns the total adjustment.
+func (c *SimulatedBeacon) IncreaseTime(seconds uint64) uint64 {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	c.timeOffset += seconds
+	return c.timeOffset
+}
+
+// getFinalityDelay returns the number of blocks finality lags behind the head.
+func (c *SimulatedBeacon) getFinalityDelay() uint64 {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	return c.finalityDelay
+}
+
+// autoMining returns the current block period and whether blocks are produced
+// automatically, i.e. block production is not paused.
+func (c *SimulatedBeacon) autoMining() (uint64, bool) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	return c.period, !c.paused
+}
+
+// subscribeSettings subscribes to changes of the runtime settings.
+func (c *SimulatedBeacon) subscribeSettings(ch chan<- struct{}) event.Subscription {
+	return c.settingsFeed.Subscribe(ch)
+}
+
+// SetPeriod changes the block period at runtime. A period of 0 switches to
+// producing a block on every transaction.
+func (c *SimulatedBeacon) SetPeriod(period uint64) {
+	c.settingsLock.Lock()
+	c.period = period
+	c.settingsLock.Unlock()
+
+	c.settingsFeed.Send(struct{}{})
+}
+
+// Pause suspends automatic block production. Blocks can still be produced on
+// demand via Commit.
+func (c *SimulatedBeacon) Pause() {
+	c.settingsLock.Lock()
+	c.paused = true
+	c.settingsLock.Unlock()
+
+	c.settingsFeed.Send(struct{}{})
+}
+
+// Resume continues automatic block production after Pause.
+func (c *SimulatedBeacon) Resume() {
+	c.settingsLock.Lock()
+	c.paused = false
+	c.settingsLock.Unlock()
+
+	c.settingsFeed.Send(struct{}{})
+}
+
+// SetFinalityDelay makes the finalized block lag the given number of blocks
+// behind the epoch boundary it would otherwise be at.
+func (c *SimulatedBeacon) SetFinalityDelay(blocks uint64) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	c.finalityDelay = blocks
+}
+
+// SkipSlots leaves the given number of slots empty before the next block, i.e.
+// the timestamp of the next block is advanced by the duration of the slots.
+func (c *SimulatedBeacon) SkipSlots(slots uint64) {
+	c.settingsLock.Lock()
+	defer c.settingsLock.Unlock()
+
+	c.emptySlots += slots
+}
+
+// Reorg replaces the last depth blocks of the chain with an equally long side
+// chain of newly built blocks. Transactions of the dropped blocks which are not
+// included in the new ones are returned to the transaction pool.
+func (c *SimulatedBeacon) Reorg(depth uint64) (common.Hash, error) {
+	c.sealLock.Lock()
+	defer c.sealLock.Unlock()
+
+	var (
+		chain = c.eth.BlockChain()
+		head  = chain.CurrentBlock()
+	)
+	if depth == 0 {
+		return common.Hash{}, errors.New("reorg depth must be positive")
+	}
+	if depth > head.Number.Uint64() {
+		return common.Hash{}, fmt.Errorf("reorg depth %d exceeds chain height %d", depth, head.Number.Uint64())
+	}
+	parent := chain.GetHeaderByNumber(head.Number.Uint64() - depth)
+	if parent == nil {
+		return common.Hash{}, errors.New("reorg ancestor not found")
+	}
+	if final := chain.CurrentFinalBlock(); final != nil && final.Number.Cmp(parent.Number) > 0 {
+		return common.Hash{}, fmt.Errorf("reorg would revert finalized block %d", final.Number)
+	}
+	c.feeRecipientLock.Lock()
+	feeRecipient := c.feeRecipient
+	c.feeRecipientLock.Unlock()
+
+	for i := uint64(0); i < depth; i++ {
+		var random [32]byte
+		rand.Read(random[:])
+
+		timestamp := parent.Time + 1
+		version, beaconRoot := c.payloadVersion(parent, timestamp, random)
+		payload, err := c.eth.Miner().BuildPayload(&miner.BuildPayloadArgs{
+			Parent:       parent.Hash(),
+			Timestamp:    timestamp,
+			FeeRecipient: feeRecipient,
+			Random:       random,
+			Withdrawals:  make(types.Withdrawals, 0),
+			BeaconRoot:   beaconRoot,
+			Version:      version,
+		})
+		if err != nil {
+			return common.Hash{}, err
+		}
+		envelope := payload.ResolveFull()
+		if envelope == nil {
+			return common.Hash{}, errors.New("payload building interrupted")
+		}
+		if err := c.insertPayload(envelope, beaconRoot); err != nil {
+			return common.Hash{}, err
+		}
+		if parent = chain.GetHeaderByHash(envelope.ExecutionPayload.BlockHash); parent == nil {
+			return common.Hash{}, errors.New("reorged block not found")
+		}
+	}
+	finalizedHash := c.curForkchoiceState.FinalizedBlockHash
+	c.setCurrentState(parent.Hash(), finalizedHash)
+	if _, err := c.engineAPI.ForkchoiceUpdatedV2(c.curForkchoiceState, nil); err != nil {
+		return common.Hash{}, err
+	}
+	if parent.Time > c.lastBlockTime {
+		c.lastBlockTime = parent.Time
+	}
+	return parent.Hash(), nil
+}
+
+// ScheduleFork schedules the given fork to activate at the specified timestamp,
+// which must be later than the current head. Only the timestamp based forks
+// supported by the simulated beacon can be scheduled, their order must be
+// retained and they must not be active yet.
+//
+// The configuration of the running chain is never modified. The new schedule is
+// persisted and takes effect once the node is restarted with its data directory,
+// the blocks produced until then are not affected. To cross a fork transition
+// without a restart, schedule the fork in the genesis configuration instead.
+func (c *SimulatedBeacon) ScheduleFork(fork forks.Fork, timestamp uint64) error {
+	c.sealLock.Lock()
+	defer c.sealLock.Unlock()
+
+	var (
+		chain   = c.eth.BlockChain()
+		head    = chain.CurrentBlock()
+		live    = chain.Config()
+		genesis = chain.Genesis().Hash()
+	)
+	if timestamp <= head.Time {
+		return fmt.Errorf("fork timestamp %d not after head timestamp %d", timestamp, head.Time)
+	}
+	// Schedule on top of any previously scheduled, not yet applied forks
+	config := rawdb.ReadChainConfig(c.eth.ChainDb(), genesis)
+	if config == nil {
+		cpy := *live
+		config = &cpy
+	}
+	switch fork {
+	case forks.Shanghai:
+		if live.IsShanghai(head.Number, head.Time) {
+			return fmt.Errorf("shanghai already active at time %d", *live.ShanghaiTime)
+		}
+		if config.CancunTime != nil && *config.CancunTime < timestamp {
+			return errors.New("shanghai can't be scheduled after cancun")
+		}
+		config.ShanghaiTime = &timestamp
+	case forks.Cancun:
+		if live.IsCancun(head.Number, head.Time) {
+			return fmt.Errorf("cancun already active at time %d", *live.CancunTime)
+		}
+		if config.ShanghaiTime == nil || *config.ShanghaiTime > timestamp {
+			return errors.New("cancun can't be scheduled before shanghai")
+		}
+		config.CancunTime = &timestamp
+	default:
+		return fmt.Errorf("unsupported fork %d", fork)
+	}
+	rawdb.WriteChainConfig(c.eth.ChainDb(), genesis, config)
+	log.Info("Scheduled fork activation, restart to apply", "fork", fork, "time", timestamp)
+	return nil
+}
+
 // loop runs the block production loop for non-zero period configuration
 func (c *SimulatedBeacon) loop() {
-	timer := time.NewTimer(0)
+	var (
+		changes = make(chan struct{}, 1)
+		sub     = c.subscribeSettings(changes)
+		timer   = time.NewTimer(0)
+	)
+	defer sub.Unsubscribe()
+	defer timer.Stop()
+
+	// schedule (re)arms the timer according to the current settings
+	schedule := func(delay time.Duration) {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if period, running := c.autoMining(); period > 0 && running {
+			timer.Reset(delay)
+		}
+	}
+	schedule(0)
 	for {
 		select {
 		case <-c.shutdownCh:
 			return
+		case <-changes:
+			period, _ := c.autoMining()
+			schedule(time.Second * time.Duration(period))
 		case <-timer.C:
+			period, running := c.autoMining()
+			if period == 0 || !running {
+				continue
+			}
 			withdrawals := c.withdrawals.gatherPending(10)
-			if err := c.sealBlock(withdrawals, uint64(time.Now().Unix())); err != nil {
+			if err := c.sealBlock(withdrawals, c.now()); err != nil {
 				log.Warn("Error performing sealing work", "err", err)
 			} else {
-				timer.Reset(time.Second * time.Duration(c.period))
+				timer.Reset(time.Second * time.Duration(period))
 			}
 		}
 	}
@@ -220,6 +545,11 @@ func (c *SimulatedBeacon) loop() {
 // finalizedBlockHash returns the block hash of the finalized block corresponding
 // to the given number or nil if doesn't exist in the chain.
 func (c *SimulatedBeacon) finalizedBlockHash(number uint64) *common.Hash {
+	if delay := c.getFinalityDelay(); delay > number {
+		number = 0
+	} else {
+		number -= delay
+	}
 	var finalizedNumber uint64
 	if number%devEpochLength == 0 {
 		finalizedNumber = number
@@ -245,7 +575,7 @@ func (c *SimulatedBeacon) setCurrentState(headHash, finalizedHash common.Hash) {
 // Commit seals a block on demand.
 func (c *SimulatedBeacon) Commit() common.Hash {
 	withdrawals := c.withdrawals.gatherPending(10)
-	if err := c.sealBlock(withdrawals, uint64(time.Now().Unix())); err != nil {
+	if err := c.sealBlock(withdrawals, c.now()); err != nil {
 		log.Warn("Error performing sealing work", "err", err)
 	}
 	return c.eth.BlockChain().CurrentBlock().Hash()
@@ -287,11 +617,10 @@ func (c *SimulatedBeacon) AdjustTime(adjustment time.Duration) error {
 }
 
 func RegisterSimulatedBeaconAPIs(stack *node.Node, sim *SimulatedBeacon) {
-	api := &api{sim}
-	if sim.period == 0 {
-		// mine on demand if period is set to 0
-		go api.loop()
-	}
+	api := &api{sim: sim}
+	// mine on demand whenever the period is set to 0
+	go api.loop()
+
 	stack.RegisterAPIs([]rpc.API{
 		{
 			Namespace: "dev",
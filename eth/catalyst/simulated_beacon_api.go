@@ -18,36 +18,74 @@ package catalyst
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params/forks"
+	"github.com/holiman/uint256"
 )
 
+// api is the dev namespace of the simulated beacon.
+//
+// Note, the state overrides and impersonated transactions produce blocks which
+// no other node accepts and which can't be re-executed, so they must only ever
+// be used on throwaway development chains.
 type api struct {
 	sim *SimulatedBeacon
+
+	impersonated     map[common.Address]struct{} // Accounts allowed to send transactions via SendTransaction
+	impersonatedLock sync.Mutex                  // lock protects the impersonated accounts
 }
 
+// loop produces a block on every new transaction or withdrawal while the
+// simulated beacon is configured to mine on demand and is not paused.
 func (a *api) loop() {
 	var (
-		newTxs = make(chan core.NewTxsEvent)
-		sub    = a.sim.eth.TxPool().SubscribeTransactions(newTxs, true)
+		newTxs  = make(chan core.NewTxsEvent)
+		sub     = a.sim.eth.TxPool().SubscribeTransactions(newTxs, true)
+		changes = make(chan struct{}, 1)
+		setSub  = a.sim.subscribeSettings(changes)
 	)
 	defer sub.Unsubscribe()
+	defer setSub.Unsubscribe()
 
 	for {
+		// Only pick up withdrawals in on-demand mode, otherwise leave them
+		// queued for the periodic block production
+		var pending chan *types.Withdrawal
+		period, running := a.sim.autoMining()
+		onDemand := period == 0 && running
+		if onDemand {
+			pending = a.sim.withdrawals.pending
+		}
 		select {
 		case <-a.sim.shutdownCh:
 			return
-		case w := <-a.sim.withdrawals.pending:
+		case <-changes:
+			// Settings changed, re-evaluate the mining mode. Transactions which
+			// arrived while paused are picked up by committing right away.
+			if period, running := a.sim.autoMining(); period == 0 && running && !onDemand {
+				if len(a.sim.eth.TxPool().Pending(false)) > 0 {
+					a.sim.Commit()
+				}
+			}
+		case w := <-pending:
 			withdrawals := append(a.sim.withdrawals.gatherPending(9), w)
-			if err := a.sim.sealBlock(withdrawals, uint64(time.Now().Unix())); err != nil {
+			if err := a.sim.sealBlock(withdrawals, a.sim.now()); err != nil {
 				log.Warn("Error performing sealing work", "err", err)
 			}
 		case <-newTxs:
-			a.sim.Commit()
+			if onDemand {
+				a.sim.Commit()
+			}
 		}
 	}
 }
@@ -59,3 +97,168 @@ func (a *api) AddWithdrawal(ctx context.Context, withdrawal *types.Withdrawal) e
 func (a *api) SetFeeRecipient(ctx context.Context, feeRecipient common.Address) {
 	a.sim.setFeeRecipient(feeRecipient)
 }
+
+// SetPeriod changes the block period in seconds. A period of 0 produces a block
+// on every transaction.
+func (a *api) SetPeriod(ctx context.Context, period uint64) {
+	a.sim.SetPeriod(period)
+}
+
+// Pause suspends automatic block production.
+func (a *api) Pause(ctx context.Context) {
+	a.sim.Pause()
+}
+
+// Resume continues automatic block production after a pause.
+func (a *api) Resume(ctx context.Context) {
+	a.sim.Resume()
+}
+
+// Mine produces the given number of blocks (or a single one if unspecified)
+// right away, regardless of the period and of the production being paused, and
+// returns the hash of the last one.
+func (a *api) Mine(ctx context.Context, blocks *uint64) common.Hash {
+	n := uint64(1)
+	if blocks != nil {
+		n = *blocks
+	}
+	head := a.sim.eth.BlockChain().CurrentBlock().Hash()
+	for i := uint64(0); i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		head = a.sim.Commit()
+	}
+	return head
+}
+
+// Reorg replaces the given number of blocks at the head of the chain with new
+// ones and returns the hash of the new head.
+func (a *api) Reorg(ctx context.Context, depth uint64) (common.Hash, error) {
+	return a.sim.Reorg(depth)
+}
+
+// SetFinalityDelay makes finality lag the given number of blocks behind.
+func (a *api) SetFinalityDelay(ctx context.Context, blocks uint64) {
+	a.sim.SetFinalityDelay(blocks)
+}
+
+// SkipSlots leaves the given number of slots empty before the next block.
+func (a *api) SkipSlots(ctx context.Context, slots uint64) {
+	a.sim.SkipSlots(slots)
+}
+
+// ScheduleFork schedules the named fork (shanghai or cancun) to activate at the
+// given timestamp, once the node is restarted.
+func (a *api) ScheduleFork(ctx context.Context, name string, timestamp uint64) error {
+	var fork forks.Fork
+	switch strings.ToLower(name) {
+	case "shanghai":
+		fork = forks.Shanghai
+	case "cancun":
+		fork = forks.Cancun
+	default:
+		return fmt.Errorf("unsupported fork %q", name)
+	}
+	return a.sim.ScheduleFork(fork, timestamp)
+}
+
+// IncreaseTime moves the clock used for new blocks forward by the given number
+// of seconds and returns the total adjustment.
+func (a *api) IncreaseTime(ctx context.Context, seconds uint64) uint64 {
+	return a.sim.IncreaseTime(seconds)
+}
+
+// SetBalance overrides the balance of an account.
+func (a *api) SetBalance(ctx context.Context, addr common.Address, balance hexutil.Big) (common.Hash, error) {
+	value, overflow := uint256.FromBig(balance.ToInt())
+	if overflow || balance.ToInt().Sign() < 0 {
+		return common.Hash{}, errors.New("balance out of range")
+	}
+	return a.sim.SetBalance(addr, value)
+}
+
+// SetNonce overrides the nonce of an account.
+func (a *api) SetNonce(ctx context.Context, addr common.Address, nonce hexutil.Uint64) (common.Hash, error) {
+	return a.sim.SetNonce(addr, uint64(nonce))
+}
+
+// SetCode overrides the code of an account.
+func (a *api) SetCode(ctx context.Context, addr common.Address, code hexutil.Bytes) (common.Hash, error) {
+	return a.sim.SetCode(addr, code)
+}
+
+// SetStorageAt overrides a storage slot of an account.
+func (a *api) SetStorageAt(ctx context.Context, addr common.Address, slot common.Hash, value common.Hash) (common.Hash, error) {
+	return a.sim.SetStorageAt(addr, slot, value)
+}
+
+// Snapshot saves the current head of the chain and returns an id to revert to it.
+func (a *api) Snapshot(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(a.sim.Snapshot())
+}
+
+// Revert rewinds the chain to a previously taken snapshot, discarding it and
+// all the snapshots taken after it.
+func (a *api) Revert(ctx context.Context, id hexutil.Uint64) (bool, error) {
+	if err := a.sim.Revert(uint64(id)); err != nil {
+		if errors.Is(err, errUnknownSnapshot) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ImpersonateAccount allows sending transactions from the given account via
+// dev_sendTransaction without having its private key.
+func (a *api) ImpersonateAccount(ctx context.Context, addr common.Address) {
+	a.impersonatedLock.Lock()
+	defer a.impersonatedLock.Unlock()
+
+	if a.impersonated == nil {
+		a.impersonated = make(map[common.Address]struct{})
+	}
+	a.impersonated[addr] = struct{}{}
+}
+
+// StopImpersonatingAccount stops impersonating the given account.
+func (a *api) StopImpersonatingAccount(ctx context.Context, addr common.Address) {
+	a.impersonatedLock.Lock()
+	defer a.impersonatedLock.Unlock()
+
+	delete(a.impersonated, addr)
+}
+
+// SendTransaction executes a transaction from an impersonated account and includes
+// it in a new block right away, returning the transaction hash.
+//
+// The transaction carries a placeholder signature, so it never enters the pool
+// and is rejected by any other node. The block including it can't be imported by
+// other nodes, nor be re-executed by this node, e.g. for tracing.
+func (a *api) SendTransaction(ctx context.Context, args ethapi.TransactionArgs) (common.Hash, error) {
+	if args.From == nil {
+		return common.Hash{}, errors.New("missing sender")
+	}
+	a.impersonatedLock.Lock()
+	_, ok := a.impersonated[*args.From]
+	a.impersonatedLock.Unlock()
+	if !ok {
+		return common.Hash{}, fmt.Errorf("account %v is not impersonated", *args.From)
+	}
+	if args.BlobHashes != nil {
+		return common.Hash{}, errors.New("blob transactions can't be impersonated")
+	}
+	if err := args.SetDefaults(ctx, a.sim.eth.APIBackend); err != nil {
+		return common.Hash{}, err
+	}
+	signer := types.LatestSigner(a.sim.eth.BlockChain().Config())
+	tx, err := args.ToTransaction().WithSignature(signer, impersonationSignature(*args.From))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := a.sim.SendImpersonated(*args.From, tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+// errUnknownSnapshot is returned if a revert is requested to a snapshot which
+// does not exist or was already reverted.
+var errUnknownSnapshot = errors.New("unknown snapshot")
+
+// ModifyState applies the given modifications to the state of the current head
+// and commits the result in a new block on top of it.
+//
+// Note, the block contains no transactions, so its state root does not follow
+// from its contents and executing it again yields a different state. Other nodes
+// reject the block, so the chain can't be synced or shared anymore. The local
+// node can't re-execute it either, e.g. when tracing it or regenerating pruned
+// state. This is only acceptable on throwaway development chains.
+func (c *SimulatedBeacon) ModifyState(modify func(statedb *state.StateDB)) (common.Hash, error) {
+	return c.commitState(func(header *types.Header, statedb *state.StateDB) (types.Transactions, types.Receipts, error) {
+		modify(statedb)
+		return nil, nil, nil
+	})
+}
+
+// commitState builds a new block on top of the current head, applying the given
+// function to its state, and makes it the new head. The transactions and receipts
+// returned by the function are included in the block as they are.
+func (c *SimulatedBeacon) commitState(apply func(header *types.Header, statedb *state.StateDB) (types.Transactions, types.Receipts, error)) (common.Hash, error) {
+	c.sealLock.Lock()
+	defer c.sealLock.Unlock()
+
+	var (
+		chain  = c.eth.BlockChain()
+		config = chain.Config()
+		parent = chain.CurrentBlock()
+	)
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	timestamp := c.now()
+	if timestamp <= c.lastBlockTime {
+		timestamp = c.lastBlockTime + 1
+	}
+	if timestamp <= parent.Time {
+		timestamp = parent.Time + 1
+	}
+	c.feeRecipientLock.Lock()
+	feeRecipient := c.feeRecipient
+	c.feeRecipientLock.Unlock()
+
+	var random common.Hash
+	rand.Read(random[:])
+
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       timestamp,
+		Coinbase:   feeRecipient,
+		Difficulty: new(big.Int),
+		MixDigest:  random,
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, parent)
+	}
+	var withdrawals []*types.Withdrawal
+	if config.IsShanghai(header.Number, header.Time) {
+		withdrawals = make([]*types.Withdrawal, 0)
+	}
+	if config.IsCancun(header.Number, header.Time) {
+		var excessBlobGas uint64
+		if config.IsCancun(parent.Number, parent.Time) {
+			excessBlobGas = eip4844.CalcExcessBlobGas(*parent.ExcessBlobGas, *parent.BlobGasUsed)
+		} else {
+			excessBlobGas = eip4844.CalcExcessBlobGas(0, 0)
+		}
+		header.BlobGasUsed = new(uint64)
+		header.ExcessBlobGas = &excessBlobGas
+		header.ParentBeaconRoot = &random
+
+		context := core.NewEVMBlockContext(header, chain, nil)
+		vmenv := vm.NewEVM(context, vm.TxContext{}, statedb, config, vm.Config{})
+		core.ProcessBeaconBlockRoot(random, vmenv, statedb)
+	}
+	txs, receipts, err := apply(header, statedb)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	block, err := c.eth.Engine().FinalizeAndAssemble(chain, header, statedb, txs, nil, receipts, withdrawals)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// The receipts were created before the block hash was known, fix them up
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		receipt.BlockHash = block.Hash()
+		for _, log := range receipt.Logs {
+			log.BlockHash = block.Hash()
+		}
+		logs = append(logs, receipt.Logs...)
+	}
+	if _, err := chain.WriteBlockAndSetHead(block, receipts, logs, statedb, true); err != nil {
+		return common.Hash{}, err
+	}
+	finalizedHash := c.finalizedBlockHash(block.NumberU64())
+	if finalizedHash == nil {
+		return common.Hash{}, errors.New("chain rewind interrupted calculation of finalized block hash")
+	}
+	c.setCurrentState(block.Hash(), *finalizedHash)
+	c.lastBlockTime = block.Time()
+
+	// Make sure the pool sees the new state before returning, so transactions
+	// relying on the modification are accepted right away
+	if err := c.eth.TxPool().Sync(); err != nil {
+		log.Warn("Failed to sync transaction pool", "err", err)
+	}
+	return block.Hash(), nil
+}
+
+// SetBalance overrides the balance of an account.
+func (c *SimulatedBeacon) SetBalance(addr common.Address, balance *uint256.Int) (common.Hash, error) {
+	return c.ModifyState(func(statedb *state.StateDB) {
+		statedb.SetBalance(addr, balance)
+	})
+}
+
+// SetNonce overrides the nonce of an account.
+func (c *SimulatedBeacon) SetNonce(addr common.Address, nonce uint64) (common.Hash, error) {
+	return c.ModifyState(func(statedb *state.StateDB) {
+		statedb.SetNonce(addr, nonce)
+	})
+}
+
+// SetCode overrides the code of an account.
+func (c *SimulatedBeacon) SetCode(addr common.Address, code []byte) (common.Hash, error) {
+	return c.ModifyState(func(statedb *state.StateDB) {
+		statedb.SetCode(addr, code)
+	})
+}
+
+// SetStorageAt overrides a single storage slot of an account.
+func (c *SimulatedBeacon) SetStorageAt(addr common.Address, slot common.Hash, value common.Hash) (common.Hash, error) {
+	return c.ModifyState(func(statedb *state.StateDB) {
+		statedb.SetState(addr, slot, value)
+	})
+}
+
+// Snapshot saves the current head of the chain and returns an identifier to
+// revert to it later.
+func (c *SimulatedBeacon) Snapshot() uint64 {
+	c.sealLock.Lock()
+	defer c.sealLock.Unlock()
+
+	c.snapshots = append(c.snapshots, c.eth.BlockChain().CurrentBlock().Hash())
+	return uint64(len(c.snapshots) - 1)
+}
+
+// Revert rewinds the chain to the head saved by the given snapshot. The snapshot
+// and all the ones taken after it are discarded.
+func (c *SimulatedBeacon) Revert(id uint64) error {
+	c.sealLock.Lock()
+	defer c.sealLock.Unlock()
+
+	if id >= uint64(len(c.snapshots)) {
+		return errUnknownSnapshot
+	}
+	var (
+		chain = c.eth.BlockChain()
+		hash  = c.snapshots[id]
+	)
+	c.snapshots = c.snapshots[:id]
+
+	header := chain.GetHeaderByHash(hash)
+	if header == nil || chain.GetCanonicalHash(header.Number.Uint64()) != hash {
+		return errUnknownSnapshot
+	}
+	if err := chain.SetHead(header.Number.Uint64()); err != nil {
+		return err
+	}
+	finalizedHash := c.finalizedBlockHash(header.Number.Uint64())
+	if finalizedHash == nil {
+		return errors.New("chain rewind interrupted calculation of finalized block hash")
+	}
+	c.setCurrentState(hash, *finalizedHash)
+	return nil
+}
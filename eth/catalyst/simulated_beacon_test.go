@@ -18,20 +18,26 @@ package catalyst
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/forks"
 )
 
 func startSimulatedBeaconEthService(t *testing.T, genesis *core.Genesis) (*node.Node, *eth.Ethereum, *SimulatedBeacon) {
@@ -139,3 +145,181 @@ func TestSimulatedBeaconSendWithdrawals(t *testing.T) {
 		}
 	}
 }
+
+// Tests that block production can be paused, resumed and re-timed at runtime.
+func TestSimulatedBeaconPause(t *testing.T) {
+	genesis := core.DeveloperGenesisBlock(10_000_000, &common.Address{})
+	node, ethService, mock := startSimulatedBeaconEthService(t, genesis)
+	defer node.Close()
+
+	chainHeadCh := make(chan core.ChainHeadEvent, 10)
+	subscription := ethService.BlockChain().SubscribeChainHeadEvent(chainHeadCh)
+	defer subscription.Unsubscribe()
+
+	mock.Pause()
+	// Drain any block produced before the pause took effect
+	time.Sleep(100 * time.Millisecond)
+	for len(chainHeadCh) > 0 {
+		<-chainHeadCh
+	}
+	select {
+	case evt := <-chainHeadCh:
+		t.Fatalf("block %d produced while paused", evt.Block.NumberU64())
+	case <-time.After(2 * time.Second):
+	}
+	mock.SetPeriod(0)
+	mock.Resume()
+	select {
+	case evt := <-chainHeadCh:
+		t.Fatalf("block %d produced without transactions", evt.Block.NumberU64())
+	case <-time.After(1500 * time.Millisecond):
+	}
+	mock.SetPeriod(1)
+	select {
+	case <-chainHeadCh:
+	case <-time.After(3 * time.Second):
+		t.Fatal("no block produced after resuming")
+	}
+}
+
+// Tests the state manipulation, time travel and impersonation methods of the
+// dev namespace.
+func TestSimulatedBeaconDevAPI(t *testing.T) {
+	var (
+		faucet = common.Address{0xfa}
+		user   = common.Address{0x11}
+		target = common.Address{0x22}
+	)
+	genesis := core.DeveloperGenesisBlock(10_000_000, &faucet)
+	node, ethService, mock := startSimulatedBeaconEthService(t, genesis)
+	defer node.Close()
+
+	mock.Pause()
+	dev := &api{sim: mock}
+	ctx := context.Background()
+
+	// Override some state and check it's reflected at the head
+	if _, err := dev.SetBalance(ctx, user, hexutil.Big(*big.NewInt(params.Ether))); err != nil {
+		t.Fatalf("failed to set balance: %v", err)
+	}
+	if _, err := dev.SetCode(ctx, target, []byte{0x60, 0x00}); err != nil {
+		t.Fatalf("failed to set code: %v", err)
+	}
+	if _, err := dev.SetStorageAt(ctx, target, common.Hash{0x01}, common.Hash{0x02}); err != nil {
+		t.Fatalf("failed to set storage: %v", err)
+	}
+	chain := ethService.BlockChain()
+	statedb, _ := chain.StateAt(chain.CurrentBlock().Root)
+	if balance := statedb.GetBalance(user); balance.ToBig().Cmp(big.NewInt(params.Ether)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, params.Ether)
+	}
+	if code := statedb.GetCode(target); len(code) != 2 {
+		t.Errorf("code mismatch: have %x", code)
+	}
+	if value := statedb.GetState(target, common.Hash{0x01}); value != (common.Hash{0x02}) {
+		t.Errorf("storage mismatch: have %x", value)
+	}
+	// Take a snapshot, mine a few blocks and revert to it
+	head := chain.CurrentBlock()
+	id := dev.Snapshot(ctx)
+	three := uint64(3)
+	dev.Mine(ctx, &three)
+	if number := chain.CurrentBlock().Number.Uint64(); number != head.Number.Uint64()+3 {
+		t.Fatalf("head number mismatch: have %d, want %d", number, head.Number.Uint64()+3)
+	}
+	if ok, err := dev.Revert(ctx, id); !ok || err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head mismatch after revert: have %d, want %d", current.Number, head.Number)
+	}
+	if ok, _ := dev.Revert(ctx, id); ok {
+		t.Fatal("reverted to discarded snapshot")
+	}
+	// Move the clock forward and check the next block's timestamp
+	dev.IncreaseTime(ctx, 1000)
+	dev.Mine(ctx, nil)
+	if now := uint64(time.Now().Unix()); chain.CurrentBlock().Time < now+1000 {
+		t.Errorf("timestamp not increased: have %d, want at least %d", chain.CurrentBlock().Time, now+1000)
+	}
+	// Impersonate the funded account and send a transaction from it
+	var (
+		gas   = hexutil.Uint64(params.TxGas)
+		value = hexutil.Big(*big.NewInt(1000))
+		dest  = common.Address{0x33}
+		args  = ethapi.TransactionArgs{From: &user, To: &dest, Gas: &gas, Value: &value}
+	)
+	if _, err := dev.SendTransaction(ctx, args); err == nil {
+		t.Fatal("transaction sent from account not impersonated")
+	}
+	dev.ImpersonateAccount(ctx, user)
+	hash, err := dev.SendTransaction(ctx, args)
+	if err != nil {
+		t.Fatalf("failed to send impersonated transaction: %v", err)
+	}
+	block := chain.GetBlockByHash(chain.CurrentBlock().Hash())
+	if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != hash {
+		t.Fatalf("impersonated transaction not included")
+	}
+	receipts := chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != 1 || receipts[0].Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("impersonated transaction failed")
+	}
+	statedb, _ = chain.StateAt(block.Root())
+	if nonce := statedb.GetNonce(user); nonce != 1 {
+		t.Errorf("nonce mismatch: have %d, want %d", nonce, 1)
+	}
+	if balance := statedb.GetBalance(dest); balance.Uint64() != 1000 {
+		t.Errorf("value not transferred: have %v, want %v", balance, 1000)
+	}
+	// Impersonated transactions must not be accepted by the pool
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chain.Config().ChainID,
+		Nonce:     1,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: big.NewInt(10 * params.GWei),
+		Gas:       params.TxGas,
+		To:        &faucet,
+	})
+	tx, _ = tx.WithSignature(types.LatestSigner(chain.Config()), impersonationSignature(user))
+	if err := ethService.APIBackend.SendTx(ctx, tx); !errors.Is(err, txpool.ErrInvalidSender) {
+		t.Errorf("impersonated transaction error mismatch: have %v, want %v", err, txpool.ErrInvalidSender)
+	}
+	dev.StopImpersonatingAccount(ctx, user)
+	if _, err := dev.SendTransaction(ctx, args); err == nil {
+		t.Error("account still impersonated")
+	}
+}
+
+// Tests that scheduling a fork persists the new schedule without modifying the
+// configuration of the running chain.
+func TestSimulatedBeaconScheduleFork(t *testing.T) {
+	genesis := core.DeveloperGenesisBlock(10_000_000, &common.Address{})
+	config := *genesis.Config
+	config.CancunTime = nil
+	genesis.Config = &config
+
+	node, ethService, mock := startSimulatedBeaconEthService(t, genesis)
+	defer node.Close()
+
+	var (
+		chain = ethService.BlockChain()
+		head  = chain.CurrentBlock()
+	)
+	if err := mock.ScheduleFork(forks.Cancun, head.Time); err == nil {
+		t.Fatal("fork scheduled in the past")
+	}
+	if err := mock.ScheduleFork(forks.Shanghai, head.Time+1000); err == nil {
+		t.Fatal("active fork rescheduled")
+	}
+	if err := mock.ScheduleFork(forks.Cancun, head.Time+1000); err != nil {
+		t.Fatalf("failed to schedule fork: %v", err)
+	}
+	if chain.Config().CancunTime != nil {
+		t.Fatal("running chain configuration modified")
+	}
+	stored := rawdb.ReadChainConfig(ethService.ChainDb(), chain.Genesis().Hash())
+	if stored == nil || stored.CancunTime == nil || *stored.CancunTime != head.Time+1000 {
+		t.Fatalf("scheduled fork not persisted: %v", stored)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// impersonationSignature returns the placeholder signature of transactions sent
+// from the given account without its private key. The R value holds the address
+// while S is zero, which no valid secp256k1 signature can have, so the sender of
+// such a transaction can't be recovered by any regular signer.
+func impersonationSignature(from common.Address) []byte {
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig[32-common.AddressLength:32], from[:])
+	return sig
+}
+
+// impersonatedSigner is a signer attributing transactions to a known sender
+// instead of recovering it from their signature. It is only used to execute the
+// impersonated transactions of the dev API, never to validate any.
+type impersonatedSigner struct {
+	types.Signer
+	from common.Address
+}
+
+func (s impersonatedSigner) Sender(tx *types.Transaction) (common.Address, error) {
+	return s.from, nil
+}
+
+// Equal only matches the same impersonated signer, so the sender it caches in a
+// transaction is never reused by any regular signer.
+func (s impersonatedSigner) Equal(s2 types.Signer) bool {
+	x, ok := s2.(impersonatedSigner)
+	return ok && x.from == s.from && s.Signer.Equal(x.Signer)
+}
+
+// SendImpersonated executes a transaction as if sent from the given account and
+// includes it in a new block on top of the current head, bypassing the pool and
+// the engine API, which would both reject it. The hash of the new block is
+// returned.
+//
+// Note, the same caveats as for ModifyState apply: the block can't be imported
+// by other nodes nor be re-executed by the local one.
+func (c *SimulatedBeacon) SendImpersonated(from common.Address, tx *types.Transaction) (common.Hash, error) {
+	if tx.Type() == types.BlobTxType {
+		return common.Hash{}, errors.New("blob transactions can't be impersonated")
+	}
+	return c.commitState(func(header *types.Header, statedb *state.StateDB) (types.Transactions, types.Receipts, error) {
+		var (
+			chain  = c.eth.BlockChain()
+			config = chain.Config()
+			signer = impersonatedSigner{Signer: types.MakeSigner(config, header.Number, header.Time), from: from}
+		)
+		msg, err := core.TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, nil, err
+		}
+		var (
+			gp  = new(core.GasPool).AddGas(header.GasLimit)
+			evm = vm.NewEVM(core.NewEVMBlockContext(header, chain, nil), core.NewEVMTxContext(msg), statedb, config, vm.Config{})
+		)
+		statedb.SetTxContext(tx.Hash(), 0)
+		receipt, err := core.ApplyTransactionWithEVM(msg, config, gp, statedb, header.Number, common.Hash{}, tx, &header.GasUsed, evm)
+		if err != nil {
+			return nil, nil, err
+		}
+		return types.Transactions{tx}, types.Receipts{receipt}, nil
+	})
+}
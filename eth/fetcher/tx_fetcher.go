@@ -83,6 +83,7 @@ var (
 	txAnnounceKnownMeter       = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/known", nil)
 	txAnnounceUnderpricedMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/underpriced", nil)
 	txAnnounceDOSMeter         = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/dos", nil)
+	txAnnounceThrottledMeter   = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/throttled", nil)
 
 	txBroadcastInMeter          = metrics.NewRegisteredMeter("eth/fetcher/transaction/broadcasts/in", nil)
 	txBroadcastKnownMeter       = metrics.NewRegisteredMeter("eth/fetcher/transaction/broadcasts/known", nil)
@@ -187,6 +188,10 @@ type TxFetcher struct {
 	requests   map[string]*txRequest               // In-flight transaction retrievals
 	alternates map[common.Hash]map[string]struct{} // In-flight transaction alternate origins if retrieval fails
 
+	// Reputation of the peers based on the usefulness of their transactions,
+	// used to throttle the announcements of the low scoring ones.
+	scores *txPeerScores
+
 	// Callbacks
 	hasTx    func(common.Hash) bool             // Retrieves a tx from the local txpool
 	addTxs   func([]*types.Transaction) []error // Insert a batch of transactions into local txpool
@@ -223,6 +228,7 @@ func NewTxFetcherForTests(
 		requests:    make(map[string]*txRequest),
 		alternates:  make(map[common.Hash]map[string]struct{}),
 		underpriced: lru.NewCache[common.Hash, time.Time](maxTxUnderpricedSetSize),
+		scores:      newTxPeerScores(),
 		hasTx:       hasTx,
 		addTxs:      addTxs,
 		fetchTxs:    fetchTxs,
@@ -311,6 +317,11 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 	// Keep track of all the propagated transactions
 	inMeter.Mark(int64(len(txs)))
 
+	// Keep the score of the peer around while judging the delivery, even if the
+	// peer is dropped concurrently
+	f.scores.begin(peer)
+	defer f.scores.end(peer)
+
 	// Push all the transactions into the pool, tracking underpriced ones to avoid
 	// re-requesting them and dropping the peer in case of malicious transfers.
 	var (
@@ -324,6 +335,7 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 			end = len(txs)
 		}
 		var (
+			useful      int64
 			duplicate   int64
 			underpriced int64
 			otherreject int64
@@ -339,7 +351,8 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 			}
 			// Track a few interesting failure types
 			switch {
-			case err == nil: // Noop, but need to handle to not count these
+			case err == nil:
+				useful++
 
 			case errors.Is(err, txpool.ErrAlreadyKnown):
 				duplicate++
@@ -360,6 +373,10 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 		underpricedMeter.Mark(underpriced)
 		otherRejectMeter.Mark(otherreject)
 
+		// Judge the peer by the transactions the pool found useful or rejected,
+		// ignoring the known ones as those might have been simply late.
+		f.scores.record(peer, uint64(useful), uint64(underpriced), uint64(otherreject))
+
 		// If 'other reject' is >25% of the deliveries in any batch, sleep a bit.
 		if otherreject > 128/4 {
 			time.Sleep(200 * time.Millisecond)
@@ -385,6 +402,12 @@ func (f *TxFetcher) Drop(peer string) error {
 	}
 }
 
+// PeerScore retrieves the reputation of a peer based on the transactions it
+// delivered, or nil if the peer didn't deliver any judged transactions yet.
+func (f *TxFetcher) PeerScore(peer string) *TxPeerScore {
+	return f.scores.stats(peer)
+}
+
 // Start boots up the announcement based synchroniser, accepting and processing
 // hash notifications and block fetches until termination requested.
 func (f *TxFetcher) Start() {
@@ -412,21 +435,31 @@ func (f *TxFetcher) loop() {
 			// Note, we could but do not filter already known transactions here as
 			// the probability of something arriving between this call and the pre-
 			// filter outside is essentially zero.
+			//
+			// Peers mostly delivering transactions rejected by the pool get a much
+			// lower allowance, so they can't keep us busy retrieving junk.
+			var (
+				limit    = maxTxAnnounces
+				dosMeter = txAnnounceDOSMeter
+			)
+			if f.scores.throttled(ann.origin) {
+				limit, dosMeter = maxThrottledTxAnnounces, txAnnounceThrottledMeter
+			}
 			used := len(f.waitslots[ann.origin]) + len(f.announces[ann.origin])
-			if used >= maxTxAnnounces {
+			if used >= limit {
 				// This can happen if a set of transactions are requested but not
 				// all fulfilled, so the remainder are rescheduled without the cap
 				// check. Should be fine as the limit is in the thousands and the
 				// request size in the hundreds.
-				txAnnounceDOSMeter.Mark(int64(len(ann.hashes)))
+				dosMeter.Mark(int64(len(ann.hashes)))
 				break
 			}
 			want := used + len(ann.hashes)
-			if want > maxTxAnnounces {
-				txAnnounceDOSMeter.Mark(int64(want - maxTxAnnounces))
+			if want > limit {
+				dosMeter.Mark(int64(want - limit))
 
-				ann.hashes = ann.hashes[:want-maxTxAnnounces]
-				ann.metas = ann.metas[:want-maxTxAnnounces]
+				ann.hashes = ann.hashes[:limit-used]
+				ann.metas = ann.metas[:limit-used]
 			}
 			// All is well, schedule the remainder of the transactions
 			idleWait := len(f.waittime) == 0
@@ -711,6 +744,8 @@ func (f *TxFetcher) loop() {
 
 		case drop := <-f.drop:
 			// A peer was dropped, remove all traces of it
+			f.scores.remove(drop.peer)
+
 			if _, ok := f.waitslots[drop.peer]; ok {
 				for hash := range f.waitslots[drop.peer] {
 					delete(f.waitlist[hash], drop.peer)
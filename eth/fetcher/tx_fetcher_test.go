@@ -1159,6 +1159,135 @@ func TestTransactionFetcherDoSProtection(t *testing.T) {
 	})
 }
 
+// Tests that peers delivering mostly rejected transactions get scored down and
+// their announcements throttled, while well behaving peers are left alone.
+func TestTransactionFetcherPeerScoring(t *testing.T) {
+	// Create a batch of transactions to be rejected and a slew of announcements
+	var txs []*types.Transaction
+	for i := 0; i < txScoreMinSamples; i++ {
+		txs = append(txs, types.NewTransaction(uint64(i), common.Address{0x01}, new(big.Int), 0, new(big.Int), nil))
+	}
+	var hashesA, hashesB []common.Hash
+	for i := 0; i < maxThrottledTxAnnounces+1; i++ {
+		hashesA = append(hashesA, common.Hash{0x01, byte(i / 256), byte(i % 256)})
+		hashesB = append(hashesB, common.Hash{0x02, byte(i / 256), byte(i % 256)})
+	}
+	var fetcher *TxFetcher
+	testTransactionFetcherParallel(t, txFetcherTest{
+		init: func() *TxFetcher {
+			fetcher = NewTxFetcher(
+				func(common.Hash) bool { return false },
+				func(txs []*types.Transaction) []error {
+					errs := make([]error, len(txs))
+					for i := 0; i < len(errs); i++ {
+						errs[i] = txpool.ErrUnderpriced
+					}
+					return errs
+				},
+				func(string, []common.Hash) error { return nil },
+				nil,
+			)
+			return fetcher
+		},
+		steps: []interface{}{
+			// Deliver a batch of junk from one peer and ensure it gets scored down
+			doTxEnqueue{peer: "A", txs: txs, direct: false},
+			doFunc(func() {
+				score := fetcher.PeerScore("A")
+				if score == nil {
+					t.Fatalf("peer score missing")
+				}
+				if score.Underpriced != txScoreMinSamples || score.Useful != 0 || score.Rejected != 0 {
+					t.Errorf("peer counters mismatch: have %d/%d/%d, want %d/%d/%d", score.Useful, score.Underpriced, score.Rejected, 0, txScoreMinSamples, 0)
+				}
+				if !score.Throttled {
+					t.Errorf("peer not throttled, score %v", score.Score)
+				}
+				if score := fetcher.PeerScore("B"); score != nil {
+					t.Errorf("unjudged peer scored: %v", score)
+				}
+			}),
+			// Announce more transactions than the throttled allowance from both
+			// peers, ensure only the junk peer gets capped
+			doTxNotify{peer: "A", hashes: hashesA},
+			doTxNotify{peer: "B", hashes: hashesB},
+			isWaiting(map[string][]common.Hash{
+				"A": hashesA[:maxThrottledTxAnnounces],
+				"B": hashesB,
+			}),
+			// Drop the junk peer and ensure its score is gone
+			doDrop("A"),
+			doFunc(func() {
+				if score := fetcher.PeerScore("A"); score != nil {
+					t.Errorf("dropped peer score retained: %v", score)
+				}
+			}),
+		},
+	})
+}
+
+// Tests that a delivery judged while its peer is dropped doesn't resurrect the
+// score of the peer.
+func TestTransactionFetcherPeerScoringDrop(t *testing.T) {
+	var txs []*types.Transaction
+	for i := 0; i < txScoreMinSamples; i++ {
+		txs = append(txs, types.NewTransaction(uint64(i), common.Address{0x01}, new(big.Int), 0, new(big.Int), nil))
+	}
+	var (
+		adding  = make(chan struct{})
+		release = make(chan struct{})
+	)
+	fetcher := NewTxFetcher(
+		func(common.Hash) bool { return false },
+		func(txs []*types.Transaction) []error {
+			close(adding)
+			<-release
+			errs := make([]error, len(txs))
+			for i := 0; i < len(errs); i++ {
+				errs[i] = txpool.ErrUnderpriced
+			}
+			return errs
+		},
+		func(string, []common.Hash) error { return nil },
+		nil,
+	)
+	fetcher.Start()
+	defer fetcher.Stop()
+
+	done := make(chan error, 1)
+	go func() { done <- fetcher.Enqueue("A", txs, false) }()
+	<-adding
+
+	// Drop the peer while its delivery is being judged
+	if err := fetcher.Drop("A"); err != nil {
+		t.Fatalf("failed to drop peer: %v", err)
+	}
+	for i := 0; ; i++ {
+		fetcher.scores.lock.Lock()
+		dropped := fetcher.scores.peers["A"] != nil && fetcher.scores.peers["A"].dropped
+		fetcher.scores.lock.Unlock()
+		if dropped {
+			break
+		}
+		if i == 100 {
+			t.Fatal("peer drop not processed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("failed to enqueue transactions: %v", err)
+	}
+	if score := fetcher.PeerScore("A"); score != nil {
+		t.Errorf("dropped peer scored: %v", score)
+	}
+	fetcher.scores.lock.Lock()
+	defer fetcher.scores.lock.Unlock()
+	if len(fetcher.scores.peers) != 0 {
+		t.Errorf("dropped peer score retained: %d entries", len(fetcher.scores.peers))
+	}
+}
+
 // Tests that underpriced transactions don't get rescheduled after being rejected.
 func TestTransactionFetcherUnderpricedDedup(t *testing.T) {
 	testTransactionFetcherParallel(t, txFetcherTest{
@@ -0,0 +1,185 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import "sync"
+
+const (
+	// txScoreMinSamples is the number of judged transactions a peer needs to
+	// deliver before its score is taken into account. Until then, peers are
+	// given the benefit of the doubt.
+	txScoreMinSamples = 64
+
+	// txScoreWindow is the number of judged transactions after which the counters
+	// of a peer are halved, so its score follows its recent behaviour and a peer
+	// can recover from a bad period (or lose an old good reputation).
+	txScoreWindow = 4096
+
+	// txScoreThrottle is the score below which the announcements of a peer are
+	// throttled.
+	txScoreThrottle = 0.5
+
+	// maxThrottledTxAnnounces is the maximum number of unique transactions a
+	// throttled peer can have announced and not yet retrieved, in place of the
+	// maxTxAnnounces allowance of well behaving peers.
+	maxThrottledTxAnnounces = 256
+)
+
+// TxPeerScore is the reputation of a peer based on the transactions it delivered,
+// as judged by the transaction pool. Already known transactions are not judged.
+type TxPeerScore struct {
+	Useful      uint64  `json:"useful"`      // Transactions accepted into the pool
+	Underpriced uint64  `json:"underpriced"` // Transactions rejected as underpriced
+	Rejected    uint64  `json:"rejected"`    // Transactions rejected for any other reason
+	Score       float64 `json:"score"`       // Ratio of useful transactions, 1 if not enough samples
+	Throttled   bool    `json:"throttled"`   // Whether the announcements of the peer are throttled
+}
+
+// txPeerScore tracks the delivered transactions of a single peer.
+type txPeerScore struct {
+	useful      uint64
+	underpriced uint64
+	rejected    uint64
+
+	delivering int  // Number of deliveries of the peer being processed
+	dropped    bool // Whether the peer was dropped while delivering
+}
+
+// score calculates the ratio of useful transactions delivered by the peer. Being
+// underpriced depends on the local pool configuration as much as on the peer, so
+// such rejections only weigh half of the other ones.
+func (s *txPeerScore) score() float64 {
+	if s.useful+s.underpriced+s.rejected < txScoreMinSamples {
+		return 1
+	}
+	useful := float64(s.useful)
+	return useful / (useful + float64(s.underpriced)/2 + float64(s.rejected))
+}
+
+// txPeerScores is the set of transaction delivery scores of the connected peers.
+// It is accessed both by the fetcher loop and the peer goroutines delivering
+// transactions, hence protected by its own lock. The score of a peer dropped while
+// one of its deliveries is processed is kept until the delivery ends, so that the
+// late records don't resurrect it.
+type txPeerScores struct {
+	peers map[string]*txPeerScore
+	lock  sync.Mutex
+}
+
+// newTxPeerScores creates an empty set of peer scores.
+func newTxPeerScores() *txPeerScores {
+	return &txPeerScores{peers: make(map[string]*txPeerScore)}
+}
+
+// begin marks the start of a delivery of a peer, whose judged transactions are
+// recorded until the matching end.
+func (s *txPeerScores) begin(peer string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := s.peers[peer]
+	if score == nil {
+		score = new(txPeerScore)
+		s.peers[peer] = score
+	}
+	score.delivering++
+}
+
+// end marks the end of a delivery of a peer, deleting the score of the peer if
+// it was dropped in the meantime.
+func (s *txPeerScores) end(peer string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := s.peers[peer]
+	if score == nil {
+		return
+	}
+	if score.delivering--; score.delivering == 0 && score.dropped {
+		delete(s.peers, peer)
+	}
+}
+
+// record accounts a batch of judged transactions delivered by a peer. Batches
+// of unknown or dropped peers are ignored.
+func (s *txPeerScores) record(peer string, useful, underpriced, rejected uint64) {
+	if useful+underpriced+rejected == 0 {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := s.peers[peer]
+	if score == nil || score.dropped {
+		return
+	}
+	score.useful += useful
+	score.underpriced += underpriced
+	score.rejected += rejected
+
+	for score.useful+score.underpriced+score.rejected > txScoreWindow {
+		score.useful /= 2
+		score.underpriced /= 2
+		score.rejected /= 2
+	}
+}
+
+// throttled reports whether the announcements of a peer should be throttled.
+func (s *txPeerScores) throttled(peer string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := s.peers[peer]
+	return score != nil && score.score() < txScoreThrottle
+}
+
+// stats retrieves the reputation of a peer, or nil if it didn't deliver any
+// judged transactions yet.
+func (s *txPeerScores) stats(peer string) *TxPeerScore {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := s.peers[peer]
+	if score == nil || score.dropped || score.useful+score.underpriced+score.rejected == 0 {
+		return nil
+	}
+	ratio := score.score()
+	return &TxPeerScore{
+		Useful:      score.useful,
+		Underpriced: score.underpriced,
+		Rejected:    score.rejected,
+		Score:       ratio,
+		Throttled:   ratio < txScoreThrottle,
+	}
+}
+
+// remove deletes the score of a disconnected peer, or marks it for deletion at
+// the end of the deliveries still being processed.
+func (s *txPeerScores) remove(peer string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	score := s.peers[peer]
+	if score == nil {
+		return
+	}
+	if score.delivering > 0 {
+		score.dropped = true
+		return
+	}
+	delete(s.peers, peer)
+}
@@ -22,6 +22,9 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -61,7 +64,7 @@ func TestFeeHistory(t *testing.T) {
 		backend := newTestBackend(t, big.NewInt(16), c.pending)
 		oracle := NewOracle(backend, config)
 
-		first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
+		first, reward, baseFee, ratio, blobBaseFee, blobRatio, err := oracle.FeeHistory(context.Background(), c.count, c.last, c.percent)
 		backend.teardown()
 		expReward := c.expCount
 		if len(c.percent) == 0 {
@@ -84,8 +87,91 @@ func TestFeeHistory(t *testing.T) {
 		if len(ratio) != c.expCount {
 			t.Fatalf("Test case %d: gasUsedRatio array length mismatch, want %d, got %d", i, c.expCount, len(ratio))
 		}
+		if len(blobBaseFee) != expBaseFee {
+			t.Fatalf("Test case %d: blobBaseFee array length mismatch, want %d, got %d", i, expBaseFee, len(blobBaseFee))
+		}
+		if len(blobRatio) != c.expCount {
+			t.Fatalf("Test case %d: blobGasUsedRatio array length mismatch, want %d, got %d", i, c.expCount, len(blobRatio))
+		}
 		if err != c.expErr && !errors.Is(err, c.expErr) {
 			t.Fatalf("Test case %d: error mismatch, want %v, got %v", i, c.expErr, err)
 		}
 	}
 }
+
+// Tests that the pending block is estimated from the pool contents if enabled,
+// falling back to the miner's pending block otherwise.
+func TestFeeHistoryPoolPending(t *testing.T) {
+	var cases = []struct {
+		fromPool      bool
+		pending, pool bool
+		expCount      int
+	}{
+		{false, false, true, 1},
+		{false, true, false, 2},
+		{true, false, true, 2},
+		{true, true, false, 1},
+	}
+	for i, c := range cases {
+		backend := newTestBackend(t, big.NewInt(16), c.pending)
+		backend.pool = c.pool
+		oracle := NewOracle(backend, Config{MaxHeaderHistory: 1000, MaxBlockHistory: 1000, PendingFromPool: c.fromPool})
+
+		_, _, _, ratio, _, _, err := oracle.FeeHistory(context.Background(), 2, rpc.PendingBlockNumber, nil)
+		backend.teardown()
+		if err != nil {
+			t.Fatalf("Test case %d: failed to retrieve fee history: %v", i, err)
+		}
+		if len(ratio) != c.expCount {
+			t.Errorf("Test case %d: block count mismatch, want %d, got %d", i, c.expCount, len(ratio))
+		}
+	}
+}
+
+// cancunBackend is a test backend overriding the chain config to have Cancun
+// active, without the test chain having to contain blob transactions.
+type cancunBackend struct {
+	*testBackend
+	config *params.ChainConfig
+}
+
+func (b *cancunBackend) ChainConfig() *params.ChainConfig {
+	return b.config
+}
+
+// Tests that the blob gas statistics of a block are reported, including the
+// blob base fee of the next block.
+func TestFeeHistoryBlobStats(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(0), false)
+	defer backend.teardown()
+
+	config := *backend.ChainConfig()
+	config.CancunTime = new(uint64)
+	oracle := NewOracle(&cancunBackend{backend, &config}, Config{})
+
+	var (
+		excess = uint64(10 * params.BlobTxTargetBlobGasPerBlock)
+		used   = uint64(params.MaxBlobGasPerBlock)
+		fees   = &blockFees{
+			blockNumber: 1,
+			header: &types.Header{
+				Number:        big.NewInt(1),
+				GasLimit:      params.GenesisGasLimit,
+				BaseFee:       big.NewInt(params.InitialBaseFee),
+				ExcessBlobGas: &excess,
+				BlobGasUsed:   &used,
+			},
+		}
+	)
+	oracle.processBlock(fees, nil)
+
+	if want := eip4844.CalcBlobFee(excess); fees.results.blobBaseFee.Cmp(want) != 0 {
+		t.Errorf("blob base fee mismatch: have %v, want %v", fees.results.blobBaseFee, want)
+	}
+	if want := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(excess, used)); fees.results.nextBlobBaseFee.Cmp(want) != 0 {
+		t.Errorf("next blob base fee mismatch: have %v, want %v", fees.results.nextBlobBaseFee, want)
+	}
+	if fees.results.blobGasUsedRatio != 1 {
+		t.Errorf("blob gas used ratio mismatch: have %v, want 1", fees.results.blobGasUsedRatio)
+	}
+}
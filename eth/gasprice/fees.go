@@ -0,0 +1,174 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	errInvalidFeeSpeed = errors.New("invalid fee speed")
+	errNoFeeMarket     = errors.New("fee market not active")
+)
+
+// FeeSpeed is the desired inclusion speed of a transaction, trading off the fees
+// paid against the number of blocks it might need to wait for inclusion.
+type FeeSpeed string
+
+const (
+	FeeSpeedSlow     FeeSpeed = "slow"
+	FeeSpeedStandard FeeSpeed = "standard"
+	FeeSpeedFast     FeeSpeed = "fast"
+)
+
+// feeMode is the parametrization of the fee suggestion for an inclusion speed.
+type feeMode struct {
+	percentile float64 // Percentile of the tips paid in recent blocks to match
+	horizon    uint64  // Number of consecutive full blocks the fee caps must survive
+}
+
+// feeModes are the fee suggestion parameters of the supported inclusion speeds.
+// The standard horizon of 6 blocks roughly doubles the base fee (1.125^6), which
+// is the slack traditionally used when filling in transaction defaults.
+var feeModes = map[FeeSpeed]feeMode{
+	FeeSpeedSlow:     {percentile: 10, horizon: 2},
+	FeeSpeedStandard: {percentile: 50, horizon: 6},
+	FeeSpeedFast:     {percentile: 90, horizon: 12},
+}
+
+// SuggestedFees is a complete set of fee parameters for a transaction to be
+// included at a desired speed.
+type SuggestedFees struct {
+	BaseFee              *big.Int // Base fee of the next block
+	BlobBaseFee          *big.Int // Blob base fee of the next block, nil before Cancun
+	MaxFeePerGas         *big.Int // Fee cap covering the projected base fee and the tip
+	MaxPriorityFeePerGas *big.Int // Tip matching the ones paid in recent blocks
+	MaxFeePerBlobGas     *big.Int // Blob fee cap covering the projected blob base fee, nil before Cancun
+}
+
+// SuggestFees returns a complete set of fee parameters for a transaction to be
+// included at the requested speed.
+//
+// The tip is picked as a percentile of the tips paid in recent blocks, the same
+// percentile being used both within and across the blocks. The fee caps are set
+// to the base fee and blob base fee reached after a number of consecutive full
+// blocks, so the transaction does not become unexecutable if the fees rise while
+// it is waiting for inclusion. Faster speeds pay higher tips and tolerate longer
+// fee increases.
+func (oracle *Oracle) SuggestFees(ctx context.Context, speed FeeSpeed) (*SuggestedFees, error) {
+	mode, ok := feeModes[speed]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errInvalidFeeSpeed, speed)
+	}
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	config := oracle.backend.ChainConfig()
+	next := new(big.Int).Add(head.Number, common.Big1)
+	if !config.IsLondon(next) {
+		return nil, errNoFeeMarket
+	}
+	tip, err := oracle.suggestTipPercentile(ctx, mode.percentile)
+	if err != nil {
+		return nil, err
+	}
+	fees := &SuggestedFees{
+		BaseFee:              eip1559.CalcBaseFee(config, head),
+		MaxPriorityFeePerGas: tip,
+	}
+	fees.MaxFeePerGas = new(big.Int).Add(projectBaseFee(config, head, mode.horizon), tip)
+
+	if config.IsCancun(next, head.Time) && head.ExcessBlobGas != nil && head.BlobGasUsed != nil {
+		excess := eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, *head.BlobGasUsed)
+		fees.BlobBaseFee = eip4844.CalcBlobFee(excess)
+		fees.MaxFeePerBlobGas = projectBlobFee(excess, mode.horizon)
+	}
+	return fees, nil
+}
+
+// suggestTipPercentile picks the requested percentile of the tips paid in each of
+// the recent blocks, and then the same percentile across the blocks. Empty blocks
+// and tips under the ignore threshold are skipped. If there is nothing to sample,
+// the regular tip suggestion is used.
+func (oracle *Oracle) suggestTipPercentile(ctx context.Context, percentile float64) (*big.Int, error) {
+	_, rewards, _, gasUsedRatios, _, _, err := oracle.FeeHistory(ctx, uint64(oracle.checkBlocks), rpc.LatestBlockNumber, []float64{percentile})
+	if err != nil {
+		return nil, err
+	}
+	var tips []*big.Int
+	for i, reward := range rewards {
+		if gasUsedRatios[i] == 0 || len(reward) == 0 || reward[0] == nil {
+			continue
+		}
+		if reward[0].Cmp(oracle.ignorePrice) < 0 {
+			continue
+		}
+		tips = append(tips, reward[0])
+	}
+	if len(tips) == 0 {
+		return oracle.SuggestTipCap(ctx)
+	}
+	slices.SortFunc(tips, func(a, b *big.Int) int { return a.Cmp(b) })
+	tip := tips[int(float64(len(tips)-1)*percentile/100)]
+	if tip.Cmp(oracle.maxPrice) > 0 {
+		tip = oracle.maxPrice
+	}
+	return new(big.Int).Set(tip), nil
+}
+
+// projectBaseFee calculates the base fee reached after the given number of blocks
+// following the head, assuming all of them are full.
+func projectBaseFee(config *params.ChainConfig, head *types.Header, blocks uint64) *big.Int {
+	parent := &types.Header{
+		Number:   head.Number,
+		GasLimit: head.GasLimit,
+		GasUsed:  head.GasUsed,
+		BaseFee:  head.BaseFee,
+	}
+	for i := uint64(0); i < blocks; i++ {
+		fee := eip1559.CalcBaseFee(config, parent)
+		parent = &types.Header{
+			Number:   new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit: parent.GasLimit,
+			GasUsed:  parent.GasLimit,
+			BaseFee:  fee,
+		}
+	}
+	return parent.BaseFee
+}
+
+// projectBlobFee calculates the blob base fee reached after the given number of
+// blocks, starting from the excess blob gas of the next one and assuming all the
+// blocks in between are full of blobs.
+func projectBlobFee(excessBlobGas uint64, blocks uint64) *big.Int {
+	for i := uint64(1); i < blocks; i++ {
+		excessBlobGas = eip4844.CalcExcessBlobGas(excessBlobGas, params.MaxBlobGasPerBlock)
+	}
+	return eip4844.CalcBlobFee(excessBlobGas)
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestSuggestFees(t *testing.T) {
+	config := Config{
+		Blocks:          20,
+		MaxBlockHistory: 1000,
+		Default:         big.NewInt(params.GWei),
+	}
+	backend := newTestBackend(t, big.NewInt(0), false)
+	defer backend.teardown()
+	oracle := NewOracle(backend, config)
+
+	head, _ := backend.HeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	next := eip1559.CalcBaseFee(backend.ChainConfig(), head)
+
+	// Block N pays a tip of N gwei, the sampled blocks are 13..32
+	var cases = []struct {
+		speed FeeSpeed
+		tip   int64
+	}{
+		{FeeSpeedSlow, 14},
+		{FeeSpeedStandard, 22},
+		{FeeSpeedFast, 30},
+	}
+	for _, c := range cases {
+		fees, err := oracle.SuggestFees(context.Background(), c.speed)
+		if err != nil {
+			t.Fatalf("speed %s: failed to suggest fees: %v", c.speed, err)
+		}
+		if want := big.NewInt(c.tip * params.GWei); fees.MaxPriorityFeePerGas.Cmp(want) != 0 {
+			t.Errorf("speed %s: tip mismatch: have %v, want %v", c.speed, fees.MaxPriorityFeePerGas, want)
+		}
+		if fees.BaseFee.Cmp(next) != 0 {
+			t.Errorf("speed %s: base fee mismatch: have %v, want %v", c.speed, fees.BaseFee, next)
+		}
+		// The fee cap should cover the base fee rising by 12.5% with every full
+		// block after the next one
+		projected := new(big.Int).Set(next)
+		for i := uint64(1); i < feeModes[c.speed].horizon; i++ {
+			projected.Add(projected, new(big.Int).Div(projected, big.NewInt(8)))
+		}
+		if want := new(big.Int).Add(projected, fees.MaxPriorityFeePerGas); fees.MaxFeePerGas.Cmp(want) != 0 {
+			t.Errorf("speed %s: fee cap mismatch: have %v, want %v", c.speed, fees.MaxFeePerGas, want)
+		}
+		if fees.BlobBaseFee != nil || fees.MaxFeePerBlobGas != nil {
+			t.Errorf("speed %s: blob fees suggested before Cancun", c.speed)
+		}
+	}
+	if _, err := oracle.SuggestFees(context.Background(), "instant"); !errors.Is(err, errInvalidFeeSpeed) {
+		t.Errorf("invalid speed error mismatch: have %v, want %v", err, errInvalidFeeSpeed)
+	}
+}
+
+func TestSuggestFeesPreLondon(t *testing.T) {
+	backend := newTestBackend(t, nil, false)
+	defer backend.teardown()
+	oracle := NewOracle(backend, Config{Blocks: 20, MaxBlockHistory: 1000})
+
+	if _, err := oracle.SuggestFees(context.Background(), FeeSpeedStandard); !errors.Is(err, errNoFeeMarket) {
+		t.Errorf("pre-London error mismatch: have %v, want %v", err, errNoFeeMarket)
+	}
+}
+
+func TestProjectBlobFee(t *testing.T) {
+	excess := uint64(10 * params.BlobTxTargetBlobGasPerBlock)
+
+	if have, want := projectBlobFee(excess, 1), eip4844.CalcBlobFee(excess); have.Cmp(want) != 0 {
+		t.Errorf("next block blob fee mismatch: have %v, want %v", have, want)
+	}
+	// Full blocks raise the excess blob gas by the difference of the max and the
+	// target blob gas
+	want := eip4844.CalcBlobFee(excess + 5*(params.MaxBlobGasPerBlock-params.BlobTxTargetBlobGasPerBlock))
+	if have := projectBlobFee(excess, 6); have.Cmp(want) != 0 {
+		t.Errorf("projected blob fee mismatch: have %v, want %v", have, want)
+	}
+}
@@ -47,6 +47,10 @@ type Config struct {
 	Default          *big.Int `toml:",omitempty"`
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
+
+	// PendingFromPool makes the fee history of the pending block be estimated
+	// from the transaction pool contents instead of the miner's pending block.
+	PendingFromPool bool
 }
 
 // OracleBackend includes all necessary background APIs for oracle.
@@ -59,6 +63,15 @@ type OracleBackend interface {
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
+// PoolBackend is an optional extension of OracleBackend, implemented by the
+// backends able to estimate the next block from their transaction pool.
+type PoolBackend interface {
+	// PoolBlockAndReceipts estimates the next block by ordering the executable
+	// transactions of the pool the way the miner would, without executing them.
+	// The receipts only carry the gas used, estimated to be the gas limits.
+	PoolBlockAndReceipts() (*types.Block, types.Receipts)
+}
+
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
@@ -67,6 +80,7 @@ type Oracle struct {
 	lastPrice   *big.Int
 	maxPrice    *big.Int
 	ignorePrice *big.Int
+	poolPending bool
 	cacheLock   sync.RWMutex
 	fetchLock   sync.Mutex
 
@@ -133,6 +147,7 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		lastPrice:        params.Default,
 		maxPrice:         maxPrice,
 		ignorePrice:      ignorePrice,
+		poolPending:      params.PendingFromPool,
 		checkBlocks:      blocks,
 		percentile:       percent,
 		maxHeaderHistory: maxHeaderHistory,
@@ -231,7 +246,18 @@ type results struct {
 // and sends it to the result channel. If the block is empty or all transactions
 // are sent by the miner itself(it doesn't make any sense to include this kind of
 // transaction prices for sampling), nil gasprice is returned.
+//
+// The sampled prices are cached along with the fee history of the block, so the
+// same block is not retrieved and sorted again on the next head.
 func (oracle *Oracle) getBlockValues(ctx context.Context, blockNum uint64, limit int, ignoreUnder *big.Int, result chan results, quit chan struct{}) {
+	key := cacheKey{number: blockNum, tipSamples: true}
+	if fees, ok := oracle.historyCache.Get(key); ok {
+		select {
+		case result <- results{fees.tips, nil}:
+		case <-quit:
+		}
+		return
+	}
 	block, err := oracle.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
 	if block == nil {
 		select {
@@ -269,6 +295,8 @@ func (oracle *Oracle) getBlockValues(ctx context.Context, blockNum uint64, limit
 			}
 		}
 	}
+	oracle.historyCache.Add(key, processedFees{tips: prices})
+
 	select {
 	case result <- results{prices, nil}:
 	case <-quit:
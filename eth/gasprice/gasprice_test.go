@@ -39,6 +39,7 @@ const testHead = 32
 type testBackend struct {
 	chain   *core.BlockChain
 	pending bool // pending block available
+	pool    bool // pending block estimate from the pool available
 }
 
 func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
@@ -105,6 +106,14 @@ func (b *testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return nil, nil
 }
 
+func (b *testBackend) PoolBlockAndReceipts() (*types.Block, types.Receipts) {
+	if b.pool {
+		block := b.chain.GetBlockByNumber(testHead + 1)
+		return block, b.chain.GetReceiptsByHash(block.Hash())
+	}
+	return nil, nil
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chain.Config()
 }
@@ -213,3 +222,33 @@ func TestSuggestTipCap(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the tips sampled for the suggestion are cached alongside the fee
+// history, and reused instead of reprocessing the blocks.
+func TestSuggestTipCapCache(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(0), false)
+	defer backend.teardown()
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	if _, err := oracle.SuggestTipCap(context.Background()); err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	// Tamper with the cached samples and ensure they are used on a new head
+	tip := big.NewInt(100 * params.GWei)
+	for number := uint64(testHead - 2); number <= testHead; number++ {
+		key := cacheKey{number: number, tipSamples: true}
+		if _, ok := oracle.historyCache.Peek(key); !ok {
+			t.Fatalf("Tip samples of block %d not cached", number)
+		}
+		oracle.historyCache.Add(key, processedFees{tips: []*big.Int{tip, tip, tip}})
+	}
+	oracle.lastHead = common.Hash{}
+
+	got, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	if got.Cmp(tip) != 0 {
+		t.Fatalf("Gas price mismatch, want %d, got %d", tip, got)
+	}
+}
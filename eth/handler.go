@@ -75,6 +75,10 @@ type txPool interface {
 	// The slice should be modifiable by the caller.
 	Pending(enforceTips bool) map[common.Address][]*txpool.LazyTransaction
 
+	// IsPrivate returns whether the transaction with the given hash is a private
+	// one, which must not be announced to the network.
+	IsPrivate(hash common.Hash) bool
+
 	// SubscribeTransactions subscribes to new transaction events. The subscriber
 	// can decide whether to receive notifications only for newly seen transactions
 	// or also for reorged out ones.
@@ -604,6 +608,7 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	var (
 		blobTxs  int // Number of blob transactions to announce only
 		largeTxs int // Number of large transactions to announce only
+		privTxs  int // Number of private transactions to withhold
 
 		directCount int // Number of transactions sent directly to peers (duplicates included)
 		directPeers int // Number of peers that were sent transactions directly
@@ -615,6 +620,11 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		// Private transactions are held locally, never leak them
+		if h.txpool.IsPrivate(tx.Hash()) {
+			privTxs++
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
 
 		var numDirect int
@@ -645,7 +655,7 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
-	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs, "blobtxs", blobTxs, "largetxs", largeTxs,
+	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs-privTxs, "blobtxs", blobTxs, "largetxs", largeTxs, "privtxs", privTxs,
 		"bcastpeers", directPeers, "bcastcount", directCount, "annpeers", annPeers, "anncount", annCount)
 }
 
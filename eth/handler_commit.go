@@ -0,0 +1,44 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/authority"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// commitHandler implements the commit.Backend interface to verify the commit
+// signatures gossiped between the validators of an authority chain.
+type commitHandler struct {
+	chain  *core.BlockChain
+	engine *authority.Authority
+}
+
+// SubmitCommit verifies a commit signature received from a remote peer and adds
+// it to the commit pool of the engine.
+func (h *commitHandler) SubmitCommit(hash common.Hash, sig []byte) error {
+	_, err := h.engine.SubmitCommit(h.chain, hash, sig)
+	return err
+}
+
+// SubscribeCommits subscribes to the commit signatures added to the commit pool
+// of the engine.
+func (h *commitHandler) SubscribeCommits(ch chan<- authority.CommitEvent) event.Subscription {
+	return h.engine.SubscribeCommits(ch)
+}
@@ -370,6 +370,96 @@ func testSendTransactions(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that private transactions are never announced to a newly connected peer,
+// nor served to it on request.
+func TestSendPrivateTransactions68(t *testing.T) { testSendPrivateTransactions(t, eth.ETH68) }
+
+func testSendPrivateTransactions(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	// Create a message handler and fill the pool with public and private transactions
+	handler := newTestHandler()
+	defer handler.close()
+
+	var public, private []*types.Transaction
+	for nonce := 0; nonce < 20; nonce++ {
+		tx := types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+		if nonce%2 == 0 {
+			public = append(public, tx)
+		} else {
+			private = append(private, tx)
+		}
+	}
+	go handler.txpool.Add(public, false, false) // Need goroutine to not block on feed
+	go handler.txpool.AddPrivate(private)       // Need goroutine to not block on feed
+	time.Sleep(250 * time.Millisecond)          // Wait until tx events get out of the system
+
+	// Create a source handler to send messages through and a sink peer to receive them
+	p2pSrc, p2pSink := p2p.MsgPipe()
+	defer p2pSrc.Close()
+	defer p2pSink.Close()
+
+	src := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{1}, "", nil, p2pSrc), p2pSrc, handler.txpool)
+	sink := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{2}, "", nil, p2pSink), p2pSink, handler.txpool)
+	defer src.Close()
+	defer sink.Close()
+
+	go handler.handler.runEthPeer(src, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(handler.handler), peer)
+	})
+	// Run the handshake locally to avoid spinning up a source handler
+	var (
+		genesis = handler.chain.Genesis()
+		head    = handler.chain.CurrentBlock()
+		td      = handler.chain.GetTd(head.Hash(), head.Number.Uint64())
+	)
+	if err := sink.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain)); err != nil {
+		t.Fatalf("failed to run protocol handshake")
+	}
+	backend := new(testEthHandler)
+
+	anns := make(chan []common.Hash)
+	annSub := backend.txAnnounces.Subscribe(anns)
+	defer annSub.Unsubscribe()
+
+	go eth.Handle(backend, sink)
+
+	// Make sure only the public transactions get announced
+	seen := make(map[common.Hash]struct{})
+	for len(seen) < len(public) {
+		select {
+		case hashes := <-anns:
+			for _, hash := range hashes {
+				seen[hash] = struct{}{}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("public transactions not announced: have %d, want %d", len(seen), len(public))
+		}
+	}
+	for _, tx := range private {
+		if _, ok := seen[tx.Hash()]; ok {
+			t.Errorf("private transaction announced: %x", tx.Hash())
+		}
+	}
+	// Request a private transaction explicitly and ensure it's not served
+	bcasts := make(chan []*types.Transaction)
+	bcastSub := backend.txBroadcasts.Subscribe(bcasts)
+	defer bcastSub.Unsubscribe()
+
+	if err := sink.RequestTxs([]common.Hash{private[0].Hash(), public[0].Hash()}); err != nil {
+		t.Fatalf("failed to request transactions: %v", err)
+	}
+	select {
+	case txs := <-bcasts:
+		if len(txs) != 1 || txs[0].Hash() != public[0].Hash() {
+			t.Errorf("served transactions mismatch: have %d, want only the public one", len(txs))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("transactions not served")
+	}
+}
+
 // Tests that transactions get propagated to all attached peers, either via direct
 // broadcasts or via announcements/retrievals.
 func TestTransactionPropagation68(t *testing.T) { testTransactionPropagation(t, eth.ETH68) }
@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"github.com/ethereum/go-ethereum/eth/fetcher"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 )
@@ -25,6 +26,8 @@ import (
 // about a connected peer.
 type ethPeerInfo struct {
 	Version uint `json:"version"` // Ethereum protocol version negotiated
+
+	Transactions *fetcher.TxPeerScore `json:"transactions,omitempty"` // Reputation based on the delivered transactions
 }
 
 // ethPeer is a wrapper around eth.Peer to maintain a few extra metadata.
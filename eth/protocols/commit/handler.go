@@ -0,0 +1,201 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package commit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/authority"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// errPeerAlreadyRegistered is returned if a peer is attempted to be added to the
+// handler, but one with the same id already exists.
+var errPeerAlreadyRegistered = errors.New("peer already registered")
+
+// Backend defines the methods needed to verify the commit signatures received
+// from remote peers and to learn about the ones to propagate.
+type Backend interface {
+	// SubmitCommit verifies a commit signature over a block and adds it to the
+	// local commit pool.
+	SubmitCommit(hash common.Hash, sig []byte) error
+
+	// SubscribeCommits subscribes to the commit signatures newly added to the
+	// local commit pool, which are to be propagated to the remote peers.
+	SubscribeCommits(ch chan<- authority.CommitEvent) event.Subscription
+}
+
+// Handler gossips the commit signatures of the validators between the peers
+// supporting the `commit` protocol. Every signature newly added to the local
+// commit pool, whether made locally or received from a peer, is relayed to all
+// the peers not known to have it yet.
+type Handler struct {
+	backend Backend
+
+	peers map[string]*Peer
+	lock  sync.RWMutex
+
+	sub event.Subscription
+	wg  sync.WaitGroup
+}
+
+// NewHandler creates a commit gossip handler on top of the given backend.
+func NewHandler(backend Backend) *Handler {
+	return &Handler{
+		backend: backend,
+		peers:   make(map[string]*Peer),
+	}
+}
+
+// MakeProtocols constructs the P2P protocol definitions for `commit`.
+func (h *Handler) MakeProtocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure
+
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  protocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return h.RunPeer(NewPeer(version, p, rw))
+			},
+			NodeInfo: func() interface{} {
+				return nil
+			},
+			PeerInfo: func(id enode.ID) interface{} {
+				return nil
+			},
+		}
+	}
+	return protocols
+}
+
+// Start subscribes to the local commit pool and starts propagating the new
+// commit signatures to the connected peers.
+func (h *Handler) Start() {
+	commits := make(chan authority.CommitEvent, maxQueuedCommits)
+	h.sub = h.backend.SubscribeCommits(commits)
+
+	h.wg.Add(1)
+	go h.broadcastLoop(commits)
+}
+
+// Stop terminates the commit propagation.
+func (h *Handler) Stop() {
+	h.sub.Unsubscribe()
+	h.wg.Wait()
+}
+
+// broadcastLoop propagates the commit signatures newly added to the local pool
+// to all the peers not knowing about them yet.
+func (h *Handler) broadcastLoop(commits chan authority.CommitEvent) {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case ev := <-commits:
+			h.BroadcastCommit(&Commit{Hash: ev.Hash, Signature: ev.Signature})
+		case <-h.sub.Err():
+			return
+		}
+	}
+}
+
+// BroadcastCommit queues a commit signature for propagation to all the peers
+// not known to have it yet.
+func (h *Handler) BroadcastCommit(commit *Commit) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	for _, peer := range h.peers {
+		if !peer.knownCommit(commit) {
+			peer.AsyncSendCommit(commit)
+		}
+	}
+}
+
+// RunPeer registers a `commit` peer and handles its inbound messages until the
+// connection is torn down.
+func (h *Handler) RunPeer(peer *Peer) error {
+	h.lock.Lock()
+	if _, ok := h.peers[peer.id]; ok {
+		h.lock.Unlock()
+		return errPeerAlreadyRegistered
+	}
+	h.peers[peer.id] = peer
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.peers, peer.id)
+		h.lock.Unlock()
+
+		close(peer.term)
+	}()
+	go peer.broadcastCommits()
+
+	for {
+		if err := h.handleMessage(peer); err != nil {
+			peer.Log().Debug("Message handling failed in `commit`", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer on the `commit` protocol. The remote connection is torn down upon
+// returning any error.
+func (h *Handler) handleMessage(peer *Peer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case CommitsMsg:
+		var commits CommitsPacket
+		if err := msg.Decode(&commits); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		for _, commit := range commits {
+			if commit == nil {
+				return fmt.Errorf("%w: nil commit", errDecode)
+			}
+			// Commits may be over blocks not yet imported locally or made by
+			// validators of another epoch, drop those without penalizing the peer
+			peer.markCommit(commit)
+			if err := h.backend.SubmitCommit(commit.Hash, commit.Signature); err != nil {
+				peer.Log().Trace("Dropped commit", "hash", commit.Hash, "err", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %v", errInvalidMsgCode, msg.Code)
+	}
+}
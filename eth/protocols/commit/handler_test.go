@@ -0,0 +1,129 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package commit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/authority"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// testBackend is a commit pool announcing every newly submitted commit, akin to
+// the authority engine.
+type testBackend struct {
+	feed    event.Feed
+	commits map[common.Hash][]byte
+	lock    sync.Mutex
+}
+
+func newTestBackend() *testBackend {
+	return &testBackend{commits: make(map[common.Hash][]byte)}
+}
+
+func (b *testBackend) SubmitCommit(hash common.Hash, sig []byte) error {
+	b.lock.Lock()
+	_, known := b.commits[hash]
+	b.commits[hash] = sig
+	b.lock.Unlock()
+
+	if !known {
+		b.feed.Send(authority.CommitEvent{Hash: hash, Signature: sig})
+	}
+	return nil
+}
+
+func (b *testBackend) SubscribeCommits(ch chan<- authority.CommitEvent) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+func (b *testBackend) count() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return len(b.commits)
+}
+
+// connect links two handlers via an in-memory message pipe.
+func connect(a, b *Handler, aid, bid string) {
+	arw, brw := p2p.MsgPipe()
+	go a.RunPeer(NewFakePeer(COMMIT1, bid, arw))
+	go b.RunPeer(NewFakePeer(COMMIT1, aid, brw))
+}
+
+// waitPeers waits until the given number of peers is registered in the handler.
+func waitPeers(t *testing.T, h *Handler, n int) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		h.lock.RLock()
+		have := len(h.peers)
+		h.lock.RUnlock()
+
+		if have == n {
+			return
+		}
+	}
+	t.Fatalf("peer count mismatch: want %d", n)
+}
+
+// Tests that commit signatures are relayed across the network, but not sent back
+// to the peers they were received from.
+func TestCommitGossip(t *testing.T) {
+	var (
+		backends = []*testBackend{newTestBackend(), newTestBackend(), newTestBackend()}
+		handlers = make([]*Handler, len(backends))
+		ids      = []string{"aaaaaaaaaaaa", "bbbbbbbbbbbb", "cccccccccccc"}
+	)
+	for i, backend := range backends {
+		handlers[i] = NewHandler(backend)
+		handlers[i].Start()
+		defer handlers[i].Stop()
+	}
+	// Connect the handlers in a line: 0 <-> 1 <-> 2
+	connect(handlers[0], handlers[1], ids[0], ids[1])
+	connect(handlers[1], handlers[2], ids[1], ids[2])
+
+	waitPeers(t, handlers[0], 1)
+	waitPeers(t, handlers[1], 2)
+	waitPeers(t, handlers[2], 1)
+
+	// Make a commit on the first node and wait until it reaches the last one
+	backends[0].SubmitCommit(common.Hash{0x01}, []byte{0x02})
+
+	timeout := time.After(5 * time.Second)
+	for backends[2].count() == 0 {
+		select {
+		case <-timeout:
+			t.Fatalf("commit not relayed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	// The relaying node must not echo the commit back to its source
+	handlers[1].lock.RLock()
+	defer handlers[1].lock.RUnlock()
+
+	for _, peer := range handlers[1].peers {
+		if !peer.knownCommit(&Commit{Hash: common.Hash{0x01}, Signature: []byte{0x02}}) {
+			t.Errorf("peer %s: commit not marked known", peer.id)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package commit
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	// maxKnownCommits is the maximum commit signatures to keep in the known list
+	// before starting to randomly evict them.
+	maxKnownCommits = 4096
+
+	// maxQueuedCommits is the maximum number of commit signatures to queue up
+	// before dropping broadcasts.
+	maxQueuedCommits = 256
+)
+
+// Peer is a collection of relevant information we have about a `commit` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for commit
+	version   uint              // Protocol version negotiated
+
+	known *lru.Cache[common.Hash, struct{}] // Set of commit signatures known to be known by this peer
+	queue chan *Commit                      // Queue of commit signatures to broadcast to the peer
+	term  chan struct{}                     // Termination channel to stop the broadcaster
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// NewPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	peer := NewFakePeer(version, p.ID().String(), rw)
+	peer.Peer = p
+	return peer
+}
+
+// NewFakePeer create a fake commit peer without a backing p2p peer, for testing
+// purposes.
+func NewFakePeer(version uint, id string, rw p2p.MsgReadWriter) *Peer {
+	return &Peer{
+		id:      id,
+		rw:      rw,
+		version: version,
+		known:   lru.NewCache[common.Hash, struct{}](maxKnownCommits),
+		queue:   make(chan *Commit, maxQueuedCommits),
+		term:    make(chan struct{}),
+		logger:  log.New("peer", id[:8]),
+	}
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negotiated `commit` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logger with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// commitKey returns the key identifying a commit signature in the known sets.
+func commitKey(commit *Commit) common.Hash {
+	return crypto.Keccak256Hash(commit.Hash[:], commit.Signature)
+}
+
+// markCommit marks a commit signature as known for the peer, ensuring that it
+// will never be propagated to this particular peer.
+func (p *Peer) markCommit(commit *Commit) {
+	p.known.Add(commitKey(commit), struct{}{})
+}
+
+// knownCommit returns whether the peer is known to have a commit signature.
+func (p *Peer) knownCommit(commit *Commit) bool {
+	return p.known.Contains(commitKey(commit))
+}
+
+// AsyncSendCommit queues a commit signature for propagation to the remote peer,
+// marking it known. If the peer's broadcast queue is full, the event is silently
+// dropped.
+func (p *Peer) AsyncSendCommit(commit *Commit) {
+	select {
+	case p.queue <- commit:
+		p.markCommit(commit)
+	default:
+		p.Log().Debug("Dropping commit propagation", "hash", commit.Hash)
+	}
+}
+
+// broadcastCommits is a write loop that batches up the queued commit signatures
+// and sends them to the remote peer, until the peer is terminated.
+func (p *Peer) broadcastCommits() {
+	for {
+		select {
+		case commit := <-p.queue:
+			commits := CommitsPacket{commit}
+		drain:
+			for len(commits) < maxQueuedCommits {
+				select {
+				case commit := <-p.queue:
+					commits = append(commits, commit)
+				default:
+					break drain
+				}
+			}
+			if err := p2p.Send(p.rw, CommitsMsg, commits); err != nil {
+				return
+			}
+			p.Log().Trace("Propagated commits", "count", len(commits))
+
+		case <-p.term:
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package commit implements the `commit` protocol, gossiping the commit
+// signatures of the validators of an authority chain between peers, so that
+// proposers can include a quorum of them and finalize blocks.
+package commit
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	COMMIT1 = 1
+)
+
+// ProtocolName is the official short name of the `commit` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "commit"
+
+// ProtocolVersions are the supported versions of the `commit` protocol (first
+// is primary).
+var ProtocolVersions = []uint{COMMIT1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{COMMIT1: 1}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 128 * 1024
+
+const (
+	CommitsMsg = 0x00
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+)
+
+// Commit is the signature of a validator committing to a block.
+type Commit struct {
+	Hash      common.Hash // Hash of the block committed to
+	Signature []byte      // Commit signature of the validator
+}
+
+// CommitsPacket is the network packet for propagating commit signatures.
+type CommitsPacket []*Commit
+
+func (*CommitsPacket) Name() string { return "Commits" }
+func (*CommitsPacket) Kind() byte   { return CommitsMsg }
@@ -82,6 +82,7 @@ type Backend interface {
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
 	GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error)
 	RPCGasCap() uint64
+	RPCTraceRange() uint64 // maximum number of blocks trace_filter can span, 0 for no limit
 	ChainConfig() *params.ChainConfig
 	Engine() consensus.Engine
 	ChainDb() ethdb.Database
@@ -92,6 +93,7 @@ type Backend interface {
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	cache   *TraceCache // Optional cache of block traces
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
@@ -147,6 +149,16 @@ func (api *API) blockByNumberAndHash(ctx context.Context, number rpc.BlockNumber
 	return api.blockByHash(ctx, hash)
 }
 
+// blockByNumberOrHash is the wrapper of the chain access function offered by
+// the backend. It will return an error if the block is not found.
+func (api *API) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.blockByHash(ctx, hash)
+	}
+	number, _ := blockNrOrHash.Number()
+	return api.blockByNumber(ctx, number)
+}
+
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*logger.Config
@@ -480,6 +492,40 @@ func (api *API) TraceBadBlock(ctx context.Context, hash common.Hash, config *Tra
 	return api.traceBlock(ctx, block, config)
 }
 
+// TraceBlockStateDiff returns the state changes made by the transactions of a
+// block, aggregated over the whole block. The per transaction diffs are available
+// via the other block tracing methods with the stateDiffTracer, and streamed one
+// per notification by the traceBlock subscription, whose final notification
+// carries the aggregated diff. Any tracer set in the config is ignored.
+func (api *API) TraceBlockStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (StateDiff, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	tracer := "stateDiffTracer"
+	if config == nil {
+		config = new(TraceConfig)
+	}
+	config = &TraceConfig{Tracer: &tracer, Timeout: config.Timeout, Reexec: config.Reexec}
+
+	results, err := api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(StateDiff)
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d (%#x) failed: %v", i, result.TxHash, result.Error)
+		}
+		var next StateDiff
+		if err := json.Unmarshal(result.Result.(json.RawMessage), &next); err != nil {
+			return nil, err
+		}
+		diff.Merge(next)
+	}
+	return diff, nil
+}
+
 // StandardTraceBlockToFile dumps the structured logs created during the
 // execution of EVM to the local file system and returns a list of files
 // to the caller.
@@ -568,13 +614,47 @@ func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Has
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requested tracer.
 func (api *API) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	if api.cache != nil {
+		if results, ok := api.cache.get(block.Hash(), config); ok {
+			return results, nil
+		}
+	}
+	results := make([]*txTraceResult, len(block.Transactions()))
+	err := api.traceBlockFunc(ctx, block, config, func(index int, result *txTraceResult) {
+		results[index] = result
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Only cache complete traces, failures might be timeouts. The results are
+	// checked once delivered, as the callback is invoked concurrently.
+	if api.cache != nil {
+		failed := false
+		for _, result := range results {
+			if result == nil || result.Error != "" {
+				failed = true
+				break
+			}
+		}
+		if !failed {
+			api.cache.put(block.Hash(), config, results)
+		}
+	}
+	return results, nil
+}
+
+// traceBlockFunc traces all the transactions contained within a block, handing
+// the result of every transaction to the callback as soon as it's available.
+// The results of high overhead tracers are produced concurrently, so they may
+// arrive out of order.
+func (api *API) traceBlockFunc(ctx context.Context, block *types.Block, config *TraceConfig, onResult func(index int, result *txTraceResult)) error {
 	if block.NumberU64() == 0 {
-		return nil, errors.New("genesis is not traceable")
+		return errors.New("genesis is not traceable")
 	}
 	// Prepare base state
 	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
@@ -582,7 +662,7 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	}
 	statedb, release, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer release()
 
@@ -591,7 +671,7 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	// in separate worker threads.
 	if config != nil && config.Tracer != nil && *config.Tracer != "" {
 		if isJS := DefaultDirectory.IsJS(*config.Tracer); isJS {
-			return api.traceBlockParallel(ctx, block, statedb, config)
+			return api.traceBlockParallel(ctx, block, statedb, config, onResult)
 		}
 	}
 	// Native tracers have low overhead
@@ -601,9 +681,11 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		is158     = api.backend.ChainConfig().IsEIP158(block.Number())
 		blockCtx  = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 		signer    = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
-		results   = make([]*txTraceResult, len(txs))
 	)
 	for i, tx := range txs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// Generate the next state snapshot fast without tracing
 		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
 		txctx := &Context{
@@ -614,27 +696,26 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		}
 		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
+		onResult(i, &txTraceResult{TxHash: tx.Hash(), Result: res})
 		// Finalize the state so any modifications are written to the trie
 		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 		statedb.Finalise(is158)
 	}
-	return results, nil
+	return nil
 }
 
 // traceBlockParallel is for tracers that have a high overhead (read JS tracers). One thread
 // runs along and executes txes without tracing enabled to generate their prestate.
 // Worker threads take the tasks and the prestate and trace them.
-func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceConfig) ([]*txTraceResult, error) {
+func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceConfig, onResult func(int, *txTraceResult)) error {
 	// Execute all the transaction contained within the block concurrently
 	var (
 		txs       = block.Transactions()
 		blockHash = block.Hash()
 		blockCtx  = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 		signer    = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
-		results   = make([]*txTraceResult, len(txs))
 		pend      sync.WaitGroup
 	)
 	threads := runtime.NumCPU()
@@ -657,10 +738,10 @@ func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, stat
 				}
 				res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config)
 				if err != nil {
-					results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error()}
+					onResult(task.index, &txTraceResult{TxHash: txs[task.index].Hash(), Error: err.Error()})
 					continue
 				}
-				results[task.index] = &txTraceResult{TxHash: txs[task.index].Hash(), Result: res}
+				onResult(task.index, &txTraceResult{TxHash: txs[task.index].Hash(), Result: res})
 			}
 		}()
 	}
@@ -695,10 +776,7 @@ txloop:
 	pend.Wait()
 
 	// If execution failed in between, abort
-	if failed != nil {
-		return nil, failed
-	}
-	return results, nil
+	return failed
 }
 
 // standardTraceBlockToFile configures a new tracer which uses standard JSON output,
@@ -869,6 +947,36 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // the trace will be conducted on the state after executing the specified transaction
 // within the specified block.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	block, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	// Apply the customization rules if required.
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		config.BlockOverrides.Apply(&vmctx)
+	}
+	// Execute the trace
+	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
+	if err != nil {
+		return nil, err
+	}
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+}
+
+// callState retrieves the block to trace calls on top of and recomputes its
+// state, or the state at a transaction of it if requested by the config.
+func (api *API) callState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*types.Block, *state.StateDB, StateReleaseFunc, error) {
 	// Try to retrieve the specified block
 	var (
 		err     error
@@ -885,14 +993,14 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 			// more flexibility and stability than trying to trace on 'pending', since
 			// the contents of 'pending' is unstable and probably not a true representation
 			// of what the next actual block is likely to contain.
-			return nil, errors.New("tracing on top of pending is not supported")
+			return nil, nil, nil, errors.New("tracing on top of pending is not supported")
 		}
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return nil, nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
@@ -906,29 +1014,9 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 		statedb, release, err = api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
 	}
 	if err != nil {
-		return nil, err
-	}
-	defer release()
-
-	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-	// Apply the customization rules if required.
-	if config != nil {
-		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
-		}
-		config.BlockOverrides.Apply(&vmctx)
-	}
-	// Execute the trace
-	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
-	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-
-	var traceConfig *TraceConfig
-	if config != nil {
-		traceConfig = &config.TraceConfig
-	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	return block, statedb, release, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
@@ -974,20 +1062,38 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	// Call Prepare to clear out the statedb access list
 	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
 	if _, err = core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit)); err != nil {
+		// Release the resources of the tracer, as its result is never retrieved
+		tracer.Stop(err)
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
 	return tracer.GetResult()
 }
 
-// APIs return the collection of RPC services the tracer package offers.
-func APIs(backend Backend) []rpc.API {
+// APIs return the collection of RPC services the tracer package offers. The
+// block traces are cached if a cache is given.
+func APIs(backend Backend, cache *TraceCache) []rpc.API {
 	// Append all the local APIs and return
-	return []rpc.API{
+	apis := []rpc.API{
 		{
 			Namespace: "debug",
-			Service:   NewAPI(backend),
+			Service:   &API{backend: backend, cache: cache},
 		},
+		{
+			Namespace: "debug",
+			Service:   NewStreamAPI(backend),
+		},
+		{
+			Namespace: "trace",
+			Service:   NewTraceAPI(backend),
+		},
+	}
+	if cache != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "admin",
+			Service:   NewCacheAPI(cache),
+		})
 	}
+	return apis
 }
 
 // overrideConfig returns a copy of original with forks enabled by override enabled,
@@ -0,0 +1,174 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BundleCall is a call of a traced bundle. The block overrides are applied on
+// top of the block context of the bundle for this call only.
+type BundleCall struct {
+	ethapi.TransactionArgs
+	BlockOverrides *ethapi.BlockOverrides `json:"blockOverrides"`
+}
+
+// BundleTraceResult holds the traces of the calls of a bundle and the changes
+// they made to the state, aggregated over the whole bundle.
+type BundleTraceResult struct {
+	Results   []*callTraceResult `json:"results"`
+	StateDiff StateDiff          `json:"stateDiff"`
+}
+
+// callTraceResult is the result of a single call of a bundle.
+type callTraceResult struct {
+	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
+	Error  string      `json:"error,omitempty"`  // Failure to apply the call
+}
+
+// TraceCallBundle lets you trace a list of calls executed in order on top of the
+// provided block, each call seeing the state changes of the ones before it. The
+// state and block overrides of the config apply to the whole bundle, the timeout
+// as well. Calls which can't be applied, for example because of an insufficient
+// balance, report an error and leave the state unchanged.
+func (api *API) TraceCallBundle(ctx context.Context, calls []*BundleCall, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*BundleTraceResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	block, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if config == nil {
+		config = new(TraceCallConfig)
+	}
+	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	if err := config.StateOverrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	config.BlockOverrides.Apply(&vmctx)
+
+	timeout := defaultTraceTimeout
+	if config.Timeout != nil {
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		chainConfig = api.backend.ChainConfig()
+		results     = &BundleTraceResult{
+			Results:   make([]*callTraceResult, len(calls)),
+			StateDiff: make(StateDiff),
+		}
+	)
+	for i, call := range calls {
+		if err := deadlineCtx.Err(); err != nil {
+			return nil, errors.New("execution timeout")
+		}
+		callctx := vmctx
+		call.BlockOverrides.Apply(&callctx)
+
+		msg, err := call.ToMessage(api.backend.RPCGasCap(), callctx.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		txctx := &Context{
+			BlockHash:   block.Hash(),
+			BlockNumber: callctx.BlockNumber,
+			TxIndex:     i,
+		}
+		// Trace the call with the requested tracer, collecting its state diff
+		tracer, err := newCallTracer(txctx, &config.TraceConfig)
+		if err != nil {
+			return nil, err
+		}
+		differ, err := DefaultDirectory.New("stateDiffTracer", txctx, nil)
+		if err != nil {
+			tracer.Stop(err)
+			return nil, err
+		}
+		vmenv := vm.NewEVM(callctx, core.NewEVMTxContext(msg), statedb, chainConfig, vm.Config{Tracer: tracerMux{tracer, differ}, NoBaseFee: true})
+
+		callCtx, stop := context.WithCancel(deadlineCtx)
+		go func() {
+			<-callCtx.Done()
+			if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+				tracer.Stop(errors.New("execution timeout"))
+				// Stop evm execution. Note cancellation is not necessarily immediate.
+				vmenv.Cancel()
+			}
+		}()
+		// Failed calls may have already bought their gas, so their changes are
+		// reverted for the next calls of the bundle
+		statedb.SetTxContext(common.Hash{}, i)
+		snapshot := statedb.Snapshot()
+		_, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
+		stop()
+		if err != nil {
+			statedb.RevertToSnapshot(snapshot)
+
+			// Release the resources of the tracer, as its result is never retrieved
+			tracer.Stop(err)
+			results.Results[i] = &callTraceResult{Error: err.Error()}
+			continue
+		}
+		res, err := tracer.GetResult()
+		if err != nil {
+			results.Results[i] = &callTraceResult{Error: err.Error()}
+		} else {
+			results.Results[i] = &callTraceResult{Result: res}
+		}
+		// Finalize the call, as the transactions of a block are
+		statedb.Finalise(chainConfig.IsEIP158(callctx.BlockNumber))
+
+		diffRes, err := differ.GetResult()
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		var diff StateDiff
+		if err := json.Unmarshal(diffRes, &diff); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		results.StateDiff.Merge(diff)
+	}
+	return results, nil
+}
+
+// newCallTracer creates the tracer requested by the config, defaulting to the
+// struct logger.
+func newCallTracer(txctx *Context, config *TraceConfig) (Tracer, error) {
+	if config.Tracer != nil {
+		return DefaultDirectory.New(*config.Tracer, txctx, config.TracerConfig)
+	}
+	return logger.NewStructLogger(config.Config), nil
+}
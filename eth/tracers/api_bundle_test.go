@@ -0,0 +1,144 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestTraceCallBundle(t *testing.T) {
+	t.Parallel()
+
+	// Increments the counter in slot 0, stores the block number in slot 1 and
+	// returns the counter
+	code := common.FromHex("6000546001018060005543600155" + "60005260206000f3")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			traceSender:   {Balance: big.NewInt(params.Ether)},
+			traceContract: {Code: code},
+		},
+	}
+	var (
+		api    = tracers.NewAPI(tracers.NewTestBackend(t, 1, genesis, nil))
+		tracer = "callTracer"
+		number = hexutil.Big(*big.NewInt(100))
+		calls  = []*tracers.BundleCall{
+			{TransactionArgs: ethapi.TransactionArgs{From: &traceSender, To: &traceContract}},
+			{TransactionArgs: ethapi.TransactionArgs{From: &traceSender, To: &traceContract}, BlockOverrides: &ethapi.BlockOverrides{Number: &number}},
+			{TransactionArgs: ethapi.TransactionArgs{From: &traceSender2, To: &traceRecvr, Value: (*hexutil.Big)(big.NewInt(params.Ether))}},
+		}
+	)
+	res, err := api.TraceCallBundle(context.Background(), calls, rpc.BlockNumberOrHashWithNumber(1), &tracers.TraceCallConfig{
+		TraceConfig: tracers.TraceConfig{Tracer: &tracer},
+	})
+	if err != nil {
+		t.Fatalf("failed to trace bundle: %v", err)
+	}
+	if len(res.Results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(res.Results), len(calls))
+	}
+	// The second call sees the counter of the first one
+	for i, want := range []uint64{1, 2} {
+		blob, _ := json.Marshal(res.Results[i])
+		var frame struct {
+			Result struct {
+				Output hexutil.Bytes `json:"output"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(blob, &frame); err != nil {
+			t.Fatalf("call %d: failed to decode result: %v", i, err)
+		}
+		if have := new(big.Int).SetBytes(frame.Result.Output); have.Uint64() != want {
+			t.Errorf("call %d: counter mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	// The unfunded call fails without stopping the bundle
+	if have := res.Results[2].Error; !strings.Contains(have, "insufficient funds") {
+		t.Errorf("unfunded call error mismatch: have %q", have)
+	}
+	// The state diff covers the whole bundle
+	contract := res.StateDiff[traceContract]
+	if contract == nil {
+		t.Fatal("missing contract diff")
+	}
+	wantStorage := map[common.Hash]common.Hash{
+		common.BigToHash(big.NewInt(0)): common.BigToHash(big.NewInt(2)),
+		common.BigToHash(big.NewInt(1)): common.BigToHash(big.NewInt(100)),
+	}
+	for slot, want := range wantStorage {
+		if have := contract.Storage[slot]; have.From != (common.Hash{}) || have.To != want {
+			t.Errorf("slot %x mismatch: have %x -> %x, want 0 -> %x", slot, have.From, have.To, want)
+		}
+	}
+	if sender := res.StateDiff[traceSender]; sender == nil || sender.Nonce == nil || sender.Nonce.From != 0 || sender.Nonce.To != 2 {
+		t.Errorf("sender diff mismatch: have %+v", sender)
+	}
+	if _, ok := res.StateDiff[traceRecvr]; ok {
+		t.Error("diff contains the recipient of the failed call")
+	}
+}
+
+// Tests that calls failing after buying their gas leave the state unchanged for
+// the rest of the bundle.
+func TestTraceCallBundleRevert(t *testing.T) {
+	t.Parallel()
+
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			traceSender: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	var (
+		api   = tracers.NewAPI(tracers.NewTestBackend(t, 1, genesis, nil))
+		gas   = hexutil.Uint64(params.TxGas)
+		price = (*hexutil.Big)(big.NewInt(2 * params.GWei))
+		data  = hexutil.Bytes{0x01}
+		calls = []*tracers.BundleCall{
+			// Below the intrinsic gas, failing after the gas is bought
+			{TransactionArgs: ethapi.TransactionArgs{From: &traceSender, To: &traceRecvr, Gas: &gas, GasPrice: price, Data: &data}},
+			// Transfers the whole balance of the sender
+			{TransactionArgs: ethapi.TransactionArgs{From: &traceSender, To: &traceRecvr, Value: (*hexutil.Big)(big.NewInt(params.Ether))}},
+		}
+	)
+	res, err := api.TraceCallBundle(context.Background(), calls, rpc.BlockNumberOrHashWithNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to trace bundle: %v", err)
+	}
+	if have := res.Results[0].Error; !strings.Contains(have, "intrinsic gas too low") {
+		t.Errorf("underpriced call error mismatch: have %q", have)
+	}
+	if have := res.Results[1].Error; have != "" {
+		t.Errorf("transfer failed after reverted call: %v", have)
+	}
+	if recipient := res.StateDiff[traceRecvr]; recipient == nil || recipient.Balance == nil || recipient.Balance.To.ToInt().Cmp(big.NewInt(params.Ether)) != 0 {
+		t.Errorf("recipient diff mismatch: have %+v", recipient)
+	}
+}
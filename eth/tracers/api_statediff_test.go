@@ -0,0 +1,182 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestTraceBlockStateDiff(t *testing.T) {
+	t.Parallel()
+
+	var (
+		// Deploys the single byte runtime code 0x00
+		deployCode = []byte{0x60, 0x00, 0x60, 0x00, 0x53, 0x60, 0x01, 0x60, 0x00, 0xf3}
+		// Destroys itself during creation, refunding the caller
+		destructCode = []byte{0x33, 0xff}
+		// Creates an empty contract, colliding with an existing account
+		factoryCode = []byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0xf0, 0x00}
+		factory     = common.Address{0xfa}
+		collided    = crypto.CreateAddress(factory, 1)
+
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				traceSender: {Balance: big.NewInt(params.Ether)},
+				factory:     {Nonce: 1, Code: factoryCode, Balance: common.Big0},
+				collided:    {Nonce: 1, Balance: big.NewInt(1)},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	backend := tracers.NewTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		for _, tx := range []types.TxData{
+			&types.LegacyTx{Nonce: 0, Gas: 100000, GasPrice: b.BaseFee(), Data: deployCode},
+			&types.LegacyTx{Nonce: 1, Gas: 100000, GasPrice: b.BaseFee(), Value: big.NewInt(7), Data: destructCode},
+			&types.LegacyTx{Nonce: 2, To: &traceRecvr, Gas: params.TxGas, GasPrice: b.BaseFee(), Value: big.NewInt(1000)},
+			&types.LegacyTx{Nonce: 3, To: &factory, Gas: 100000, GasPrice: b.BaseFee()},
+		} {
+			b.AddTx(types.MustSignNewTx(traceKey, signer, tx))
+		}
+	})
+	var (
+		api       = tracers.NewAPI(backend)
+		deployed  = crypto.CreateAddress(traceSender, 0)
+		destroyed = crypto.CreateAddress(traceSender, 1)
+		tracer    = "stateDiffTracer"
+	)
+	// Check the diffs of the individual transactions
+	results, err := api.TraceBlockByNumber(context.Background(), 1, &tracers.TraceConfig{Tracer: &tracer})
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	diffs := make([]tracers.StateDiff, len(results))
+	for i, result := range results {
+		blob, _ := json.Marshal(result)
+		var res struct {
+			Result tracers.StateDiff `json:"result"`
+		}
+		if err := json.Unmarshal(blob, &res); err != nil {
+			t.Fatalf("failed to decode result %d: %v", i, err)
+		}
+		diffs[i] = res.Result
+	}
+	if diff := diffs[0][deployed]; diff == nil || diff.Kind != tracers.DiffAdded || !diff.Created || diff.Code == nil || len(diff.Code.To) != 1 {
+		t.Errorf("deployed contract diff mismatch: have %+v", diff)
+	}
+	if diff, ok := diffs[1][destroyed]; ok {
+		t.Errorf("contract destroyed during creation in diff: %+v", diff)
+	}
+	if diff := diffs[1][traceSender]; diff == nil || diff.Nonce == nil || diff.Nonce.From != 1 || diff.Nonce.To != 2 {
+		t.Errorf("sender diff mismatch: have %+v", diff)
+	}
+	if diff := diffs[2][traceRecvr]; diff == nil || diff.Kind != tracers.DiffAdded || diff.Balance.To.ToInt().Int64() != 1000 {
+		t.Errorf("recipient diff mismatch: have %+v", diff)
+	}
+	if diff := diffs[3][factory]; diff == nil || diff.Kind != tracers.DiffChanged || diff.Nonce == nil || diff.Nonce.To != 2 {
+		t.Errorf("factory diff mismatch: have %+v", diff)
+	}
+	if diff, ok := diffs[3][collided]; ok {
+		t.Errorf("collided account in diff: %+v", diff)
+	}
+	// Check the aggregated diff of the block
+	diff, err := api.TraceBlockStateDiff(context.Background(), rpc.BlockNumberOrHashWithNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block state diff: %v", err)
+	}
+	sender := diff[traceSender]
+	if sender == nil || sender.Kind != tracers.DiffChanged || sender.Nonce.From != 0 || sender.Nonce.To != 4 {
+		t.Errorf("aggregated sender diff mismatch: have %+v", sender)
+	}
+	if sender.Balance == nil || sender.Balance.From.ToInt().Cmp(big.NewInt(params.Ether)) != 0 {
+		t.Errorf("aggregated sender balance mismatch: have %+v", sender.Balance)
+	}
+	if account := diff[deployed]; account == nil || account.Kind != tracers.DiffAdded || !account.Created {
+		t.Errorf("aggregated contract diff mismatch: have %+v", account)
+	}
+	if _, ok := diff[destroyed]; ok {
+		t.Error("aggregated diff contains destroyed contract")
+	}
+	if _, ok := diff[common.Address{}]; ok {
+		t.Error("aggregated diff contains zero address")
+	}
+	if _, ok := diff[collided]; ok {
+		t.Error("aggregated diff contains collided account")
+	}
+	// Check the streamed diffs, the aggregated one being sent last
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("debug", tracers.NewStreamAPI(backend)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	type notification struct {
+		Seq    int               `json:"seq"`
+		Result tracers.StateDiff `json:"result"`
+		Done   bool              `json:"done"`
+	}
+	ch := make(chan *notification)
+	sub, err := client.Subscribe(context.Background(), "debug", ch, "traceBlock", rpc.BlockNumberOrHashWithNumber(1), &tracers.TraceConfig{Tracer: &tracer})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for seq := 0; ; seq++ {
+		var res *notification
+		select {
+		case res = <-ch:
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for diffs")
+		}
+		if res.Seq != seq {
+			t.Fatalf("sequence number mismatch: have %d, want %d", res.Seq, seq)
+		}
+		if !res.Done {
+			if have, want := len(res.Result), len(diffs[seq]); have != want {
+				t.Errorf("streamed diff %d size mismatch: have %d, want %d", seq, have, want)
+			}
+			continue
+		}
+		if seq != len(diffs) {
+			t.Fatalf("stream ended after %d diffs, want %d", seq, len(diffs))
+		}
+		if have, want := len(res.Result), len(diff); have != want {
+			t.Errorf("streamed block diff size mismatch: have %d, want %d", have, want)
+		}
+		if sender := res.Result[traceSender]; sender == nil || sender.Nonce == nil || sender.Nonce.To != 4 {
+			t.Errorf("streamed block sender diff mismatch: have %+v", sender)
+		}
+		break
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// StreamAPI is the collection of tracing subscriptions exposed over the private
+// debugging endpoint, streaming results as soon as they're available instead of
+// collecting them in memory. It shares the debug namespace with API, whose
+// methods of the same name return all results at once.
+type StreamAPI struct {
+	api *API
+}
+
+// NewStreamAPI creates a new API definition for the tracing subscriptions of
+// the Ethereum service.
+func NewStreamAPI(backend Backend) *StreamAPI {
+	return &StreamAPI{api: NewAPI(backend)}
+}
+
+// txTraceNotification is the streamed result of a single transaction trace.
+// The last notification of a stream marks its end, carrying the failure which
+// aborted tracing if any.
+type txTraceNotification struct {
+	Seq    int          `json:"seq"`              // Index of the transaction, the number of transactions in the final notification
+	TxHash *common.Hash `json:"txHash,omitempty"` // Transaction hash
+	Result interface{}  `json:"result,omitempty"` // Trace results produced by the tracer, the block state diff in the final notification of the stateDiffTracer
+	Error  string       `json:"error,omitempty"`  // Trace failure produced by the tracer
+	Done   bool         `json:"done,omitempty"`   // Whether this is the final notification
+}
+
+// TraceBlock streams the trace of every transaction in a block as soon as it
+// finished. Results are sent in order, except for JS tracers which trace the
+// transactions concurrently, hence the sequence number. Unsubscribing aborts
+// tracing before the next transaction.
+//
+// With the stateDiffTracer, the final notification also carries the state diff
+// aggregated over the whole block, unless some transaction failed to trace.
+func (api *StreamAPI) TraceBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (*rpc.Subscription, error) {
+	block, err := api.api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	// Tracing outlives the subscription request, abort it when the client
+	// unsubscribes or goes away
+	traceCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-sub.Err():
+		case <-notifier.Closed():
+		case <-traceCtx.Done():
+		}
+		cancel()
+	}()
+	var diff StateDiff
+	if config != nil && config.Tracer != nil && *config.Tracer == "stateDiffTracer" {
+		diff = make(StateDiff)
+	}
+	go func() {
+		defer cancel()
+
+		err := api.api.traceBlockFunc(traceCtx, block, config, func(index int, result *txTraceResult) {
+			if diff != nil {
+				var next StateDiff
+				if raw, ok := result.Result.(json.RawMessage); !ok || result.Error != "" || json.Unmarshal(raw, &next) != nil {
+					diff = nil
+				} else {
+					diff.Merge(next)
+				}
+			}
+			notifier.Notify(sub.ID, &txTraceNotification{
+				Seq:    index,
+				TxHash: &result.TxHash,
+				Result: result.Result,
+				Error:  result.Error,
+			})
+		})
+		if errors.Is(err, context.Canceled) && traceCtx.Err() != nil {
+			log.Debug("Block trace subscription aborted", "number", block.NumberU64(), "hash", block.Hash())
+			return
+		}
+		final := &txTraceNotification{Seq: len(block.Transactions()), Done: true}
+		if err != nil {
+			final.Error = err.Error()
+		} else if diff != nil {
+			final.Result = diff
+		}
+		notifier.Notify(sub.ID, final)
+	}()
+	return sub, nil
+}
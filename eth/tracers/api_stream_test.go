@@ -0,0 +1,133 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func init() {
+	// Struct logger flagged as high overhead, forcing parallel block tracing
+	DefaultDirectory.Register("testParallelTracer", func(*Context, json.RawMessage) (Tracer, error) {
+		return logger.NewStructLogger(nil), nil
+	}, true)
+}
+
+// streamedTrace is a received txTraceNotification.
+type streamedTrace struct {
+	Seq    int             `json:"seq"`
+	TxHash *common.Hash    `json:"txHash"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+	Done   bool            `json:"done"`
+}
+
+func TestStreamTraceBlock(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	var (
+		signer = types.HomesteadSigner{}
+		txs    []common.Hash
+	)
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		for nonce := uint64(0); nonce < 10; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+			txs = append(txs, tx.Hash())
+		}
+	})
+	defer backend.chain.Stop()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("debug", NewStreamAPI(backend)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	parallel := "testParallelTracer"
+	for _, config := range []*TraceConfig{nil, {Tracer: &parallel}} {
+		ch := make(chan *streamedTrace)
+		sub, err := client.Subscribe(context.Background(), "debug", ch, "traceBlock", rpc.BlockNumberOrHashWithNumber(1), config)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		seen := make(map[int]bool)
+		for done := false; !done; {
+			select {
+			case trace := <-ch:
+				if trace.Done {
+					if trace.Seq != len(txs) || trace.Error != "" {
+						t.Errorf("final notification mismatch: %+v", trace)
+					}
+					done = true
+					continue
+				}
+				if trace.Seq < 0 || trace.Seq >= len(txs) || seen[trace.Seq] {
+					t.Fatalf("unexpected sequence number %d", trace.Seq)
+				}
+				seen[trace.Seq] = true
+				if trace.TxHash == nil || *trace.TxHash != txs[trace.Seq] {
+					t.Errorf("transaction %d hash mismatch: have %v, want %v", trace.Seq, trace.TxHash, txs[trace.Seq])
+				}
+				if len(trace.Result) == 0 || trace.Error != "" {
+					t.Errorf("transaction %d missing result: %+v", trace.Seq, trace)
+				}
+			case err := <-sub.Err():
+				t.Fatalf("subscription failed: %v", err)
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for traces")
+			}
+		}
+		if len(seen) != len(txs) {
+			t.Errorf("result count mismatch: have %d, want %d", len(seen), len(txs))
+		}
+		sub.Unsubscribe()
+	}
+	// Unsubscribing mid-block stops the stream
+	ch := make(chan *streamedTrace, 1)
+	sub, err := client.Subscribe(context.Background(), "debug", ch, "traceBlock", rpc.BlockNumberOrHashWithNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	<-ch
+	sub.Unsubscribe()
+
+	// The genesis block can't be traced
+	if _, err := client.Subscribe(context.Background(), "debug", ch, "traceBlock", rpc.BlockNumberOrHashWithNumber(0), nil); err == nil {
+		t.Error("genesis block traced")
+	}
+}
@@ -59,6 +59,8 @@ type testBackend struct {
 
 	refHook func() // Hook is invoked when the requested state is referenced
 	relHook func() // Hook is invoked when the requested state is released
+
+	traceRange uint64 // Maximum block range of trace_filter, unlimited if 0
 }
 
 // testBackend creates a new test backend. OBS: After test is done, teardown must be
@@ -122,6 +124,10 @@ func (b *testBackend) RPCGasCap() uint64 {
 	return 25000000
 }
 
+func (b *testBackend) RPCTraceRange() uint64 {
+	return b.traceRange
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chainConfig
 }
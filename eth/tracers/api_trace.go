@@ -0,0 +1,513 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Trace types which can be requested from the trace_replay* and trace_call methods.
+const (
+	traceTypeTrace     = "trace"
+	traceTypeVmTrace   = "vmTrace"
+	traceTypeStateDiff = "stateDiff"
+)
+
+// traceTypes is the set of trace types requested for a replay.
+type traceTypes struct {
+	trace     bool
+	vmTrace   bool
+	stateDiff bool
+}
+
+// parseTraceTypes validates the requested trace types.
+func parseTraceTypes(requested []string) (traceTypes, error) {
+	var tt traceTypes
+	for _, typ := range requested {
+		switch typ {
+		case traceTypeTrace:
+			tt.trace = true
+		case traceTypeVmTrace:
+			tt.vmTrace = true
+		case traceTypeStateDiff:
+			tt.stateDiff = true
+		default:
+			return tt, fmt.Errorf("invalid trace type %q", typ)
+		}
+	}
+	return tt, nil
+}
+
+// traceResults is the result of replaying a transaction, in the format of
+// OpenEthereum. Trace types which weren't requested are left empty.
+type traceResults struct {
+	Output          hexutil.Bytes                   `json:"output"`
+	StateDiff       map[common.Address]*accountDiff `json:"stateDiff"`
+	Trace           []*parityTrace                  `json:"trace"`
+	VmTrace         *vmTrace                        `json:"vmTrace"`
+	TransactionHash *common.Hash                    `json:"transactionHash,omitempty"`
+}
+
+// TraceFilterArgs are the arguments of trace_filter. Missing block numbers
+// default to the latest block.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"` // Match any of these senders, or all if empty
+	ToAddress   []common.Address `json:"toAddress"`   // Match any of these recipients, or all if empty
+	After       *uint64          `json:"after"`       // Number of matching traces to skip
+	Count       *uint64          `json:"count"`       // Maximum number of traces to return
+}
+
+// TraceAPI is the collection of tracing APIs compatible with the trace namespace
+// of OpenEthereum. Call frames are traced by the flatCallTracer, state diffs by
+// the stateDiffTracer, so those need to be registered in the default directory.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the trace namespace.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// Block returns the call traces of all transactions in a block.
+func (api *TraceAPI) Block(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*parityTrace, error) {
+	block, err := api.block(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return []*parityTrace{}, nil
+	}
+	results, err := api.replayBlock(ctx, block, traceTypes{trace: true})
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*parityTrace, 0)
+	for _, result := range results {
+		traces = append(traces, result.Trace...)
+	}
+	return traces, nil
+}
+
+// Transaction returns the call traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*parityTrace, error) {
+	result, err := api.replayTransaction(ctx, hash, traceTypes{trace: true})
+	if err != nil {
+		return nil, err
+	}
+	return result.Trace, nil
+}
+
+// Filter returns the call traces of a block range matching the given addresses.
+// The range may span at most as many blocks as configured via the backend.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*parityTrace, error) {
+	from, err := api.blockNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: %d > %d", from, to)
+	}
+	if limit := api.api.backend.RPCTraceRange(); limit > 0 && to-from >= limit {
+		return nil, fmt.Errorf("block range too large: %d blocks, max %d", to-from+1, limit)
+	}
+	var (
+		fromAddrs = make(map[common.Address]bool)
+		toAddrs   = make(map[common.Address]bool)
+		skip      uint64
+		traces    = make([]*parityTrace, 0)
+	)
+	for _, addr := range args.FromAddress {
+		fromAddrs[addr] = true
+	}
+	for _, addr := range args.ToAddress {
+		toAddrs[addr] = true
+	}
+	if args.After != nil {
+		skip = *args.After
+	}
+	if args.Count != nil && *args.Count == 0 {
+		return traces, nil
+	}
+	if from == 0 {
+		from = 1 // genesis is not traceable
+	}
+	// The parent state of every block is derived from the one of the previous
+	// block, instead of being regenerated from scratch for each of them
+	var (
+		statedb *state.StateDB
+		release StateReleaseFunc
+	)
+	defer func() {
+		if release != nil {
+			release()
+		}
+	}()
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		parent, err := api.api.blockByNumberAndHash(ctx, rpc.BlockNumber(number-1), block.ParentHash())
+		if err != nil {
+			return nil, err
+		}
+		// Switch over to preferDisk mode if the memory used by the regenerated
+		// states exceeds the limit, as when tracing a chain segment
+		var preferDisk bool
+		if statedb != nil {
+			s1, s2, s3 := statedb.Database().TrieDB().Size()
+			preferDisk = s1+s2+s3 > defaultTracechainMemLimit
+		}
+		next, nextRelease, err := api.api.backend.StateAtBlock(ctx, parent, defaultTraceReexec, statedb, false, preferDisk)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			release()
+		}
+		statedb, release = next, nextRelease
+
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		results, err := api.replayBlockAt(ctx, block, statedb.Copy(), traceTypes{trace: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			for _, trace := range result.Trace {
+				if !matchTrace(trace, fromAddrs, toAddrs) {
+					continue
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				traces = append(traces, trace)
+				if args.Count != nil && uint64(len(traces)) >= *args.Count {
+					return traces, nil
+				}
+			}
+		}
+	}
+	return traces, nil
+}
+
+// matchTrace reports whether a trace matches the address filters of trace_filter.
+// A trace has to match both the sender and the recipient filter, if set.
+func matchTrace(trace *parityTrace, fromAddrs, toAddrs map[common.Address]bool) bool {
+	if len(fromAddrs) == 0 && len(toAddrs) == 0 {
+		return true
+	}
+	from, to := trace.addresses()
+	if len(fromAddrs) > 0 && (from == nil || !fromAddrs[*from]) {
+		return false
+	}
+	if len(toAddrs) > 0 && (to == nil || !toAddrs[*to]) {
+		return false
+	}
+	return true
+}
+
+// ReplayBlockTransactions replays all transactions in a block, returning the
+// requested traces of each.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, requested []string) ([]*traceResults, error) {
+	tt, err := parseTraceTypes(requested)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.block(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return []*traceResults{}, nil
+	}
+	results, err := api.replayBlock(ctx, block, tt)
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		hash := block.Transactions()[i].Hash()
+		result.TransactionHash = &hash
+		stripTraceContext(result.Trace)
+	}
+	return results, nil
+}
+
+// ReplayTransaction replays a transaction, returning the requested traces.
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, requested []string) (*traceResults, error) {
+	tt, err := parseTraceTypes(requested)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.replayTransaction(ctx, hash, tt)
+	if err != nil {
+		return nil, err
+	}
+	stripTraceContext(result.Trace)
+	return result, nil
+}
+
+// Call executes a call on top of the given block, returning the requested traces.
+func (api *TraceAPI) Call(ctx context.Context, args ethapi.TransactionArgs, requested []string, blockNrOrHash *rpc.BlockNumberOrHash) (*traceResults, error) {
+	tt, err := parseTraceTypes(requested)
+	if err != nil {
+		return nil, err
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	block, err := api.block(ctx, *blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	statedb, release, err := api.api.backend.StateAtBlock(ctx, block, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	msg, err := args.ToMessage(api.api.backend.RPCGasCap(), block.BaseFee())
+	if err != nil {
+		return nil, err
+	}
+	vmctx := core.NewEVMBlockContext(block.Header(), api.api.chainContext(ctx), nil)
+	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, tt)
+}
+
+// block retrieves a block by number or hash. Tracing on top of pending is not
+// supported, as in the debug namespace.
+func (api *TraceAPI) block(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.api.blockByHash(ctx, hash)
+	}
+	number, _ := blockNrOrHash.Number()
+	if number == rpc.PendingBlockNumber {
+		return nil, errors.New("tracing on top of pending is not supported")
+	}
+	return api.api.blockByNumber(ctx, number)
+}
+
+// blockNumber resolves a block number of a filter, defaulting to the latest block.
+func (api *TraceAPI) blockNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	if number != nil && *number >= 0 {
+		return uint64(*number), nil
+	}
+	tag := rpc.LatestBlockNumber
+	if number != nil {
+		tag = *number
+	}
+	if tag == rpc.PendingBlockNumber {
+		return 0, errors.New("tracing on top of pending is not supported")
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %v not found", tag)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// replayBlock re-executes all transactions of a block, tracing them according to
+// the requested trace types.
+func (api *TraceAPI) replayBlock(ctx context.Context, block *types.Block, tt traceTypes) ([]*traceResults, error) {
+	parent, err := api.api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, release, err := api.api.backend.StateAtBlock(ctx, parent, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return api.replayBlockAt(ctx, block, statedb, tt)
+}
+
+// replayBlockAt re-executes all transactions of a block on top of the given
+// parent state, tracing them according to the requested trace types.
+func (api *TraceAPI) replayBlockAt(ctx context.Context, block *types.Block, statedb *state.StateDB, tt traceTypes) ([]*traceResults, error) {
+	var (
+		err      error
+		txs      = block.Transactions()
+		is158    = api.api.backend.ChainConfig().IsEIP158(block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.api.chainContext(ctx), nil)
+		signer   = types.MakeSigner(api.api.backend.ChainConfig(), block.Number(), block.Time())
+		results  = make([]*traceResults, len(txs))
+	)
+	for i, tx := range txs {
+		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
+		txctx := &Context{
+			BlockHash:   block.Hash(),
+			BlockNumber: block.Number(),
+			TxIndex:     i,
+			TxHash:      tx.Hash(),
+		}
+		if results[i], err = api.traceTx(ctx, msg, txctx, blockCtx, statedb, tt); err != nil {
+			return nil, err
+		}
+		statedb.Finalise(is158)
+	}
+	return results, nil
+}
+
+// replayTransaction re-executes a single transaction on top of its parent state.
+func (api *TraceAPI) replayTransaction(ctx context.Context, hash common.Hash, tt traceTypes) (*traceResults, error) {
+	found, _, blockHash, blockNumber, index, err := api.api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, ethapi.NewTxIndexingError()
+	}
+	if !found {
+		return nil, errTxNotFound
+	}
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	block, err := api.api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, release, err := api.api.backend.StateAtTransaction(ctx, block, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	txctx := &Context{
+		BlockHash:   blockHash,
+		BlockNumber: block.Number(),
+		TxIndex:     int(index),
+		TxHash:      hash,
+	}
+	return api.traceTx(ctx, msg, txctx, vmctx, statedb, tt)
+}
+
+// traceTx executes the given message in the provided environment, collecting
+// the requested traces.
+func (api *TraceAPI) traceTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, tt traceTypes) (*traceResults, error) {
+	var (
+		mux     tracerMux
+		tracers []Tracer
+		callIdx = -1
+		diffIdx = -1
+		vmt     *vmTracer
+	)
+	if tt.trace {
+		tracer, err := DefaultDirectory.New("flatCallTracer", txctx, json.RawMessage(`{"convertParityErrors":true}`))
+		if err != nil {
+			return nil, err
+		}
+		callIdx, tracers = len(tracers), append(tracers, tracer)
+	}
+	if tt.stateDiff {
+		tracer, err := DefaultDirectory.New("stateDiffTracer", txctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		diffIdx, tracers = len(tracers), append(tracers, tracer)
+	}
+	for _, tracer := range tracers {
+		mux = append(mux, tracer)
+	}
+	if tt.vmTrace {
+		vmt = new(vmTracer)
+		mux = append(mux, vmt)
+	}
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(message), statedb, api.api.backend.ChainConfig(), vm.Config{Tracer: mux, NoBaseFee: true})
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, defaultTraceTimeout)
+	go func() {
+		<-deadlineCtx.Done()
+		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+			for _, tracer := range tracers {
+				tracer.Stop(errors.New("execution timeout"))
+			}
+			vmenv.Cancel()
+		}
+	}()
+	defer cancel()
+
+	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+	results := &traceResults{
+		Output: result.ReturnData,
+		Trace:  make([]*parityTrace, 0),
+	}
+	if callIdx >= 0 {
+		res, err := tracers[callIdx].GetResult()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(res, &results.Trace); err != nil {
+			return nil, err
+		}
+	}
+	if diffIdx >= 0 {
+		res, err := tracers[diffIdx].GetResult()
+		if err != nil {
+			return nil, err
+		}
+		if results.StateDiff, err = parityStateDiff(res); err != nil {
+			return nil, err
+		}
+	}
+	if vmt != nil {
+		if deadlineCtx.Err() != nil {
+			return nil, errors.New("execution timeout")
+		}
+		results.VmTrace = vmt.root
+	}
+	return results, nil
+}
+
+// stripTraceContext removes the block and transaction context from traces, as
+// the replay methods don't report them.
+func stripTraceContext(traces []*parityTrace) {
+	for _, trace := range traces {
+		trace.BlockHash, trace.BlockNumber = nil, nil
+		trace.TransactionHash, trace.TransactionPosition = nil, nil
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+var (
+	traceKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	traceSender   = crypto.PubkeyToAddress(traceKey.PublicKey)
+	traceKey2, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	traceSender2  = crypto.PubkeyToAddress(traceKey2.PublicKey)
+	traceContract = common.HexToAddress("0x00000000000000000000000000000000000c0de0")
+	traceCallee   = common.HexToAddress("0x00000000000000000000000000000000000c0de1")
+	traceRecvr    = common.HexToAddress("0x00000000000000000000000000000000000000ee")
+)
+
+// newTraceTestAPI creates a chain with two blocks: the first one calls a contract
+// storing a value and calling another contract, then transfers some ether; the
+// second one transfers ether from another sender.
+func newTraceTestAPI(t *testing.T) (*tracers.TraceAPI, []*types.Transaction) {
+	backend, txs := newTraceTestBackend(t)
+	return tracers.NewTraceAPI(backend), txs
+}
+
+// newTraceTestBackend creates the chain of newTraceTestAPI, returning its backend.
+func newTraceTestBackend(t *testing.T) (tracers.Backend, []*types.Transaction) {
+	code := []byte{
+		byte(0x60), 0x2a, byte(0x60), 0x00, byte(0x55), // SSTORE(0, 42)
+		byte(0x60), 0x00, byte(0x60), 0x00, byte(0x60), 0x00, byte(0x60), 0x00, byte(0x60), 0x00, // retSize, retOffset, argsSize, argsOffset, value
+		byte(0x73), // PUSH20 callee
+	}
+	code = append(code, traceCallee.Bytes()...)
+	code = append(code, byte(0x5a), byte(0xf1), byte(0x00)) // GAS, CALL, STOP
+
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			traceSender:   {Balance: big.NewInt(params.Ether)},
+			traceSender2:  {Balance: big.NewInt(params.Ether)},
+			traceContract: {Code: code},
+			traceCallee:   {Code: []byte{0x00}},
+		},
+	}
+	var (
+		signer = types.LatestSigner(genesis.Config)
+		txs    []*types.Transaction
+	)
+	backend := tracers.NewTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		switch i {
+		case 0:
+			tx1 := types.MustSignNewTx(traceKey, signer, &types.LegacyTx{Nonce: 0, To: &traceContract, Gas: 100000, GasPrice: b.BaseFee()})
+			tx2 := types.MustSignNewTx(traceKey, signer, &types.LegacyTx{Nonce: 1, To: &traceRecvr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()})
+			b.AddTx(tx1)
+			b.AddTx(tx2)
+			txs = append(txs, tx1, tx2)
+		case 1:
+			tx := types.MustSignNewTx(traceKey2, signer, &types.LegacyTx{Nonce: 0, To: &traceRecvr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()})
+			b.AddTx(tx)
+			txs = append(txs, tx)
+		}
+	})
+	return backend, txs
+}
+
+// decodeJSON round-trips a result through JSON to inspect it in its RPC form.
+func decodeJSON(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	blob, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+	var res interface{}
+	if err := json.Unmarshal(blob, &res); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	return res
+}
+
+func TestTraceAPIBlock(t *testing.T) {
+	t.Parallel()
+	api, txs := newTraceTestAPI(t)
+
+	traces, err := api.Block(context.Background(), rpc.BlockNumberOrHashWithNumber(1))
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	res := decodeJSON(t, traces).([]interface{})
+	if len(res) != 3 {
+		t.Fatalf("trace count mismatch: have %d, want 3", len(res))
+	}
+	want := []struct {
+		tx           *types.Transaction
+		position     float64
+		subtraces    float64
+		traceAddress int
+		to           common.Address
+	}{
+		{txs[0], 0, 1, 0, traceContract},
+		{txs[0], 0, 0, 1, traceCallee},
+		{txs[1], 1, 0, 0, traceRecvr},
+	}
+	for i, w := range want {
+		trace := res[i].(map[string]interface{})
+		if trace["type"] != "call" {
+			t.Errorf("trace %d: type mismatch: have %v, want call", i, trace["type"])
+		}
+		if trace["blockNumber"] != float64(1) {
+			t.Errorf("trace %d: block number mismatch: have %v, want 1", i, trace["blockNumber"])
+		}
+		if trace["transactionHash"] != w.tx.Hash().Hex() {
+			t.Errorf("trace %d: transaction hash mismatch: have %v, want %v", i, trace["transactionHash"], w.tx.Hash())
+		}
+		if trace["transactionPosition"] != w.position {
+			t.Errorf("trace %d: transaction position mismatch: have %v, want %v", i, trace["transactionPosition"], w.position)
+		}
+		if trace["subtraces"] != w.subtraces {
+			t.Errorf("trace %d: subtraces mismatch: have %v, want %v", i, trace["subtraces"], w.subtraces)
+		}
+		if have := len(trace["traceAddress"].([]interface{})); have != w.traceAddress {
+			t.Errorf("trace %d: trace address depth mismatch: have %d, want %d", i, have, w.traceAddress)
+		}
+		if to := trace["action"].(map[string]interface{})["to"]; to != hexutil.Encode(w.to[:]) {
+			t.Errorf("trace %d: recipient mismatch: have %v, want %v", i, to, w.to)
+		}
+	}
+	// Tracing single transactions should yield the same frames
+	traces, err = api.Transaction(context.Background(), txs[0].Hash())
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if len(traces) != 2 {
+		t.Errorf("transaction trace count mismatch: have %d, want 2", len(traces))
+	}
+}
+
+func TestTraceAPIFilter(t *testing.T) {
+	t.Parallel()
+	api, txs := newTraceTestAPI(t)
+
+	var (
+		one   = rpc.BlockNumber(1)
+		two   = rpc.BlockNumber(2)
+		count = func(n uint64) *uint64 { return &n }
+	)
+	tests := []struct {
+		args TraceFilterArgs
+		want []common.Hash // transaction hashes of the expected traces
+	}{
+		{TraceFilterArgs{FromBlock: &one, ToBlock: &two}, []common.Hash{txs[0].Hash(), txs[0].Hash(), txs[1].Hash(), txs[2].Hash()}},
+		{TraceFilterArgs{FromBlock: &two}, []common.Hash{txs[2].Hash()}},
+		{TraceFilterArgs{FromBlock: &one, ToBlock: &two, FromAddress: []common.Address{traceSender2}}, []common.Hash{txs[2].Hash()}},
+		{TraceFilterArgs{FromBlock: &one, ToBlock: &two, ToAddress: []common.Address{traceCallee}}, []common.Hash{txs[0].Hash()}},
+		{TraceFilterArgs{FromBlock: &one, ToBlock: &two, FromAddress: []common.Address{traceSender}, ToAddress: []common.Address{traceRecvr}}, []common.Hash{txs[1].Hash()}},
+		{TraceFilterArgs{FromBlock: &one, ToBlock: &two, After: count(2), Count: count(1)}, []common.Hash{txs[1].Hash()}},
+		{TraceFilterArgs{FromBlock: &one, ToBlock: &two, Count: count(0)}, nil},
+	}
+	for i, tt := range tests {
+		traces, err := api.Filter(context.Background(), tt.args)
+		if err != nil {
+			t.Fatalf("test %d: failed to filter traces: %v", i, err)
+		}
+		if len(traces) != len(tt.want) {
+			t.Errorf("test %d: trace count mismatch: have %d, want %d", i, len(traces), len(tt.want))
+			continue
+		}
+		for j, trace := range traces {
+			if *trace.TransactionHash != tt.want[j] {
+				t.Errorf("test %d, trace %d: transaction mismatch: have %x, want %x", i, j, *trace.TransactionHash, tt.want[j])
+			}
+		}
+	}
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &two, ToBlock: &one}); err == nil {
+		t.Error("inverted range accepted")
+	}
+	// Ranges spanning more blocks than configured must be rejected
+	backend, _ := newTraceTestBackend(t)
+	tracers.SetTraceRange(backend, 1)
+	limited := tracers.NewTraceAPI(backend)
+
+	if _, err := limited.Filter(context.Background(), TraceFilterArgs{FromBlock: &one, ToBlock: &two}); err == nil {
+		t.Error("range over the limit accepted")
+	}
+	if traces, err := limited.Filter(context.Background(), TraceFilterArgs{FromBlock: &two, ToBlock: &two}); err != nil || len(traces) != 1 {
+		t.Errorf("range within the limit mismatch: have %d traces, err %v", len(traces), err)
+	}
+}
+
+// TraceFilterArgs aliases the filter arguments for brevity.
+type TraceFilterArgs = tracers.TraceFilterArgs
+
+func TestTraceAPIReplay(t *testing.T) {
+	t.Parallel()
+	api, txs := newTraceTestAPI(t)
+
+	results, err := api.ReplayBlockTransactions(context.Background(), rpc.BlockNumberOrHashWithNumber(1), []string{"trace", "vmTrace", "stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to replay block: %v", err)
+	}
+	res := decodeJSON(t, results).([]interface{})
+	if len(res) != 2 {
+		t.Fatalf("result count mismatch: have %d, want 2", len(res))
+	}
+	first := res[0].(map[string]interface{})
+	if first["transactionHash"] != txs[0].Hash().Hex() {
+		t.Errorf("transaction hash mismatch: have %v, want %v", first["transactionHash"], txs[0].Hash())
+	}
+	// The replayed traces don't carry the block context
+	trace := first["trace"].([]interface{})[0].(map[string]interface{})
+	if _, ok := trace["blockHash"]; ok {
+		t.Error("replayed trace has block hash")
+	}
+	// The state diff should contain the stored value and the nonce bump
+	diff := first["stateDiff"].(map[string]interface{})
+	storage := diff[hexutil.Encode(traceContract[:])].(map[string]interface{})["storage"].(map[string]interface{})
+	slot := storage[common.Hash{}.Hex()].(map[string]interface{})["*"].(map[string]interface{})
+	if slot["from"] != (common.Hash{}).Hex() || slot["to"] != common.BigToHash(big.NewInt(42)).Hex() {
+		t.Errorf("storage diff mismatch: have %v", slot)
+	}
+	nonce := diff[hexutil.Encode(traceSender[:])].(map[string]interface{})["nonce"].(map[string]interface{})["*"].(map[string]interface{})
+	if nonce["from"] != "0x0" || nonce["to"] != "0x1" {
+		t.Errorf("nonce diff mismatch: have %v", nonce)
+	}
+	if code := diff[hexutil.Encode(traceSender[:])].(map[string]interface{})["code"]; code != "=" {
+		t.Errorf("code diff mismatch: have %v, want =", code)
+	}
+	// The vm trace should contain the store and the call into the callee
+	var (
+		ops   = first["vmTrace"].(map[string]interface{})["ops"].([]interface{})
+		store = ops[2].(map[string]interface{})["ex"].(map[string]interface{})["store"].(map[string]interface{})
+		call  = ops[len(ops)-2].(map[string]interface{})
+	)
+	if store["key"] != "0x0" || store["val"] != "0x2a" {
+		t.Errorf("store mismatch: have %v", store)
+	}
+	if call["sub"] == nil {
+		t.Error("call without sub trace")
+	}
+	if push := call["ex"].(map[string]interface{})["push"].([]interface{}); len(push) != 1 || push[0] != "0x1" {
+		t.Errorf("call result mismatch: have %v, want [0x1]", push)
+	}
+	// A plain transfer creates the recipient
+	second := res[1].(map[string]interface{})
+	recipient := second["stateDiff"].(map[string]interface{})[hexutil.Encode(traceRecvr[:])].(map[string]interface{})
+	if balance := recipient["balance"].(map[string]interface{})["+"]; balance != "0x3e8" {
+		t.Errorf("created balance mismatch: have %v, want 0x3e8", balance)
+	}
+	if second["vmTrace"].(map[string]interface{})["ops"] == nil {
+		t.Error("missing vm trace ops")
+	}
+	// Replaying a single transaction should only include the requested traces
+	result, err := api.ReplayTransaction(context.Background(), txs[0].Hash(), []string{"stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to replay transaction: %v", err)
+	}
+	if len(result.Trace) != 0 || result.VmTrace != nil || result.StateDiff == nil {
+		t.Errorf("unexpected trace types in result: %+v", result)
+	}
+	if _, err := api.ReplayTransaction(context.Background(), txs[0].Hash(), []string{"unknown"}); err == nil {
+		t.Error("unknown trace type accepted")
+	}
+}
+
+func TestTraceAPICall(t *testing.T) {
+	t.Parallel()
+	api, _ := newTraceTestAPI(t)
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	result, err := api.Call(context.Background(), ethapi.TransactionArgs{From: &traceSender, To: &traceContract}, []string{"trace", "stateDiff"}, &latest)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if len(result.Trace) != 2 {
+		t.Errorf("trace count mismatch: have %d, want 2", len(result.Trace))
+	}
+	// The value was already stored by the first block, so the storage is unchanged
+	res := decodeJSON(t, result).(map[string]interface{})
+	if account, ok := res["stateDiff"].(map[string]interface{})[hexutil.Encode(traceContract[:])]; ok {
+		t.Errorf("unexpected contract diff: %v", account)
+	}
+	if res["vmTrace"] != nil {
+		t.Errorf("unexpected vm trace: %v", res["vmTrace"])
+	}
+}
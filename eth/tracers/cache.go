@@ -0,0 +1,317 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	traceCacheHitMeter   = metrics.NewRegisteredMeter("eth/tracers/cache/hit", nil)
+	traceCacheMissMeter  = metrics.NewRegisteredMeter("eth/tracers/cache/miss", nil)
+	traceCacheEvictMeter = metrics.NewRegisteredMeter("eth/tracers/cache/evict", nil)
+	traceCacheSizeGauge  = metrics.NewRegisteredGauge("eth/tracers/cache/size", nil)
+)
+
+// chainSideSubscriber is the part of the chain the trace cache listens to for
+// blocks dropped from the canonical chain.
+type chainSideSubscriber interface {
+	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
+}
+
+// TraceCache is a bounded on-disk cache of block traces, keyed by the hash of
+// the block and the hash of the tracer configuration. Entries are evicted in
+// least recently used order once the cache exceeds its size limit, and dropped
+// when their block is reorged out of the canonical chain.
+type TraceCache struct {
+	db    ethdb.KeyValueStore
+	chain chainSideSubscriber
+	limit uint64
+
+	lock    sync.Mutex
+	size    uint64                   // Total size of the keys and values in the database
+	entries map[string]*list.Element // Index of the entries by database key
+	lru     *list.List               // Entries ordered by last access, most recent first
+
+	sub  event.Subscription
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// traceCacheEntry is the index entry of a cached trace.
+type traceCacheEntry struct {
+	key  string
+	size uint64
+}
+
+// NewTraceCache creates a trace cache on top of the given database, limited to
+// the given number of bytes. Dropped blocks are invalidated once the cache is
+// started, if the chain is non-nil.
+func NewTraceCache(db ethdb.KeyValueStore, limit uint64, chain chainSideSubscriber) *TraceCache {
+	c := &TraceCache{
+		db:      db,
+		chain:   chain,
+		limit:   limit,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		quit:    make(chan struct{}),
+	}
+	// Index the entries persisted by earlier runs, considering them the least
+	// recently used
+	it := db.NewIterator(nil, nil)
+	for it.Next() {
+		c.insert(string(it.Key()), uint64(len(it.Key())+len(it.Value())))
+	}
+	it.Release()
+	c.evict()
+
+	log.Info("Opened trace cache", "entries", c.lru.Len(), "size", common.StorageSize(c.size), "limit", common.StorageSize(limit))
+	return c
+}
+
+// Start implements node.Lifecycle, invalidating the traces of blocks dropped
+// from the canonical chain in the background.
+func (c *TraceCache) Start() error {
+	if c.chain == nil {
+		return nil
+	}
+	events := make(chan core.ChainSideEvent, 16)
+	c.sub = c.chain.SubscribeChainSideEvent(events)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			select {
+			case ev := <-events:
+				c.InvalidateBlock(ev.Block.Hash())
+			case <-c.sub.Err():
+				return
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the invalidation loop.
+func (c *TraceCache) Stop() error {
+	if c.sub != nil {
+		c.sub.Unsubscribe()
+	}
+	close(c.quit)
+	c.wg.Wait()
+	return nil
+}
+
+// traceCacheKey returns the database key of the trace of a block with the given
+// configuration, or false if the configuration isn't cacheable.
+func traceCacheKey(block common.Hash, config *TraceConfig) ([]byte, bool) {
+	var (
+		tracer       string
+		loggerConfig interface{}
+		tracerConfig []byte
+	)
+	if config != nil {
+		if config.Tracer != nil {
+			tracer = *config.Tracer
+		}
+		if config.Config != nil {
+			loggerConfig = config.Config
+		}
+		if len(config.TracerConfig) > 0 {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, config.TracerConfig); err != nil {
+				return nil, false
+			}
+			tracerConfig = buf.Bytes()
+		}
+	}
+	// The timeout and the reexec depth don't affect successful traces
+	blob, err := json.Marshal([]interface{}{tracer, loggerConfig, json.RawMessage(tracerConfig)})
+	if err != nil {
+		return nil, false
+	}
+	return append(block.Bytes(), crypto.Keccak256(blob)...), true
+}
+
+// get retrieves the trace of a block with the given configuration.
+func (c *TraceCache) get(block common.Hash, config *TraceConfig) ([]*txTraceResult, bool) {
+	key, ok := traceCacheKey(block, config)
+	if !ok {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[string(key)]
+	if !ok {
+		traceCacheMissMeter.Mark(1)
+		return nil, false
+	}
+	blob, err := c.db.Get(key)
+	if err != nil {
+		log.Warn("Failed to read cached trace", "block", block, "err", err)
+		c.remove(elem)
+		traceCacheMissMeter.Mark(1)
+		return nil, false
+	}
+	// Keep the results as raw JSON to return them exactly as traced
+	var cached []*struct {
+		TxHash common.Hash
+		Result json.RawMessage
+		Error  string
+	}
+	if err := json.Unmarshal(blob, &cached); err != nil {
+		log.Warn("Failed to decode cached trace", "block", block, "err", err)
+		c.remove(elem)
+		traceCacheMissMeter.Mark(1)
+		return nil, false
+	}
+	results := make([]*txTraceResult, len(cached))
+	for i, res := range cached {
+		results[i] = &txTraceResult{TxHash: res.TxHash, Error: res.Error}
+		if len(res.Result) > 0 {
+			results[i].Result = res.Result
+		}
+	}
+	c.lru.MoveToFront(elem)
+	traceCacheHitMeter.Mark(1)
+	return results, true
+}
+
+// put stores the trace of a block with the given configuration, evicting the
+// least recently used entries if the cache gets too large.
+func (c *TraceCache) put(block common.Hash, config *TraceConfig, results []*txTraceResult) {
+	key, ok := traceCacheKey(block, config)
+	if !ok {
+		return
+	}
+	blob, err := json.Marshal(results)
+	if err != nil {
+		return
+	}
+	size := uint64(len(key) + len(blob))
+	if size > c.limit {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[string(key)]; ok {
+		c.remove(elem)
+	}
+	if err := c.db.Put(key, blob); err != nil {
+		log.Warn("Failed to write cached trace", "block", block, "err", err)
+		return
+	}
+	c.insert(string(key), size)
+	c.lru.MoveToFront(c.entries[string(key)])
+	c.evict()
+}
+
+// InvalidateBlock removes all cached traces of a block.
+func (c *TraceCache) InvalidateBlock(block common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	it := c.db.NewIterator(block.Bytes(), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if elem, ok := c.entries[string(it.Key())]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// Purge removes all cached traces, returning the number of removed entries.
+func (c *TraceCache) Purge() (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	purged := 0
+	for c.lru.Len() > 0 {
+		if err := c.db.Delete([]byte(c.lru.Back().Value.(*traceCacheEntry).key)); err != nil {
+			return purged, err
+		}
+		c.drop(c.lru.Back())
+		purged++
+	}
+	return purged, nil
+}
+
+// insert adds an entry to the index as the least recently used one. The lock
+// must be held or the cache not yet shared.
+func (c *TraceCache) insert(key string, size uint64) {
+	c.entries[key] = c.lru.PushBack(&traceCacheEntry{key: key, size: size})
+	c.size += size
+	traceCacheSizeGauge.Update(int64(c.size))
+}
+
+// remove deletes an entry from the database and the index.
+func (c *TraceCache) remove(elem *list.Element) {
+	if err := c.db.Delete([]byte(elem.Value.(*traceCacheEntry).key)); err != nil {
+		log.Warn("Failed to delete cached trace", "err", err)
+	}
+	c.drop(elem)
+}
+
+// drop deletes an entry from the index.
+func (c *TraceCache) drop(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*traceCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	traceCacheSizeGauge.Update(int64(c.size))
+}
+
+// evict removes the least recently used entries until the cache fits its limit.
+func (c *TraceCache) evict() {
+	for c.size > c.limit && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+		traceCacheEvictMeter.Mark(1)
+	}
+}
+
+// CacheAPI is the collection of trace cache management methods exposed over
+// the admin endpoint.
+type CacheAPI struct {
+	cache *TraceCache
+}
+
+// NewCacheAPI creates a new API definition for managing the trace cache.
+func NewCacheAPI(cache *TraceCache) *CacheAPI {
+	return &CacheAPI{cache: cache}
+}
+
+// PurgeTraceCache removes all cached traces, returning the number of removed
+// entries.
+func (api *CacheAPI) PurgeTraceCache() (int, error) {
+	return api.cache.Purge()
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testSideFeed is a chain emitting side events on demand.
+type testSideFeed struct {
+	feed event.Feed
+}
+
+func (f *testSideFeed) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return f.feed.Subscribe(ch)
+}
+
+func TestTraceCache(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		cache   = NewTraceCache(db, 1500, nil)
+		block1  = common.HexToHash("0x01")
+		block2  = common.HexToHash("0x02")
+		tracer  = "callTracer"
+		results = []*txTraceResult{{TxHash: common.HexToHash("0xaa"), Result: json.RawMessage(`{"b":1,"a":2}`)}}
+	)
+	cache.put(block1, &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall": true}`)}, results)
+
+	// The tracer configuration is part of the key, ignoring whitespace
+	if _, ok := cache.get(block1, &TraceConfig{Tracer: &tracer}); ok {
+		t.Error("hit with different tracer config")
+	}
+	if _, ok := cache.get(block1, nil); ok {
+		t.Error("hit with default tracer")
+	}
+	if _, ok := cache.get(block2, &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall":true}`)}); ok {
+		t.Error("hit for different block")
+	}
+	have, ok := cache.get(block1, &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall":true}`)})
+	if !ok {
+		t.Fatal("cached trace missing")
+	}
+	blob, _ := json.Marshal(have)
+	if want := `[{"txHash":"0x00000000000000000000000000000000000000000000000000000000000000aa","result":{"b":1,"a":2}}]`; string(blob) != want {
+		t.Errorf("cached trace mismatch: have %s, want %s", blob, want)
+	}
+	// Entries persist across restarts
+	cache = NewTraceCache(db, 1500, nil)
+	if _, ok := cache.get(block1, &TraceConfig{Tracer: &tracer, TracerConfig: json.RawMessage(`{"onlyTopCall":true}`)}); !ok {
+		t.Error("cached trace lost on reopen")
+	}
+	// Exceeding the limit evicts the least recently used entries
+	large := []*txTraceResult{{Result: json.RawMessage(`"` + strings.Repeat("x", 400) + `"`)}}
+	cache.put(block1, nil, large)
+	cache.put(block2, nil, large)
+	if _, ok := cache.get(block1, nil); !ok {
+		t.Fatal("recent entry evicted")
+	}
+	cache.put(common.HexToHash("0x03"), nil, large)
+	if _, ok := cache.get(block2, nil); ok {
+		t.Error("least recently used entry not evicted")
+	}
+	if _, ok := cache.get(block1, nil); !ok {
+		t.Error("recently used entry evicted")
+	}
+	if cache.size > cache.limit {
+		t.Errorf("cache exceeds limit: %d > %d", cache.size, cache.limit)
+	}
+	// Purging empties both the index and the database
+	if n, err := cache.Purge(); err != nil || n == 0 {
+		t.Fatalf("purge failed: %d entries, %v", n, err)
+	}
+	if cache.size != 0 || cache.lru.Len() != 0 {
+		t.Errorf("index not empty after purge: %d entries, %d bytes", cache.lru.Len(), cache.size)
+	}
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	if it.Next() {
+		t.Error("database not empty after purge")
+	}
+}
+
+func TestTraceCacheInvalidation(t *testing.T) {
+	var (
+		feed  = new(testSideFeed)
+		cache = NewTraceCache(rawdb.NewMemoryDatabase(), 1024, feed)
+		block = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+		other = common.HexToHash("0x02")
+	)
+	if err := cache.Start(); err != nil {
+		t.Fatalf("failed to start cache: %v", err)
+	}
+	defer cache.Stop()
+
+	cache.put(block.Hash(), nil, []*txTraceResult{})
+	cache.put(other, nil, []*txTraceResult{})
+
+	feed.feed.Send(core.ChainSideEvent{Block: block})
+	for i := 0; ; i++ {
+		if _, ok := cache.get(block.Hash(), nil); !ok {
+			break
+		}
+		if i == 100 {
+			t.Fatal("dropped block not invalidated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := cache.get(other, nil); !ok {
+		t.Error("unrelated block invalidated")
+	}
+}
+
+func TestTraceBlockCached(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), types.HomesteadSigner{}, accounts[0].key)
+		b.AddTx(tx)
+	})
+	defer backend.chain.Stop()
+
+	var refs atomic.Uint32
+	backend.refHook = func() { refs.Add(1) }
+
+	api := &API{backend: backend, cache: NewTraceCache(rawdb.NewMemoryDatabase(), 1024*1024, nil)}
+	first, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	second, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if n := refs.Load(); n != 1 {
+		t.Errorf("state regenerated for cached trace: %d times", n)
+	}
+	have, _ := json.Marshal(second)
+	want, _ := json.Marshal(first)
+	if string(have) != string(want) {
+		t.Errorf("cached trace mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+// Tests that the traces of high overhead tracers, produced concurrently, are
+// cached.
+func TestTraceBlockCachedParallel(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		for nonce := uint64(0); nonce < 8; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), types.HomesteadSigner{}, accounts[0].key)
+			b.AddTx(tx)
+		}
+	})
+	defer backend.chain.Stop()
+
+	var refs atomic.Uint32
+	backend.refHook = func() { refs.Add(1) }
+
+	var (
+		api    = &API{backend: backend, cache: NewTraceCache(rawdb.NewMemoryDatabase(), 1024*1024, nil)}
+		tracer = "callTracer"
+	)
+	for i := 0; i < 2; i++ {
+		results, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(1), &TraceConfig{Tracer: &tracer})
+		if err != nil {
+			t.Fatalf("failed to trace block: %v", err)
+		}
+		if len(results) != 8 {
+			t.Fatalf("result count mismatch: have %d, want 8", len(results))
+		}
+	}
+	if n := refs.Load(); n != 1 {
+		t.Errorf("state regenerated for cached trace: %d times", n)
+	}
+}
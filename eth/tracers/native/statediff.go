@@ -0,0 +1,239 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("stateDiffTracer", newStateDiffTracer, false)
+}
+
+// diffAccount is the state of an account before the transaction.
+type diffAccount struct {
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// exists reports whether the account is non-empty in the sense of EIP-161.
+func (a *diffAccount) exists() bool {
+	return a.nonce > 0 || a.balance.Sign() != 0 || len(a.code) > 0
+}
+
+// stateDiffTracer collects the changes a transaction makes to the accounts and
+// storage slots it touches, classifying the accounts as added, removed or changed.
+type stateDiffTracer struct {
+	noopTracer
+	env       *vm.EVM
+	pre       map[common.Address]*diffAccount
+	created   map[common.Address]bool
+	result    tracers.StateDiff
+	gasLimit  uint64      // Amount of gas bought for the whole tx
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+func newStateDiffTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	return &stateDiffTracer{
+		pre:     make(map[common.Address]*diffAccount),
+		created: make(map[common.Address]bool),
+		result:  make(tracers.StateDiff),
+	}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *stateDiffTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	t.lookupAccount(env.Context.Coinbase)
+
+	// The gas purchase, nonce bump and value transfer already happened, revert
+	// them to get the state before the transaction.
+	t.pre[to].balance = new(big.Int).Sub(t.pre[to].balance, value)
+
+	cost := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(t.gasLimit))
+	if blobs := len(env.TxContext.BlobHashes); blobs > 0 && env.Context.BlobBaseFee != nil {
+		blobGas := new(big.Int).SetUint64(uint64(blobs) * params.BlobTxBlobGasPerBlob)
+		cost.Add(cost, blobGas.Mul(blobGas, env.Context.BlobBaseFee))
+	}
+	t.pre[from].balance = new(big.Int).Add(t.pre[from].balance, cost.Add(cost, value))
+	t.pre[from].nonce--
+
+	if create {
+		// The nonce of the new contract was already set as well
+		t.pre[to].nonce = 0
+		t.created[to] = true
+	}
+}
+
+// CaptureTxStart implements the EVMLogger interface, recording the gas bought.
+func (t *stateDiffTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *stateDiffTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || t.interrupt.Load() {
+		return
+	}
+	stackData := scope.Stack.Data()
+	stackLen := len(stackData)
+	caller := scope.Contract.Address()
+	switch {
+	case stackLen >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		t.lookupStorage(caller, common.Hash(stackData[stackLen-1].Bytes32()))
+	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
+		t.lookupAccount(common.Address(stackData[stackLen-1].Bytes20()))
+	case stackLen >= 5 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
+		t.lookupAccount(common.Address(stackData[stackLen-2].Bytes20()))
+	case op == vm.CREATE:
+		addr := crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller))
+		t.lookupCreated(addr)
+	case stackLen >= 4 && op == vm.CREATE2:
+		offset, size := stackData[stackLen-2], stackData[stackLen-3]
+		init, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(offset.Uint64()), int64(size.Uint64()))
+		if err != nil {
+			log.Warn("Failed to copy CREATE2 input", "err", err, "tracer", "stateDiffTracer", "offset", offset, "size", size)
+			return
+		}
+		addr := crypto.CreateAddress2(caller, stackData[stackLen-4].Bytes32(), crypto.Keccak256(init))
+		t.lookupCreated(addr)
+	}
+}
+
+// CaptureTxEnd compares the touched accounts with their state after the
+// transaction, before any empty accounts are deleted.
+func (t *stateDiffTracer) CaptureTxEnd(restGas uint64) {
+	if t.env == nil {
+		return
+	}
+	for addr, pre := range t.pre {
+		var (
+			destroyed = t.env.StateDB.HasSelfDestructed(addr)
+			post      = &diffAccount{balance: new(big.Int), storage: make(map[common.Hash]common.Hash)}
+		)
+		if !destroyed {
+			post.balance = t.env.StateDB.GetBalance(addr).ToBig()
+			post.nonce = t.env.StateDB.GetNonce(addr)
+			post.code = t.env.StateDB.GetCode(addr)
+		}
+		for slot := range pre.storage {
+			if !destroyed {
+				post.storage[slot] = t.env.StateDB.GetState(addr, slot)
+			}
+		}
+		diff := &tracers.AccountDiff{
+			Destroyed: destroyed,
+			Created:   t.created[addr] && !destroyed && post.nonce > 0,
+		}
+		switch existedBefore, existsAfter := pre.exists(), post.exists(); {
+		case existedBefore && existsAfter:
+			diff.Kind = tracers.DiffChanged
+		case existedBefore:
+			diff.Kind = tracers.DiffRemoved
+		case existsAfter:
+			diff.Kind = tracers.DiffAdded
+		default:
+			continue // untouched empty account, or created and destroyed in the transaction
+		}
+		if pre.balance.Cmp(post.balance) != 0 {
+			diff.Balance = &tracers.Change[*hexutil.Big]{From: (*hexutil.Big)(pre.balance), To: (*hexutil.Big)(post.balance)}
+		}
+		if pre.nonce != post.nonce {
+			diff.Nonce = &tracers.Change[hexutil.Uint64]{From: hexutil.Uint64(pre.nonce), To: hexutil.Uint64(post.nonce)}
+		}
+		if !bytes.Equal(pre.code, post.code) {
+			diff.Code = &tracers.Change[hexutil.Bytes]{From: pre.code, To: post.code}
+		}
+		for slot, value := range pre.storage {
+			if value != post.storage[slot] {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash]tracers.Change[common.Hash])
+				}
+				diff.Storage[slot] = tracers.Change[common.Hash]{From: value, To: post.storage[slot]}
+			}
+		}
+		if diff.Empty() {
+			continue
+		}
+		t.result[addr] = diff
+	}
+}
+
+// GetResult returns the json-encoded state diff, and any error arising from the
+// encoding or forceful termination (via `Stop`).
+func (t *stateDiffTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.result)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *stateDiffTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// lookupAccount records the state of an account before it's first modified.
+func (t *stateDiffTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = &diffAccount{
+		balance: t.env.StateDB.GetBalance(addr).ToBig(),
+		nonce:   t.env.StateDB.GetNonce(addr),
+		code:    t.env.StateDB.GetCode(addr),
+		storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupCreated records the state of an account a contract is deployed to. The
+// deployment fails if the address collides with an account having a nonce or
+// code, which is then only modified, not created.
+func (t *stateDiffTracer) lookupCreated(addr common.Address) {
+	t.lookupAccount(addr)
+	if t.env.StateDB.GetNonce(addr) == 0 && t.env.StateDB.GetCodeSize(addr) == 0 {
+		t.created[addr] = true
+	}
+}
+
+// lookupStorage records the value of a storage slot before it's first modified.
+// The account must have been looked up before.
+func (t *stateDiffTracer) lookupStorage(addr common.Address, slot common.Hash) {
+	if _, ok := t.pre[addr].storage[slot]; ok {
+		return
+	}
+	t.pre[addr].storage[slot] = t.env.StateDB.GetState(addr, slot)
+}
@@ -0,0 +1,419 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// parityTrace is a single call frame in the flat trace format of OpenEthereum.
+// The action and result are passed through from the flatCallTracer.
+type parityTrace struct {
+	Action              json.RawMessage `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber         *uint64         `json:"blockNumber,omitempty"`
+	Error               string          `json:"error,omitempty"`
+	Result              json.RawMessage `json:"result"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64         `json:"transactionPosition,omitempty"`
+	Type                string          `json:"type"`
+}
+
+// addresses returns the sender and recipient side addresses of the traced call
+// frame, as used by trace_filter.
+func (t *parityTrace) addresses() (from, to *common.Address) {
+	var action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	}
+	json.Unmarshal(t.Action, &action)
+
+	switch t.Type {
+	case "create":
+		var result struct {
+			Address *common.Address `json:"address"`
+		}
+		json.Unmarshal(t.Result, &result)
+		return action.From, result.Address
+	case "suicide":
+		return action.Address, action.RefundAddress
+	default:
+		return action.From, action.To
+	}
+}
+
+// vmTrace is the trace of the instructions executed in a single call frame, in
+// the vmTrace format of OpenEthereum.
+type vmTrace struct {
+	Code hexutil.Bytes  `json:"code"`
+	Ops  []*vmOperation `json:"ops"`
+}
+
+// vmOperation is a single executed instruction of a vmTrace.
+type vmOperation struct {
+	Cost uint64      `json:"cost"`
+	Ex   *vmExecuted `json:"ex"`
+	Pc   uint64      `json:"pc"`
+	Sub  *vmTrace    `json:"sub"`
+
+	gas       uint64         // Gas available before executing the instruction
+	pushes    int            // Number of stack items written by the instruction
+	memOffset uint64         // Offset of the memory written by the instruction
+	memSize   uint64         // Size of the memory written by the instruction
+	store     *vmStorageDiff // Storage slot written by the instruction
+}
+
+// vmExecuted is the effect of an executed instruction.
+type vmExecuted struct {
+	Mem   *vmMemoryDiff   `json:"mem"`
+	Push  []*hexutil.U256 `json:"push"`
+	Store *vmStorageDiff  `json:"store"`
+	Used  uint64          `json:"used"`
+}
+
+// vmMemoryDiff is a memory region written by an instruction.
+type vmMemoryDiff struct {
+	Data hexutil.Bytes `json:"data"`
+	Off  uint64        `json:"off"`
+}
+
+// vmStorageDiff is a storage slot written by an instruction.
+type vmStorageDiff struct {
+	Key *hexutil.U256 `json:"key"`
+	Val *hexutil.U256 `json:"val"`
+}
+
+// finish records the effect of the instruction, given the gas and the scope
+// right after its execution.
+func (op *vmOperation) finish(gas uint64, scope *vm.ScopeContext) {
+	ex := &vmExecuted{
+		Push:  make([]*hexutil.U256, 0, op.pushes),
+		Store: op.store,
+		Used:  gas,
+	}
+	if stack := scope.Stack.Data(); op.pushes <= len(stack) {
+		for _, item := range stack[len(stack)-op.pushes:] {
+			ex.Push = append(ex.Push, (*hexutil.U256)(item.Clone()))
+		}
+	}
+	if op.memSize > 0 {
+		if data, err := GetMemoryCopyPadded(scope.Memory, int64(op.memOffset), int64(op.memSize)); err == nil {
+			ex.Mem = &vmMemoryDiff{Data: data, Off: op.memOffset}
+		}
+	}
+	op.Ex = ex
+}
+
+// stackPushes returns the number of stack items written by an instruction. As
+// in OpenEthereum, DUP and SWAP report all the items they touched.
+func stackPushes(op vm.OpCode) int {
+	switch {
+	case op.IsPush():
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.TSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST,
+		vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY, vm.MCOPY,
+		vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT:
+		return 0
+	}
+	return 1
+}
+
+// memoryWrite returns the memory region an instruction is about to write, given
+// the stack before its execution.
+func memoryWrite(op vm.OpCode, stack *vm.Stack) (offset, size *big.Int) {
+	var off, length int
+	switch op {
+	case vm.MSTORE:
+		if len(stack.Data()) < 1 {
+			return nil, nil
+		}
+		return stack.Back(0).ToBig(), big.NewInt(32)
+	case vm.MSTORE8:
+		if len(stack.Data()) < 1 {
+			return nil, nil
+		}
+		return stack.Back(0).ToBig(), big.NewInt(1)
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY:
+		off, length = 0, 2
+	case vm.EXTCODECOPY:
+		off, length = 1, 3
+	case vm.CALL, vm.CALLCODE:
+		off, length = 5, 6
+	case vm.DELEGATECALL, vm.STATICCALL:
+		off, length = 4, 5
+	default:
+		return nil, nil
+	}
+	if len(stack.Data()) <= length {
+		return nil, nil
+	}
+	return stack.Back(off).ToBig(), stack.Back(length).ToBig()
+}
+
+// vmTraceFrame is a call frame being traced by the vmTracer.
+type vmTraceFrame struct {
+	trace   *vmTrace
+	pending *vmOperation // Last instruction, waiting for its effects
+}
+
+// vmTracer collects the vmTrace of a transaction.
+type vmTracer struct {
+	env    *vm.EVM
+	root   *vmTrace
+	frames []*vmTraceFrame
+}
+
+func (t *vmTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *vmTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *vmTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.root = t.enter(to, create, input)
+}
+
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit()
+}
+
+func (t *vmTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	trace := t.enter(to, typ == vm.CREATE || typ == vm.CREATE2, input)
+
+	// Attach the sub trace to the instruction opening the call frame. Self
+	// destructs open a frame too, but they don't execute any code.
+	parent := t.frames[len(t.frames)-2].trace
+	if typ != vm.SELFDESTRUCT && len(parent.Ops) > 0 {
+		parent.Ops[len(parent.Ops)-1].Sub = trace
+	}
+}
+
+func (t *vmTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exit()
+}
+
+// enter opens a new call frame executing the code of the given account, or the
+// init code of a contract creation.
+func (t *vmTracer) enter(to common.Address, create bool, input []byte) *vmTrace {
+	code := input
+	if !create {
+		code = t.env.StateDB.GetCode(to)
+	}
+	trace := &vmTrace{Code: common.CopyBytes(code), Ops: make([]*vmOperation, 0)}
+	t.frames = append(t.frames, &vmTraceFrame{trace: trace})
+	return trace
+}
+
+// exit closes the current call frame. The instruction ending it can't change the
+// stack, memory or storage, so only its gas usage is recorded.
+func (t *vmTracer) exit() {
+	frame := t.frames[len(t.frames)-1]
+	if op := frame.pending; op != nil {
+		op.Ex = &vmExecuted{Push: make([]*hexutil.U256, 0), Used: op.gas - op.Cost}
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+func (t *vmTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	frame := t.frames[len(t.frames)-1]
+	if frame.pending != nil {
+		frame.pending.finish(gas, scope)
+		frame.pending = nil
+	}
+	operation := &vmOperation{Pc: pc, Cost: cost, gas: gas}
+	frame.trace.Ops = append(frame.trace.Ops, operation)
+
+	// Instructions failing before execution have no effects
+	if err != nil {
+		return
+	}
+	operation.pushes = stackPushes(op)
+	if offset, size := memoryWrite(op, scope.Stack); size != nil && size.Sign() > 0 && offset.IsUint64() && size.IsUint64() {
+		operation.memOffset, operation.memSize = offset.Uint64(), size.Uint64()
+	}
+	if op == vm.SSTORE && len(scope.Stack.Data()) >= 2 {
+		operation.store = &vmStorageDiff{
+			Key: (*hexutil.U256)(scope.Stack.Back(0).Clone()),
+			Val: (*hexutil.U256)(scope.Stack.Back(1).Clone()),
+		}
+	}
+	frame.pending = operation
+}
+
+func (t *vmTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	// Reverts still end the frame gracefully, everything else leaves the
+	// failing instruction without effects
+	frame := t.frames[len(t.frames)-1]
+	if !errors.Is(err, vm.ErrExecutionReverted) {
+		frame.pending = nil
+	}
+}
+
+// diffValue is a change of a single value in the stateDiff format of OpenEthereum.
+type diffValue struct {
+	kind     string // "+" for added, "-" for removed, "*" for changed, "=" for unchanged
+	from, to interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d diffValue) MarshalJSON() ([]byte, error) {
+	switch d.kind {
+	case "+":
+		return json.Marshal(map[string]interface{}{"+": d.to})
+	case "-":
+		return json.Marshal(map[string]interface{}{"-": d.from})
+	case "*":
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": d.from, "to": d.to}})
+	default:
+		return json.Marshal("=")
+	}
+}
+
+// accountDiff is the change of an account in the stateDiff format.
+type accountDiff struct {
+	Balance diffValue                 `json:"balance"`
+	Code    diffValue                 `json:"code"`
+	Nonce   diffValue                 `json:"nonce"`
+	Storage map[common.Hash]diffValue `json:"storage"`
+}
+
+// parityStateDiff converts the output of the stateDiffTracer into the stateDiff
+// format. Fields missing from the diff of an added or removed account were zero
+// on both sides.
+func parityStateDiff(result json.RawMessage) (map[common.Address]*accountDiff, error) {
+	var diff StateDiff
+	if err := json.Unmarshal(result, &diff); err != nil {
+		return nil, err
+	}
+	accounts := make(map[common.Address]*accountDiff, len(diff))
+	for addr, change := range diff {
+		var (
+			balance = Change[*hexutil.Big]{From: new(hexutil.Big), To: new(hexutil.Big)}
+			nonce   Change[hexutil.Uint64]
+			code    = Change[hexutil.Bytes]{From: hexutil.Bytes{}, To: hexutil.Bytes{}}
+		)
+		if change.Balance != nil {
+			balance = *change.Balance
+		}
+		if change.Nonce != nil {
+			nonce = *change.Nonce
+		}
+		if change.Code != nil {
+			code = *change.Code
+		}
+		account := &accountDiff{Storage: make(map[common.Hash]diffValue)}
+		switch change.Kind {
+		case DiffAdded:
+			account.Balance = diffValue{kind: "+", to: balance.To}
+			account.Nonce = diffValue{kind: "+", to: nonce.To}
+			account.Code = diffValue{kind: "+", to: code.To}
+			for slot, value := range change.Storage {
+				account.Storage[slot] = diffValue{kind: "+", to: value.To}
+			}
+		case DiffRemoved:
+			account.Balance = diffValue{kind: "-", from: balance.From}
+			account.Nonce = diffValue{kind: "-", from: nonce.From}
+			account.Code = diffValue{kind: "-", from: code.From}
+			for slot, value := range change.Storage {
+				account.Storage[slot] = diffValue{kind: "-", from: value.From}
+			}
+		default:
+			if change.Balance != nil {
+				account.Balance = diffValue{kind: "*", from: balance.From, to: balance.To}
+			}
+			if change.Nonce != nil {
+				account.Nonce = diffValue{kind: "*", from: nonce.From, to: nonce.To}
+			}
+			if change.Code != nil {
+				account.Code = diffValue{kind: "*", from: code.From, to: code.To}
+			}
+			for slot, value := range change.Storage {
+				account.Storage[slot] = diffValue{kind: "*", from: value.From, to: value.To}
+			}
+		}
+		accounts[addr] = account
+	}
+	return accounts, nil
+}
+
+// tracerMux fans the EVM events out to several tracers.
+type tracerMux []vm.EVMLogger
+
+func (t tracerMux) CaptureTxStart(gasLimit uint64) {
+	for _, tracer := range t {
+		tracer.CaptureTxStart(gasLimit)
+	}
+}
+
+func (t tracerMux) CaptureTxEnd(restGas uint64) {
+	for _, tracer := range t {
+		tracer.CaptureTxEnd(restGas)
+	}
+}
+
+func (t tracerMux) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	for _, tracer := range t {
+		tracer.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (t tracerMux) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	for _, tracer := range t {
+		tracer.CaptureEnd(output, gasUsed, err)
+	}
+}
+
+func (t tracerMux) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	for _, tracer := range t {
+		tracer.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (t tracerMux) CaptureExit(output []byte, gasUsed uint64, err error) {
+	for _, tracer := range t {
+		tracer.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (t tracerMux) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	for _, tracer := range t {
+		tracer.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (t tracerMux) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	for _, tracer := range t {
+		tracer.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// processEnv makes the test binary act as a plugin process.
+const processEnv = "GETH_TEST_TRACER_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(processEnv) != "" {
+		Main(newCountingTracer)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// countingTracer counts the events it receives.
+type countingTracer struct {
+	NoopTracer
+	result countingResult
+}
+
+type countingResult struct {
+	Config json.RawMessage `json:"config"`
+	Block  *big.Int        `json:"block"`
+	Starts int             `json:"starts"`
+	Ends   int             `json:"ends"`
+	Steps  map[string]int  `json:"steps"`
+	Stores [][2]string     `json:"stores"`
+}
+
+func newCountingTracer(ctx *Context, config json.RawMessage) (Tracer, error) {
+	if string(config) == `"fail"` {
+		return nil, errors.New("bad config")
+	}
+	return &countingTracer{result: countingResult{Config: config, Block: ctx.BlockNumber, Steps: make(map[string]int)}}, nil
+}
+
+func (t *countingTracer) Start(frame *Frame) { t.result.Starts++ }
+
+func (t *countingTracer) End(result *FrameResult) { t.result.Ends++ }
+
+func (t *countingTracer) Step(step *Step) {
+	t.result.Steps[step.Op.String()]++
+	if step.Op == vm.SSTORE && len(step.Stack) >= 2 {
+		key, value := step.Stack[len(step.Stack)-1], step.Stack[len(step.Stack)-2]
+		t.result.Stores = append(t.result.Stores, [2]string{key.Hex(), value.Hex()})
+	}
+}
+
+func (t *countingTracer) Result() (json.RawMessage, error) {
+	if string(t.result.Config) == `"failResult"` {
+		return nil, errors.New("no result")
+	}
+	return json.Marshal(&t.result)
+}
+
+// testCode stores 1 at slot 0 and 2 at slot 1.
+var testCode = []byte{
+	byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+	byte(vm.PUSH1), 0x02, byte(vm.PUSH1), 0x01, byte(vm.SSTORE),
+	byte(vm.STOP),
+}
+
+// startSocketPlugin serves the counting tracer on a unix socket, registering it
+// under the given name.
+func startSocketPlugin(t *testing.T, name string) {
+	path := filepath.Join(t.TempDir(), "plugin.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	go ServeListener(listener, newCountingTracer)
+	t.Cleanup(func() { listener.Close() })
+
+	Register(name, socketPrefix+path)
+}
+
+// runTracer executes the test code with the named tracer.
+func runTracer(t *testing.T, name string, config string) (*countingResult, error) {
+	t.Helper()
+
+	ctx := &tracers.Context{BlockNumber: big.NewInt(7)}
+	tracer, err := tracers.DefaultDirectory.New(name, ctx, json.RawMessage(config))
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := runtime.Execute(testCode, nil, &runtime.Config{EVMConfig: vm.Config{Tracer: tracer}}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	result := new(countingResult)
+	if err := json.Unmarshal(res, result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	return result, nil
+}
+
+func TestSocketPlugin(t *testing.T) {
+	startSocketPlugin(t, "testSocketPlugin")
+
+	// All events are forwarded by default, without the stack
+	result, err := runTracer(t, "testSocketPlugin", `{"config": {"foo": 1}}`)
+	if err != nil {
+		t.Fatalf("trace failed: %v", err)
+	}
+	want := &countingResult{
+		Config: json.RawMessage(`{"foo":1}`),
+		Block:  big.NewInt(7),
+		Starts: 1,
+		Ends:   1,
+		Steps:  map[string]int{"PUSH1": 4, "SSTORE": 2, "STOP": 1},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result mismatch:\nhave %+v\nwant %+v", result, want)
+	}
+	// Only the storage writes with their stack, acknowledging every event
+	result, err = runTracer(t, "testSocketPlugin", `{"events": ["step"], "opcodes": ["SSTORE"], "stack": true, "window": 1}`)
+	if err != nil {
+		t.Fatalf("trace failed: %v", err)
+	}
+	want = &countingResult{
+		Config: json.RawMessage("null"),
+		Block:  big.NewInt(7),
+		Steps:  map[string]int{"SSTORE": 2},
+		Stores: [][2]string{{"0x0", "0x1"}, {"0x1", "0x2"}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("filtered result mismatch:\nhave %+v\nwant %+v", result, want)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	startSocketPlugin(t, "testFailingPlugin")
+
+	if _, err := runTracer(t, "testFailingPlugin", `{"config": "fail"}`); err == nil || err.Error() != "tracer plugin failed: bad config" {
+		t.Errorf("constructor error mismatch: have %v", err)
+	}
+	if _, err := runTracer(t, "testFailingPlugin", `{"config": "failResult"}`); err == nil || err.Error() != "tracer plugin failed: no result" {
+		t.Errorf("result error mismatch: have %v", err)
+	}
+	if _, err := runTracer(t, "testFailingPlugin", `{"events": ["bogus"]}`); err == nil {
+		t.Error("unknown event accepted")
+	}
+	if _, err := runTracer(t, "testFailingPlugin", `{"opcodes": ["BOGUS"]}`); err == nil {
+		t.Error("unknown opcode accepted")
+	}
+	// Stopping the tracer disconnects the plugin
+	tracer, err := tracers.DefaultDirectory.New("testFailingPlugin", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	tracer.Stop(errors.New("stopped"))
+	if _, _, err := runtime.Execute(testCode, nil, &runtime.Config{EVMConfig: vm.Config{Tracer: tracer}}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if _, err := tracer.GetResult(); err == nil || err.Error() != "stopped" {
+		t.Errorf("stop reason mismatch: have %v", err)
+	}
+	// Plugins which can't be reached fail the tracer construction
+	Register("testMissingPlugin", socketPrefix+filepath.Join(t.TempDir(), "missing.sock"))
+	if _, err := tracers.DefaultDirectory.New("testMissingPlugin", nil, nil); err == nil {
+		t.Error("missing plugin accepted")
+	}
+}
+
+func TestProcessPlugin(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skipf("test executable unavailable: %v", err)
+	}
+	t.Setenv(processEnv, "1")
+	Register("testProcessPlugin", executable)
+
+	result, err := runTracer(t, "testProcessPlugin", `{"opcodes": ["SSTORE"], "stack": true, "window": 2}`)
+	if err != nil {
+		t.Fatalf("trace failed: %v", err)
+	}
+	want := &countingResult{
+		Config: json.RawMessage("null"),
+		Block:  big.NewInt(7),
+		Starts: 1,
+		Ends:   1,
+		Steps:  map[string]int{"SSTORE": 2},
+		Stores: [][2]string{{"0x0", "0x1"}, {"0x1", "0x2"}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result mismatch:\nhave %+v\nwant %+v", result, want)
+	}
+}
+
+// Tests that a plugin process is reused for consecutive traces, and that it's
+// terminated when a trace is stopped instead of completed.
+func TestProcessReuse(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skipf("test executable unavailable: %v", err)
+	}
+	t.Setenv(processEnv, "1")
+
+	pool := &connPool{endpoint: executable}
+	trace := func() *tracer {
+		tracer, err := newTracer(pool, &tracers.Context{BlockNumber: big.NewInt(7)}, nil)
+		if err != nil {
+			t.Fatalf("failed to create tracer: %v", err)
+		}
+		if _, _, err := runtime.Execute(testCode, nil, &runtime.Config{EVMConfig: vm.Config{Tracer: tracer}}); err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		return tracer
+	}
+	var first *conn
+	for i := 0; i < 3; i++ {
+		tracer := trace()
+		if first == nil {
+			first = tracer.conn
+		} else if tracer.conn != first {
+			t.Fatalf("trace %d: plugin process not reused", i)
+		}
+		if _, err := tracer.GetResult(); err != nil {
+			t.Fatalf("trace %d failed: %v", i, err)
+		}
+		if len(pool.idle) != 1 {
+			t.Fatalf("trace %d: idle connection count mismatch: have %d, want 1", i, len(pool.idle))
+		}
+	}
+	// Stopping a trace without retrieving its result terminates the process
+	tracer := trace()
+	tracer.Stop(errors.New("stopped"))
+	if len(pool.idle) != 0 {
+		t.Fatalf("idle connection count mismatch: have %d, want 0", len(pool.idle))
+	}
+	if state := tracer.conn.ReadWriteCloser.(*processConn).cmd.ProcessState; state == nil {
+		t.Fatal("plugin process still running")
+	}
+}
+
+func TestProtocolRoundtrip(t *testing.T) {
+	var (
+		enc   encoder
+		from  = common.HexToAddress("0x01")
+		to    = common.HexToAddress("0x02")
+		value = big.NewInt(12345)
+	)
+	enc.uint8(uint8(vm.DELEGATECALL))
+	enc.address(from)
+	enc.address(to)
+	enc.uint64(21000)
+	enc.bigInt(value)
+	enc.rest([]byte{0xde, 0xad})
+
+	tracer := &recordingTracer{}
+	if err := dispatch(tracer, msgEnter, enc.buf); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	want := &Frame{Type: vm.DELEGATECALL, From: from, To: to, Gas: 21000, Value: value, Input: []byte{0xde, 0xad}}
+	if !reflect.DeepEqual(tracer.frame, want) {
+		t.Errorf("frame mismatch: have %+v, want %+v", tracer.frame, want)
+	}
+	// Truncated messages are rejected
+	if err := dispatch(tracer, msgEnter, enc.buf[:30]); err == nil {
+		t.Error("truncated message accepted")
+	}
+}
+
+type recordingTracer struct {
+	NoopTracer
+	frame *Frame
+}
+
+func (t *recordingTracer) Enter(frame *Frame) { t.frame = frame }
+
+func (t *recordingTracer) Result() (json.RawMessage, error) { return nil, nil }
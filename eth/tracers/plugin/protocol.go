@@ -0,0 +1,226 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package plugin implements tracers running outside of the node.
+//
+// The node streams the EVM events of a traced transaction to an external program
+// over a unix socket, or over the standard input and output of a child process.
+// The program answers with the JSON result of the trace. Every message is a frame
+// of a single type byte, a big-endian 32-bit payload length and the payload:
+//
+//	init     (host)   JSON {"context": {...}, "config": ...}, answered by ready or error
+//	txStart  (host)   gasLimit u64
+//	txEnd    (host)   restGas u64
+//	start    (host)   op u8, from [20], to [20], gas u64, value [32], input bytes
+//	end      (host)   gasUsed u64, error string, output bytes
+//	enter    (host)   same as start
+//	exit     (host)   same as end
+//	step     (host)   pc u64, op u8, gas u64, cost u64, depth u16, error string,
+//	                  stack u16 count of [32] words (top last), memory bytes
+//	fault    (host)   same as step
+//	result   (host)   empty, answered by result or error
+//	ready    (plugin) empty
+//	ack      (plugin) number of events processed u64
+//	result   (plugin) JSON result
+//	error    (plugin) error string
+//
+// Once the result or error of a trace was delivered, the node may start the next
+// trace on the same stream with another init, or close it. Plugin processes and
+// connections are thus reused for consecutive transactions.
+//
+// Strings and bytes are prefixed by their big-endian 32-bit length, except for
+// the trailing field of a message which spans the rest of the payload.
+//
+// The host stops sending events when the plugin falls behind by a configurable
+// window of unacknowledged events, so a slow plugin slows down the traced
+// execution instead of buffering events without bound.
+package plugin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// Message types sent by the host.
+const (
+	msgInit byte = iota + 1
+	msgTxStart
+	msgTxEnd
+	msgStart
+	msgEnd
+	msgEnter
+	msgExit
+	msgStep
+	msgFault
+	msgResult
+)
+
+// Message types sent by the plugin.
+const (
+	msgReady byte = iota + 0x81
+	msgAck
+	msgResultData
+	msgError
+)
+
+// maxFrameSize is the maximum size of a message, protecting both sides from
+// allocating huge buffers for corrupt frames.
+const maxFrameSize = 64 * 1024 * 1024
+
+var (
+	errFrameTooLarge = errors.New("frame too large")
+	errShortMessage  = errors.New("message too short")
+)
+
+// writeFrame writes a single message.
+func writeFrame(w *bufio.Writer, typ byte, payload []byte) error {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame reads a single message.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return 0, nil, errFrameTooLarge
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// encoder assembles the payload of a message.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) reset() { e.buf = e.buf[:0] }
+
+func (e *encoder) uint8(v uint8) { e.buf = append(e.buf, v) }
+
+func (e *encoder) uint16(v uint16) { e.buf = binary.BigEndian.AppendUint16(e.buf, v) }
+
+func (e *encoder) uint64(v uint64) { e.buf = binary.BigEndian.AppendUint64(e.buf, v) }
+
+func (e *encoder) address(addr common.Address) { e.buf = append(e.buf, addr[:]...) }
+
+func (e *encoder) word(v *uint256.Int) {
+	b := v.Bytes32()
+	e.buf = append(e.buf, b[:]...)
+}
+
+// bigInt encodes a non-negative value of up to 256 bits, nil being zero.
+func (e *encoder) bigInt(v *big.Int) {
+	var word uint256.Int
+	if v != nil {
+		word.SetFromBig(v)
+	}
+	e.word(&word)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) error(err error) {
+	if err == nil {
+		e.bytes(nil)
+		return
+	}
+	e.bytes([]byte(err.Error()))
+}
+
+// rest appends the trailing field of a message.
+func (e *encoder) rest(b []byte) { e.buf = append(e.buf, b...) }
+
+// decoder disassembles the payload of a message. Decoding errors are sticky,
+// the values decoded after an error are zero.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	if len(d.buf) < n {
+		d.err = errShortMessage
+		return make([]byte, n)
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) uint8() uint8 { return d.next(1)[0] }
+
+func (d *decoder) uint16() uint16 { return binary.BigEndian.Uint16(d.next(2)) }
+
+func (d *decoder) uint64() uint64 { return binary.BigEndian.Uint64(d.next(8)) }
+
+func (d *decoder) address() common.Address {
+	return common.BytesToAddress(d.next(common.AddressLength))
+}
+
+func (d *decoder) word() uint256.Int {
+	var word uint256.Int
+	word.SetBytes32(d.next(32))
+	return word
+}
+
+func (d *decoder) bigInt() *big.Int {
+	word := d.word()
+	return word.ToBig()
+}
+
+func (d *decoder) bytes() []byte {
+	size := binary.BigEndian.Uint32(d.next(4))
+	if int64(size) > int64(len(d.buf)) {
+		if d.err == nil {
+			d.err = errShortMessage
+		}
+		return nil
+	}
+	return common.CopyBytes(d.next(int(size)))
+}
+
+func (d *decoder) string() string { return string(d.bytes()) }
+
+func (d *decoder) rest() []byte {
+	b := common.CopyBytes(d.buf)
+	d.buf = nil
+	return b
+}
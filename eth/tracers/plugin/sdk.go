@@ -0,0 +1,308 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+// Context contains the contextual infos of the traced transaction.
+type Context struct {
+	BlockHash   common.Hash `json:"blockHash"`   // Hash of the block the tx is contained within (zero if dangling tx or call)
+	BlockNumber *big.Int    `json:"blockNumber"` // Number of the block the tx is contained within (zero if dangling tx or call)
+	TxIndex     int         `json:"txIndex"`     // Index of the transaction within a block (zero if dangling tx or call)
+	TxHash      common.Hash `json:"txHash"`      // Hash of the transaction being traced (zero if dangling call)
+}
+
+// Frame is the start of a call frame. The outermost frame is of type CALL or
+// CREATE.
+type Frame struct {
+	Type  vm.OpCode
+	From  common.Address
+	To    common.Address
+	Gas   uint64
+	Value *big.Int
+	Input []byte
+}
+
+// FrameResult is the end of a call frame.
+type FrameResult struct {
+	GasUsed uint64
+	Err     string
+	Output  []byte
+}
+
+// Step is the execution of a single instruction. The stack and the memory are
+// only available if requested in the tracer configuration.
+type Step struct {
+	Pc     uint64
+	Op     vm.OpCode
+	Gas    uint64
+	Cost   uint64
+	Depth  int
+	Err    string
+	Stack  []uint256.Int // Top of the stack last
+	Memory []byte
+}
+
+// Tracer is implemented by plugins to trace a single transaction.
+type Tracer interface {
+	TxStart(gasLimit uint64)
+	TxEnd(restGas uint64)
+	Start(frame *Frame)
+	End(result *FrameResult)
+	Enter(frame *Frame)
+	Exit(result *FrameResult)
+	Step(step *Step)
+	Fault(step *Step)
+
+	// Result returns the JSON result of the trace.
+	Result() (json.RawMessage, error)
+}
+
+// NoopTracer ignores all events, to be embedded by tracers only interested in
+// some of them.
+type NoopTracer struct{}
+
+func (NoopTracer) TxStart(gasLimit uint64)  {}
+func (NoopTracer) TxEnd(restGas uint64)     {}
+func (NoopTracer) Start(frame *Frame)       {}
+func (NoopTracer) End(result *FrameResult)  {}
+func (NoopTracer) Enter(frame *Frame)       {}
+func (NoopTracer) Exit(result *FrameResult) {}
+func (NoopTracer) Step(step *Step)          {}
+func (NoopTracer) Fault(step *Step)         {}
+
+// NewTracerFunc creates the tracer of a transaction, given the configuration
+// passed by the user in the "config" field of the tracer configuration.
+type NewTracerFunc func(ctx *Context, config json.RawMessage) (Tracer, error)
+
+// Main serves traces over the standard input and output until it's closed, as
+// used by plugins started as a process by the node.
+func Main(newTracer NewTracerFunc) {
+	if err := Serve(os.Stdin, os.Stdout, newTracer); err != nil {
+		fmt.Fprintln(os.Stderr, "Tracer plugin failed:", err)
+		os.Exit(1)
+	}
+}
+
+// ListenAndServe serves traces on a unix socket, each connection tracing
+// consecutive transactions.
+func ListenAndServe(path string, newTracer NewTracerFunc) error {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	return ServeListener(listener, newTracer)
+}
+
+// ServeListener serves traces on the connections accepted by the listener
+// until it's closed.
+func ServeListener(listener net.Listener, newTracer NewTracerFunc) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := Serve(conn, conn, newTracer); err != nil {
+				log.Debug("Tracer plugin session failed", "err", err)
+			}
+		}()
+	}
+}
+
+// Serve traces the consecutive transactions sent over the stream. It returns
+// once the node closed the stream or a trace failed.
+func Serve(r io.Reader, w io.Writer, newTracer NewTracerFunc) error {
+	var (
+		br = bufio.NewReader(r)
+		bw = bufio.NewWriter(w)
+	)
+	for {
+		s := &session{r: br, w: bw}
+		if err := s.run(newTracer); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil // Stream closed by the node
+			}
+			return err
+		}
+	}
+}
+
+// session is the plugin side of a trace.
+type session struct {
+	r *bufio.Reader
+	w *bufio.Writer
+
+	processed uint64 // Number of events processed
+	acked     uint64 // Number of events acknowledged
+	interval  uint64 // Number of events between acknowledgements
+}
+
+func (s *session) run(newTracer NewTracerFunc) error {
+	typ, payload, err := readFrame(s.r)
+	if err != nil {
+		return err
+	}
+	if typ != msgInit {
+		return fmt.Errorf("unexpected message %#x, want init", typ)
+	}
+	var init initMessage
+	if err := json.Unmarshal(payload, &init); err != nil {
+		return s.fail(err)
+	}
+	if init.Context == nil {
+		init.Context = new(Context)
+	}
+	if s.interval = init.Window / 2; s.interval == 0 {
+		s.interval = 1
+	}
+	tracer, err := newTracer(init.Context, init.Config)
+	if err != nil {
+		return s.fail(err)
+	}
+	if err := s.reply(msgReady, nil); err != nil {
+		return err
+	}
+	for {
+		typ, payload, err := readFrame(s.r)
+		if err != nil {
+			return err // Tracing aborted by the node on EOF
+		}
+		if typ == msgResult {
+			result, err := tracer.Result()
+			if err != nil {
+				return s.fail(err)
+			}
+			return s.reply(msgResultData, result)
+		}
+		if err := dispatch(tracer, typ, payload); err != nil {
+			return s.fail(err)
+		}
+		if s.processed++; s.processed-s.acked >= s.interval {
+			if err := s.reply(msgAck, binary.BigEndian.AppendUint64(nil, s.processed)); err != nil {
+				return err
+			}
+			s.acked = s.processed
+		}
+	}
+}
+
+// reply sends a message to the node.
+func (s *session) reply(typ byte, payload []byte) error {
+	if err := writeFrame(s.w, typ, payload); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// fail reports an error to the node, ending the session.
+func (s *session) fail(err error) error {
+	if werr := s.reply(msgError, []byte(err.Error())); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// dispatch decodes an event and passes it to the tracer.
+func dispatch(tracer Tracer, typ byte, payload []byte) error {
+	dec := &decoder{buf: payload}
+	switch typ {
+	case msgTxStart:
+		if gasLimit := dec.uint64(); dec.err == nil {
+			tracer.TxStart(gasLimit)
+		}
+	case msgTxEnd:
+		if restGas := dec.uint64(); dec.err == nil {
+			tracer.TxEnd(restGas)
+		}
+	case msgStart, msgEnter:
+		frame := &Frame{
+			Type:  vm.OpCode(dec.uint8()),
+			From:  dec.address(),
+			To:    dec.address(),
+			Gas:   dec.uint64(),
+			Value: dec.bigInt(),
+			Input: dec.rest(),
+		}
+		if dec.err == nil {
+			if typ == msgStart {
+				tracer.Start(frame)
+			} else {
+				tracer.Enter(frame)
+			}
+		}
+	case msgEnd, msgExit:
+		result := &FrameResult{
+			GasUsed: dec.uint64(),
+			Err:     dec.string(),
+			Output:  dec.rest(),
+		}
+		if dec.err == nil {
+			if typ == msgEnd {
+				tracer.End(result)
+			} else {
+				tracer.Exit(result)
+			}
+		}
+	case msgStep, msgFault:
+		step := &Step{
+			Pc:    dec.uint64(),
+			Op:    vm.OpCode(dec.uint8()),
+			Gas:   dec.uint64(),
+			Cost:  dec.uint64(),
+			Depth: int(dec.uint16()),
+			Err:   dec.string(),
+		}
+		if size := int(dec.uint16()); size > 0 {
+			step.Stack = make([]uint256.Int, size)
+			for i := range step.Stack {
+				step.Stack[i] = dec.word()
+			}
+		}
+		step.Memory = dec.rest()
+		if dec.err == nil {
+			if typ == msgStep {
+				tracer.Step(step)
+			} else {
+				tracer.Fault(step)
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected message %#x", typ)
+	}
+	return dec.err
+}
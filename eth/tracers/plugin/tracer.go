@@ -0,0 +1,582 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+const (
+	// defaultWindow is the default number of events the plugin may fall behind.
+	defaultWindow = 1024
+
+	// processExitTimeout is the time a plugin process is given to exit after
+	// its connection is closed, before it is killed.
+	processExitTimeout = time.Second
+
+	// maxIdleConns is the maximum number of idle connections kept per plugin for
+	// the next traces, e.g. of the transactions of a block.
+	maxIdleConns = 16
+
+	// idleConnTimeout is the time after which an idle connection is closed.
+	idleConnTimeout = 30 * time.Second
+
+	// socketPrefix marks endpoints which are unix sockets instead of executables.
+	socketPrefix = "unix://"
+)
+
+// Event filter bits.
+const (
+	eventTxStart uint16 = 1 << iota
+	eventTxEnd
+	eventStart
+	eventEnd
+	eventEnter
+	eventExit
+	eventStep
+	eventFault
+)
+
+var eventNames = map[string]uint16{
+	"txStart": eventTxStart,
+	"txEnd":   eventTxEnd,
+	"start":   eventStart,
+	"end":     eventEnd,
+	"enter":   eventEnter,
+	"exit":    eventExit,
+	"step":    eventStep,
+	"fault":   eventFault,
+}
+
+// Register makes the plugin at the given endpoint available as a tracer of the
+// given name in the default directory. The endpoint is either the path of an
+// executable, started as needed and spoken to over its standard input and
+// output, or a unix socket prefixed by "unix://". Connections are reused for
+// consecutive traces.
+func Register(name string, endpoint string) {
+	pool := &connPool{endpoint: endpoint}
+	tracers.DefaultDirectory.Register(name, func(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+		return newTracer(pool, ctx, cfg)
+	}, false)
+}
+
+// conn is a connection to a plugin.
+type conn struct {
+	io.ReadWriteCloser
+	r *bufio.Reader
+	w *bufio.Writer
+
+	idle *time.Timer // Closes the connection if it stays idle, nil while in use
+}
+
+// connPool keeps the idle connections to a plugin, so the traces of consecutive
+// transactions don't start a process or connect to the socket for each of them.
+type connPool struct {
+	endpoint string
+
+	lock sync.Mutex
+	idle []*conn // Idle connections, most recently used last
+}
+
+// get returns an idle connection, or connects to the plugin if there is none.
+// The second return value reports whether the connection was reused.
+func (p *connPool) get() (*conn, bool, error) {
+	p.lock.Lock()
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+
+		// The connection may be in the process of being closed as idle
+		if c.idle.Stop() {
+			c.idle = nil
+			p.lock.Unlock()
+			return c, true, nil
+		}
+	}
+	p.lock.Unlock()
+
+	rw, err := dial(p.endpoint)
+	if err != nil {
+		return nil, false, err
+	}
+	return &conn{ReadWriteCloser: rw, r: bufio.NewReader(rw), w: bufio.NewWriter(rw)}, false, nil
+}
+
+// put returns a connection whose trace completed to the pool.
+func (p *connPool) put(c *conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.idle) >= maxIdleConns {
+		go c.Close()
+		return
+	}
+	c.idle = time.AfterFunc(idleConnTimeout, func() {
+		p.lock.Lock()
+		for i, idle := range p.idle {
+			if idle == c {
+				p.idle = append(p.idle[:i], p.idle[i+1:]...)
+				break
+			}
+		}
+		p.lock.Unlock()
+		c.Close()
+	})
+	p.idle = append(p.idle, c)
+}
+
+// config is the tracer configuration of a plugin tracer.
+type config struct {
+	Events  []string        `json:"events"`  // Events forwarded to the plugin, all by default
+	Opcodes []string        `json:"opcodes"` // Opcodes whose steps and faults are forwarded, all by default
+	Stack   bool            `json:"stack"`   // Whether to include the stack in steps
+	Memory  bool            `json:"memory"`  // Whether to include the memory in steps
+	Window  uint64          `json:"window"`  // Maximum number of events the plugin may fall behind
+	Config  json.RawMessage `json:"config"`  // Configuration passed to the plugin
+}
+
+// initMessage is the payload of the init message.
+type initMessage struct {
+	Context *Context        `json:"context"`
+	Config  json.RawMessage `json:"config,omitempty"`
+	Window  uint64          `json:"window"`
+}
+
+// tracer forwards the EVM events to a plugin.
+type tracer struct {
+	pool *connPool
+	conn *conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	enc  encoder
+
+	events  uint16     // Bitmask of the forwarded events
+	opcodes *[256]bool // Forwarded steps and faults, nil for all
+	stack   bool
+	memory  bool
+	window  uint64
+
+	sent  uint64 // Number of events sent
+	acked uint64 // Number of events processed by the plugin
+	err   error  // Failure of the plugin, stops forwarding events
+
+	closeOnce sync.Once
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+func newTracer(pool *connPool, ctx *tracers.Context, cfg json.RawMessage) (*tracer, error) {
+	var config config
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	t := &tracer{
+		pool:   pool,
+		events: ^uint16(0),
+		stack:  config.Stack,
+		memory: config.Memory,
+		window: config.Window,
+	}
+	if t.window == 0 {
+		t.window = defaultWindow
+	}
+	if len(config.Events) > 0 {
+		t.events = 0
+		for _, name := range config.Events {
+			event, ok := eventNames[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown event %q", name)
+			}
+			t.events |= event
+		}
+	}
+	if len(config.Opcodes) > 0 {
+		t.opcodes = new([256]bool)
+		for _, name := range config.Opcodes {
+			op := vm.StringToOp(name)
+			if op.String() != name {
+				return nil, fmt.Errorf("unknown opcode %q", name)
+			}
+			t.opcodes[op] = true
+		}
+	}
+	init, err := json.Marshal(&initMessage{Context: newContext(ctx), Config: config.Config, Window: t.window})
+	if err != nil {
+		return nil, err
+	}
+	// Hand the configuration to the plugin and wait until it's ready. Reused
+	// connections may have been dropped by the plugin in the meantime, in which
+	// case the next one is tried.
+	for {
+		conn, reused, err := pool.get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to tracer plugin: %w", err)
+		}
+		t.conn, t.r, t.w = conn, conn.r, conn.w
+		if err = t.request(msgInit, init, msgReady); err == nil {
+			return t, nil
+		}
+		conn.Close()
+		if !reused || !isDisconnect(err) {
+			return nil, err
+		}
+	}
+}
+
+// isDisconnect reports whether the error is caused by the plugin having closed
+// the connection.
+func isDisconnect(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// dial connects to the plugin at the endpoint.
+func dial(endpoint string) (io.ReadWriteCloser, error) {
+	if path, ok := strings.CutPrefix(endpoint, socketPrefix); ok {
+		return net.Dial("unix", path)
+	}
+	cmd := exec.Command(endpoint)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &processConn{ReadCloser: stdout, WriteCloser: stdin, cmd: cmd}, nil
+}
+
+// processConn is the connection to a plugin process.
+type processConn struct {
+	io.ReadCloser
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// Close closes the standard input of the process, and waits for it to exit.
+func (c *processConn) Close() error {
+	c.WriteCloser.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(processExitTimeout):
+		c.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// request sends a message and waits for the answer of the given type.
+func (t *tracer) request(typ byte, payload []byte, answer byte) error {
+	if err := writeFrame(t.w, typ, payload); err != nil {
+		return err
+	}
+	if err := t.w.Flush(); err != nil {
+		return err
+	}
+	for {
+		typ, payload, err := t.read()
+		if err != nil {
+			return err
+		}
+		if typ == answer {
+			return nil
+		}
+		if typ == msgResultData {
+			return fmt.Errorf("unexpected result from tracer plugin: %s", payload)
+		}
+	}
+}
+
+// read reads the next message of the plugin, handling acknowledgements and
+// errors. Acknowledgements are passed on for the caller to make progress.
+func (t *tracer) read() (byte, []byte, error) {
+	typ, payload, err := readFrame(t.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch typ {
+	case msgAck:
+		dec := &decoder{buf: payload}
+		if acked := dec.uint64(); dec.err == nil && acked > t.acked {
+			t.acked = acked
+		}
+	case msgError:
+		return 0, nil, fmt.Errorf("tracer plugin failed: %s", payload)
+	case msgReady, msgResultData:
+	default:
+		return 0, nil, fmt.Errorf("unexpected tracer plugin message %#x", typ)
+	}
+	return typ, payload, nil
+}
+
+// send forwards an event with the payload assembled in the encoder, blocking
+// while the plugin lags behind by a full window.
+func (t *tracer) send(typ byte) {
+	if t.err != nil || t.interrupt.Load() {
+		return
+	}
+	if err := writeFrame(t.w, typ, t.enc.buf); err != nil {
+		t.fail(err)
+		return
+	}
+	t.sent++
+	if t.sent-t.acked < t.window {
+		return
+	}
+	if err := t.w.Flush(); err != nil {
+		t.fail(err)
+		return
+	}
+	for t.sent-t.acked >= t.window {
+		if _, _, err := t.read(); err != nil {
+			t.fail(err)
+			return
+		}
+	}
+}
+
+// fail records the first failure of the plugin, after which no further events
+// are sent. The failure is reported with the result.
+func (t *tracer) fail(err error) {
+	if t.interrupt.Load() {
+		return // The connection was closed by Stop
+	}
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// close ends the session, disconnecting the plugin.
+func (t *tracer) close() {
+	t.closeOnce.Do(func() { t.conn.Close() })
+}
+
+// release ends the session after a delivered result, returning the connection
+// to the pool unless the session was closed already.
+func (t *tracer) release() {
+	t.closeOnce.Do(func() { t.pool.put(t.conn) })
+}
+
+// CaptureTxStart implements the EVMLogger interface.
+func (t *tracer) CaptureTxStart(gasLimit uint64) {
+	if t.events&eventTxStart == 0 {
+		return
+	}
+	t.enc.reset()
+	t.enc.uint64(gasLimit)
+	t.send(msgTxStart)
+}
+
+// CaptureTxEnd implements the EVMLogger interface.
+func (t *tracer) CaptureTxEnd(restGas uint64) {
+	if t.events&eventTxEnd == 0 {
+		return
+	}
+	t.enc.reset()
+	t.enc.uint64(restGas)
+	t.send(msgTxEnd)
+}
+
+// CaptureStart implements the EVMLogger interface.
+func (t *tracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	if t.events&eventStart == 0 {
+		return
+	}
+	op := vm.CALL
+	if create {
+		op = vm.CREATE
+	}
+	t.encodeFrame(op, from, to, input, gas, value)
+	t.send(msgStart)
+}
+
+// CaptureEnd implements the EVMLogger interface.
+func (t *tracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if t.events&eventEnd == 0 {
+		return
+	}
+	t.encodeFrameResult(output, gasUsed, err)
+	t.send(msgEnd)
+}
+
+// CaptureEnter implements the EVMLogger interface.
+func (t *tracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.events&eventEnter == 0 {
+		return
+	}
+	t.encodeFrame(typ, from, to, input, gas, value)
+	t.send(msgEnter)
+}
+
+// CaptureExit implements the EVMLogger interface.
+func (t *tracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.events&eventExit == 0 {
+		return
+	}
+	t.encodeFrameResult(output, gasUsed, err)
+	t.send(msgExit)
+}
+
+// CaptureState implements the EVMLogger interface.
+func (t *tracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.events&eventStep == 0 || (t.opcodes != nil && !t.opcodes[op]) {
+		return
+	}
+	t.encodeStep(pc, op, gas, cost, scope, depth, err)
+	t.send(msgStep)
+}
+
+// CaptureFault implements the EVMLogger interface.
+func (t *tracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if t.events&eventFault == 0 || (t.opcodes != nil && !t.opcodes[op]) {
+		return
+	}
+	t.encodeStep(pc, op, gas, cost, scope, depth, err)
+	t.send(msgFault)
+}
+
+func (t *tracer) encodeFrame(op vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.enc.reset()
+	t.enc.uint8(uint8(op))
+	t.enc.address(from)
+	t.enc.address(to)
+	t.enc.uint64(gas)
+	t.enc.bigInt(value)
+	t.enc.rest(input)
+}
+
+func (t *tracer) encodeFrameResult(output []byte, gasUsed uint64, err error) {
+	t.enc.reset()
+	t.enc.uint64(gasUsed)
+	t.enc.error(err)
+	t.enc.rest(output)
+}
+
+func (t *tracer) encodeStep(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.enc.reset()
+	t.enc.uint64(pc)
+	t.enc.uint8(uint8(op))
+	t.enc.uint64(gas)
+	t.enc.uint64(cost)
+	t.enc.uint16(uint16(depth))
+	t.enc.error(err)
+	if t.stack {
+		stack := scope.Stack.Data()
+		t.enc.uint16(uint16(len(stack)))
+		for i := range stack {
+			t.enc.word(&stack[i])
+		}
+	} else {
+		t.enc.uint16(0)
+	}
+	if t.memory {
+		t.enc.rest(scope.Memory.Data())
+	}
+}
+
+// GetResult requests the result from the plugin and ends the session.
+func (t *tracer) GetResult() (json.RawMessage, error) {
+	if t.interrupt.Load() {
+		t.close()
+		return nil, t.reason
+	}
+	if t.err != nil {
+		t.close()
+		return nil, t.err
+	}
+	res, err := t.result()
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+	t.release()
+	return res, nil
+}
+
+// result requests the result from the plugin.
+func (t *tracer) result() (json.RawMessage, error) {
+	if err := writeFrame(t.w, msgResult, nil); err != nil {
+		return nil, err
+	}
+	if err := t.w.Flush(); err != nil {
+		return nil, err
+	}
+	for {
+		typ, payload, err := t.read()
+		if err != nil {
+			if t.interrupt.Load() {
+				return nil, t.reason
+			}
+			return nil, err
+		}
+		if typ == msgResultData {
+			if !json.Valid(payload) {
+				return nil, errors.New("invalid result from tracer plugin")
+			}
+			return payload, nil
+		}
+	}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment,
+// disconnecting the plugin.
+func (t *tracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+	t.close()
+}
+
+// newContext converts the tracing context for the plugin.
+func newContext(ctx *tracers.Context) *Context {
+	if ctx == nil {
+		return new(Context)
+	}
+	return &Context{
+		BlockHash:   ctx.BlockHash,
+		BlockNumber: ctx.BlockNumber,
+		TxIndex:     ctx.TxIndex,
+		TxHash:      ctx.TxHash,
+	}
+}
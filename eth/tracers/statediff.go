@@ -0,0 +1,139 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DiffKind classifies the change of an account in a StateDiff.
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"   // The account didn't exist before
+	DiffRemoved DiffKind = "removed" // The account doesn't exist afterwards
+	DiffChanged DiffKind = "changed" // The account exists before and afterwards
+)
+
+// Change is the change of a single value.
+type Change[T any] struct {
+	From T `json:"from"`
+	To   T `json:"to"`
+}
+
+// AccountDiff is the change of a single account. Only the changed fields are set.
+//
+// Storage changes are limited to the slots accessed during execution, so the
+// storage wiped by destroying an account is not listed completely.
+type AccountDiff struct {
+	Kind      DiffKind                            `json:"kind"`
+	Created   bool                                `json:"created,omitempty"`   // Contract deployed to the account
+	Destroyed bool                                `json:"destroyed,omitempty"` // Account removed by a self-destruct (EIP-6780: only in its creation tx)
+	Balance   *Change[*hexutil.Big]               `json:"balance,omitempty"`
+	Nonce     *Change[hexutil.Uint64]             `json:"nonce,omitempty"`
+	Code      *Change[hexutil.Bytes]              `json:"code,omitempty"`
+	Storage   map[common.Hash]Change[common.Hash] `json:"storage,omitempty"`
+}
+
+// Empty reports whether the diff contains no change at all.
+func (d *AccountDiff) Empty() bool {
+	return d.Kind == DiffChanged && !d.Created && !d.Destroyed &&
+		d.Balance == nil && d.Nonce == nil && d.Code == nil && len(d.Storage) == 0
+}
+
+// copy returns a copy of the diff which can be merged into without modifying
+// the original one.
+func (d *AccountDiff) copy() *AccountDiff {
+	cpy := *d
+	if d.Storage != nil {
+		cpy.Storage = make(map[common.Hash]Change[common.Hash], len(d.Storage))
+		for slot, change := range d.Storage {
+			cpy.Storage[slot] = change
+		}
+	}
+	return &cpy
+}
+
+// StateDiff is the change of the state caused by a transaction or a block, as
+// produced by the stateDiffTracer.
+type StateDiff map[common.Address]*AccountDiff
+
+// Merge folds the diff of a subsequent transaction into the diff, aggregating the
+// changes into a diff of both transactions. Accounts which end up unchanged are
+// removed.
+func (d StateDiff) Merge(next StateDiff) {
+	for addr, b := range next {
+		a, ok := d[addr]
+		if !ok {
+			d[addr] = b.copy()
+			continue
+		}
+		existedBefore, existsAfter := a.Kind != DiffAdded, b.Kind != DiffRemoved
+		switch {
+		case existedBefore && existsAfter:
+			a.Kind = DiffChanged
+		case existedBefore:
+			a.Kind = DiffRemoved
+		case existsAfter:
+			a.Kind = DiffAdded
+		default:
+			// The account was only alive between the transactions
+			delete(d, addr)
+			continue
+		}
+		a.Created = (a.Created && !b.Destroyed) || b.Created
+		a.Destroyed = (a.Destroyed && !b.Created) || b.Destroyed
+
+		a.Balance = mergeChange(a.Balance, b.Balance, func(x, y *hexutil.Big) bool { return x.ToInt().Cmp(y.ToInt()) == 0 })
+		a.Nonce = mergeChange(a.Nonce, b.Nonce, func(x, y hexutil.Uint64) bool { return x == y })
+		a.Code = mergeChange(a.Code, b.Code, func(x, y hexutil.Bytes) bool { return bytes.Equal(x, y) })
+		for slot, change := range b.Storage {
+			if prev, ok := a.Storage[slot]; ok {
+				change.From = prev.From
+			}
+			if change.From == change.To {
+				delete(a.Storage, slot)
+				continue
+			}
+			if a.Storage == nil {
+				a.Storage = make(map[common.Hash]Change[common.Hash])
+			}
+			a.Storage[slot] = change
+		}
+		if a.Empty() {
+			delete(d, addr)
+		}
+	}
+}
+
+// mergeChange combines two subsequent changes of a value, dropping it if it
+// ended up unchanged.
+func mergeChange[T any](a, b *Change[T], equal func(x, y T) bool) *Change[T] {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case equal(a.From, b.To):
+		return nil
+	default:
+		return &Change[T]{From: a.From, To: b.To}
+	}
+}
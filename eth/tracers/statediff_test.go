@@ -0,0 +1,72 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func balanceChange(from, to int64) *Change[*hexutil.Big] {
+	return &Change[*hexutil.Big]{From: (*hexutil.Big)(big.NewInt(from)), To: (*hexutil.Big)(big.NewInt(to))}
+}
+
+func TestStateDiffMerge(t *testing.T) {
+	var (
+		sender   = common.HexToAddress("0x01")
+		contract = common.HexToAddress("0x02")
+		temp     = common.HexToAddress("0x03")
+		slot1    = common.HexToHash("0x01")
+		slot2    = common.HexToHash("0x02")
+	)
+	diff := StateDiff{
+		sender: {Kind: DiffChanged, Balance: balanceChange(100, 90), Nonce: &Change[hexutil.Uint64]{From: 0, To: 1}},
+		contract: {Kind: DiffAdded, Created: true, Code: &Change[hexutil.Bytes]{From: hexutil.Bytes{}, To: hexutil.Bytes{0x00}}, Storage: map[common.Hash]Change[common.Hash]{
+			slot1: {From: common.Hash{}, To: common.HexToHash("0x2a")},
+		}},
+		temp: {Kind: DiffAdded, Balance: balanceChange(0, 5)},
+	}
+	next := StateDiff{
+		sender: {Kind: DiffChanged, Balance: balanceChange(90, 100)},
+		contract: {Kind: DiffChanged, Storage: map[common.Hash]Change[common.Hash]{
+			slot1: {From: common.HexToHash("0x2a"), To: common.Hash{}},
+			slot2: {From: common.Hash{}, To: common.HexToHash("0x01")},
+		}},
+		temp: {Kind: DiffRemoved, Balance: balanceChange(5, 0)},
+	}
+	diff.Merge(next)
+
+	// The sender's balance was restored, but the nonce was bumped
+	if have := diff[sender]; have == nil || have.Balance != nil || have.Nonce == nil || have.Nonce.From != 0 || have.Nonce.To != 1 {
+		t.Errorf("sender diff mismatch: have %+v", have)
+	}
+	// The contract stays added and created, with only the second slot changed
+	have := diff[contract]
+	if have == nil || have.Kind != DiffAdded || !have.Created || have.Code == nil {
+		t.Fatalf("contract diff mismatch: have %+v", have)
+	}
+	if len(have.Storage) != 1 || have.Storage[slot2].To != common.HexToHash("0x01") {
+		t.Errorf("contract storage mismatch: have %v", have.Storage)
+	}
+	// The account only alive between the transactions is gone
+	if have, ok := diff[temp]; ok {
+		t.Errorf("temporary account in diff: %+v", have)
+	}
+}
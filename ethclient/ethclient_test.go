@@ -533,6 +533,11 @@ func testStatusFunctions(t *testing.T, client *rpc.Client) {
 			big.NewInt(671627818),
 		},
 		GasUsedRatio: []float64{0.008912678667376286},
+		BlobBaseFee: []*big.Int{
+			new(big.Int).SetBits([]big.Word{}), // decoded zeros are non-nil
+			new(big.Int).SetBits([]big.Word{}),
+		},
+		BlobGasUsedRatio: []float64{0},
 	}
 	if !reflect.DeepEqual(history, want) {
 		t.Fatalf("FeeHistory result doesn't match expected: (got: %v, want: %v)", history, want)
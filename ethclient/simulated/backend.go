@@ -77,9 +77,12 @@ func NewBackend(alloc core.GenesisAlloc, options ...func(nodeConf *node.Config,
 	nodeConf.DataDir = ""
 	nodeConf.P2P = p2p.Config{NoDiscovery: true}
 
+	// Copy the chain config, as forks may be rescheduled on the live chain
+	config := *params.AllDevChainProtocolChanges
+
 	ethConf := ethconfig.Defaults
 	ethConf.Genesis = &core.Genesis{
-		Config:   params.AllDevChainProtocolChanges,
+		Config:   &config,
 		GasLimit: ethconfig.Defaults.Miner.GasCeil,
 		Alloc:    alloc,
 	}
@@ -181,6 +184,25 @@ func (n *Backend) AdjustTime(adjustment time.Duration) error {
 	return n.beacon.AdjustTime(adjustment)
 }
 
+// Reorg replaces the last depth blocks of the chain with newly built ones and
+// returns the hash of the new head. Transactions of the dropped blocks which
+// are not included in the new ones are returned to the transaction pool.
+func (n *Backend) Reorg(depth uint64) (common.Hash, error) {
+	return n.beacon.Reorg(depth)
+}
+
+// SetFinalityDelay makes the finalized block lag the given number of blocks
+// behind the head's epoch boundary.
+func (n *Backend) SetFinalityDelay(blocks uint64) {
+	n.beacon.SetFinalityDelay(blocks)
+}
+
+// SkipSlots leaves the given number of slots empty, advancing the timestamp of
+// the next committed block accordingly.
+func (n *Backend) SkipSlots(slots uint64) {
+	n.beacon.SkipSlots(slots)
+}
+
 // Client returns a client that accesses the simulated chain.
 func (n *Backend) Client() Client {
 	return n.client
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var _ bind.ContractBackend = (Client)(nil)
@@ -307,3 +308,99 @@ func TestAdjustTimeAfterFork(t *testing.T) {
 		t.Errorf("failed to build block on fork")
 	}
 }
+
+// Tests that reorging the head of the chain drops the transactions of the old
+// blocks back into the pool, from where they are included again.
+func TestReorg(t *testing.T) {
+	t.Parallel()
+	sim := simTestBackend(testAddr)
+	defer sim.Close()
+
+	client := sim.Client()
+	ctx := context.Background()
+
+	tx, err := newTx(sim, testKey)
+	if err != nil {
+		t.Fatalf("could not create transaction: %v", err)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("sending transaction: %v", err)
+	}
+	sim.Commit()
+	old := sim.Commit()
+
+	if _, err := sim.Reorg(3); err == nil {
+		t.Fatal("reorg deeper than the chain succeeded")
+	}
+	head, err := sim.Reorg(2)
+	if err != nil {
+		t.Fatalf("reorg failed: %v", err)
+	}
+	if head == old {
+		t.Fatal("head unchanged after reorg")
+	}
+	header, _ := client.HeaderByNumber(ctx, nil)
+	if header.Hash() != head || header.Number.Uint64() != 2 {
+		t.Fatalf("head mismatch: have %d/%x, want %d/%x", header.Number, header.Hash(), 2, head)
+	}
+	if _, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+		t.Fatal("transaction still included after reorg")
+	}
+	sim.Commit()
+	receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("transaction not included again: %v", err)
+	}
+	if n := receipt.BlockNumber.Uint64(); n != 3 {
+		t.Errorf("transaction included in wrong block: have %d, want %d", n, 3)
+	}
+}
+
+// Tests that finality can be delayed.
+func TestFinalityDelay(t *testing.T) {
+	t.Parallel()
+	sim := simTestBackend(testAddr)
+	defer sim.Close()
+
+	client := sim.Client()
+	ctx := context.Background()
+
+	sim.SetFinalityDelay(10)
+	for i := 0; i < 40; i++ {
+		sim.Commit()
+	}
+	final, err := client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		t.Fatalf("failed to retrieve finalized header: %v", err)
+	}
+	if final.Number.Uint64() != 0 {
+		t.Errorf("finalized number mismatch: have %d, want %d", final.Number, 0)
+	}
+	sim.SetFinalityDelay(0)
+	sim.Commit()
+
+	final, _ = client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if final.Number.Uint64() != 32 {
+		t.Errorf("finalized number mismatch: have %d, want %d", final.Number, 32)
+	}
+}
+
+// Tests that empty slots advance the timestamp of the next block.
+func TestSkipSlots(t *testing.T) {
+	t.Parallel()
+	sim := simTestBackend(testAddr)
+	defer sim.Close()
+
+	client := sim.Client()
+	ctx := context.Background()
+
+	sim.Commit()
+	prev, _ := client.HeaderByNumber(ctx, nil)
+
+	sim.SkipSlots(100)
+	sim.Commit()
+	head, _ := client.HeaderByNumber(ctx, nil)
+	if head.Time < prev.Time+100 {
+		t.Errorf("timestamp not advanced: have %d, want at least %d", head.Time, prev.Time+100)
+	}
+}
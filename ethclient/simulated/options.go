@@ -17,10 +17,12 @@
 package simulated
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params/forks"
 )
 
 // WithBlockGasLimit configures the simulated backend to target a specific gas limit
@@ -53,3 +55,24 @@ func WithMinerMinTip(tip *big.Int) func(nodeConf *node.Config, ethConf *ethconfi
 		ethConf.Miner.GasPrice = tip
 	}
 }
+
+// WithForkTime configures the simulated backend to activate a timestamp based
+// fork (Shanghai or Cancun) at the given block time. Forks are active from the
+// genesis block by default, a later time allows testing contracts across the
+// transition.
+func WithForkTime(fork forks.Fork, timestamp uint64) func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+	if fork != forks.Shanghai && fork != forks.Cancun {
+		panic(fmt.Sprintf("unsupported fork %d", fork))
+	}
+	return func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+		// Copy the config to avoid modifying a shared instance
+		config := *ethConf.Genesis.Config
+		switch fork {
+		case forks.Shanghai:
+			config.ShanghaiTime = &timestamp
+		case forks.Cancun:
+			config.CancunTime = &timestamp
+		}
+		ethConf.Genesis.Config = &config
+	}
+}
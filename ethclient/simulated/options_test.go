@@ -21,10 +21,12 @@ import (
 	"math/big"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/params/forks"
 )
 
 // Tests that the simulator starts with the initial gas limit in the genesis block,
@@ -71,3 +73,30 @@ func TestWithCallGasLimitOption(t *testing.T) {
 		t.Fatalf("error mismatch: have %v, want %v", err, core.ErrIntrinsicGas)
 	}
 }
+
+// Tests that the simulator activates forks at the configured time.
+func TestWithForkTimeOption(t *testing.T) {
+	sim := NewBackend(core.GenesisAlloc{}, WithForkTime(forks.Cancun, uint64(time.Now().Unix())+1000))
+	defer sim.Close()
+
+	client := sim.Client()
+	sim.Commit()
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve head block: %v", err)
+	}
+	if head.ExcessBlobGas != nil {
+		t.Fatal("cancun active before fork time")
+	}
+	if err := sim.AdjustTime(2000 * time.Second); err != nil {
+		t.Fatalf("failed to adjust time: %v", err)
+	}
+	head, _ = client.HeaderByNumber(context.Background(), nil)
+	if head.ExcessBlobGas == nil {
+		t.Fatal("cancun not active after fork time")
+	}
+	// Ensure the shared default config was not modified
+	if params.AllDevChainProtocolChanges.CancunTime != nil {
+		t.Fatal("default chain config modified")
+	}
+}
@@ -224,10 +224,12 @@ type FeeHistoryReader interface {
 // FeeHistory provides recent fee market data that consumers can use to determine
 // a reasonable maxPriorityFeePerGas value.
 type FeeHistory struct {
-	OldestBlock  *big.Int     // block corresponding to first response value
-	Reward       [][]*big.Int // list every txs priority fee per block
-	BaseFee      []*big.Int   // list of each block's base fee
-	GasUsedRatio []float64    // ratio of gas used out of the total available limit
+	OldestBlock      *big.Int     // block corresponding to first response value
+	Reward           [][]*big.Int // list every txs priority fee per block
+	BaseFee          []*big.Int   // list of each block's base fee
+	GasUsedRatio     []float64    // ratio of gas used out of the total available limit
+	BlobBaseFee      []*big.Int   // list of each block's blob base fee
+	BlobGasUsedRatio []float64    // ratio of blob gas used out of the total available limit
 }
 
 // A PendingStateReader provides access to the pending state, which is the result of all
@@ -17,11 +17,13 @@
 package ethapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -36,10 +38,12 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/gasestimator"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -89,15 +93,17 @@ func (s *EthereumAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, e
 }
 
 type feeHistoryResult struct {
-	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
-	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
-	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
-	GasUsedRatio []float64        `json:"gasUsedRatio"`
+	OldestBlock      *hexutil.Big     `json:"oldestBlock"`
+	Reward           [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee          []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio     []float64        `json:"gasUsedRatio"`
+	BlobBaseFee      []*hexutil.Big   `json:"baseFeePerBlobGas,omitempty"`
+	BlobGasUsedRatio []float64        `json:"blobGasUsedRatio,omitempty"`
 }
 
 // FeeHistory returns the fee market history.
 func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
-	oldest, reward, baseFee, gasUsed, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
+	oldest, reward, baseFee, gasUsed, blobBaseFee, blobGasUsed, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
 	if err != nil {
 		return nil, err
 	}
@@ -120,9 +126,47 @@ func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecim
 			results.BaseFee[i] = (*hexutil.Big)(v)
 		}
 	}
+	if blobBaseFee != nil {
+		results.BlobBaseFee = make([]*hexutil.Big, len(blobBaseFee))
+		for i, v := range blobBaseFee {
+			results.BlobBaseFee[i] = (*hexutil.Big)(v)
+		}
+	}
+	if blobGasUsed != nil {
+		results.BlobGasUsedRatio = blobGasUsed
+	}
 	return results, nil
 }
 
+// feeSuggestionResult is the set of fee parameters suggested for a transaction.
+type feeSuggestionResult struct {
+	BaseFee              *hexutil.Big `json:"baseFeePerGas"`
+	BlobBaseFee          *hexutil.Big `json:"blobBaseFeePerGas,omitempty"`
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
+	MaxFeePerBlobGas     *hexutil.Big `json:"maxFeePerBlobGas,omitempty"`
+}
+
+// SuggestFees returns a complete set of fee parameters for a transaction to be
+// included at the requested speed: slow, standard (default) or fast.
+func (s *EthereumAPI) SuggestFees(ctx context.Context, speed *string) (*feeSuggestionResult, error) {
+	mode := gasprice.FeeSpeedStandard
+	if speed != nil {
+		mode = gasprice.FeeSpeed(*speed)
+	}
+	fees, err := s.b.SuggestFees(ctx, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &feeSuggestionResult{
+		BaseFee:              (*hexutil.Big)(fees.BaseFee),
+		BlobBaseFee:          (*hexutil.Big)(fees.BlobBaseFee),
+		MaxFeePerGas:         (*hexutil.Big)(fees.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*hexutil.Big)(fees.MaxPriorityFeePerGas),
+		MaxFeePerBlobGas:     (*hexutil.Big)(fees.MaxFeePerBlobGas),
+	}, nil
+}
+
 // Syncing returns false in case the node is currently not syncing with the network. It can be up-to-date or has not
 // yet received the latest block headers from its pears. In case it is synchronizing:
 // - startingBlock: block number this node started to synchronize from
@@ -453,11 +497,11 @@ func (s *PersonalAccountAPI) signTransaction(ctx context.Context, args *Transact
 		return nil, err
 	}
 	// Set some sanity defaults and terminate on failure
-	if err := args.setDefaults(ctx, s.b); err != nil {
+	if err := args.SetDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
 	// Assemble the transaction and sign with the wallet
-	tx := args.toTransaction()
+	tx := args.ToTransaction()
 
 	return wallet.SignTxWithPassphrase(account, passwd, tx, s.b.ChainConfig().ChainID)
 }
@@ -506,7 +550,7 @@ func (s *PersonalAccountAPI) SignTransaction(ctx context.Context, args Transacti
 		return nil, errors.New("nonce not specified")
 	}
 	// Before actually signing the transaction, ensure the transaction fee is reasonable.
-	tx := args.toTransaction()
+	tx := args.ToTransaction()
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
 		return nil, err
 	}
@@ -1456,15 +1500,42 @@ type accessListResult struct {
 	Accesslist *types.AccessList `json:"accessList"`
 	Error      string            `json:"error,omitempty"`
 	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+
+	// Fields of the optimized mode
+	GasUsedWithoutList *hexutil.Uint64    `json:"gasUsedWithoutList,omitempty"`
+	Entries            []*AccessListEntry `json:"entries,omitempty"`
+}
+
+// AccessListOptions configures the access list creation.
+type AccessListOptions struct {
+	// Optimize prunes the entries costing more than they save and reports the
+	// gas effect of every entry.
+	Optimize bool `json:"optimize"`
+}
+
+// AccessListEntry is the gas effect of an address or storage slot entry of an
+// access list, as measured by executing the transaction without it. The effect
+// of an address entry includes the storage slots listed with it.
+type AccessListEntry struct {
+	Address    common.Address `json:"address"`
+	StorageKey *common.Hash   `json:"storageKey,omitempty"`
+	Cost       hexutil.Uint64 `json:"cost"`     // Intrinsic gas of the entry
+	Savings    hexutil.Uint64 `json:"savings"`  // Execution gas saved by the entry
+	Included   bool           `json:"included"` // Whether the entry is in the optimized list
 }
 
 // CreateAccessList creates an EIP-2930 type AccessList for the given transaction.
 // Reexec and BlockNrOrHash can be specified to create the accessList on top of a certain state.
-func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+// In optimized mode, the unprofitable entries are pruned and the gas used without
+// an access list is reported, together with the effect of each entry.
+func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, opts *AccessListOptions) (*accessListResult, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
+	if opts != nil && opts.Optimize {
+		return OptimizedAccessList(ctx, s.b, bNrOrHash, args, s.b.RPCEVMTimeout())
+	}
 	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
 	if err != nil {
 		return nil, err
@@ -1476,14 +1547,24 @@ func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionAr
 	return result, nil
 }
 
-// AccessList creates an access list for the given transaction.
-// If the accesslist creation fails an error is returned.
-// If the transaction itself fails, an vmErr is returned.
-func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+// accessListRunner executes a transaction with different access lists on top
+// of the same state.
+type accessListRunner struct {
+	b           Backend
+	db          *state.StateDB
+	header      *types.Header
+	args        TransactionArgs
+	to          common.Address
+	precompiles []common.Address
+}
+
+// newAccessListRunner retrieves the execution context and fills the missing
+// fields of the transaction.
+func newAccessListRunner(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs) (*accessListRunner, error) {
 	// Retrieve the execution context
 	db, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if db == nil || err != nil {
-		return nil, 0, nil, err
+		return nil, err
 	}
 	// If the gas amount is not set, default to RPC gas cap.
 	if args.Gas == nil {
@@ -1492,8 +1573,8 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 	}
 
 	// Ensure any missing fields are filled, extract the recipient and input data
-	if err := args.setDefaults(ctx, b); err != nil {
-		return nil, 0, nil, err
+	if err := args.SetDefaults(ctx, b); err != nil {
+		return nil, err
 	}
 	var to common.Address
 	if args.To != nil {
@@ -1505,38 +1586,223 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 	// Retrieve the precompiles since they don't need to be added to the access list
 	precompiles := vm.ActivePrecompiles(b.ChainConfig().Rules(header.Number, isPostMerge, header.Time))
 
+	return &accessListRunner{b: b, db: db, header: header, args: args, to: to, precompiles: precompiles}, nil
+}
+
+// run applies the transaction with the given access list, tracing the accounts
+// and storage slots it touches.
+func (r *accessListRunner) run(ctx context.Context, accessList types.AccessList) (*core.ExecutionResult, *logger.AccessListTracer, error) {
+	// Copy the original db so we don't modify it
+	statedb := r.db.Copy()
+	// Set the accesslist to the last al
+	args := r.args
+	args.AccessList = &accessList
+	msg, err := args.ToMessage(r.b.RPCGasCap(), r.header.BaseFee)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Apply the transaction with the access list tracer
+	tracer := logger.NewAccessListTracer(accessList, args.from(), r.to, r.precompiles)
+	config := vm.Config{Tracer: tracer, NoBaseFee: true}
+	vmenv := r.b.GetEVM(ctx, msg, statedb, r.header, &config, nil)
+
+	// Abort the execution if the request is cancelled or times out
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			vmenv.Cancel()
+		case <-done:
+		}
+	}()
+	res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if vmenv.Cancelled() {
+		return nil, nil, fmt.Errorf("execution aborted: %w", ctx.Err())
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply transaction: %v err: %v", args.ToTransaction().Hash(), err)
+	}
+	return res, tracer, nil
+}
+
+// converge expands the access list until it contains everything touched by
+// the transaction.
+func (r *accessListRunner) converge(ctx context.Context) (types.AccessList, *core.ExecutionResult, error) {
 	// Create an initial tracer
-	prevTracer := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
-	if args.AccessList != nil {
-		prevTracer = logger.NewAccessListTracer(*args.AccessList, args.from(), to, precompiles)
+	prevTracer := logger.NewAccessListTracer(nil, r.args.from(), r.to, r.precompiles)
+	if r.args.AccessList != nil {
+		prevTracer = logger.NewAccessListTracer(*r.args.AccessList, r.args.from(), r.to, r.precompiles)
 	}
 	for {
 		// Retrieve the current access list to expand
 		accessList := prevTracer.AccessList()
 		log.Trace("Creating access list", "input", accessList)
 
-		// Copy the original db so we don't modify it
-		statedb := db.Copy()
-		// Set the accesslist to the last al
-		args.AccessList = &accessList
-		msg, err := args.ToMessage(b.RPCGasCap(), header.BaseFee)
+		res, tracer, err := r.run(ctx, accessList)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tracer.Equal(prevTracer) {
+			return accessList, res, nil
+		}
+		prevTracer = tracer
+	}
+}
+
+// AccessList creates an access list for the given transaction.
+// If the accesslist creation fails an error is returned.
+// If the transaction itself fails, an vmErr is returned.
+func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+	runner, err := newAccessListRunner(ctx, b, blockNrOrHash, args)
+	if runner == nil || err != nil {
+		return nil, 0, nil, err
+	}
+	acl, res, err := runner.converge(ctx)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return acl, res.UsedGas, res.Err, nil
+}
+
+// maxOptimizedAccessListEntries is the maximum number of address and storage
+// slot entries an access list may have to be optimized, as every entry is
+// measured by executing the transaction once more.
+const maxOptimizedAccessListEntries = 256
+
+// OptimizedAccessList creates an access list for the given transaction, keeping
+// only the entries which reduce the gas used. Starting from the full access list,
+// every storage slot and then every address entry is dropped if executing the
+// transaction without it uses no more gas.
+//
+// The timeout bounds all the executions together rather than each of them, and
+// access lists with too many entries are refused upfront.
+func OptimizedAccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs, timeout time.Duration) (*accessListResult, error) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	runner, err := newAccessListRunner(ctx, b, blockNrOrHash, args)
+	if runner == nil || err != nil {
+		return nil, err
+	}
+	acl, res, err := runner.converge(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count := len(acl) + acl.StorageKeys(); count > maxOptimizedAccessListEntries {
+		return nil, fmt.Errorf("access list too large to optimize: have %d entries, max %d", count, maxOptimizedAccessListEntries)
+	}
+	sortAccessList(acl)
+
+	bare, _, err := runner.run(ctx, types.AccessList{})
+	if err != nil {
+		return nil, err
+	}
+	var entries []*AccessListEntry
+
+	// measure executes the transaction without an entry, returning the result
+	// and whether the entry saves gas, along with its execution gas savings
+	measure := func(without types.AccessList, cost uint64) (*core.ExecutionResult, bool, uint64, error) {
+		trial, _, err := runner.run(ctx, without)
 		if err != nil {
-			return nil, 0, nil, err
+			return nil, false, 0, err
+		}
+		// Entries making the difference between success and failure are kept
+		if (trial.Err == nil) != (res.Err == nil) {
+			return trial, true, cost, nil
 		}
+		var savings uint64
+		if trial.UsedGas+cost > res.UsedGas {
+			savings = trial.UsedGas + cost - res.UsedGas
+		}
+		return trial, trial.UsedGas > res.UsedGas, savings, nil
+	}
+	for i := range acl {
+		for j := 0; j < len(acl[i].StorageKeys); {
+			key := acl[i].StorageKeys[j]
+			without := copyAccessList(acl)
+			without[i].StorageKeys = append(without[i].StorageKeys[:j], without[i].StorageKeys[j+1:]...)
 
-		// Apply the transaction with the access list tracer
-		tracer := logger.NewAccessListTracer(accessList, args.from(), to, precompiles)
-		config := vm.Config{Tracer: tracer, NoBaseFee: true}
-		vmenv := b.GetEVM(ctx, msg, statedb, header, &config, nil)
-		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
+			trial, keep, savings, err := measure(without, params.TxAccessListStorageKeyGas)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &AccessListEntry{
+				Address:    acl[i].Address,
+				StorageKey: &key,
+				Cost:       hexutil.Uint64(params.TxAccessListStorageKeyGas),
+				Savings:    hexutil.Uint64(savings),
+				Included:   keep,
+			})
+			if keep {
+				j++
+				continue
+			}
+			acl, res = without, trial
+		}
+	}
+	for i := 0; i < len(acl); {
+		tuple := acl[i]
+		without := append(copyAccessList(acl[:i]), copyAccessList(acl[i+1:])...)
+
+		cost := params.TxAccessListAddressGas + uint64(len(tuple.StorageKeys))*params.TxAccessListStorageKeyGas
+		trial, keep, savings, err := measure(without, cost)
 		if err != nil {
-			return nil, 0, nil, fmt.Errorf("failed to apply transaction: %v err: %v", args.toTransaction().Hash(), err)
+			return nil, err
 		}
-		if tracer.Equal(prevTracer) {
-			return accessList, res.UsedGas, res.Err, nil
+		entries = append(entries, &AccessListEntry{
+			Address:  tuple.Address,
+			Cost:     hexutil.Uint64(cost),
+			Savings:  hexutil.Uint64(savings),
+			Included: keep,
+		})
+		if keep {
+			i++
+			continue
 		}
-		prevTracer = tracer
+		acl, res = without, trial
 	}
+	withoutList := hexutil.Uint64(bare.UsedGas)
+	result := &accessListResult{
+		Accesslist:         &acl,
+		GasUsed:            hexutil.Uint64(res.UsedGas),
+		GasUsedWithoutList: &withoutList,
+		Entries:            entries,
+	}
+	if res.Err != nil {
+		result.Error = res.Err.Error()
+	}
+	return result, nil
+}
+
+// sortAccessList orders the entries of an access list, to make the results of
+// the optimization deterministic.
+func sortAccessList(acl types.AccessList) {
+	sort.Slice(acl, func(i, j int) bool {
+		return bytes.Compare(acl[i].Address[:], acl[j].Address[:]) < 0
+	})
+	for _, tuple := range acl {
+		keys := tuple.StorageKeys
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	}
+}
+
+// copyAccessList returns a deep copy of an access list.
+func copyAccessList(acl types.AccessList) types.AccessList {
+	cpy := make(types.AccessList, len(acl))
+	for i, tuple := range acl {
+		cpy[i] = types.AccessTuple{
+			Address:     tuple.Address,
+			StorageKeys: append([]common.Hash{}, tuple.StorageKeys...),
+		}
+	}
+	return cpy
 }
 
 // TransactionAPI exposes methods for reading and creating transaction data.
@@ -1744,6 +2010,12 @@ func (s *TransactionAPI) sign(addr common.Address, tx *types.Transaction) (*type
 
 // SubmitTransaction is a helper function that submits tx to txPool and logs a message.
 func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	return submitTransaction(ctx, b, tx, false, nil)
+}
+
+// submitTransaction submits tx to txPool, either publicly, privately or under the
+// given inclusion conditions, and logs a message.
+func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, private bool, cond *txpool.Conditional) (common.Hash, error) {
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
@@ -1753,7 +2025,16 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
-	if err := b.SendTx(ctx, tx); err != nil {
+	send := b.SendTx
+	switch {
+	case cond != nil:
+		send = func(ctx context.Context, tx *types.Transaction) error {
+			return b.SendConditionalTx(ctx, tx, cond)
+		}
+	case private:
+		send = b.SendPrivateTx
+	}
+	if err := send(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
 	// Print a log with full tx details for manual investigations and interventions
@@ -1766,9 +2047,9 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 
 	if tx.To() == nil {
 		addr := crypto.CreateAddress(from, tx.Nonce())
-		log.Info("Submitted contract creation", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "contract", addr.Hex(), "value", tx.Value())
+		log.Info("Submitted contract creation", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "contract", addr.Hex(), "value", tx.Value(), "private", private, "conditional", cond != nil)
 	} else {
-		log.Info("Submitted transaction", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value())
+		log.Info("Submitted transaction", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value(), "private", private, "conditional", cond != nil)
 	}
 	return tx.Hash(), nil
 }
@@ -1795,11 +2076,11 @@ func (s *TransactionAPI) SendTransaction(ctx context.Context, args TransactionAr
 	}
 
 	// Set some sanity defaults and terminate on failure
-	if err := args.setDefaults(ctx, s.b); err != nil {
+	if err := args.SetDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
 	// Assemble the transaction and sign with the wallet
-	tx := args.toTransaction()
+	tx := args.ToTransaction()
 
 	signed, err := wallet.SignTx(account, tx, s.b.ChainConfig().ChainID)
 	if err != nil {
@@ -1815,11 +2096,11 @@ func (s *TransactionAPI) FillTransaction(ctx context.Context, args TransactionAr
 	args.blobSidecarAllowed = true
 
 	// Set some sanity defaults and terminate on failure
-	if err := args.setDefaults(ctx, s.b); err != nil {
+	if err := args.SetDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
 	// Assemble the transaction and obtain rlp
-	tx := args.toTransaction()
+	tx := args.ToTransaction()
 	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
@@ -1837,6 +2118,32 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateRawTransaction will add the signed transaction to the transaction
+// pool as a private one: it is made available to locally built blocks, but it
+// is never announced to the network. Once its private lifetime passes, it gets
+// dropped or published, depending on the transaction pool configuration.
+func (s *TransactionAPI) SendPrivateRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, tx, true, nil)
+}
+
+// SendRawTransactionConditional will add the signed transaction to the transaction
+// pool, to be included into locally built blocks only while the given conditions
+// hold: the storage of the known accounts must match the expected storage roots
+// or slot values, and the block must be within the given number and timestamp
+// ranges. Like private transactions, conditional ones are never announced to the
+// network. They are dropped as soon as their conditions can no longer be met.
+func (s *TransactionAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, cond TransactionConditional) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, tx, false, cond.toConditional())
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -1884,11 +2191,11 @@ func (s *TransactionAPI) SignTransaction(ctx context.Context, args TransactionAr
 	if args.Nonce == nil {
 		return nil, errors.New("nonce not specified")
 	}
-	if err := args.setDefaults(ctx, s.b); err != nil {
+	if err := args.SetDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
 	// Before actually sign the transaction, ensure the transaction fee is reasonable.
-	tx := args.toTransaction()
+	tx := args.ToTransaction()
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
 		return nil, err
 	}
@@ -1933,10 +2240,10 @@ func (s *TransactionAPI) Resend(ctx context.Context, sendArgs TransactionArgs, g
 	if sendArgs.Nonce == nil {
 		return common.Hash{}, errors.New("missing transaction nonce in transaction spec")
 	}
-	if err := sendArgs.setDefaults(ctx, s.b); err != nil {
+	if err := sendArgs.SetDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
-	matchTx := sendArgs.toTransaction()
+	matchTx := sendArgs.ToTransaction()
 
 	// Before replacing the old transaction, ensure the _new_ transaction fee is reasonable.
 	var price = matchTx.GasPrice()
@@ -1966,7 +2273,7 @@ func (s *TransactionAPI) Resend(ctx context.Context, sendArgs TransactionArgs, g
 			if gasLimit != nil && *gasLimit != 0 {
 				sendArgs.Gas = gasLimit
 			}
-			signedTx, err := s.sign(sendArgs.from(), sendArgs.toTransaction())
+			signedTx, err := s.sign(sendArgs.from(), sendArgs.ToTransaction())
 			if err != nil {
 				return common.Hash{}, err
 			}
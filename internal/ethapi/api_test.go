@@ -28,6 +28,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,10 +44,12 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/blocktest"
@@ -431,6 +434,7 @@ type testBackend struct {
 	pending *types.Block
 	accman  *accounts.Manager
 	acc     accounts.Account
+	oracle  *gasprice.Oracle
 }
 
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, engine consensus.Engine, generator func(i int, b *core.BlockGen)) *testBackend {
@@ -457,6 +461,15 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, engine consensus.E
 	}
 
 	backend := &testBackend{db: db, chain: chain, accman: accman, acc: acc}
+	backend.oracle = gasprice.NewOracle(backend, gasprice.Config{
+		Blocks:           20,
+		Percentile:       60,
+		MaxHeaderHistory: 1024,
+		MaxBlockHistory:  1024,
+		Default:          big.NewInt(0),
+		MaxPrice:         gasprice.DefaultMaxPrice,
+		IgnorePrice:      gasprice.DefaultIgnorePrice,
+	})
 	return backend
 }
 
@@ -468,8 +481,11 @@ func (b testBackend) SyncProgress() ethereum.SyncProgress { return ethereum.Sync
 func (b testBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
 	return big.NewInt(0), nil
 }
-func (b testBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
-	return nil, nil, nil, nil, nil
+func (b testBackend) SuggestFees(ctx context.Context, speed gasprice.FeeSpeed) (*gasprice.SuggestedFees, error) {
+	return b.oracle.SuggestFees(ctx, speed)
+}
+func (b testBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, []*big.Int, []float64, error) {
+	return nil, nil, nil, nil, nil, nil, nil
 }
 func (b testBackend) ChainDb() ethdb.Database           { return b.db }
 func (b testBackend) AccountManager() *accounts.Manager { return b.accman }
@@ -577,7 +593,7 @@ func (b testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscr
 	panic("implement me")
 }
 func (b testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
-	panic("implement me")
+	return b.chain.SubscribeChainHeadEvent(ch)
 }
 func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	panic("implement me")
@@ -585,6 +601,12 @@ func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) even
 func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	panic("implement me")
 }
+func (b testBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	panic("implement me")
+}
+func (b testBackend) SendConditionalTx(ctx context.Context, signedTx *types.Transaction, cond *txpool.Conditional) error {
+	panic("implement me")
+}
 func (b testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.db, txHash)
 	return true, tx, blockHash, blockNumber, index, nil
@@ -623,6 +645,95 @@ func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.Match
 	panic("implement me")
 }
 
+func TestCreateAccessListOptimized(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		contract = common.HexToAddress("0xc0de")
+		other    = common.HexToAddress("0xbb")
+		// Loads a slot of the contract, the balance of another account and the
+		// balance of the coinbase, which is warm from Shanghai on
+		code = append(append(common.FromHex("6001545073"), other.Bytes()...), common.FromHex("315060003150"+"00")...)
+
+		genesis = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				contract:         {Code: code},
+			},
+		}
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	args := TransactionArgs{From: &accounts[0].addr, To: &contract}
+
+	full, err := api.CreateAccessList(context.Background(), args, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if len(*full.Accesslist) != 3 {
+		t.Fatalf("full access list mismatch: have %v", *full.Accesslist)
+	}
+	res, err := api.CreateAccessList(context.Background(), args, nil, &AccessListOptions{Optimize: true})
+	if err != nil {
+		t.Fatalf("failed to create optimized access list: %v", err)
+	}
+	// Only the other account is worth listing
+	if want := (types.AccessList{{Address: other, StorageKeys: []common.Hash{}}}); !reflect.DeepEqual(*res.Accesslist, want) {
+		t.Errorf("access list mismatch: have %v, want %v", *res.Accesslist, want)
+	}
+	if res.GasUsedWithoutList == nil || uint64(*res.GasUsedWithoutList) != uint64(res.GasUsed)+100 {
+		t.Errorf("gas mismatch: have %d, without list %v", res.GasUsed, res.GasUsedWithoutList)
+	}
+	slot := common.BigToHash(big.NewInt(1))
+	want := []*AccessListEntry{
+		{Address: contract, StorageKey: &slot, Cost: 1900, Savings: 2000, Included: true},
+		{Address: common.Address{}, Cost: 2400, Savings: 0, Included: false},
+		{Address: other, Cost: 2400, Savings: 2500, Included: true},
+		{Address: contract, Cost: 4300, Savings: 2000, Included: false},
+	}
+	if !reflect.DeepEqual(res.Entries, want) {
+		t.Errorf("entries mismatch")
+		for _, entry := range res.Entries {
+			t.Logf("have %+v", entry)
+		}
+	}
+}
+
+func TestCreateAccessListOptimizedLimit(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		contract = common.HexToAddress("0xc0de")
+		// Loads the first 300 slots of the contract in a loop
+		code = common.FromHex("60005b8054506001018061012c1160025700")
+
+		genesis = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				contract:         {Code: code},
+			},
+		}
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	args := TransactionArgs{From: &accounts[0].addr, To: &contract}
+
+	full, err := api.CreateAccessList(context.Background(), args, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if keys := full.Accesslist.StorageKeys(); keys != 300 {
+		t.Fatalf("storage key count mismatch: have %d, want %d", keys, 300)
+	}
+	if _, err := api.CreateAccessList(context.Background(), args, nil, &AccessListOptions{Optimize: true}); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("oversized access list error mismatch: have %v", err)
+	}
+}
+
 func TestEstimateGas(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -1008,7 +1119,7 @@ func TestSignTransaction(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	expect := `{"type":"0x2","chainId":"0x1","nonce":"0x0","to":"0x703c4b2bd70c169f5717101caee543299fc946c7","gas":"0x5208","gasPrice":null,"maxPriorityFeePerGas":"0x0","maxFeePerGas":"0x684ee180","value":"0x1","input":"0x","accessList":[],"v":"0x0","r":"0x8fabeb142d585dd9247f459f7e6fe77e2520c88d50ba5d220da1533cea8b34e1","s":"0x582dd68b21aef36ba23f34e49607329c20d981d30404daf749077f5606785ce7","yParity":"0x0","hash":"0x93927839207cfbec395da84b8a2bc38b7b65d2cb2819e9fef1f091f5b1d4cc8f"}`
+	expect := `{"type":"0x2","chainId":"0x1","nonce":"0x0","to":"0x703c4b2bd70c169f5717101caee543299fc946c7","gas":"0x5208","gasPrice":null,"maxPriorityFeePerGas":"0x0","maxFeePerGas":"0x523c4353","value":"0x1","input":"0x","accessList":[],"v":"0x0","r":"0xe9fcb5b8f59713ff782b93ef7807c40161ab74e2bca2a973f416aed1b3d1c711","s":"0x5dd0e8700d712c36e4f4168e801fb08c3d15ab8dcaddd3c200f280a6b69ed2c8","yParity":"0x0","hash":"0xe13a8b8c60a1d8d0eff42b00be590ec8db2389e0a4941fa4d3ba816933207b1b"}`
 	if !bytes.Equal(tx, []byte(expect)) {
 		t.Errorf("result mismatch. Have:\n%s\nWant:\n%s\n", tx, expect)
 	}
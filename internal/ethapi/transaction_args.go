@@ -27,10 +27,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
-	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -95,8 +95,8 @@ func (args *TransactionArgs) data() []byte {
 	return nil
 }
 
-// setDefaults fills in default values for unspecified tx fields.
-func (args *TransactionArgs) setDefaults(ctx context.Context, b Backend) error {
+// SetDefaults fills in default values for unspecified tx fields.
+func (args *TransactionArgs) SetDefaults(ctx context.Context, b Backend) error {
 	if err := args.setBlobTxSidecar(ctx, b); err != nil {
 		return err
 	}
@@ -213,66 +213,48 @@ func (args *TransactionArgs) setFeeDefaults(ctx context.Context, b Backend) erro
 	}
 
 	// Now attempt to fill in default value depending on whether London is active or not.
-	if b.ChainConfig().IsCancun(head.Number, head.Time) {
-		if err := args.setCancunFeeDefaults(ctx, head, b); err != nil {
-			return err
-		}
-	} else if isLondon {
-		if args.BlobFeeCap != nil {
+	if isLondon {
+		if args.BlobFeeCap != nil && !b.ChainConfig().IsCancun(head.Number, head.Time) {
 			return errors.New("maxFeePerBlobGas is not valid before Cancun is active")
 		}
-		// London is active, set maxPriorityFeePerGas and maxFeePerGas.
-		if err := args.setLondonFeeDefaults(ctx, head, b); err != nil {
-			return err
-		}
-	} else {
-		if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil || args.BlobFeeCap != nil {
-			return errors.New("maxFeePerGas and maxPriorityFeePerGas and maxFeePerBlobGas are not valid before London is active")
-		}
-		// London not active, set gas price.
-		price, err := b.SuggestGasTipCap(ctx)
+		fees, err := b.SuggestFees(ctx, gasprice.FeeSpeedStandard)
 		if err != nil {
 			return err
 		}
-		args.GasPrice = (*hexutil.Big)(price)
+		// Set maxFeePerBlobGas if it is missing.
+		if args.BlobHashes != nil && args.BlobFeeCap == nil {
+			if fees.MaxFeePerBlobGas == nil {
+				return errors.New("blob fee suggestion unavailable")
+			}
+			args.BlobFeeCap = (*hexutil.Big)(fees.MaxFeePerBlobGas)
+		}
+		// London is active, set maxPriorityFeePerGas and maxFeePerGas.
+		return args.setLondonFeeDefaults(fees)
+	}
+	if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil || args.BlobFeeCap != nil {
+		return errors.New("maxFeePerGas and maxPriorityFeePerGas and maxFeePerBlobGas are not valid before London is active")
 	}
+	// London not active, set gas price.
+	price, err := b.SuggestGasTipCap(ctx)
+	if err != nil {
+		return err
+	}
+	args.GasPrice = (*hexutil.Big)(price)
 	return nil
 }
 
-// setCancunFeeDefaults fills in reasonable default fee values for unspecified fields.
-func (args *TransactionArgs) setCancunFeeDefaults(ctx context.Context, head *types.Header, b Backend) error {
-	// Set maxFeePerBlobGas if it is missing.
-	if args.BlobHashes != nil && args.BlobFeeCap == nil {
-		// ExcessBlobGas must be set for a Cancun block.
-		blobBaseFee := eip4844.CalcBlobFee(*head.ExcessBlobGas)
-		// Set the max fee to be 2 times larger than the previous block's blob base fee.
-		// The additional slack allows the tx to not become invalidated if the base
-		// fee is rising.
-		val := new(big.Int).Mul(blobBaseFee, big.NewInt(2))
-		args.BlobFeeCap = (*hexutil.Big)(val)
-	}
-	return args.setLondonFeeDefaults(ctx, head, b)
-}
-
-// setLondonFeeDefaults fills in reasonable default fee values for unspecified fields.
-func (args *TransactionArgs) setLondonFeeDefaults(ctx context.Context, head *types.Header, b Backend) error {
+// setLondonFeeDefaults fills in the suggested fee values for unspecified fields.
+func (args *TransactionArgs) setLondonFeeDefaults(fees *gasprice.SuggestedFees) error {
 	// Set maxPriorityFeePerGas if it is missing.
 	if args.MaxPriorityFeePerGas == nil {
-		tip, err := b.SuggestGasTipCap(ctx)
-		if err != nil {
-			return err
-		}
-		args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(fees.MaxPriorityFeePerGas)
 	}
-	// Set maxFeePerGas if it is missing.
+	// Set maxFeePerGas if it is missing. The suggested fee cap covers the base fee
+	// projected over a few full blocks, so the transaction does not become invalid
+	// if the base fee is rising. Keep that slack on top of a user specified tip.
 	if args.MaxFeePerGas == nil {
-		// Set the max fee to be 2 times larger than the previous block's base fee.
-		// The additional slack allows the tx to not become invalidated if the base
-		// fee is rising.
-		val := new(big.Int).Add(
-			args.MaxPriorityFeePerGas.ToInt(),
-			new(big.Int).Mul(head.BaseFee, big.NewInt(2)),
-		)
+		val := new(big.Int).Sub(fees.MaxFeePerGas, fees.MaxPriorityFeePerGas)
+		val.Add(val, args.MaxPriorityFeePerGas.ToInt())
 		args.MaxFeePerGas = (*hexutil.Big)(val)
 	}
 	// Both EIP-1559 fee parameters are now set; sanity check them.
@@ -447,9 +429,9 @@ func (args *TransactionArgs) ToMessage(globalGasCap uint64, baseFee *big.Int) (*
 	return msg, nil
 }
 
-// toTransaction converts the arguments to a transaction.
-// This assumes that setDefaults has been called.
-func (args *TransactionArgs) toTransaction() *types.Transaction {
+// ToTransaction converts the arguments to a transaction.
+// This assumes that SetDefaults has been called.
+func (args *TransactionArgs) ToTransaction() *types.Transaction {
 	var data types.TxData
 	switch {
 	case args.BlobHashes != nil:
@@ -32,8 +32,10 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
@@ -54,8 +56,10 @@ func TestSetFeeDefaults(t *testing.T) {
 		b        = newBackendMock()
 		zero     = (*hexutil.Big)(big.NewInt(0))
 		fortytwo = (*hexutil.Big)(big.NewInt(42))
-		maxFee   = (*hexutil.Big)(new(big.Int).Add(new(big.Int).Mul(b.current.BaseFee, big.NewInt(2)), fortytwo.ToInt()))
-		al       = &types.AccessList{types.AccessTuple{Address: common.Address{0xaa}, StorageKeys: []common.Hash{{0x01}}}}
+		// The base fee of 10 rises to 16 over the 6 full blocks the standard
+		// fee cap must survive, the tip falling back to the oracle default
+		maxFee = (*hexutil.Big)(big.NewInt(16 + 42))
+		al     = &types.AccessList{types.AccessTuple{Address: common.Address{0xaa}, StorageKeys: []common.Hash{{0x01}}}}
 	)
 
 	tests := []test{
@@ -167,7 +171,7 @@ func TestSetFeeDefaults(t *testing.T) {
 			"london",
 			&TransactionArgs{MaxFeePerGas: maxFee, MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(1000))},
 			nil,
-			errors.New("maxFeePerGas (0x3e) < maxPriorityFeePerGas (0x3e8)"),
+			errors.New("maxFeePerGas (0x3a) < maxPriorityFeePerGas (0x3e8)"),
 		},
 		{
 			"dynamic fee tx, maxFee < priorityFee while setting default",
@@ -229,10 +233,11 @@ func TestSetFeeDefaults(t *testing.T) {
 			errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified"),
 		},
 		{
+			// The blob fee cap covers 6 blocks full of blobs
 			"fill maxFeePerBlobGas",
 			"cancun",
 			&TransactionArgs{BlobHashes: []common.Hash{}},
-			&TransactionArgs{BlobHashes: []common.Hash{}, BlobFeeCap: (*hexutil.Big)(big.NewInt(4)), MaxFeePerGas: maxFee, MaxPriorityFeePerGas: fortytwo},
+			&TransactionArgs{BlobHashes: []common.Hash{}, BlobFeeCap: (*hexutil.Big)(big.NewInt(3)), MaxFeePerGas: maxFee, MaxPriorityFeePerGas: fortytwo},
 			nil,
 		},
 	}
@@ -259,6 +264,7 @@ func TestSetFeeDefaults(t *testing.T) {
 type backendMock struct {
 	current *types.Header
 	config  *params.ChainConfig
+	oracle  *gasprice.Oracle
 }
 
 func newBackendMock() *backendMock {
@@ -280,7 +286,7 @@ func newBackendMock() *backendMock {
 		LondonBlock:         big.NewInt(1000),
 		CancunTime:          &cancunTime,
 	}
-	return &backendMock{
+	b := &backendMock{
 		current: &types.Header{
 			Difficulty: big.NewInt(10000000000),
 			Number:     big.NewInt(1100),
@@ -292,6 +298,16 @@ func newBackendMock() *backendMock {
 		},
 		config: config,
 	}
+	b.oracle = gasprice.NewOracle(b, gasprice.Config{
+		Blocks:           20,
+		Percentile:       60,
+		MaxHeaderHistory: 1024,
+		MaxBlockHistory:  1024,
+		Default:          big.NewInt(42),
+		MaxPrice:         gasprice.DefaultMaxPrice,
+		IgnorePrice:      gasprice.DefaultIgnorePrice,
+	})
+	return b
 }
 
 func (b *backendMock) setFork(fork string) error {
@@ -305,8 +321,9 @@ func (b *backendMock) setFork(fork string) error {
 		b.current.Number = big.NewInt(1100)
 		b.current.Time = 700
 		// Blob base fee will be 2
-		excess := uint64(2314058)
+		excess, used := uint64(2314058), uint64(0)
 		b.current.ExcessBlobGas = &excess
+		b.current.BlobGasUsed = &used
 	} else {
 		return errors.New("invalid fork")
 	}
@@ -316,13 +333,16 @@ func (b *backendMock) setFork(fork string) error {
 func (b *backendMock) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
 	return big.NewInt(42), nil
 }
+func (b *backendMock) SuggestFees(ctx context.Context, speed gasprice.FeeSpeed) (*gasprice.SuggestedFees, error) {
+	return b.oracle.SuggestFees(ctx, speed)
+}
 func (b *backendMock) CurrentHeader() *types.Header     { return b.current }
 func (b *backendMock) ChainConfig() *params.ChainConfig { return b.config }
 
 // Other methods needed to implement Backend interface.
 func (b *backendMock) SyncProgress() ethereum.SyncProgress { return ethereum.SyncProgress{} }
-func (b *backendMock) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
-	return nil, nil, nil, nil, nil
+func (b *backendMock) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, []*big.Int, []float64, error) {
+	return nil, nil, nil, nil, nil, nil, nil
 }
 func (b *backendMock) ChainDb() ethdb.Database           { return nil }
 func (b *backendMock) AccountManager() *accounts.Manager { return nil }
@@ -333,7 +353,16 @@ func (b *backendMock) RPCTxFeeCap() float64              { return 0 }
 func (b *backendMock) UnprotectedAllowed() bool          { return false }
 func (b *backendMock) SetHead(number uint64)             {}
 func (b *backendMock) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
-	return nil, nil
+	// Serve a chain of empty blocks identical to the current one
+	if number == rpc.LatestBlockNumber {
+		return b.current, nil
+	}
+	if number < 0 || uint64(number) > b.current.Number.Uint64() {
+		return nil, nil
+	}
+	header := types.CopyHeader(b.current)
+	header.Number = big.NewInt(int64(number))
+	return header, nil
 }
 func (b *backendMock) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	return nil, nil
@@ -343,7 +372,11 @@ func (b *backendMock) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rp
 }
 func (b *backendMock) CurrentBlock() *types.Header { return nil }
 func (b *backendMock) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
-	return nil, nil
+	header, _ := b.HeaderByNumber(ctx, number)
+	if header == nil {
+		return nil, nil
+	}
+	return types.NewBlockWithHeader(header), nil
 }
 func (b *backendMock) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
 	return nil, nil
@@ -379,6 +412,12 @@ func (b *backendMock) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) eve
 	return nil
 }
 func (b *backendMock) SendTx(ctx context.Context, signedTx *types.Transaction) error { return nil }
+func (b *backendMock) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return nil
+}
+func (b *backendMock) SendConditionalTx(ctx context.Context, signedTx *types.Transaction, cond *txpool.Conditional) error {
+	return nil
+}
 func (b *backendMock) GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error) {
 	return false, nil, [32]byte{}, 0, 0, nil
 }
@@ -0,0 +1,91 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
+)
+
+// TransactionConditional represents the inclusion conditions of a transaction
+// submitted through eth_sendRawTransactionConditional.
+type TransactionConditional struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Uint64                 `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Uint64                 `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                 `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                 `json:"timestampMax,omitempty"`
+}
+
+// KnownAccount is the expected storage of an account, encoded either as its
+// storage root hash, or as an object mapping storage slots to their values.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a KnownAccount) MarshalJSON() ([]byte, error) {
+	if a.StorageRoot != nil {
+		return json.Marshal(a.StorageRoot)
+	}
+	return json.Marshal(a.StorageSlots)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *KnownAccount) UnmarshalJSON(input []byte) error {
+	input = bytes.TrimSpace(input)
+	if len(input) > 0 && input[0] == '"' {
+		a.StorageRoot = new(common.Hash)
+		return json.Unmarshal(input, a.StorageRoot)
+	}
+	if len(input) > 0 && input[0] == '{' {
+		return json.Unmarshal(input, &a.StorageSlots)
+	}
+	return errors.New("known account must be a storage root or a map of storage slots")
+}
+
+// toConditional converts the conditions into their transaction pool form.
+func (c *TransactionConditional) toConditional() *txpool.Conditional {
+	cond := &txpool.Conditional{
+		KnownAccounts: make(map[common.Address]txpool.KnownAccount, len(c.KnownAccounts)),
+	}
+	for addr, account := range c.KnownAccounts {
+		cond.KnownAccounts[addr] = txpool.KnownAccount{
+			StorageRoot:  account.StorageRoot,
+			StorageSlots: account.StorageSlots,
+		}
+	}
+	if c.BlockNumberMin != nil {
+		cond.BlockNumberMin = uint64(*c.BlockNumberMin)
+	}
+	if c.BlockNumberMax != nil {
+		cond.BlockNumberMax = uint64(*c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil {
+		cond.TimestampMin = uint64(*c.TimestampMin)
+	}
+	if c.TimestampMax != nil {
+		cond.TimestampMax = uint64(*c.TimestampMax)
+	}
+	return cond
+}
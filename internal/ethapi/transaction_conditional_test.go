@@ -0,0 +1,72 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+)
+
+func TestTransactionConditionalJSON(t *testing.T) {
+	input := `{
+		"knownAccounts": {
+			"0x00000000000000000000000000000000000000aa": "0x0100000000000000000000000000000000000000000000000000000000000000",
+			"0x00000000000000000000000000000000000000bb": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		},
+		"blockNumberMin": "0x10",
+		"timestampMax": "0x20"
+	}`
+	var cond TransactionConditional
+	if err := json.Unmarshal([]byte(input), &cond); err != nil {
+		t.Fatalf("failed to decode conditional: %v", err)
+	}
+	root := common.Hash{0x01}
+	want := &txpool.Conditional{
+		KnownAccounts: map[common.Address]txpool.KnownAccount{
+			common.HexToAddress("0xaa"): {StorageRoot: &root},
+			common.HexToAddress("0xbb"): {StorageSlots: map[common.Hash]common.Hash{
+				common.HexToHash("0x01"): common.HexToHash("0x02"),
+			}},
+		},
+		BlockNumberMin: 0x10,
+		TimestampMax:   0x20,
+	}
+	if have := cond.toConditional(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("conditional mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+	// Round trip the known accounts through the encoder
+	blob, err := json.Marshal(cond)
+	if err != nil {
+		t.Fatalf("failed to encode conditional: %v", err)
+	}
+	var decoded TransactionConditional
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode encoded conditional: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cond) {
+		t.Fatalf("round trip mismatch:\nhave %+v\nwant %+v", decoded, cond)
+	}
+	if err := json.Unmarshal([]byte(`{"knownAccounts": {"0x00000000000000000000000000000000000000aa": 1}}`), new(TransactionConditional)); err == nil {
+		t.Fatalf("malformed known account accepted")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// estimateBlock assembles an estimate of the next block on top of the current
+// head, filled with the executable transactions of the pool in the order they
+// would be included by the miner. The transactions are not executed, they are
+// assumed to be valid and to use up their gas limits, so the estimate is cheap
+// to compute even if the node is not building blocks.
+func (w *worker) estimateBlock() (*types.Block, types.Receipts) {
+	parent := w.chain.CurrentBlock()
+
+	timestamp := uint64(time.Now().Unix())
+	if parent.Time >= timestamp {
+		timestamp = parent.Time + 1
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   core.CalcGasLimit(parent.GasLimit, w.config.GasCeil),
+		Time:       timestamp,
+	}
+	if w.chainConfig.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(w.chainConfig, parent)
+		if !w.chainConfig.IsLondon(parent.Number) {
+			parentGasLimit := parent.GasLimit * w.chainConfig.ElasticityMultiplier()
+			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
+		}
+	}
+	if w.chainConfig.IsCancun(header.Number, header.Time) {
+		var excessBlobGas uint64
+		if w.chainConfig.IsCancun(parent.Number, parent.Time) {
+			excessBlobGas = eip4844.CalcExcessBlobGas(*parent.ExcessBlobGas, *parent.BlobGasUsed)
+		} else {
+			excessBlobGas = eip4844.CalcExcessBlobGas(0, 0)
+		}
+		header.BlobGasUsed = new(uint64)
+		header.ExcessBlobGas = &excessBlobGas
+	}
+	// Split the pending transactions into locals and remotes, same as when
+	// filling an actual block.
+	pending := w.eth.TxPool().Pending(true)
+
+	localTxs, remoteTxs := make(map[common.Address][]*txpool.LazyTransaction), pending
+	for _, account := range w.eth.TxPool().Locals() {
+		if txs := remoteTxs[account]; len(txs) > 0 {
+			delete(remoteTxs, account)
+			localTxs[account] = txs
+		}
+	}
+	w.mu.RLock()
+	tip := w.tip
+	w.mu.RUnlock()
+
+	var (
+		signer   = types.MakeSigner(w.chainConfig, header.Number, header.Time)
+		txs      []*types.Transaction
+		receipts types.Receipts
+		blobGas  uint64
+	)
+	fill := func(set *transactionsByPriceAndNonce, minTip *big.Int) {
+		for header.GasLimit-header.GasUsed >= params.TxGas {
+			ltx, fees := set.Peek()
+			if ltx == nil {
+				break
+			}
+			if header.GasLimit-header.GasUsed < ltx.Gas {
+				set.Pop()
+				continue
+			}
+			if params.MaxBlobGasPerBlock-blobGas < ltx.BlobGas {
+				set.Pop()
+				continue
+			}
+			if fees.Cmp(minTip) < 0 {
+				break
+			}
+			if ltx.Conditional != nil && !ltx.Conditional.Includable(header.Number.Uint64(), header.Time) {
+				set.Pop()
+				continue
+			}
+			tx := ltx.Resolve()
+			if tx == nil {
+				set.Pop()
+				continue
+			}
+			header.GasUsed += tx.Gas()
+			blobGas += tx.BlobGas()
+
+			txs = append(txs, tx)
+			receipts = append(receipts, &types.Receipt{
+				Type:              tx.Type(),
+				CumulativeGasUsed: header.GasUsed,
+				TxHash:            tx.Hash(),
+				GasUsed:           tx.Gas(),
+				BlobGasUsed:       tx.BlobGas(),
+			})
+			set.Shift()
+		}
+	}
+	if len(localTxs) > 0 {
+		fill(newTransactionsByPriceAndNonce(signer, localTxs, header.BaseFee), new(big.Int))
+	}
+	if len(remoteTxs) > 0 {
+		fill(newTransactionsByPriceAndNonce(signer, remoteTxs, header.BaseFee), tip)
+	}
+	if header.BlobGasUsed != nil {
+		*header.BlobGasUsed = blobGas
+	}
+	return types.NewBlockWithHeader(header).WithBody(txs, nil), receipts
+}
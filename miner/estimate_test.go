@@ -0,0 +1,66 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the pending block estimate is filled from the pool contents in
+// nonce order, with the gas limits standing in for the gas used.
+func TestEstimateBlock(t *testing.T) {
+	t.Parallel()
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	if errs := b.txPool.Add(newTxs, false, true); errs[0] != nil {
+		t.Fatalf("failed to add transaction: %v", errs[0])
+	}
+	block, receipts := w.estimateBlock()
+
+	head := b.chain.CurrentBlock()
+	if block.NumberU64() != head.Number.Uint64()+1 {
+		t.Fatalf("block number mismatch: have %d, want %d", block.NumberU64(), head.Number.Uint64()+1)
+	}
+	if want := eip1559.CalcBaseFee(ethashChainConfig, head); block.BaseFee().Cmp(want) != 0 {
+		t.Errorf("base fee mismatch: have %v, want %v", block.BaseFee(), want)
+	}
+	want := append(pendingTxs[:len(pendingTxs):len(pendingTxs)], newTxs...)
+	if len(block.Transactions()) != len(want) || len(receipts) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d txs and %d receipts, want %d", len(block.Transactions()), len(receipts), len(want))
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Hash() != want[i].Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, tx.Hash(), want[i].Hash())
+		}
+		if receipts[i].GasUsed != tx.Gas() {
+			t.Errorf("receipt %d gas used mismatch: have %d, want %d", i, receipts[i].GasUsed, tx.Gas())
+		}
+	}
+	if block.GasUsed() != uint64(len(want))*params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", block.GasUsed(), uint64(len(want))*params.TxGas)
+	}
+}
@@ -229,6 +229,13 @@ func (miner *Miner) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return miner.worker.pendingBlockAndReceipts()
 }
 
+// EstimateBlockAndReceipts estimates the next block from the transaction pool
+// contents, ordered the way the miner would include them. The transactions are
+// not executed, the receipts only carry their gas limits as the gas used.
+func (miner *Miner) EstimateBlockAndReceipts() (*types.Block, types.Receipts) {
+	return miner.worker.estimateBlock()
+}
+
 func (miner *Miner) SetEtherbase(addr common.Address) {
 	miner.worker.setEtherbase(addr)
 }
@@ -848,6 +848,12 @@ func (w *worker) commitTransactions(env *environment, txs *transactionsByPriceAn
 			txs.Pop()
 			continue
 		}
+		// Check the conditions of conditional transactions against the pending
+		// state, and drop them from the pool once they can't be met anymore.
+		if ltx.Conditional != nil && !w.checkConditional(env, ltx.Hash, ltx.Conditional) {
+			txs.Pop()
+			continue
+		}
 		// Error may be ignored here. The error has already been checked
 		// during transaction acceptance is the transaction pool.
 		from, _ := types.Sender(env.signer, tx)
@@ -900,6 +906,108 @@ func (w *worker) commitTransactions(env *environment, txs *transactionsByPriceAn
 	return nil
 }
 
+// checkConditional returns whether the conditions of a transaction allow it to be
+// included next into the block being built. If the conditions can no longer be
+// met, either because the block range was passed or because the storage of the
+// known accounts changed, the transaction is dropped from the pool.
+func (w *worker) checkConditional(env *environment, hash common.Hash, cond *txpool.Conditional) bool {
+	number, time := env.header.Number.Uint64(), env.header.Time
+	if !cond.Includable(number, time) {
+		if cond.Expired(number, time) {
+			log.Trace("Dropping expired conditional transaction", "hash", hash)
+			w.eth.TxPool().DropConditional(hash)
+		}
+		return false
+	}
+	pending := &conditionalState{
+		StateDB:     env.state,
+		deleteEmpty: w.chainConfig.IsEIP158(env.header.Number),
+	}
+	if err := cond.CheckState(pending); err != nil {
+		log.Trace("Dropping failed conditional transaction", "hash", hash, "err", err)
+		w.eth.TxPool().DropConditional(hash)
+		return false
+	}
+	return true
+}
+
+// conditionalState exposes the pending state of the block being built to the
+// checks of the transaction conditions. The storage roots of the accounts are
+// only updated when hashing the state, so it is hashed before retrieving them.
+type conditionalState struct {
+	*state.StateDB
+	deleteEmpty bool
+	hashed      bool
+}
+
+// GetStorageRoot retrieves the storage root of an account in the pending state.
+func (s *conditionalState) GetStorageRoot(addr common.Address) common.Hash {
+	if !s.hashed {
+		s.StateDB.IntermediateRoot(s.deleteEmpty)
+		s.hashed = true
+	}
+	return s.StateDB.GetStorageRoot(addr)
+}
+
+// commitBundles includes the given bundles into the block, each one atomically:
+// if any of its transactions fails, or reverts without being allowed to, all of
+// its transactions are rolled back and the next bundle is tried.
+func (w *worker) commitBundles(env *environment, bundles []*txpool.Bundle, interrupt *atomic.Int32) error {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, bundle := range bundles {
+		// Check interruption signal and abort building if it's fired.
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		if err := w.commitBundle(env, bundle); err != nil {
+			log.Debug("Bundle failed, skipped", "hash", bundle.Hash(), "err", err)
+		}
+	}
+	return nil
+}
+
+// commitBundle includes all the transactions of the bundle into the block, or
+// none of them if any fails or reverts without being allowed to.
+func (w *worker) commitBundle(env *environment, bundle *txpool.Bundle) error {
+	for i, tx := range bundle.Txs {
+		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
+			return fmt.Errorf("transaction %d (%x): replay protected before eip155", i, tx.Hash())
+		}
+	}
+	// Transactions are finalised one by one, so state snapshots can't span the
+	// bundle: keep a copy of the state to roll back to instead.
+	var (
+		backup  = env.state.Copy()
+		gas     = env.gasPool.Gas()
+		gasUsed = env.header.GasUsed
+		count   = len(env.txs)
+	)
+	for i, tx := range bundle.Txs {
+		env.state.SetTxContext(tx.Hash(), env.tcount+i)
+
+		receipt, err := w.applyTransaction(env, tx)
+		if err == nil && receipt.Status == types.ReceiptStatusFailed && !bundle.CanRevert(tx.Hash()) {
+			err = errors.New("reverted")
+		}
+		if err != nil {
+			env.state.StopPrefetcher()
+			env.state = backup
+			env.gasPool.SetGas(gas)
+			env.header.GasUsed = gasUsed
+			env.txs, env.receipts = env.txs[:count], env.receipts[:count]
+			return fmt.Errorf("transaction %d (%x): %w", i, tx.Hash(), err)
+		}
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+	}
+	env.tcount += len(bundle.Txs)
+	return nil
+}
+
 // generateParams wraps various of settings for generating sealing task.
 type generateParams struct {
 	timestamp   uint64            // The timestamp for sealing task
@@ -1010,11 +1118,17 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 		}
 	}
 
-	// Fill the block with all available pending transactions.
+	// Fill the block with all available pending transactions, bundles first.
 	w.mu.RLock()
 	tip := w.tip
 	w.mu.RUnlock()
 
+	if bundles := w.eth.TxPool().Bundles(env.header.Number.Uint64(), env.header.Time); len(bundles) > 0 {
+		if err := w.commitBundles(env, bundles, interrupt); err != nil {
+			return err
+		}
+	}
+
 	if len(localTxs) > 0 {
 		txs := newTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
 		if err := w.commitTransactions(env, txs, interrupt, new(big.Int)); err != nil {
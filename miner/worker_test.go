@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/bundlepool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -135,7 +136,8 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		t.Fatalf("core.NewBlockChain failed: %v", err)
 	}
 	pool := legacypool.New(testTxPoolConfig, chain)
-	txpool, _ := txpool.New(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), chain, []txpool.SubPool{pool})
+	bundles := bundlepool.New(bundlepool.DefaultConfig, chain)
+	txpool, _ := txpool.New(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), chain, []txpool.SubPool{pool, bundles})
 
 	return &testWorkerBackend{
 		db:      db,
@@ -508,3 +510,138 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+// Tests that bundles are included atomically: a bundle with a transaction that
+// reverts without being allowed to is left out entirely.
+func TestCommitBundles(t *testing.T) {
+	t.Parallel()
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		gasPrice = big.NewInt(10 * params.InitialBaseFee)
+		transfer = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: gasPrice,
+		})
+		revert = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    1,
+			Gas:      100000,
+			GasPrice: gasPrice,
+			Data:     common.FromHex("0x60006000fd"), // Reverting contract creation
+		})
+		strict  = &txpool.Bundle{Txs: types.Transactions{transfer, revert}, BlockNumber: 1}
+		lenient = &txpool.Bundle{Txs: types.Transactions{transfer, revert}, BlockNumber: 1, RevertingTxHashes: []common.Hash{revert.Hash()}}
+	)
+	env, err := w.prepareWork(&generateParams{
+		parentHash: b.chain.CurrentBlock().Hash(),
+		timestamp:  b.chain.CurrentBlock().Time + 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to prepare work: %v", err)
+	}
+	defer env.discard()
+
+	// The strict bundle reverts and must leave no trace
+	if err := w.commitBundles(env, []*txpool.Bundle{strict}, nil); err != nil {
+		t.Fatalf("failed to commit bundles: %v", err)
+	}
+	if len(env.txs) != 0 || len(env.receipts) != 0 || env.header.GasUsed != 0 {
+		t.Fatalf("reverted bundle included: %d txs, %d gas used", len(env.txs), env.header.GasUsed)
+	}
+	if balance := env.state.GetBalance(testUserAddress); !balance.IsZero() {
+		t.Fatalf("reverted bundle state leaked: balance %v", balance)
+	}
+	// The lenient bundle allows the revert and must be fully included
+	if err := w.commitBundles(env, []*txpool.Bundle{lenient}, nil); err != nil {
+		t.Fatalf("failed to commit bundles: %v", err)
+	}
+	if len(env.txs) != 2 || env.txs[0].Hash() != transfer.Hash() || env.txs[1].Hash() != revert.Hash() {
+		t.Fatalf("bundle not included: %d txs", len(env.txs))
+	}
+	if env.receipts[1].Status != types.ReceiptStatusFailed {
+		t.Errorf("allowed revert not recorded as failed")
+	}
+	if balance := env.state.GetBalance(testUserAddress); balance.Uint64() != 1000 {
+		t.Errorf("bundle state mismatch: balance %v, want 1000", balance)
+	}
+}
+
+// Tests that the conditions of transactions are checked against the pending state
+// of the block being built, and that failing transactions are dropped from the pool.
+func TestCheckConditional(t *testing.T) {
+	t.Parallel()
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		contract = common.Address{0xc0}
+		slot     = common.Hash{0x01}
+		tx       = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    1,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(10 * params.InitialBaseFee),
+		})
+		slots = &txpool.Conditional{
+			KnownAccounts: map[common.Address]txpool.KnownAccount{
+				contract: {StorageSlots: map[common.Hash]common.Hash{slot: {}}},
+			},
+		}
+		empty = &txpool.Conditional{
+			KnownAccounts: map[common.Address]txpool.KnownAccount{
+				contract: {StorageRoot: &types.EmptyRootHash},
+			},
+		}
+	)
+	if err := b.txPool.AddConditional(tx, slots, true); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	env, err := w.prepareWork(&generateParams{
+		parentHash: b.chain.CurrentBlock().Hash(),
+		timestamp:  b.chain.CurrentBlock().Time + 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to prepare work: %v", err)
+	}
+	defer env.discard()
+
+	// Transactions not yet includable must be skipped, but kept in the pool
+	early := &txpool.Conditional{BlockNumberMin: env.header.Number.Uint64() + 1}
+	if w.checkConditional(env, tx.Hash(), early) {
+		t.Fatalf("conditional transaction includable before its block range")
+	}
+	if !b.txPool.Has(tx.Hash()) {
+		t.Fatalf("conditional transaction dropped before its block range")
+	}
+	env.state.SetNonce(contract, 1)
+	if !w.checkConditional(env, tx.Hash(), slots) || !w.checkConditional(env, tx.Hash(), empty) {
+		t.Fatalf("holding conditions rejected")
+	}
+	// Modify the storage in the pending state, both conditions must fail and
+	// the transaction must be dropped
+	env.state.SetState(contract, slot, common.Hash{0x02})
+	if w.checkConditional(env, tx.Hash(), empty) {
+		t.Fatalf("storage root condition accepted after the storage changed")
+	}
+	if w.checkConditional(env, tx.Hash(), slots) {
+		t.Fatalf("storage slot condition accepted after the storage changed")
+	}
+	if b.txPool.Has(tx.Hash()) {
+		t.Fatalf("failed conditional transaction not dropped")
+	}
+}
@@ -363,8 +363,9 @@ type ChainConfig struct {
 	TerminalTotalDifficultyPassed bool `json:"terminalTotalDifficultyPassed,omitempty"`
 
 	// Various consensus engines
-	Ethash *EthashConfig `json:"ethash,omitempty"`
-	Clique *CliqueConfig `json:"clique,omitempty"`
+	Ethash    *EthashConfig    `json:"ethash,omitempty"`
+	Clique    *CliqueConfig    `json:"clique,omitempty"`
+	Authority *AuthorityConfig `json:"authority,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -386,6 +387,19 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// AuthorityConfig is the consensus engine configs for permissioned proof-of-authority
+// sealing with a contract managed validator set and instant finality.
+type AuthorityConfig struct {
+	Period            uint64         `json:"period"`            // Number of seconds between blocks to enforce
+	Epoch             uint64         `json:"epoch"`             // Epoch length to refresh the validator set
+	ValidatorContract common.Address `json:"validatorContract"` // System contract holding the validator set
+}
+
+// String implements the stringer interface, returning the consensus engine details.
+func (c *AuthorityConfig) String() string {
+	return "authority"
+}
+
 // Description returns a human-readable description of ChainConfig.
 func (c *ChainConfig) Description() string {
 	var banner string
@@ -413,6 +427,8 @@ func (c *ChainConfig) Description() string {
 		} else {
 			banner += "Consensus: Beacon (proof-of-stake), merged from Clique (proof-of-authority)\n"
 		}
+	case c.Authority != nil:
+		banner += "Consensus: Authority (permissioned proof-of-authority)\n"
 	default:
 		banner += "Consensus: unknown\n"
 	}